@@ -0,0 +1,154 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/pedrotunin/go-jwt-auth/internal/config"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+
+	_ "github.com/lib/pq"
+)
+
+// output is what a command prints: Data as JSON with -json, or Text
+// otherwise.
+type output struct {
+	Data any
+	Text func(w io.Writer)
+}
+
+// setupFunc connects to the database and builds the services. Commands call
+// it once their flags are parsed, so '-h' works without a database.
+type setupFunc func() *config.AdminTools
+
+type command struct {
+	name        string
+	description string
+	run         func(args []string, setup setupFunc) (*output, error)
+}
+
+var commands = []command{
+	{"create-user", "create an active user", createUser},
+	{"activate-user", "verify a pending user or reactivate an inactive one", activateUser},
+	{"deactivate-user", "block a user from logging in and revoke their sessions", deactivateUser},
+	{"reset-password", "e-mail a password reset link to a user", resetPassword},
+	{"revoke-sessions", "revoke every session of a user", revokeSessions},
+	{"list-apps", "list apps, or the soft-deleted ones with -deleted", listApps},
+	{"restore-app", "restore a soft-deleted app", restoreApp},
+	{"stats", "print user, app, organization and refresh token counts", printStats},
+}
+
+// errUsage is returned by commands called with invalid flags. The flag
+// package already printed the details.
+var errUsage = errors.New("invalid usage")
+
+func usage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintln(w, "usage: admin [-json] [-verbose] <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-16s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "flags:")
+	flag.PrintDefaults()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "run 'admin <command> -h' for the flags of a command.")
+}
+
+func main() {
+	jsonOutput := flag.Bool("json", false, "print results and errors as JSON")
+	verbose := flag.Bool("verbose", false, "print the service logs to stderr")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == flag.Arg(0) {
+			cmd = &commands[i]
+		}
+	}
+
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	// The services log every step; only operators debugging want that.
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	// The .env file is optional here: the tool is also run with the env of
+	// a deployed API.
+	err := godotenv.Load(".env")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		fail(*jsonOutput, fmt.Errorf("error loading .env: %w", err))
+	}
+
+	var db *sql.DB
+	setup := func() *config.AdminTools {
+		db, err = config.OpenDB()
+		if err != nil {
+			fail(*jsonOutput, fmt.Errorf("error connecting to database: %w", err))
+		}
+
+		tools := &config.AdminTools{DB: db}
+		tools.Setup()
+		return tools
+	}
+
+	out, err := cmd.run(flag.Args()[1:], setup)
+	if db != nil {
+		db.Close()
+	}
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fail(*jsonOutput, err)
+	}
+
+	if *jsonOutput {
+		printJSON(os.Stdout, map[string]any{"data": out.Data})
+		return
+	}
+
+	out.Text(os.Stdout)
+}
+
+// fail prints err, as the API error body with -json, and exits.
+func fail(jsonOutput bool, err error) {
+	if jsonOutput {
+		printJSON(os.Stdout, utils.GetErrorResponse(err))
+	} else {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err.Error())
+	}
+
+	os.Exit(1)
+}
+
+func printJSON(w io.Writer, v any) {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	err := encoder.Encode(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: encoding output: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
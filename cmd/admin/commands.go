@@ -0,0 +1,437 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/config"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: admin %s [flags]\n\nflags:\n", name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil {
+		return errUsage
+	}
+
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected argument %q\n", fs.Arg(0))
+		fs.Usage()
+		return errUsage
+	}
+
+	return nil
+}
+
+// userFlags adds the -id and -email flags picking the user a command acts on.
+func userFlags(fs *flag.FlagSet) (id *int, email *string) {
+	id = fs.Int("id", 0, "id of the user")
+	email = fs.String("email", "", "e-mail of the user, instead of -id")
+	return id, email
+}
+
+func checkUserFlags(fs *flag.FlagSet, id int, email string) error {
+	if (id == 0) == (email == "") {
+		fmt.Fprintln(fs.Output(), "exactly one of -id and -email is required")
+		fs.Usage()
+		return errUsage
+	}
+
+	return nil
+}
+
+func findUser(tools *config.AdminTools, id int, email string) (*models.User, error) {
+	if email != "" {
+		return tools.UserService.GetUserByEmail(strings.TrimSpace(email))
+	}
+
+	return tools.AdminService.GetUser(id)
+}
+
+func userOutput(user *models.User, message string) *output {
+	return &output{
+		Data: user,
+		Text: func(w io.Writer) {
+			fmt.Fprintln(w, message)
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(tw, "id\t%d\n", user.ID)
+			fmt.Fprintf(tw, "email\t%s\n", user.Email)
+			fmt.Fprintf(tw, "status\t%s\n", user.Status)
+			fmt.Fprintf(tw, "created at\t%s\n", formatTime(user.CreatedAt))
+			tw.Flush()
+		},
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+// readPassword reads the password from the first line of stdin, which keeps
+// it out of the shell history.
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "password: ")
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func createUser(args []string, setup setupFunc) (*output, error) {
+	fs := newFlagSet("create-user")
+	email := fs.String("email", "", "e-mail of the new user")
+	password := fs.String("password", "", "password of the new user, read from stdin when empty")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if *email == "" {
+		fmt.Fprintln(fs.Output(), "-email is required")
+		fs.Usage()
+		return nil, errUsage
+	}
+
+	if *password == "" {
+		*password, err = readPassword()
+		if err != nil {
+			return nil, fmt.Errorf("error reading password: %w", err)
+		}
+	}
+
+	user, err := models.NewUser(strings.TrimSpace(*email), *password)
+	if err != nil {
+		return nil, err
+	}
+
+	tools := setup()
+
+	err = tools.PasswordPolicyService.Validate(user.Password, user.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	// Users created by an operator skip the e-mail verification.
+	user.Status = utils.UserStatusActive
+
+	err = tools.UserService.CreateUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err = tools.AdminService.GetUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return userOutput(user, "user created"), nil
+}
+
+func activateUser(args []string, setup setupFunc) (*output, error) {
+	fs := newFlagSet("activate-user")
+	id, email := userFlags(fs)
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkUserFlags(fs, *id, *email)
+	if err != nil {
+		return nil, err
+	}
+
+	tools := setup()
+
+	user, err := findUser(tools, *id, *email)
+	if err != nil {
+		return nil, err
+	}
+
+	switch user.Status {
+	case utils.UserStatusPending:
+		err = tools.AdminService.VerifyUser(user.ID)
+	case utils.UserStatusInactive:
+		err = tools.AdminService.ReactivateUser(user.ID)
+	default:
+		return nil, errors.New("user is already active")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err = tools.AdminService.GetUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return userOutput(user, "user activated"), nil
+}
+
+func deactivateUser(args []string, setup setupFunc) (*output, error) {
+	fs := newFlagSet("deactivate-user")
+	id, email := userFlags(fs)
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkUserFlags(fs, *id, *email)
+	if err != nil {
+		return nil, err
+	}
+
+	tools := setup()
+
+	user, err := findUser(tools, *id, *email)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tools.AdminService.DeactivateUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err = tools.AdminService.GetUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return userOutput(user, "user deactivated, sessions revoked"), nil
+}
+
+type passwordResetOutput struct {
+	UserID    models.UserID    `json:"user_id"`
+	Email     models.UserEmail `json:"email"`
+	ExpiresAt time.Time        `json:"expires_at"`
+	// ResetLink is only set with -print-link; otherwise it was e-mailed.
+	ResetLink string `json:"reset_link,omitempty"`
+}
+
+func resetPassword(args []string, setup setupFunc) (*output, error) {
+	fs := newFlagSet("reset-password")
+	id, email := userFlags(fs)
+	printLink := fs.Bool("print-link", false, "print the reset link instead of e-mailing it")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkUserFlags(fs, *id, *email)
+	if err != nil {
+		return nil, err
+	}
+
+	tools := setup()
+
+	user, err := findUser(tools, *id, *email)
+	if err != nil {
+		return nil, err
+	}
+
+	reset, err := tools.PasswordResetService.CreatePasswordReset(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := passwordResetOutput{
+		UserID:    reset.User.ID,
+		Email:     reset.User.Email,
+		ExpiresAt: reset.ExpiresAt,
+	}
+
+	if *printLink {
+		result.ResetLink = reset.Link
+	} else {
+		err = tools.PasswordResetService.SendPasswordResetEmail(reset)
+		if err != nil {
+			return nil, fmt.Errorf("error sending password reset e-mail: %w", err)
+		}
+	}
+
+	return &output{
+		Data: result,
+		Text: func(w io.Writer) {
+			if result.ResetLink != "" {
+				fmt.Fprintf(w, "password reset link for %s, valid until %s:\n%s\n", result.Email, formatTime(result.ExpiresAt), result.ResetLink)
+				return
+			}
+
+			fmt.Fprintf(w, "password reset e-mail sent to %s, valid until %s\n", result.Email, formatTime(result.ExpiresAt))
+		},
+	}, nil
+}
+
+func revokeSessions(args []string, setup setupFunc) (*output, error) {
+	fs := newFlagSet("revoke-sessions")
+	id, email := userFlags(fs)
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkUserFlags(fs, *id, *email)
+	if err != nil {
+		return nil, err
+	}
+
+	tools := setup()
+
+	user, err := findUser(tools, *id, *email)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tools.AdminService.RevokeSessions(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return userOutput(user, "sessions revoked"), nil
+}
+
+func listApps(args []string, setup setupFunc) (*output, error) {
+	fs := newFlagSet("list-apps")
+	deleted := fs.Bool("deleted", false, "list the soft-deleted apps instead of the live ones")
+	userID := fs.Int("user-id", 0, "only list the apps owned by this user")
+	limit := fs.Int("limit", 100, "maximum number of apps to list")
+	offset := fs.Int("offset", 0, "number of apps to skip")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if *limit < 1 || *offset < 0 {
+		fmt.Fprintln(fs.Output(), "-limit must be positive and -offset not negative")
+		fs.Usage()
+		return nil, errUsage
+	}
+
+	tools := setup()
+
+	apps, err := tools.AppService.ListApps(models.AppFilter{
+		UserID:  *userID,
+		Deleted: *deleted,
+		Limit:   *limit,
+		Offset:  *offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &output{
+		Data: apps,
+		Text: func(w io.Writer) {
+			if len(apps) == 0 {
+				fmt.Fprintln(w, "no apps found")
+				return
+			}
+
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tNAME\tOWNER\tORGANIZATION\tCREATED\tDELETED")
+			for _, app := range apps {
+				organization := "-"
+				if app.OrganizationID != nil {
+					organization = strconv.Itoa(*app.OrganizationID)
+				}
+
+				fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%s\t%s\n", app.ID, app.Name, app.UserID, organization, formatTime(app.CreatedAt), formatTime(app.DeletedAt))
+			}
+			tw.Flush()
+		},
+	}, nil
+}
+
+func restoreApp(args []string, setup setupFunc) (*output, error) {
+	fs := newFlagSet("restore-app")
+	id := fs.Int("id", 0, "id of the deleted app")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if *id == 0 {
+		fmt.Fprintln(fs.Output(), "-id is required")
+		fs.Usage()
+		return nil, errUsage
+	}
+
+	tools := setup()
+
+	err = tools.AppService.RestoreApp(*id)
+	if err != nil {
+		return nil, err
+	}
+
+	app, err := tools.AppService.GetAppByID(*id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &output{
+		Data: app,
+		Text: func(w io.Writer) {
+			fmt.Fprintf(w, "app %d (%s) restored\n", app.ID, app.Name)
+		},
+	}, nil
+}
+
+func printStats(args []string, setup setupFunc) (*output, error) {
+	fs := newFlagSet("stats")
+
+	err := parseFlags(fs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	tools := setup()
+
+	stats, err := tools.StatsService.GetStats()
+	if err != nil {
+		return nil, err
+	}
+
+	return &output{
+		Data: stats,
+		Text: func(w io.Writer) {
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(tw, "users\t%d\t(%d active, %d pending, %d inactive)\n", stats.Users.Total, stats.Users.Active, stats.Users.Pending, stats.Users.Inactive)
+			fmt.Fprintf(tw, "apps\t%d\t(%d deleted)\n", stats.Apps.Total, stats.Apps.Deleted)
+			fmt.Fprintf(tw, "organizations\t%d\t\n", stats.Organizations)
+			fmt.Fprintf(tw, "active refresh tokens\t%d\t\n", stats.ActiveRefreshTokens)
+			tw.Flush()
+		},
+	}, nil
+}
@@ -1,10 +1,11 @@
 package main
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -23,23 +24,7 @@ func main() {
 
 	log.Print("connecting to database")
 
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
-
-	psqlInfo := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		dbHost, dbPort, dbUser, dbPassword, dbName,
-	)
-
-	db, err := sql.Open("postgres", psqlInfo)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	err = db.Ping()
+	db, err := config.OpenDB()
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -55,11 +40,24 @@ func main() {
 	router := gin.Default()
 	router.Use(gin.Recovery())
 
+	// Rate limits are keyed by client IP, so X-Forwarded-For is only
+	// trusted when it comes from one of our own proxies.
+	var trustedProxies []string
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		trustedProxies = strings.Split(proxies, ",")
+	}
+
+	err = router.SetTrustedProxies(trustedProxies)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	app := &config.Application{
 		Router: router,
 		DB:     db,
 	}
 	app.Setup()
+	app.StartJobs(context.Background())
 
 	port := os.Getenv("PORT")
 
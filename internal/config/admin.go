@@ -0,0 +1,62 @@
+package config
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+)
+
+// AdminTools holds the services behind the admin command line tool. They are
+// built from the same env vars as the API, so the passwords it hashes and the
+// reset links it sends work there.
+type AdminTools struct {
+	DB *sql.DB
+
+	UserService           services.IUserService
+	AdminService          services.IAdminService
+	PasswordPolicyService services.IPasswordPolicyService
+	PasswordResetService  services.IPasswordResetService
+	AppService            services.IAppService
+	StatsService          services.IStatsService
+}
+
+func (tools *AdminTools) Setup() {
+	baseURL := getEnv("APP_BASE_URL", "http://localhost:8080")
+	passwordResetURL := getEnv("PASSWORD_RESET_URL", baseURL+"/reset-password")
+	passwordResetTTL := getEnvDuration("PASSWORD_RESET_TTL", time.Hour)
+
+	userRepository := repositories.NewPSQLUserRepository(tools.DB)
+	refreshTokenRepository := repositories.NewPSQLRefreshTokenRepository(tools.DB)
+	passwordResetTokenRepository := repositories.NewPSQLPasswordResetTokenRepository(tools.DB)
+	appRepository := repositories.NewPSQLAppRepository(tools.DB)
+	appMemberRepository := repositories.NewPSQLAppMemberRepository(tools.DB)
+	statsRepository := repositories.NewPSQLStatsRepository(tools.DB)
+
+	mailerService := services.NewSendGridMailerService(
+		os.Getenv("SENDGRID_SENDER_NAME"),
+		os.Getenv("SENDGRID_SENDER_EMAIL"),
+		os.Getenv("SENDGRID_API_KEY"),
+	)
+	hashService := newHashService()
+	tools.PasswordPolicyService = newPasswordPolicyService()
+	tools.UserService = services.NewUserService(userRepository, hashService)
+	tools.AdminService = services.NewAdminService(userRepository, refreshTokenRepository)
+	tools.PasswordResetService = services.NewPasswordResetService(
+		passwordResetTokenRepository,
+		userRepository,
+		refreshTokenRepository,
+		hashService,
+		tools.PasswordPolicyService,
+		mailerService,
+		passwordResetURL,
+		passwordResetTTL,
+	)
+	tools.AppService = services.NewAppService(appRepository, appMemberRepository, nil)
+	tools.StatsService = services.NewStatsService(statsRepository)
+
+	log.Print("finished admin tools setup")
+}
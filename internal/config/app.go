@@ -1,21 +1,31 @@
 package config
 
 import (
+	"context"
 	"database/sql"
+	"encoding/base64"
 	"log"
 	"os"
+	"time"
 
+	"github.com/alexedwards/argon2id"
 	"github.com/gin-gonic/gin"
 	"github.com/pedrotunin/go-jwt-auth/internal/controllers"
+	"github.com/pedrotunin/go-jwt-auth/internal/jobs"
 	"github.com/pedrotunin/go-jwt-auth/internal/middlewares"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
 	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
 	"github.com/pedrotunin/go-jwt-auth/internal/routes"
 	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+	"github.com/pedrotunin/go-jwt-auth/internal/validators"
 )
 
 type Application struct {
 	Router gin.IRouter
 	DB     *sql.DB
+
+	jobs []jobs.Job
 }
 
 func (app *Application) Setup() {
@@ -31,11 +41,98 @@ func (app *Application) Setup() {
 		log.Panic("JWT_REFRESH_TOKEN_SECRET env var not found")
 	}
 
+	tokenTTL := getEnvDuration("JWT_TOKEN_TTL", 5*time.Minute)
+	refreshTokenTTL := getEnvDuration("JWT_REFRESH_TOKEN_TTL", 7*24*time.Hour)
+
+	encryptionKey := getEncryptionKey()
+	mfaIssuer := getEnv("MFA_ISSUER", "go-jwt-auth")
+	mfaMaxAttempts := getEnvInt("MFA_MAX_ATTEMPTS", 5)
+
+	baseURL := getEnv("APP_BASE_URL", "http://localhost:8080")
+	adminEmails := getEnvList("ADMIN_EMAILS")
+	passwordResetURL := getEnv("PASSWORD_RESET_URL", baseURL+"/reset-password")
+	passwordResetTTL := getEnvDuration("PASSWORD_RESET_TTL", time.Hour)
+	impersonationTokenTTL := getEnvDuration("IMPERSONATION_TOKEN_TTL", 15*time.Minute)
+	invitationURL := getEnv("INVITATION_URL", baseURL+"/accept-invitation")
+	invitationTTL := getEnvDuration("INVITATION_TTL", 7*24*time.Hour)
+	appTransferURL := getEnv("APP_TRANSFER_URL", baseURL+"/accept-app-transfer")
+	appTransferTTL := getEnvDuration("APP_TRANSFER_TTL", 7*24*time.Hour)
+	scimToken := os.Getenv("SCIM_TOKEN")
+	webAuthnConfig := services.WebAuthnConfig{
+		RPID:         getEnv("WEBAUTHN_RP_ID", "localhost"),
+		RPName:       getEnv("WEBAUTHN_RP_NAME", "go-jwt-auth"),
+		Origin:       getEnv("WEBAUTHN_ORIGIN", baseURL),
+		ChallengeTTL: getEnvDuration("WEBAUTHN_CHALLENGE_TTL", 5*time.Minute),
+	}
+	dataExportLinkTTL := getEnvDuration("DATA_EXPORT_LINK_TTL", 24*time.Hour)
+	magicLinkURL := getEnv("MAGIC_LINK_URL", baseURL+"/magic-link")
+	magicLinkTTL := getEnvDuration("MAGIC_LINK_TTL", 15*time.Minute)
+	magicLinkSameBrowser := getEnvBool("MAGIC_LINK_SAME_BROWSER", false)
+	emailOTPTTL := getEnvDuration("EMAIL_OTP_TTL", 10*time.Minute)
+	emailOTPMaxAttempts := getEnvInt("EMAIL_OTP_MAX_ATTEMPTS", 5)
+	accountLockoutConfig := services.AccountLockoutConfig{
+		Threshold:   getEnvInt("LOGIN_LOCKOUT_THRESHOLD", 5),
+		Duration:    getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		BackoffBase: getEnvDuration("LOGIN_BACKOFF_BASE", time.Second),
+	}
+
+	accountDeletionGracePeriod := getEnvDuration("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour)
+	accountDeletionJobInterval := getEnvDuration("ACCOUNT_DELETION_JOB_INTERVAL", time.Hour)
+	accountDeletionMode := getEnv("ACCOUNT_DELETION_MODE", utils.AccountDeletionModeDelete)
+	if accountDeletionMode != utils.AccountDeletionModeDelete && accountDeletionMode != utils.AccountDeletionModeAnonymize {
+		log.Panicf("ACCOUNT_DELETION_MODE env var must be %q or %q", utils.AccountDeletionModeDelete, utils.AccountDeletionModeAnonymize)
+	}
+
+	rateLimitStore := getEnv("RATE_LIMIT_STORE", "memory")
+	loginIPRateLimit := getEnvRateLimit("RATE_LIMIT_LOGIN_IP", models.RateLimit{Burst: 20, Interval: time.Minute})
+	loginAccountRateLimit := getEnvRateLimit("RATE_LIMIT_LOGIN_ACCOUNT", models.RateLimit{Burst: 5, Interval: time.Minute})
+	refreshIPRateLimit := getEnvRateLimit("RATE_LIMIT_REFRESH_IP", models.RateLimit{Burst: 30, Interval: time.Minute})
+	signupIPRateLimit := getEnvRateLimit("RATE_LIMIT_SIGNUP_IP", models.RateLimit{Burst: 10, Interval: time.Hour})
+	emailIPRateLimit := getEnvRateLimit("RATE_LIMIT_EMAIL_IP", models.RateLimit{Burst: 10, Interval: time.Hour})
+	emailAccountRateLimit := getEnvRateLimit("RATE_LIMIT_EMAIL_ACCOUNT", models.RateLimit{Burst: 3, Interval: 15 * time.Minute})
+	rateLimitBucketMaxAge := max(
+		loginIPRateLimit.Interval,
+		loginAccountRateLimit.Interval,
+		refreshIPRateLimit.Interval,
+		signupIPRateLimit.Interval,
+		emailIPRateLimit.Interval,
+		emailAccountRateLimit.Interval,
+	)
+
 	// Setup repositories
 	userRepository := repositories.NewPSQLUserRepository(app.DB)
 	refreshTokenRepository := repositories.NewPSQLRefreshTokenRepository(app.DB)
 	evtRepository := repositories.NewPSQLEmailVerificationTokenRepository(app.DB)
 	appRepository := repositories.NewPSQLAppRepository(app.DB)
+	dataExportRepository := repositories.NewPSQLDataExportRepository(app.DB)
+	totpCredentialRepository := repositories.NewPSQLTOTPCredentialRepository(app.DB)
+	mfaRecoveryCodeRepository := repositories.NewPSQLMFARecoveryCodeRepository(app.DB)
+	mfaChallengeRepository := repositories.NewPSQLMFAChallengeRepository(app.DB)
+	webAuthnCredentialRepository := repositories.NewPSQLWebAuthnCredentialRepository(app.DB)
+	webAuthnChallengeRepository := repositories.NewPSQLWebAuthnChallengeRepository(app.DB)
+	magicLinkTokenRepository := repositories.NewPSQLMagicLinkTokenRepository(app.DB)
+	emailOTPCodeRepository := repositories.NewPSQLEmailOTPCodeRepository(app.DB)
+	accountLockoutRepository := repositories.NewPSQLAccountLockoutRepository(app.DB)
+	loginEventRepository := repositories.NewPSQLLoginEventRepository(app.DB)
+	passwordResetTokenRepository := repositories.NewPSQLPasswordResetTokenRepository(app.DB)
+	rbacRepository := repositories.NewPSQLRBACRepository(app.DB)
+	impersonationEventRepository := repositories.NewPSQLImpersonationEventRepository(app.DB)
+	invitationRepository := repositories.NewPSQLInvitationRepository(app.DB)
+	organizationRepository := repositories.NewPSQLOrganizationRepository(app.DB)
+	appMemberRepository := repositories.NewPSQLAppMemberRepository(app.DB)
+	appTransferRepository := repositories.NewPSQLAppTransferRepository(app.DB)
+	appUserPoolRepository := repositories.NewPSQLAppUserPoolRepository(app.DB)
+	appUserRepository := repositories.NewPSQLAppUserRepository(app.DB)
+
+	var rateLimitRepository repositories.RateLimitRepository
+	switch rateLimitStore {
+	case "memory":
+		rateLimitRepository = repositories.NewMemoryRateLimitRepository()
+	case "postgres":
+		rateLimitRepository = repositories.NewPSQLRateLimitRepository(app.DB)
+	default:
+		log.Panic("RATE_LIMIT_STORE env var must be \"memory\" or \"postgres\"")
+	}
 
 	// Setup services
 	sendGridMailerService := services.NewSendGridMailerService(
@@ -43,41 +140,325 @@ func (app *Application) Setup() {
 		os.Getenv("SENDGRID_SENDER_EMAIL"),
 		os.Getenv("SENDGRID_API_KEY"),
 	)
-	hashService := services.NewHashService()
-	jwtService := services.NewJWTService(tokenSecret, refreshTokenSecret, refreshTokenRepository, hashService)
+	hashService := newHashService()
+	encryptionService, err := services.NewAESGCMEncryptionService(encryptionKey)
+	if err != nil {
+		log.Panicf("error creating encryption service: %s", err.Error())
+	}
+	passwordPolicyService := newPasswordPolicyService()
+	rbacService := services.NewRBACService(rbacRepository, userRepository, adminEmails)
+	invitationService := services.NewInvitationService(
+		invitationRepository,
+		userRepository,
+		rbacRepository,
+		organizationRepository,
+		appMemberRepository,
+		hashService,
+		passwordPolicyService,
+		invitationTTL,
+	)
+	organizationService := services.NewOrganizationService(organizationRepository, invitationService)
+	jwtService := services.NewJWTService(
+		tokenSecret,
+		refreshTokenSecret,
+		tokenTTL,
+		refreshTokenTTL,
+		refreshTokenRepository,
+		hashService,
+		rbacService,
+		organizationService,
+	)
 	userService := services.NewUserService(userRepository, hashService)
 	evtService := services.NewEmailVerificationTokenService(evtRepository)
-	appService := services.NewAppService(appRepository)
+	appService := services.NewAppService(appRepository, appMemberRepository, invitationService)
+	appTransferService := services.NewAppTransferService(
+		appTransferRepository,
+		appRepository,
+		userRepository,
+		organizationRepository,
+		hashService,
+		appTransferTTL,
+	)
+	appUserPoolService := services.NewAppUserPoolService(
+		appUserPoolRepository,
+		appUserRepository,
+		hashService,
+		passwordPolicyService,
+		tokenSecret,
+		refreshTokenSecret,
+		tokenTTL,
+		refreshTokenTTL,
+	)
+	scimService := services.NewSCIMService(
+		userRepository,
+		rbacRepository,
+		refreshTokenRepository,
+		hashService,
+		passwordPolicyService,
+	)
+	mfaService := services.NewMFAService(
+		totpCredentialRepository,
+		mfaRecoveryCodeRepository,
+		webAuthnCredentialRepository,
+		mfaChallengeRepository,
+		encryptionService,
+		hashService,
+		mfaIssuer,
+		mfaMaxAttempts,
+	)
+	webAuthnService := services.NewWebAuthnService(
+		webAuthnCredentialRepository,
+		webAuthnChallengeRepository,
+		userRepository,
+		hashService,
+		mfaService,
+		webAuthnConfig,
+	)
+	magicLinkService := services.NewMagicLinkService(
+		magicLinkTokenRepository,
+		userRepository,
+		hashService,
+		tokenSecret,
+		magicLinkTTL,
+		magicLinkSameBrowser,
+	)
+	emailOTPService := services.NewEmailOTPService(
+		emailOTPCodeRepository,
+		userRepository,
+		tokenSecret,
+		emailOTPTTL,
+		emailOTPMaxAttempts,
+	)
+	accountLockoutService := services.NewAccountLockoutService(accountLockoutRepository, hashService, accountLockoutConfig)
+	loginHistoryService := services.NewLoginHistoryService(loginEventRepository)
+	adminService := services.NewAdminService(userRepository, refreshTokenRepository)
+	passwordResetService := services.NewPasswordResetService(
+		passwordResetTokenRepository,
+		userRepository,
+		refreshTokenRepository,
+		hashService,
+		passwordPolicyService,
+		sendGridMailerService,
+		passwordResetURL,
+		passwordResetTTL,
+	)
+	impersonationService := services.NewImpersonationService(
+		impersonationEventRepository,
+		userRepository,
+		jwtService,
+		impersonationTokenTTL,
+	)
+	accountDeletionService := services.NewAccountDeletionService(
+		userRepository,
+		refreshTokenRepository,
+		hashService,
+		accountDeletionGracePeriod,
+		accountDeletionMode,
+	)
+	dataExportService := services.NewDataExportService(
+		dataExportRepository,
+		userRepository,
+		appRepository,
+		refreshTokenRepository,
+		evtRepository,
+		loginEventRepository,
+		totpCredentialRepository,
+		mfaRecoveryCodeRepository,
+		webAuthnCredentialRepository,
+		organizationRepository,
+		invitationRepository,
+		appMemberRepository,
+		hashService,
+		dataExportLinkTTL,
+	)
 
 	// Setup controllers
 	authController := &controllers.AuthController{
-		UserService: userService,
-		HashService: hashService,
-		JWTService:  jwtService,
+		UserService:           userService,
+		HashService:           hashService,
+		JWTService:            jwtService,
+		MFAService:            mfaService,
+		WebAuthnService:       webAuthnService,
+		MagicLinkService:      magicLinkService,
+		EmailOTPService:       emailOTPService,
+		AccountLockoutService: accountLockoutService,
+		LoginHistoryService:   loginHistoryService,
+		PasswordResetService:  passwordResetService,
+		MailerService:         sendGridMailerService,
+		BaseURL:               baseURL,
+		MagicLinkURL:          magicLinkURL,
 	}
-	userController := controllers.NewUserController(userService, evtService, sendGridMailerService)
+	userController := controllers.NewUserController(
+		userService,
+		evtService,
+		sendGridMailerService,
+		accountDeletionService,
+		mfaService,
+		passwordPolicyService,
+		loginHistoryService,
+	)
 	appController := &controllers.AppController{
-		AppService: appService,
+		AppService:          appService,
+		OrganizationService: organizationService,
+		MailerService:       sendGridMailerService,
+		InvitationURL:       invitationURL,
 	}
+	dataExportController := controllers.NewDataExportController(dataExportService, userService, sendGridMailerService, baseURL)
+	mfaController := controllers.NewMFAController(mfaService, userService)
+	webAuthnController := controllers.NewWebAuthnController(webAuthnService, userService)
+	adminController := controllers.NewAdminController(
+		adminService,
+		passwordResetService,
+		impersonationService,
+	)
+	roleController := controllers.NewRoleController(rbacService)
+	invitationController := controllers.NewInvitationController(invitationService, sendGridMailerService, invitationURL)
+	organizationController := controllers.NewOrganizationController(
+		organizationService,
+		jwtService,
+		sendGridMailerService,
+		invitationURL,
+	)
+
+	appTransferController := controllers.NewAppTransferController(
+		appTransferService,
+		appService,
+		organizationService,
+		sendGridMailerService,
+		appTransferURL,
+	)
+	appUserPoolController := controllers.NewAppUserPoolController(appUserPoolService, appService, organizationService)
+	scimController := controllers.NewSCIMController(scimService, baseURL)
 
 	// Setup middlewares
 	authenticatedUserMiddleware := middlewares.NewAuthenticatedUserMiddleware(jwtService)
+	permissionMiddleware := middlewares.NewPermissionMiddleware()
+	impersonationMiddleware := middlewares.NewImpersonationMiddleware()
 	loggerMiddleware := middlewares.NewLoggerMiddleware()
+	provisioningMiddleware := middlewares.NewProvisioningMiddleware(scimToken)
+	rateLimitMiddleware := middlewares.NewRateLimitMiddleware(rateLimitRepository, map[string][]middlewares.RateLimitRule{
+		"login": {
+			{Name: "ip", Key: middlewares.KeyByIP, Limit: loginIPRateLimit},
+			{Name: "account", Key: middlewares.KeyByAccount, Limit: loginAccountRateLimit},
+		},
+		"pool-login": {
+			{Name: "ip", Key: middlewares.KeyByIP, Limit: loginIPRateLimit},
+			{Name: "account", Key: middlewares.KeyByPoolAccount, Limit: loginAccountRateLimit},
+		},
+		"refresh": {
+			{Name: "ip", Key: middlewares.KeyByIP, Limit: refreshIPRateLimit},
+		},
+		"signup": {
+			{Name: "ip", Key: middlewares.KeyByIP, Limit: signupIPRateLimit},
+		},
+		"email": {
+			{Name: "ip", Key: middlewares.KeyByIP, Limit: emailIPRateLimit},
+			{Name: "account", Key: middlewares.KeyByAccount, Limit: emailAccountRateLimit},
+		},
+	})
 
 	// Setup Routes
 	routes := &routes.Routes{
 		Router: app.Router,
 		Middlewares: &middlewares.Middlewares{
 			AuthenticatedUserMiddleware: authenticatedUserMiddleware,
+			PermissionMiddleware:        permissionMiddleware,
+			ImpersonationMiddleware:     impersonationMiddleware,
 			LoggerMiddleware:            loggerMiddleware,
+			RateLimitMiddleware:         rateLimitMiddleware,
+			ProvisioningMiddleware:      provisioningMiddleware,
 		},
 		Controllers: &controllers.Controllers{
-			AuthController: authController,
-			UserController: userController,
-			AppController:  appController,
+			AuthController:         authController,
+			UserController:         userController,
+			AppController:          appController,
+			DataExportController:   dataExportController,
+			MFAController:          mfaController,
+			WebAuthnController:     webAuthnController,
+			AdminController:        adminController,
+			RoleController:         roleController,
+			InvitationController:   invitationController,
+			OrganizationController: organizationController,
+			AppTransferController:  appTransferController,
+			AppUserPoolController:  appUserPoolController,
+			SCIMController:         scimController,
 		},
 	}
 	routes.Setup()
 
+	// Setup jobs
+	app.jobs = []jobs.Job{
+		jobs.NewAccountDeletionJob(accountDeletionService, accountDeletionJobInterval),
+		jobs.NewRateLimitCleanupJob(rateLimitRepository, 10*time.Minute, rateLimitBucketMaxAge),
+	}
+
 	log.Print("finished app setup")
 }
+
+// StartJobs starts the background jobs registered during Setup. They stop
+// when ctx is cancelled.
+func (app *Application) StartJobs(ctx context.Context) {
+	for _, job := range app.jobs {
+		job.Start(ctx)
+	}
+
+	log.Printf("started %d background jobs", len(app.jobs))
+}
+
+// newHashService builds the password hashing service from the ARGON2ID_* and
+// PASSWORD_PEPPERS env vars.
+func newHashService() services.IHashService {
+	argon2idParams := &argon2id.Params{
+		Memory:      uint32(getEnvInt("ARGON2ID_MEMORY", 64*1024)),
+		Iterations:  uint32(getEnvInt("ARGON2ID_ITERATIONS", 1)),
+		Parallelism: uint8(getEnvInt("ARGON2ID_PARALLELISM", 2)),
+		SaltLength:  uint32(getEnvInt("ARGON2ID_SALT_LENGTH", 16)),
+		KeyLength:   uint32(getEnvInt("ARGON2ID_KEY_LENGTH", 32)),
+	}
+	passwordPeppers := getEnvPasswordPeppers("PASSWORD_PEPPERS")
+
+	return services.NewHashService(argon2idParams, passwordPeppers...)
+}
+
+// newPasswordPolicyService builds the password policy from the PASSWORD_*
+// env vars, with the breached password list when BREACHED_PASSWORDS_PATH is
+// set.
+func newPasswordPolicyService() services.IPasswordPolicyService {
+	passwordPolicy := validators.PasswordPolicy{
+		MinLength:     getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		MaxLength:     getEnvInt("PASSWORD_MAX_LENGTH", 128),
+		RequireUpper:  getEnvBool("PASSWORD_REQUIRE_UPPERCASE", false),
+		RequireLower:  getEnvBool("PASSWORD_REQUIRE_LOWERCASE", false),
+		RequireDigit:  getEnvBool("PASSWORD_REQUIRE_DIGIT", false),
+		RequireSymbol: getEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+		MinStrength:   getEnvInt("PASSWORD_MIN_STRENGTH", 2),
+	}
+
+	var breachedPasswordChecker services.IBreachedPasswordChecker
+	if breachedPasswordsPath := os.Getenv("BREACHED_PASSWORDS_PATH"); breachedPasswordsPath != "" {
+		var err error
+		breachedPasswordChecker, err = services.NewBreachedPasswordChecker(breachedPasswordsPath)
+		if err != nil {
+			log.Panicf("error loading breached passwords: %s", err.Error())
+		}
+	}
+
+	return services.NewPasswordPolicyService(passwordPolicy, breachedPasswordChecker)
+}
+
+// getEncryptionKey reads the base64 encoded 32 byte ENCRYPTION_KEY. It is kept
+// apart from the JWT secrets, so rotating or leaking those never exposes the
+// stored TOTP secrets.
+func getEncryptionKey() []byte {
+	encoded := os.Getenv("ENCRYPTION_KEY")
+	if encoded == "" {
+		log.Panic("ENCRYPTION_KEY env var not found")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		log.Panic("ENCRYPTION_KEY env var must be 32 bytes encoded in base64")
+	}
+
+	return key
+}
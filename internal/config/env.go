@@ -0,0 +1,133 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+)
+
+func getEnv(key, fallback string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Panicf("%s env var is not a valid duration: %s", key, err.Error())
+	}
+
+	return duration
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Panicf("%s env var is not a valid boolean: %s", key, err.Error())
+	}
+
+	return b
+}
+
+func getEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		log.Panicf("%s env var is not a valid integer: %s", key, err.Error())
+	}
+
+	return i
+}
+
+// getEnvRateLimit reads a rate limit written as "<requests>/<interval>",
+// e.g. "5/1m" for five requests per minute.
+func getEnvRateLimit(key string, fallback models.RateLimit) models.RateLimit {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	burst, interval, found := strings.Cut(value, "/")
+	if !found {
+		log.Panicf("%s env var must be written as <requests>/<interval>", key)
+	}
+
+	b, err := strconv.Atoi(burst)
+	if err != nil || b <= 0 {
+		log.Panicf("%s env var has an invalid number of requests", key)
+	}
+
+	d, err := time.ParseDuration(interval)
+	if err != nil || d <= 0 {
+		log.Panicf("%s env var has an invalid interval", key)
+	}
+
+	return models.RateLimit{Burst: b, Interval: d}
+}
+
+// getEnvList splits a comma-separated env var, dropping empty items.
+func getEnvList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// getEnvPasswordPeppers parses a comma-separated list of <version>:<secret>
+// pairs. The first pair is the current pepper.
+func getEnvPasswordPeppers(key string) []services.PasswordPepper {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	var peppers []services.PasswordPepper
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		version, secret, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || version == "" || strings.Contains(version, "$") {
+			log.Panicf("%s env var must be written as <version>:<secret>[,<version>:<secret>...]", key)
+		}
+
+		if len(secret) < 16 {
+			log.Panicf("%s env var pepper %q must be at least 16 characters long", key, version)
+		}
+
+		if seen[version] {
+			log.Panicf("%s env var has duplicated pepper version %q", key, version)
+		}
+		seen[version] = true
+
+		peppers = append(peppers, services.PasswordPepper{Version: version, Secret: []byte(secret)})
+	}
+
+	return peppers
+}
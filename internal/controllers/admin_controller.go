@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IAdminController interface {
+	ListUsers(c *gin.Context)
+	GetUser(c *gin.Context)
+	DeactivateUser(c *gin.Context)
+	ReactivateUser(c *gin.Context)
+	VerifyUser(c *gin.Context)
+	ResetUserPassword(c *gin.Context)
+	ImpersonateUser(c *gin.Context)
+	GetUserImpersonations(c *gin.Context)
+}
+
+type AdminController struct {
+	adminService         services.IAdminService
+	passwordResetService services.IPasswordResetService
+	impersonationService services.IImpersonationService
+}
+
+func NewAdminController(
+	adminService services.IAdminService,
+	passwordResetService services.IPasswordResetService,
+	impersonationService services.IImpersonationService,
+) IAdminController {
+	return &AdminController{
+		adminService:         adminService,
+		passwordResetService: passwordResetService,
+		impersonationService: impersonationService,
+	}
+}
+
+// adminUserStatus maps errors of the admin user operations to a response.
+func adminUserStatus(err error) (int, error) {
+	switch {
+	case errors.Is(err, utils.ErrUserNotFound):
+		return http.StatusNotFound, utils.ErrUserNotFound
+	case errors.Is(err, utils.ErrUserNotPending), errors.Is(err, utils.ErrUserNotInactive), errors.Is(err, utils.ErrUserInactive):
+		return http.StatusConflict, err
+	case errors.Is(err, utils.ErrImpersonationSelf), errors.Is(err, utils.ErrImpersonationReasonInvalid):
+		return http.StatusBadRequest, err
+	default:
+		return http.StatusInternalServerError, utils.ErrInternalServerError
+	}
+}
+
+func (ac *AdminController) ListUsers(c *gin.Context) {
+	page, pageSize, err := getPagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(err))
+		return
+	}
+
+	status := c.Query("status")
+	if status != "" && status != utils.UserStatusActive && status != utils.UserStatusInactive && status != utils.UserStatusPending {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrInvalidUserStatus))
+		return
+	}
+
+	users, total, err := ac.adminService.ListUsers(models.UserFilter{
+		Status: status,
+		Email:  c.Query("email"),
+		Limit:  pageSize,
+		Offset: (page - 1) * pageSize,
+	})
+	if err != nil {
+		log.Printf("ListUsers: error listing users: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": map[string]any{
+			"users":     users,
+			"page":      page,
+			"page_size": pageSize,
+			"total":     total,
+		},
+	})
+}
+
+func (ac *AdminController) GetUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Printf("GetUser: invalid userID: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrInvalidUserID))
+		return
+	}
+
+	user, err := ac.adminService.GetUser(userID)
+	if err != nil {
+		log.Printf("GetUser: error getting user: %s", err.Error())
+		status, err := adminUserStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": user,
+	})
+}
+
+func (ac *AdminController) DeactivateUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Printf("DeactivateUser: invalid userID: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrInvalidUserID))
+		return
+	}
+
+	err = ac.adminService.DeactivateUser(userID)
+	if err != nil {
+		log.Printf("DeactivateUser: error deactivating user: %s", err.Error())
+		status, err := adminUserStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"message": "user deactivated",
+	})
+}
+
+func (ac *AdminController) ReactivateUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Printf("ReactivateUser: invalid userID: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrInvalidUserID))
+		return
+	}
+
+	err = ac.adminService.ReactivateUser(userID)
+	if err != nil {
+		log.Printf("ReactivateUser: error reactivating user: %s", err.Error())
+		status, err := adminUserStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"message": "user reactivated",
+	})
+}
+
+func (ac *AdminController) VerifyUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Printf("VerifyUser: invalid userID: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrInvalidUserID))
+		return
+	}
+
+	err = ac.adminService.VerifyUser(userID)
+	if err != nil {
+		log.Printf("VerifyUser: error verifying user: %s", err.Error())
+		status, err := adminUserStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"message": "user verified",
+	})
+}
+
+// ResetUserPassword e-mails the user a link to choose a new password.
+func (ac *AdminController) ResetUserPassword(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Printf("ResetUserPassword: invalid userID: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrInvalidUserID))
+		return
+	}
+
+	reset, err := ac.passwordResetService.CreatePasswordReset(userID)
+	if err != nil {
+		log.Printf("ResetUserPassword: error creating password reset: %s", err.Error())
+		status, err := adminUserStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	err = ac.passwordResetService.SendPasswordResetEmail(reset)
+	if err != nil {
+		log.Printf("ResetUserPassword: error sending password reset email: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, map[string]string{
+		"message": "password reset e-mail sent",
+	})
+}
+
+type impersonateUserDTO struct {
+	Reason string `json:"reason"`
+}
+
+// ImpersonateUser issues a short-lived access token for the user on behalf of
+// the authenticated admin. The reason is kept in the audit log.
+func (ac *AdminController) ImpersonateUser(c *gin.Context) {
+	actorID, exists := c.Get("userID")
+	if !exists {
+		log.Print("ImpersonateUser: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Printf("ImpersonateUser: invalid userID: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrInvalidUserID))
+		return
+	}
+
+	var impersonateDTO impersonateUserDTO
+
+	err = c.ShouldBindJSON(&impersonateDTO)
+	if err != nil {
+		log.Printf("ImpersonateUser: error during binding impersonateUserDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	impersonation, err := ac.impersonationService.Impersonate(
+		actorID.(models.UserID),
+		userID,
+		impersonateDTO.Reason,
+		c.ClientIP(),
+		c.Request.UserAgent(),
+	)
+	if err != nil {
+		log.Printf("ImpersonateUser: error impersonating user: %s", err.Error())
+		status, err := adminUserStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": map[string]any{
+			"token":      impersonation.Token,
+			"expires_at": impersonation.ExpiresAt,
+			"user":       impersonation.User,
+		},
+	})
+}
+
+func (ac *AdminController) GetUserImpersonations(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Printf("GetUserImpersonations: invalid userID: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrInvalidUserID))
+		return
+	}
+
+	page, pageSize, err := getPagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(err))
+		return
+	}
+
+	events, total, err := ac.impersonationService.GetImpersonations(userID, page, pageSize)
+	if err != nil {
+		log.Printf("GetUserImpersonations: error getting impersonations: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": map[string]any{
+			"impersonations": events,
+			"page":           page,
+			"page_size":      pageSize,
+			"total":          total,
+		},
+	})
+}
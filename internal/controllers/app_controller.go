@@ -19,73 +19,198 @@ type IAppController interface {
 	Create(c *gin.Context)
 	Update(c *gin.Context)
 	DeleteByID(c *gin.Context)
+	GetMembers(c *gin.Context)
+	AddMember(c *gin.Context)
+	RemoveMember(c *gin.Context)
 }
 
 type AppController struct {
-	AppService services.IAppService
+	AppService          services.IAppService
+	OrganizationService services.IOrganizationService
+	MailerService       services.MailerService
+	InvitationURL       string
 }
 
-func (ac *AppController) GetAll(c *gin.Context) {
-	userID, exists := c.Get("userID")
+// requestOrganizationID returns the active organization of the access token,
+// nil in the personal context.
+func requestOrganizationID(c *gin.Context) *models.OrganizationID {
+	value, exists := c.Get("claims")
 	if !exists {
-		log.Print("GetAll: userID value do not exists in context")
-		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
-		return
+		return nil
+	}
+
+	return value.(*services.TokenClaims).OrganizationID
+}
+
+func sameOrganization(a, b *models.OrganizationID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return *a == *b
+}
+
+// appRole resolves the role userID holds on app. Apps are only visible from
+// the context that owns them: personal apps outside any organization,
+// organization apps with that organization active. On organization apps,
+// owners and admins of the organization act as app owners and its members as
+// editors, unless they were given a higher role as collaborators.
+func (ac *AppController) appRole(c *gin.Context, app *models.App, userID models.UserID) (models.AppRole, int, error) {
+	if !sameOrganization(app.OrganizationID, requestOrganizationID(c)) {
+		return "", http.StatusNotFound, utils.ErrAppNotFound
+	}
+
+	role, err := ac.AppService.GetAppRole(app.ID, userID)
+	if err != nil && !errors.Is(err, utils.ErrAppMemberNotFound) {
+		log.Printf("appRole: error getting app role: %s", err.Error())
+		return "", http.StatusInternalServerError, utils.ErrInternalServerError
+	}
+
+	if app.OrganizationID != nil {
+		organizationRole, err := ac.OrganizationService.GetMemberRole(*app.OrganizationID, userID)
+		if errors.Is(err, utils.ErrOrganizationNotFound) {
+			return "", http.StatusNotFound, utils.ErrAppNotFound
+		}
+		if err != nil {
+			log.Printf("appRole: error getting organization role: %s", err.Error())
+			return "", http.StatusInternalServerError, utils.ErrInternalServerError
+		}
+
+		inherited := utils.AppRoleOwner
+		if organizationRole == utils.OrganizationRoleMember {
+			inherited = utils.AppRoleEditor
+		}
+
+		if !services.HasAppRole(role, inherited) {
+			role = inherited
+		}
+	}
+
+	if role == "" {
+		return "", http.StatusNotFound, utils.ErrAppNotFound
 	}
 
-	apps, err := ac.AppService.GetUserApps(userID.(models.UserID))
+	return role, http.StatusOK, nil
+}
+
+// checkAppAccess tells whether userID holds at least the required role on
+// app. Users without any role do not get to know the app exists.
+func (ac *AppController) checkAppAccess(c *gin.Context, app *models.App, userID models.UserID, required models.AppRole) (int, error) {
+	role, status, err := ac.appRole(c, app, userID)
 	if err != nil {
+		return status, err
+	}
 
-		log.Printf("GetAll: error getting apps: %s", err.Error())
+	if !services.HasAppRole(role, required) {
+		return http.StatusForbidden, utils.ErrForbidden
+	}
+
+	return http.StatusOK, nil
+}
+
+// getRequestApp loads the app of the :id path parameter and checks the
+// authenticated user holds at least the required role on it. It writes the
+// error response itself and returns ok=false on failure.
+func (ac *AppController) getRequestApp(c *gin.Context, caller string, required models.AppRole) (*models.App, bool) {
+	appID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Printf("%s: invalid appID: %s", caller, err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrAppIDInvalid))
+		return nil, false
+	}
+
+	value, exists := c.Get("userID")
+	if !exists {
+		log.Printf("%s: userID value do not exists in context", caller)
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return nil, false
+	}
+
+	app, err := ac.AppService.GetAppByID(appID)
+	if err != nil {
+		log.Printf("%s: error getting app: %s", caller, err.Error())
 
 		if errors.Is(err, utils.ErrAppNotFound) {
 			c.JSON(http.StatusNotFound, utils.GetErrorResponse(utils.ErrAppNotFound))
-			return
+			return nil, false
 		}
 
 		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
-		return
+		return nil, false
 	}
 
-	c.JSON(http.StatusOK, map[string]any{
-		"data": map[string]any{
-			"apps": apps,
-		},
-	})
+	status, err := ac.checkAppAccess(c, app, value.(models.UserID), required)
+	if err != nil {
+		log.Printf("%s: access to app denied: %s", caller, err.Error())
+		c.JSON(status, utils.GetErrorResponse(err))
+		return nil, false
+	}
+
+	return app, true
 }
 
-func (ac *AppController) GetOne(c *gin.Context) {
-	appID, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		log.Printf("GetOne: invalid appID: %s", err.Error())
-		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrAppIDInvalid))
-		return
+// appMemberErrorStatus maps errors of the app member operations to a
+// response.
+func appMemberErrorStatus(err error) (int, error) {
+	switch {
+	case errors.Is(err, utils.ErrUserNotFound), errors.Is(err, utils.ErrAppMemberNotFound), errors.Is(err, utils.ErrAppNotFound):
+		return http.StatusNotFound, err
+	case errors.Is(err, utils.ErrAppRoleInvalid), errors.Is(err, utils.ErrInvalidEmail):
+		return http.StatusBadRequest, err
+	case errors.Is(err, utils.ErrAppMemberAlreadyExists), errors.Is(err, utils.ErrAppLastOwner):
+		return http.StatusConflict, err
+	default:
+		return http.StatusInternalServerError, utils.ErrInternalServerError
 	}
+}
 
+func (ac *AppController) GetAll(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
-		log.Print("GetOne: userID value do not exists in context")
+		log.Print("GetAll: userID value do not exists in context")
 		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
 		return
 	}
 
-	app, err := ac.AppService.GetAppByID(appID)
+	var apps []models.App
+	var err error
+
+	if organizationID := requestOrganizationID(c); organizationID != nil {
+		_, err = ac.OrganizationService.GetMemberRole(*organizationID, userID.(models.UserID))
+		if err == nil {
+			apps, err = ac.AppService.GetOrganizationApps(*organizationID)
+		}
+	} else {
+		apps, err = ac.AppService.GetUserApps(userID.(models.UserID))
+	}
 	if err != nil {
 
-		log.Printf("GetOne: error getting app: %s", err.Error())
+		log.Printf("GetAll: error getting apps: %s", err.Error())
 
 		if errors.Is(err, utils.ErrAppNotFound) {
 			c.JSON(http.StatusNotFound, utils.GetErrorResponse(utils.ErrAppNotFound))
 			return
 		}
 
+		if errors.Is(err, utils.ErrOrganizationNotFound) {
+			c.JSON(http.StatusNotFound, utils.GetErrorResponse(utils.ErrOrganizationNotFound))
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
 		return
 	}
 
-	if app.UserID != userID {
-		log.Print("GetOne: userIDs do not match")
-		c.JSON(http.StatusForbidden, utils.GetErrorResponse(utils.ErrUserIDsDoNotMatch))
+	c.JSON(http.StatusOK, map[string]any{
+		"data": map[string]any{
+			"apps": apps,
+		},
+	})
+}
+
+func (ac *AppController) GetOne(c *gin.Context) {
+	app, ok := ac.getRequestApp(c, "GetOne", utils.AppRoleViewer)
+	if !ok {
 		return
 	}
 
@@ -128,6 +253,17 @@ func (ac *AppController) Create(c *gin.Context) {
 		return
 	}
 
+	app.OrganizationID = requestOrganizationID(c)
+	if app.OrganizationID != nil {
+		_, err = ac.OrganizationService.GetMemberRole(*app.OrganizationID, app.UserID)
+		if err != nil {
+			log.Printf("Create: error checking organization membership: %s", err.Error())
+			status, err := organizationErrorStatus(err)
+			c.JSON(status, utils.GetErrorResponse(err))
+			return
+		}
+	}
+
 	err = ac.AppService.CreateApp(app)
 	if err != nil {
 		log.Printf("Create: error creating app: %s", err.Error())
@@ -158,37 +294,8 @@ func (ac *AppController) Update(c *gin.Context) {
 		return
 	}
 
-	appID, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		log.Printf("Update: invalid appID: %s", err.Error())
-		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrAppIDInvalid))
-		return
-	}
-
-	userID, exists := c.Get("userID")
-	if !exists {
-		log.Print("Update: userID value do not exists in context")
-		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
-		return
-	}
-
-	app, err := ac.AppService.GetAppByID(appID)
-	if err != nil {
-
-		log.Printf("Update: error getting app: %s", err.Error())
-
-		if errors.Is(err, utils.ErrAppNotFound) {
-			c.JSON(http.StatusNotFound, utils.GetErrorResponse(utils.ErrAppNotFound))
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
-		return
-	}
-
-	if app.UserID != userID {
-		log.Print("Update: userIDs do not match")
-		c.JSON(http.StatusForbidden, utils.GetErrorResponse(utils.ErrUserIDsDoNotMatch))
+	app, ok := ac.getRequestApp(c, "Update", utils.AppRoleEditor)
+	if !ok {
 		return
 	}
 
@@ -212,45 +319,123 @@ func (ac *AppController) Update(c *gin.Context) {
 }
 
 func (ac *AppController) DeleteByID(c *gin.Context) {
-	appID, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		log.Printf("DeleteByID: invalid appID: %s", err.Error())
-		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrAppIDInvalid))
+	app, ok := ac.getRequestApp(c, "DeleteByID", utils.AppRoleOwner)
+	if !ok {
 		return
 	}
 
-	app, err := ac.AppService.GetAppByID(appID)
+	err := ac.AppService.DeleteApp(app.ID)
 	if err != nil {
-		log.Printf("DeleteByID: error getting app: %s", err.Error())
+		log.Printf("DeleteByID: error deleting app: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
 
-		if errors.Is(err, utils.ErrAppNotFound) {
-			c.JSON(http.StatusNotFound, utils.GetErrorResponse(utils.ErrAppNotFound))
-			return
-		}
+	c.String(http.StatusOK, "")
+}
 
+func (ac *AppController) GetMembers(c *gin.Context) {
+	app, ok := ac.getRequestApp(c, "GetMembers", utils.AppRoleViewer)
+	if !ok {
+		return
+	}
+
+	members, err := ac.AppService.GetAppMembers(app.ID)
+	if err != nil {
+		log.Printf("GetMembers: error getting app members: %s", err.Error())
 		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
 		return
 	}
 
+	c.JSON(http.StatusOK, map[string]any{
+		"data": map[string]any{
+			"members": members,
+		},
+	})
+}
+
+type addAppMemberDTO struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// AddMember e-mails an invitation to collaborate on the app. It answers 202
+// whether or not the e-mail has an account.
+func (ac *AppController) AddMember(c *gin.Context) {
+	var memberDTO addAppMemberDTO
+
+	err := c.ShouldBindJSON(&memberDTO)
+	if err != nil {
+		log.Printf("AddMember: error during binding addAppMemberDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	app, ok := ac.getRequestApp(c, "AddMember", utils.AppRoleOwner)
+	if !ok {
+		return
+	}
+
 	userID, exists := c.Get("userID")
 	if !exists {
-		log.Print("DeleteByID: userID value do not exists in context")
+		log.Print("AddMember: userID value do not exists in context")
 		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
 		return
 	}
 
-	if app.UserID != userID {
-		log.Print("DeleteByID: userIDs do not match")
-		c.JSON(http.StatusForbidden, utils.GetErrorResponse(utils.ErrUserIDsDoNotMatch))
+	invitation, err := ac.AppService.AddAppMember(app.ID, userID.(models.UserID), memberDTO.Email, memberDTO.Role)
+	if err != nil {
+		log.Printf("AddMember: error adding app member: %s", err.Error())
+		status, err := appMemberErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
 		return
 	}
 
-	err = ac.AppService.DeleteApp(app.ID)
+	err = sendInvitationEmail(ac.MailerService, ac.InvitationURL, invitation.Invitation, invitation.Token)
 	if err != nil {
-		log.Printf("DeleteByID: error deleting app: %s", err.Error())
+		log.Printf("AddMember: error sending invitation email: %s", err.Error())
 		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
 		return
 	}
 
-	c.String(http.StatusOK, "")
+	c.JSON(http.StatusAccepted, map[string]any{
+		"data": map[string]any{
+			"invitation": invitation.Invitation,
+		},
+	})
+}
+
+// RemoveMember removes a collaborator. Owners may remove anyone and every
+// collaborator may remove themselves.
+func (ac *AppController) RemoveMember(c *gin.Context) {
+	memberID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		log.Printf("RemoveMember: invalid userID: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrInvalidUserID))
+		return
+	}
+
+	required := utils.AppRoleOwner
+	if value, exists := c.Get("userID"); exists && value.(models.UserID) == memberID {
+		required = utils.AppRoleViewer
+	}
+
+	app, ok := ac.getRequestApp(c, "RemoveMember", required)
+	if !ok {
+		return
+	}
+
+	err = ac.AppService.RemoveAppMember(app, memberID)
+	if err != nil {
+		log.Printf("RemoveMember: error removing app member: %s", err.Error())
+		status, err := appMemberErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"message": "member removed",
+	})
 }
@@ -0,0 +1,288 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IAppTransferController interface {
+	CreateTransfer(c *gin.Context)
+	ListAppTransfers(c *gin.Context)
+	CancelTransfer(c *gin.Context)
+	ListIncomingTransfers(c *gin.Context)
+	AcceptTransfer(c *gin.Context)
+	AcceptTransferByToken(c *gin.Context)
+}
+
+type AppTransferController struct {
+	appTransferService services.IAppTransferService
+	apps               *AppController
+	mailerService      services.MailerService
+	appTransferURL     string
+}
+
+func NewAppTransferController(
+	appTransferService services.IAppTransferService,
+	appService services.IAppService,
+	organizationService services.IOrganizationService,
+	mailerService services.MailerService,
+	appTransferURL string,
+) IAppTransferController {
+	return &AppTransferController{
+		appTransferService: appTransferService,
+		apps: &AppController{
+			AppService:          appService,
+			OrganizationService: organizationService,
+		},
+		mailerService:  mailerService,
+		appTransferURL: appTransferURL,
+	}
+}
+
+// appTransferErrorStatus maps errors of the app transfer operations to a
+// response.
+func appTransferErrorStatus(err error) (int, error) {
+	switch {
+	case errors.Is(err, utils.ErrAppTransferNotFound), errors.Is(err, utils.ErrOrganizationNotFound):
+		return http.StatusNotFound, err
+	case errors.Is(err, utils.ErrAppTransferRecipientInvalid), errors.Is(err, utils.ErrAppTransferInvalid):
+		return http.StatusBadRequest, err
+	case errors.Is(err, utils.ErrForbidden):
+		return http.StatusForbidden, err
+	case errors.Is(err, utils.ErrAppTransferExpired):
+		return http.StatusGone, err
+	default:
+		return http.StatusInternalServerError, utils.ErrInternalServerError
+	}
+}
+
+func (atc *AppTransferController) sendAppTransferEmails(app *models.App, transfer *services.AppTransferToken) error {
+	tmpl, err := template.ParseFiles("templates/app_transfer_email.html")
+	if err != nil {
+		log.Printf("sendAppTransferEmails: error parsing template: %s", err.Error())
+		return err
+	}
+
+	for _, email := range transfer.RecipientEmails {
+		var htmlBody bytes.Buffer
+
+		err = tmpl.Execute(&htmlBody, struct {
+			UserEmail       string
+			AppName         string
+			ForOrganization bool
+			TransferLink    string
+			ExpiresAt       string
+		}{
+			UserEmail:       email,
+			AppName:         app.Name,
+			ForOrganization: transfer.Transfer.ToOrganizationID != nil,
+			TransferLink:    atc.appTransferURL + "?token=" + url.QueryEscape(transfer.Token),
+			ExpiresAt:       transfer.Transfer.ExpiresAt.UTC().Format(time.RFC1123),
+		})
+		if err != nil {
+			log.Printf("sendAppTransferEmails: error executing template: %s", err.Error())
+			return err
+		}
+
+		err = atc.mailerService.SendEmail("", email, "An app was offered to you", "", htmlBody.String())
+		if err != nil {
+			log.Printf("sendAppTransferEmails: error sending email: %s", err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
+
+type createAppTransferDTO struct {
+	Email          string                 `json:"email"`
+	OrganizationID *models.OrganizationID `json:"organization_id"`
+}
+
+// CreateTransfer offers the app to another user or organization and e-mails
+// the recipient a link to accept it. Only app owners may transfer an app.
+func (atc *AppTransferController) CreateTransfer(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("CreateTransfer: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	var transferDTO createAppTransferDTO
+
+	err := c.ShouldBindJSON(&transferDTO)
+	if err != nil {
+		log.Printf("CreateTransfer: error during binding createAppTransferDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	app, ok := atc.apps.getRequestApp(c, "CreateTransfer", utils.AppRoleOwner)
+	if !ok {
+		return
+	}
+
+	transfer, err := atc.appTransferService.CreateTransfer(app, userID.(models.UserID), transferDTO.Email, transferDTO.OrganizationID)
+	if err != nil {
+		log.Printf("CreateTransfer: error creating transfer: %s", err.Error())
+		status, err := appTransferErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	err = atc.sendAppTransferEmails(app, transfer)
+	if err != nil {
+		log.Printf("CreateTransfer: error sending transfer emails: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusCreated, map[string]any{
+		"data": transfer.Transfer,
+	})
+}
+
+func (atc *AppTransferController) ListAppTransfers(c *gin.Context) {
+	app, ok := atc.apps.getRequestApp(c, "ListAppTransfers", utils.AppRoleOwner)
+	if !ok {
+		return
+	}
+
+	transfers, err := atc.appTransferService.GetAppTransfers(app.ID)
+	if err != nil {
+		log.Printf("ListAppTransfers: error getting transfers: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": transfers,
+	})
+}
+
+func (atc *AppTransferController) CancelTransfer(c *gin.Context) {
+	transferID, err := strconv.Atoi(c.Param("transferId"))
+	if err != nil {
+		log.Printf("CancelTransfer: invalid transferID: %s", err.Error())
+		c.JSON(http.StatusNotFound, utils.GetErrorResponse(utils.ErrAppTransferNotFound))
+		return
+	}
+
+	app, ok := atc.apps.getRequestApp(c, "CancelTransfer", utils.AppRoleOwner)
+	if !ok {
+		return
+	}
+
+	err = atc.appTransferService.CancelTransfer(app.ID, transferID)
+	if err != nil {
+		log.Printf("CancelTransfer: error cancelling transfer: %s", err.Error())
+		status, err := appTransferErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"message": "app transfer cancelled",
+	})
+}
+
+func (atc *AppTransferController) ListIncomingTransfers(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("ListIncomingTransfers: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	transfers, err := atc.appTransferService.GetIncomingTransfers(userID.(models.UserID))
+	if err != nil {
+		log.Printf("ListIncomingTransfers: error getting transfers: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": transfers,
+	})
+}
+
+func (atc *AppTransferController) AcceptTransfer(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("AcceptTransfer: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	transferID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Printf("AcceptTransfer: invalid transferID: %s", err.Error())
+		c.JSON(http.StatusNotFound, utils.GetErrorResponse(utils.ErrAppTransferNotFound))
+		return
+	}
+
+	transfer, err := atc.appTransferService.AcceptTransfer(transferID, userID.(models.UserID))
+	if err != nil {
+		log.Printf("AcceptTransfer: error accepting transfer: %s", err.Error())
+		status, err := appTransferErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": transfer,
+	})
+}
+
+type acceptAppTransferDTO struct {
+	Token string `json:"token"`
+}
+
+// AcceptTransferByToken accepts the transfer of an e-mailed link on behalf
+// of the authenticated user, who must be its recipient.
+func (atc *AppTransferController) AcceptTransferByToken(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("AcceptTransferByToken: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	var acceptDTO acceptAppTransferDTO
+
+	err := c.ShouldBindJSON(&acceptDTO)
+	if err != nil {
+		log.Printf("AcceptTransferByToken: error during binding acceptAppTransferDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	transfer, err := atc.appTransferService.AcceptTransferByToken(acceptDTO.Token, userID.(models.UserID))
+	if err != nil {
+		log.Printf("AcceptTransferByToken: error accepting transfer: %s", err.Error())
+		status, err := appTransferErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": transfer,
+	})
+}
@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IAppUserPoolController interface {
+	GetUserPool(c *gin.Context)
+	ConfigureUserPool(c *gin.Context)
+	SignUp(c *gin.Context)
+	Login(c *gin.Context)
+	Refresh(c *gin.Context)
+}
+
+type AppUserPoolController struct {
+	appUserPoolService services.IAppUserPoolService
+	apps               *AppController
+}
+
+func NewAppUserPoolController(
+	appUserPoolService services.IAppUserPoolService,
+	appService services.IAppService,
+	organizationService services.IOrganizationService,
+) IAppUserPoolController {
+	return &AppUserPoolController{
+		appUserPoolService: appUserPoolService,
+		apps: &AppController{
+			AppService:          appService,
+			OrganizationService: organizationService,
+		},
+	}
+}
+
+// appUserPoolErrorStatus maps errors of the user pool endpoints to a
+// response.
+func appUserPoolErrorStatus(err error) (int, error) {
+	switch {
+	case errors.Is(err, utils.ErrAppUserPoolNotFound):
+		return http.StatusNotFound, err
+	case errors.Is(err, utils.ErrInvalidEmail), errors.Is(err, utils.ErrPasswordTooShort), errors.Is(err, utils.ErrRefreshTokenInvalid):
+		return http.StatusBadRequest, err
+	case errors.Is(err, utils.ErrUserEmailAlreadyExists), errors.Is(err, utils.ErrEmailPasswordIncorrect):
+		return http.StatusUnprocessableEntity, err
+	case errors.Is(err, utils.ErrUserInactive):
+		return http.StatusForbidden, err
+	default:
+		return http.StatusInternalServerError, utils.ErrInternalServerError
+	}
+}
+
+func (upc *AppUserPoolController) GetUserPool(c *gin.Context) {
+	app, ok := upc.apps.getRequestApp(c, "GetUserPool", utils.AppRoleOwner)
+	if !ok {
+		return
+	}
+
+	pool, err := upc.appUserPoolService.GetUserPool(app.ID)
+	if err != nil {
+		log.Printf("GetUserPool: error getting user pool: %s", err.Error())
+		status, err := appUserPoolErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": pool,
+	})
+}
+
+type configureUserPoolDTO struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// ConfigureUserPool enables or disables the user pool of the app. Only app
+// owners may configure it.
+func (upc *AppUserPoolController) ConfigureUserPool(c *gin.Context) {
+	var poolDTO configureUserPoolDTO
+
+	err := c.ShouldBindJSON(&poolDTO)
+	if err == nil && poolDTO.Enabled == nil {
+		err = errors.New("enabled is required")
+	}
+	if err != nil {
+		log.Printf("ConfigureUserPool: error during binding configureUserPoolDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	app, ok := upc.apps.getRequestApp(c, "ConfigureUserPool", utils.AppRoleOwner)
+	if !ok {
+		return
+	}
+
+	pool, err := upc.appUserPoolService.ConfigureUserPool(app.ID, *poolDTO.Enabled)
+	if err != nil {
+		log.Printf("ConfigureUserPool: error configuring user pool: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": pool,
+	})
+}
+
+type appUserCredentialsDTO struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func (upc *AppUserPoolController) SignUp(c *gin.Context) {
+	var credentialsDTO appUserCredentialsDTO
+
+	err := c.ShouldBindJSON(&credentialsDTO)
+	if err != nil {
+		log.Printf("SignUp: error during binding appUserCredentialsDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	user, err := upc.appUserPoolService.SignUp(c.Param("clientId"), credentialsDTO.Email, credentialsDTO.Password)
+	if err != nil {
+		log.Printf("SignUp: error signing up: %s", err.Error())
+
+		var policyErr *utils.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			c.JSON(http.StatusBadRequest, utils.GetPasswordPolicyErrorResponse(policyErr))
+			return
+		}
+
+		status, err := appUserPoolErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, map[string]any{
+		"data": user,
+	})
+}
+
+func (upc *AppUserPoolController) Login(c *gin.Context) {
+	var credentialsDTO appUserCredentialsDTO
+
+	err := c.ShouldBindJSON(&credentialsDTO)
+	if err != nil {
+		log.Printf("Login: error during binding appUserCredentialsDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	tokens, err := upc.appUserPoolService.Login(c.Param("clientId"), credentialsDTO.Email, credentialsDTO.Password)
+	if err != nil {
+		log.Printf("Login: error logging in app user: %s", err.Error())
+		status, err := appUserPoolErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (upc *AppUserPoolController) Refresh(c *gin.Context) {
+	var refreshDTO refreshDTO
+
+	err := c.ShouldBindJSON(&refreshDTO)
+	if err != nil {
+		log.Printf("Refresh: error during binding refreshDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	tokens, err := upc.appUserPoolService.Refresh(c.Param("clientId"), refreshDTO.RefreshToken)
+	if err != nil {
+		log.Printf("Refresh: error refreshing app user tokens: %s", err.Error())
+		status, err := appUserPoolErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
@@ -1,30 +1,60 @@
 package controllers
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"html/template"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pedrotunin/go-jwt-auth/internal/models"
 	"github.com/pedrotunin/go-jwt-auth/internal/services"
 	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+	"github.com/pedrotunin/go-jwt-auth/internal/validators"
 )
 
 type IAuthController interface {
 	Login(c *gin.Context)
 	Logout(c *gin.Context)
 	Refresh(c *gin.Context)
+	VerifyMFA(c *gin.Context)
+	BeginWebAuthnLogin(c *gin.Context)
+	FinishWebAuthnLogin(c *gin.Context)
+	RequestMagicLink(c *gin.Context)
+	OpenMagicLink(c *gin.Context)
+	ConsumeMagicLink(c *gin.Context)
+	RequestEmailOTP(c *gin.Context)
+	VerifyEmailOTP(c *gin.Context)
+	Unlock(c *gin.Context)
+	ResetPassword(c *gin.Context)
 }
 
 type AuthController struct {
-	UserService services.IUserService
-	HashService services.IHashService
-	JWTService  services.IJWTService
+	UserService           services.IUserService
+	HashService           services.IHashService
+	JWTService            services.IJWTService
+	MFAService            services.IMFAService
+	WebAuthnService       services.IWebAuthnService
+	MagicLinkService      services.IMagicLinkService
+	EmailOTPService       services.IEmailOTPService
+	AccountLockoutService services.IAccountLockoutService
+	LoginHistoryService   services.ILoginHistoryService
+	PasswordResetService  services.IPasswordResetService
+	MailerService         services.MailerService
+	BaseURL               string
+	MagicLinkURL          string
 }
 
+// magicLinkBindingCookie holds the browser binding nonce of a magic link.
+const magicLinkBindingCookie = "magic_link_binding"
+
 type loginDTO struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
@@ -49,12 +79,15 @@ func (ac *AuthController) Login(c *gin.Context) {
 		return
 	}
 
+	// Every outcome that depends on the account (unknown e-mail, wrong
+	// password) costs one hash comparison and produces the same response, and
+	// the account status is only revealed to someone who knows the password.
 	user, err := ac.UserService.GetUserByEmail(loginDTO.Email)
 	if err != nil {
 		log.Printf("Login: error getting user: %s", err.Error())
 
 		if errors.Is(err, utils.ErrUserNotFound) {
-			c.JSON(http.StatusUnprocessableEntity, utils.GetErrorResponse(utils.ErrEmailPasswordIncorrect))
+			ac.failUnknownEmailLogin(c, loginDTO.Email, loginDTO.Password)
 			return
 		}
 
@@ -62,7 +95,23 @@ func (ac *AuthController) Login(c *gin.Context) {
 		return
 	}
 
+	if !ac.checkLoginAllowed(c, user.ID, utils.LoginMethodPassword, "Login") {
+		return
+	}
+
+	err = ac.HashService.ComparePassword(loginDTO.Password, user.Password)
+	if err != nil {
+		log.Printf("Login: error comparing password and hash: %s", err.Error())
+
+		ac.recordFailedLogin(user)
+		ac.recordLoginFailure(c, user.ID, utils.LoginMethodPassword, utils.LoginFailurePasswordIncorrect)
+
+		c.JSON(http.StatusUnprocessableEntity, utils.GetErrorResponse(utils.ErrEmailPasswordIncorrect))
+		return
+	}
+
 	if err := ac.UserService.VerifyActiveUser(user); err != nil {
+		ac.recordLoginFailure(c, user.ID, utils.LoginMethodPassword, utils.LoginFailureAccountNotActive)
 
 		if errors.Is(err, utils.ErrUserPending) {
 			c.JSON(http.StatusBadRequest, utils.GetErrorResponse(fmt.Errorf("user is pending activation, check your e-mail to activate.")))
@@ -73,30 +122,272 @@ func (ac *AuthController) Login(c *gin.Context) {
 		return
 	}
 
-	err = ac.HashService.CompareArgon2id(loginDTO.Password, user.Password)
+	err = ac.UserService.UpgradePasswordHash(user, loginDTO.Password)
 	if err != nil {
-		log.Printf("Login: error comparing password and hash: %s", err.Error())
+		log.Printf("Login: error upgrading password hash: %s", err.Error())
+	}
 
-		c.JSON(http.StatusUnprocessableEntity, utils.GetErrorResponse(utils.ErrEmailPasswordIncorrect))
+	ac.completeLogin(c, user.ID, utils.LoginMethodPassword, "Login")
+}
+
+// failUnknownEmailLogin answers a login for an e-mail without an account
+// exactly like a wrong password: same hash cost, same backoff and lock, same
+// response.
+func (ac *AuthController) failUnknownEmailLogin(c *gin.Context, email string, password string) {
+	retryAfter, err := ac.AccountLockoutService.CheckAllowedEmail(email)
+	if err != nil {
+		log.Printf("Login: login not allowed: %s", err.Error())
+
+		if errors.Is(err, utils.ErrAccountLocked) || errors.Is(err, utils.ErrLoginThrottled) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, utils.GetErrorResponse(err))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	ac.HashService.CompareDummyPassword(password)
+
+	err = ac.AccountLockoutService.RecordEmailFailure(email)
+	if err != nil {
+		log.Printf("Login: error recording failed login: %s", err.Error())
+	}
+
+	c.JSON(http.StatusUnprocessableEntity, utils.GetErrorResponse(utils.ErrEmailPasswordIncorrect))
+}
+
+// checkLoginAllowed answers the request with 429 and returns false when the
+// account is locked or must wait before its next attempt.
+func (ac *AuthController) checkLoginAllowed(c *gin.Context, userID models.UserID, method string, caller string) bool {
+	retryAfter, err := ac.AccountLockoutService.CheckAllowed(userID)
+	if err == nil {
+		return true
+	}
+
+	log.Printf("%s: login not allowed: %s", caller, err.Error())
+
+	if errors.Is(err, utils.ErrAccountLocked) || errors.Is(err, utils.ErrLoginThrottled) {
+		reason := utils.LoginFailureThrottled
+		if errors.Is(err, utils.ErrAccountLocked) {
+			reason = utils.LoginFailureAccountLocked
+		}
+		ac.recordLoginFailure(c, userID, method, reason)
+
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, utils.GetErrorResponse(err))
+		return false
+	}
+
+	c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+	return false
+}
+
+// checkMFAChallenge validates the mfa_token of a second factor step and checks
+// neither the token nor the account ran out of attempts. It answers the
+// request itself and returns nil when the step may not go on.
+func (ac *AuthController) checkMFAChallenge(c *gin.Context, mfaToken string, method string, caller string) *services.MFATokenClaims {
+	claims, err := ac.JWTService.ValidateMFAToken(mfaToken)
+	if err != nil {
+		log.Printf("%s: error validating mfa token: %s", caller, err.Error())
+		c.JSON(http.StatusUnauthorized, utils.GetErrorResponse(utils.ErrMFATokenInvalid))
+		return nil
+	}
+
+	err = ac.MFAService.CheckChallenge(claims.ID)
+	if err != nil {
+		log.Printf("%s: mfa token rejected: %s", caller, err.Error())
+
+		if errors.Is(err, utils.ErrMFATokenInvalid) {
+			c.JSON(http.StatusUnauthorized, utils.GetErrorResponse(utils.ErrMFATokenInvalid))
+			return nil
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return nil
+	}
+
+	if !ac.checkLoginAllowed(c, claims.UserID, method, caller) {
+		return nil
+	}
+
+	return claims
+}
+
+// recordMFAFailure counts a wrong second factor against the mfa_token, which
+// dies after a few of them, and against the account like a wrong password.
+func (ac *AuthController) recordMFAFailure(c *gin.Context, claims *services.MFATokenClaims, method string, reason string) {
+	ac.recordLoginFailure(c, claims.UserID, method, reason)
+
+	err := ac.MFAService.RecordChallengeFailure(claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		log.Printf("recordMFAFailure: error recording challenge failure: %s", err.Error())
+	}
+
+	user, err := ac.UserService.GetUserByID(claims.UserID)
+	if err != nil {
+		log.Printf("recordMFAFailure: error getting user: %s", err.Error())
+		return
+	}
+
+	ac.recordFailedLogin(user)
+}
+
+// recordFailedLogin counts a wrong password for user and, when that locks the
+// account, e-mails the owner an unlock link.
+func (ac *AuthController) recordFailedLogin(user *models.User) {
+	lock, err := ac.AccountLockoutService.RecordFailure(user.ID)
+	if err != nil {
+		log.Printf("recordFailedLogin: error recording failed login: %s", err.Error())
+		return
+	}
+
+	if lock == nil {
+		return
+	}
+
+	url := fmt.Sprintf("%s/v1/auth/unlock/%s", ac.BaseURL, lock.UnlockToken)
+
+	err = ac.sendAccountLockedEmail(user, url, lock.LockedUntil)
+	if err != nil {
+		log.Printf("recordFailedLogin: error sending account locked email: %s", err.Error())
+	}
+}
+
+// recordLoginFailure adds a failed attempt to the user's login history.
+func (ac *AuthController) recordLoginFailure(c *gin.Context, userID models.UserID, method string, reason string) {
+	err := ac.LoginHistoryService.RecordFailure(userID, method, reason, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		log.Printf("recordLoginFailure: error recording failed login: %s", err.Error())
+	}
+}
+
+// recordLoginSuccess adds a successful login to the user's login history and
+// warns the owner by e-mail when it came from a device not seen before.
+func (ac *AuthController) recordLoginSuccess(c *gin.Context, userID models.UserID, method string, caller string) {
+	ipAddress := c.ClientIP()
+	userAgent := c.Request.UserAgent()
+
+	newDevice, err := ac.LoginHistoryService.RecordSuccess(userID, method, ipAddress, userAgent)
+	if err != nil {
+		log.Printf("%s: error recording login history: %s", caller, err.Error())
+		return
+	}
+
+	if !newDevice {
+		return
+	}
+
+	user, err := ac.UserService.GetUserByID(userID)
+	if err != nil {
+		log.Printf("%s: error getting user for new device email: %s", caller, err.Error())
+		return
+	}
+
+	err = ac.sendNewDeviceLoginEmail(user, ipAddress, userAgent, time.Now())
+	if err != nil {
+		log.Printf("%s: error sending new device email: %s", caller, err.Error())
+	}
+}
+
+func (ac *AuthController) sendNewDeviceLoginEmail(user *models.User, ipAddress string, userAgent string, at time.Time) error {
+	var htmlBody bytes.Buffer
+
+	tmpl, err := template.ParseFiles("templates/new_device_login_email.html")
+	if err != nil {
+		log.Printf("sendNewDeviceLoginEmail: error parsing template: %s", err.Error())
+		return err
+	}
+
+	err = tmpl.Execute(&htmlBody, struct {
+		UserEmail string
+		IPAddress string
+		UserAgent string
+		LoginAt   string
+	}{
+		UserEmail: user.Email,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		LoginAt:   at.UTC().Format(time.RFC1123),
+	})
+	if err != nil {
+		log.Printf("sendNewDeviceLoginEmail: error executing template: %s", err.Error())
+		return err
+	}
+
+	err = ac.MailerService.SendEmail("", user.Email, "New login to your account", "", htmlBody.String())
+	if err != nil {
+		log.Printf("sendNewDeviceLoginEmail: error sending email: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// completeLogin finishes a successful first factor: users with MFA get an
+// mfa_token for the second step, everyone else gets their tokens.
+func (ac *AuthController) completeLogin(c *gin.Context, userID models.UserID, method string, caller string) {
+	mfaMethods, err := ac.MFAService.GetMFAMethods(userID)
+	if err != nil {
+		log.Printf("%s: error checking mfa: %s", caller, err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
 		return
+	}
+
+	if len(mfaMethods) > 0 {
+		mfaToken, err := ac.JWTService.GenerateMFAToken(userID)
+		if err != nil {
+			log.Printf("%s: error generating mfa token: %s", caller, err.Error())
+			c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+			return
+		}
 
+		log.Printf("%s: mfa required", caller)
+		c.JSON(http.StatusOK, map[string]any{
+			"messagge":     "mfa required",
+			"mfa_required": true,
+			"mfa_methods":  mfaMethods,
+			"mfa_token":    mfaToken,
+		})
+		return
 	}
 
-	accessToken, err := ac.JWTService.GenerateToken(user.ID)
+	ac.issueTokens(c, userID, method, caller)
+}
+
+// issueTokens answers the request with a new access/refresh token pair for
+// userID. It is the last step of every successful login flow.
+func (ac *AuthController) issueTokens(c *gin.Context, userID models.UserID, method string, caller string) {
+	accessToken, err := ac.JWTService.GenerateToken(userID)
 	if err != nil {
-		log.Printf("Login: error generating token: %s", err.Error())
+		log.Printf("%s: error generating token: %s", caller, err.Error())
 		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
 		return
 	}
 
-	refreshToken, err := ac.JWTService.GenerateRefreshToken(user.ID)
+	refreshToken, err := ac.JWTService.GenerateRefreshToken(userID)
 	if err != nil {
-		log.Printf("Login: error generating refresh token: %s", err.Error())
+		log.Printf("%s: error generating refresh token: %s", caller, err.Error())
 		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
 		return
 	}
 
-	log.Printf("Login: login successful")
+	// Failures are only forgotten once every factor passed, so a correct
+	// password does not clear failed second factors.
+	err = ac.AccountLockoutService.RecordSuccess(userID)
+	if err != nil {
+		log.Printf("%s: error resetting failed logins: %s", caller, err.Error())
+	}
+
+	err = ac.UserService.RecordLogin(userID)
+	if err != nil {
+		log.Printf("%s: error recording login: %s", caller, err.Error())
+	}
+
+	ac.recordLoginSuccess(c, userID, method, caller)
+
+	log.Printf("%s: login successful", caller)
 	c.JSON(http.StatusOK, map[string]string{
 		"messagge":      "login successful",
 		"access_token":  accessToken,
@@ -185,3 +476,534 @@ func (ac *AuthController) Refresh(c *gin.Context) {
 	})
 
 }
+
+type verifyMFADTO struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func (ac *AuthController) VerifyMFA(c *gin.Context) {
+	var verifyDTO verifyMFADTO
+
+	err := c.ShouldBindJSON(&verifyDTO)
+	if err != nil {
+		log.Printf("VerifyMFA: error during binding verifyMFADTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	claims := ac.checkMFAChallenge(c, verifyDTO.MFAToken, utils.LoginMethodMFA, "VerifyMFA")
+	if claims == nil {
+		return
+	}
+
+	if verifyDTO.RecoveryCode != "" {
+		err = ac.MFAService.VerifyRecoveryCode(claims.UserID, verifyDTO.RecoveryCode)
+	} else {
+		err = ac.MFAService.VerifyTOTP(claims.UserID, verifyDTO.Code)
+	}
+	if err != nil {
+		log.Printf("VerifyMFA: error verifying code: %s", err.Error())
+
+		if errors.Is(err, utils.ErrMFACodeInvalid) || errors.Is(err, utils.ErrMFACodeReused) || errors.Is(err, utils.ErrRecoveryCodeInvalid) {
+			ac.recordMFAFailure(c, claims, utils.LoginMethodMFA, utils.LoginFailureMFACodeInvalid)
+			c.JSON(http.StatusUnprocessableEntity, utils.GetErrorResponse(err))
+			return
+		}
+
+		if errors.Is(err, utils.ErrMFANotEnabled) {
+			c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrMFANotEnabled))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	ac.issueTokens(c, claims.UserID, utils.LoginMethodMFA, "VerifyMFA")
+}
+
+type beginWebAuthnLoginDTO struct {
+	MFAToken string `json:"mfa_token"`
+}
+
+// BeginWebAuthnLogin starts a passkey assertion. With an mfa_token it is the
+// second factor of a password login; without one it is a passwordless login.
+func (ac *AuthController) BeginWebAuthnLogin(c *gin.Context) {
+	var beginDTO beginWebAuthnLoginDTO
+
+	err := c.ShouldBindJSON(&beginDTO)
+	if err != nil {
+		log.Printf("BeginWebAuthnLogin: error during binding beginWebAuthnLoginDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	var userID *models.UserID
+	if beginDTO.MFAToken != "" {
+		claims := ac.checkMFAChallenge(c, beginDTO.MFAToken, utils.LoginMethodWebAuthn, "BeginWebAuthnLogin")
+		if claims == nil {
+			return
+		}
+		userID = &claims.UserID
+	}
+
+	options, err := ac.WebAuthnService.BeginLogin(userID)
+	if err != nil {
+		log.Printf("BeginWebAuthnLogin: error starting login: %s", err.Error())
+
+		if errors.Is(err, utils.ErrWebAuthnCredentialNotFound) {
+			c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrWebAuthnCredentialNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": map[string]any{
+			"public_key": options,
+		},
+	})
+}
+
+type finishWebAuthnLoginDTO struct {
+	MFAToken   string                `json:"mfa_token"`
+	Credential webAuthnCredentialDTO `json:"credential"`
+}
+
+func (ac *AuthController) FinishWebAuthnLogin(c *gin.Context) {
+	var finishDTO finishWebAuthnLoginDTO
+
+	err := c.ShouldBindJSON(&finishDTO)
+	if err != nil {
+		log.Printf("FinishWebAuthnLogin: error during binding finishWebAuthnLoginDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	assertion, err := finishDTO.Credential.toAssertion()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	var claims *services.MFATokenClaims
+	var expectedUserID *models.UserID
+	if finishDTO.MFAToken != "" {
+		claims = ac.checkMFAChallenge(c, finishDTO.MFAToken, utils.LoginMethodWebAuthn, "FinishWebAuthnLogin")
+		if claims == nil {
+			return
+		}
+		expectedUserID = &claims.UserID
+	}
+
+	userID, err := ac.WebAuthnService.FinishLogin(expectedUserID, assertion)
+	if err != nil {
+		log.Printf("FinishWebAuthnLogin: error verifying assertion: %s", err.Error())
+
+		status, err := webAuthnErrorStatus(err)
+		if claims != nil && status != http.StatusInternalServerError {
+			ac.recordMFAFailure(c, claims, utils.LoginMethodWebAuthn, utils.LoginFailureWebAuthnInvalid)
+		}
+
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	// The password step already checked the account state for second factor
+	// logins; passwordless logins have to do it here.
+	if expectedUserID == nil {
+		user, err := ac.UserService.GetUserByID(userID)
+		if err != nil {
+			log.Printf("FinishWebAuthnLogin: error getting user: %s", err.Error())
+			c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+			return
+		}
+
+		if err := ac.UserService.VerifyActiveUser(user); err != nil {
+			c.JSON(http.StatusBadRequest, utils.GetErrorResponse(err))
+			return
+		}
+	}
+
+	ac.issueTokens(c, userID, utils.LoginMethodWebAuthn, "FinishWebAuthnLogin")
+}
+
+func (ac *AuthController) sendMagicLinkEmail(user *models.User, url string, expiresAt time.Time) error {
+	var htmlBody bytes.Buffer
+
+	tmpl, err := template.ParseFiles("templates/magic_link_email.html")
+	if err != nil {
+		log.Printf("sendMagicLinkEmail: error parsing template: %s", err.Error())
+		return err
+	}
+
+	err = tmpl.Execute(&htmlBody, struct {
+		UserEmail string
+		LoginLink string
+		ExpiresAt string
+	}{
+		UserEmail: user.Email,
+		LoginLink: url,
+		ExpiresAt: expiresAt.UTC().Format(time.RFC1123),
+	})
+	if err != nil {
+		log.Printf("sendMagicLinkEmail: error executing template: %s", err.Error())
+		return err
+	}
+
+	err = ac.MailerService.SendEmail("", user.Email, "Your login link", "", htmlBody.String())
+	if err != nil {
+		log.Printf("sendMagicLinkEmail: error sending email: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+type magicLinkDTO struct {
+	Email string `json:"email"`
+}
+
+func (ac *AuthController) RequestMagicLink(c *gin.Context) {
+	var linkDTO magicLinkDTO
+
+	err := c.ShouldBindJSON(&linkDTO)
+	if err != nil {
+		log.Printf("RequestMagicLink: error during binding magicLinkDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	err = validators.IsValidEmail(linkDTO.Email)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(err))
+		return
+	}
+
+	// The response is the same whether or not the e-mail belongs to an
+	// account, so this endpoint cannot be used to enumerate users.
+	response := map[string]string{
+		"message": "if the e-mail is registered, a login link was sent to it.",
+	}
+
+	link, err := ac.MagicLinkService.CreateMagicLink(linkDTO.Email)
+	if err != nil {
+		log.Printf("RequestMagicLink: error creating magic link: %s", err.Error())
+
+		if errors.Is(err, utils.ErrUserNotFound) || errors.Is(err, utils.ErrUserInactive) {
+			c.JSON(http.StatusAccepted, response)
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	url := fmt.Sprintf("%s/v1/auth/magic-link/%s", ac.BaseURL, link.Token)
+
+	err = ac.sendMagicLinkEmail(link.User, url, link.ExpiresAt)
+	if err != nil {
+		log.Printf("RequestMagicLink: error sending magic link email: %s", err.Error())
+	}
+
+	if link.Binding != "" {
+		maxAge := int(time.Until(link.ExpiresAt).Seconds())
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(magicLinkBindingCookie, link.Binding, maxAge, "/v1/auth/magic-link", "", strings.HasPrefix(ac.BaseURL, "https://"), true)
+	}
+
+	c.JSON(http.StatusAccepted, response)
+}
+
+// OpenMagicLink only forwards the e-mailed link to MAGIC_LINK_URL. Mail
+// scanners and prefetchers follow links in e-mails, so a GET must never
+// consume the single-use token; the page posts it to ConsumeMagicLink.
+func (ac *AuthController) OpenMagicLink(c *gin.Context) {
+	c.Redirect(http.StatusSeeOther, ac.MagicLinkURL+"?token="+url.QueryEscape(c.Param("token")))
+}
+
+type consumeMagicLinkDTO struct {
+	Token string `json:"token"`
+}
+
+func (ac *AuthController) ConsumeMagicLink(c *gin.Context) {
+	var linkDTO consumeMagicLinkDTO
+
+	err := c.ShouldBindJSON(&linkDTO)
+	if err != nil {
+		log.Printf("ConsumeMagicLink: error during binding consumeMagicLinkDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	// A missing cookie is left to the service, which only requires it for
+	// links that were bound to a browser.
+	binding, _ := c.Cookie(magicLinkBindingCookie)
+
+	user, err := ac.MagicLinkService.ConsumeMagicLink(linkDTO.Token, binding)
+	if err != nil {
+		log.Printf("ConsumeMagicLink: error consuming magic link: %s", err.Error())
+
+		if errors.Is(err, utils.ErrMagicLinkInvalid) || errors.Is(err, utils.ErrMagicLinkBrowserMismatch) {
+			c.JSON(http.StatusBadRequest, utils.GetErrorResponse(err))
+			return
+		}
+
+		if errors.Is(err, utils.ErrMagicLinkExpired) {
+			c.JSON(http.StatusGone, utils.GetErrorResponse(utils.ErrMagicLinkExpired))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	if binding != "" {
+		c.SetCookie(magicLinkBindingCookie, "", -1, "/v1/auth/magic-link", "", strings.HasPrefix(ac.BaseURL, "https://"), true)
+	}
+
+	if err := ac.UserService.VerifyActiveUser(user); err != nil {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(err))
+		return
+	}
+
+	ac.completeLogin(c, user.ID, utils.LoginMethodMagicLink, "ConsumeMagicLink")
+}
+
+func (ac *AuthController) sendEmailOTPEmail(user *models.User, code string, expiresAt time.Time) error {
+	var htmlBody bytes.Buffer
+
+	tmpl, err := template.ParseFiles("templates/email_otp_email.html")
+	if err != nil {
+		log.Printf("sendEmailOTPEmail: error parsing template: %s", err.Error())
+		return err
+	}
+
+	err = tmpl.Execute(&htmlBody, struct {
+		UserEmail string
+		Code      string
+		ExpiresAt string
+	}{
+		UserEmail: user.Email,
+		Code:      code,
+		ExpiresAt: expiresAt.UTC().Format(time.RFC1123),
+	})
+	if err != nil {
+		log.Printf("sendEmailOTPEmail: error executing template: %s", err.Error())
+		return err
+	}
+
+	err = ac.MailerService.SendEmail("", user.Email, "Your login code", "", htmlBody.String())
+	if err != nil {
+		log.Printf("sendEmailOTPEmail: error sending email: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+type requestEmailOTPDTO struct {
+	Email string `json:"email"`
+}
+
+func (ac *AuthController) RequestEmailOTP(c *gin.Context) {
+	var otpDTO requestEmailOTPDTO
+
+	err := c.ShouldBindJSON(&otpDTO)
+	if err != nil {
+		log.Printf("RequestEmailOTP: error during binding requestEmailOTPDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	err = validators.IsValidEmail(otpDTO.Email)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(err))
+		return
+	}
+
+	// Same answer for unknown e-mails, see RequestMagicLink.
+	response := map[string]string{
+		"message": "if the e-mail is registered, a login code was sent to it.",
+	}
+
+	user, code, expiresAt, err := ac.EmailOTPService.RequestCode(otpDTO.Email)
+	if err != nil {
+		log.Printf("RequestEmailOTP: error creating code: %s", err.Error())
+
+		if errors.Is(err, utils.ErrUserNotFound) || errors.Is(err, utils.ErrUserInactive) {
+			c.JSON(http.StatusAccepted, response)
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	err = ac.sendEmailOTPEmail(user, code, expiresAt)
+	if err != nil {
+		log.Printf("RequestEmailOTP: error sending code email: %s", err.Error())
+	}
+
+	c.JSON(http.StatusAccepted, response)
+}
+
+type verifyEmailOTPDTO struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+func (ac *AuthController) VerifyEmailOTP(c *gin.Context) {
+	var otpDTO verifyEmailOTPDTO
+
+	err := c.ShouldBindJSON(&otpDTO)
+	if err != nil {
+		log.Printf("VerifyEmailOTP: error during binding verifyEmailOTPDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	user, err := ac.EmailOTPService.VerifyCode(otpDTO.Email, otpDTO.Code)
+	if err != nil {
+		log.Printf("VerifyEmailOTP: error verifying code: %s", err.Error())
+
+		if errors.Is(err, utils.ErrEmailOTPInvalid) {
+			c.JSON(http.StatusUnprocessableEntity, utils.GetErrorResponse(utils.ErrEmailOTPInvalid))
+			return
+		}
+
+		if errors.Is(err, utils.ErrEmailOTPAttemptsExceeded) {
+			c.JSON(http.StatusTooManyRequests, utils.GetErrorResponse(utils.ErrEmailOTPAttemptsExceeded))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	if err := ac.UserService.VerifyActiveUser(user); err != nil {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(err))
+		return
+	}
+
+	ac.completeLogin(c, user.ID, utils.LoginMethodEmailOTP, "VerifyEmailOTP")
+}
+
+func (ac *AuthController) sendAccountLockedEmail(user *models.User, url string, lockedUntil time.Time) error {
+	var htmlBody bytes.Buffer
+
+	tmpl, err := template.ParseFiles("templates/account_locked_email.html")
+	if err != nil {
+		log.Printf("sendAccountLockedEmail: error parsing template: %s", err.Error())
+		return err
+	}
+
+	err = tmpl.Execute(&htmlBody, struct {
+		UserEmail   string
+		UnlockLink  string
+		LockedUntil string
+	}{
+		UserEmail:   user.Email,
+		UnlockLink:  url,
+		LockedUntil: lockedUntil.UTC().Format(time.RFC1123),
+	})
+	if err != nil {
+		log.Printf("sendAccountLockedEmail: error executing template: %s", err.Error())
+		return err
+	}
+
+	err = ac.MailerService.SendEmail("", user.Email, "Your account was locked", "", htmlBody.String())
+	if err != nil {
+		log.Printf("sendAccountLockedEmail: error sending email: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (ac *AuthController) Unlock(c *gin.Context) {
+	token := c.Param("token")
+
+	err := ac.AccountLockoutService.Unlock(token)
+	if err != nil {
+		log.Printf("Unlock: error unlocking account: %s", err.Error())
+
+		if errors.Is(err, utils.ErrUnlockTokenInvalid) {
+			c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrUnlockTokenInvalid))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"message": "account unlocked, you can log in again.",
+	})
+}
+
+type resetPasswordDTO struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (ac *AuthController) ResetPassword(c *gin.Context) {
+	var resetDTO resetPasswordDTO
+
+	err := c.ShouldBindJSON(&resetDTO)
+	if err != nil {
+		log.Printf("ResetPassword: error during binding resetPasswordDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	err = ac.PasswordResetService.ResetPassword(resetDTO.Token, resetDTO.Password)
+	if err != nil {
+		log.Printf("ResetPassword: error resetting password: %s", err.Error())
+
+		var policyErr *utils.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			c.JSON(http.StatusBadRequest, utils.GetPasswordPolicyErrorResponse(policyErr))
+			return
+		}
+
+		if errors.Is(err, utils.ErrPasswordResetTokenInvalid) {
+			c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrPasswordResetTokenInvalid))
+			return
+		}
+
+		if errors.Is(err, utils.ErrPasswordResetTokenExpired) {
+			c.JSON(http.StatusGone, utils.GetErrorResponse(utils.ErrPasswordResetTokenExpired))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"message": "password reset",
+	})
+}
@@ -1,7 +1,17 @@
 package controllers
 
 type Controllers struct {
-	AuthController IAuthController
-	UserController IUserController
-	AppController  IAppController
+	AuthController         IAuthController
+	UserController         IUserController
+	AppController          IAppController
+	DataExportController   IDataExportController
+	MFAController          IMFAController
+	WebAuthnController     IWebAuthnController
+	AdminController        IAdminController
+	RoleController         IRoleController
+	InvitationController   IInvitationController
+	OrganizationController IOrganizationController
+	AppTransferController  IAppTransferController
+	AppUserPoolController  IAppUserPoolController
+	SCIMController         ISCIMController
 }
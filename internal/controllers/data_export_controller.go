@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IDataExportController interface {
+	Create(c *gin.Context)
+	Download(c *gin.Context)
+}
+
+type DataExportController struct {
+	dataExportService services.IDataExportService
+	userService       services.IUserService
+	mailerService     services.MailerService
+	baseURL           string
+}
+
+func NewDataExportController(
+	dataExportService services.IDataExportService,
+	userService services.IUserService,
+	mailerService services.MailerService,
+	baseURL string,
+) IDataExportController {
+	return &DataExportController{
+		dataExportService: dataExportService,
+		userService:       userService,
+		mailerService:     mailerService,
+		baseURL:           baseURL,
+	}
+}
+
+func (dec *DataExportController) sendDataExportEmail(user *models.User, url string, expiresAt time.Time) error {
+	var htmlBody bytes.Buffer
+
+	tmpl, err := template.ParseFiles("templates/data_export_email.html")
+	if err != nil {
+		log.Printf("sendDataExportEmail: error parsing template: %s", err.Error())
+		return err
+	}
+
+	err = tmpl.Execute(&htmlBody, struct {
+		UserEmail    string
+		DownloadLink string
+		ExpiresAt    string
+	}{
+		UserEmail:    user.Email,
+		DownloadLink: url,
+		ExpiresAt:    expiresAt.UTC().Format(time.RFC1123),
+	})
+	if err != nil {
+		log.Printf("sendDataExportEmail: error executing template: %s", err.Error())
+		return err
+	}
+
+	err = dec.mailerService.SendEmail("", user.Email, "Your data export is ready", "", htmlBody.String())
+	if err != nil {
+		log.Printf("sendDataExportEmail: error sending email: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (dec *DataExportController) Create(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("Create: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	user, err := dec.userService.GetUserByID(userID.(models.UserID))
+	if err != nil {
+		log.Printf("Create: error getting user: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	token, expiresAt, err := dec.dataExportService.CreateExport(user.ID)
+	if err != nil {
+		log.Printf("Create: error creating data export: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	url := fmt.Sprintf("%s/v1/exports/%s", dec.baseURL, token)
+
+	err = dec.sendDataExportEmail(user, url, expiresAt)
+	if err != nil {
+		log.Printf("Create: error sending data export email: %s", err.Error())
+	}
+
+	c.JSON(http.StatusCreated, map[string]any{
+		"message": "data export created, check e-mail for the download link.",
+		"data": map[string]any{
+			"download_url": url,
+			"expires_at":   expiresAt,
+		},
+	})
+}
+
+func (dec *DataExportController) Download(c *gin.Context) {
+	token := c.Param("token")
+
+	export, err := dec.dataExportService.GetExport(token)
+	if err != nil {
+		log.Printf("Download: error getting data export: %s", err.Error())
+
+		if errors.Is(err, utils.ErrDataExportNotFound) {
+			c.JSON(http.StatusNotFound, utils.GetErrorResponse(utils.ErrDataExportNotFound))
+			return
+		}
+
+		if errors.Is(err, utils.ErrDataExportExpired) {
+			c.JSON(http.StatusGone, utils.GetErrorResponse(utils.ErrDataExportExpired))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	filename := fmt.Sprintf("user-%d-export-%s.zip", export.UserID, export.CreatedAt.UTC().Format("20060102150405"))
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/zip", export.Archive)
+}
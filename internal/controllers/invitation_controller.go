@@ -0,0 +1,288 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IInvitationController interface {
+	CreateInvitation(c *gin.Context)
+	ListInvitations(c *gin.Context)
+	RevokeInvitation(c *gin.Context)
+	AcceptInvitation(c *gin.Context)
+	JoinWithInvitation(c *gin.Context)
+}
+
+type InvitationController struct {
+	invitationService services.IInvitationService
+	mailerService     services.MailerService
+	invitationURL     string
+}
+
+func NewInvitationController(
+	invitationService services.IInvitationService,
+	mailerService services.MailerService,
+	invitationURL string,
+) IInvitationController {
+	return &InvitationController{
+		invitationService: invitationService,
+		mailerService:     mailerService,
+		invitationURL:     invitationURL,
+	}
+}
+
+// invitationErrorStatus maps errors of the invitation operations to a response.
+func invitationErrorStatus(err error) (int, error) {
+	switch {
+	case errors.Is(err, utils.ErrInvitationNotFound), errors.Is(err, utils.ErrRoleNotFound):
+		return http.StatusNotFound, err
+	case errors.Is(err, utils.ErrInvalidEmail), errors.Is(err, utils.ErrPasswordTooShort), errors.Is(err, utils.ErrInvitationInvalid):
+		return http.StatusBadRequest, err
+	case errors.Is(err, utils.ErrForbidden):
+		return http.StatusForbidden, err
+	case errors.Is(err, utils.ErrUserEmailAlreadyExists), errors.Is(err, utils.ErrOrganizationMemberAlreadyExists), errors.Is(err, utils.ErrAppMemberAlreadyExists):
+		return http.StatusConflict, err
+	case errors.Is(err, utils.ErrInvitationExpired):
+		return http.StatusGone, err
+	default:
+		return http.StatusInternalServerError, utils.ErrInternalServerError
+	}
+}
+
+// invitationJoinTarget names what the invitee joins in the e-mail, empty for
+// plain account invitations.
+func invitationJoinTarget(invitation *models.Invitation) string {
+	switch {
+	case invitation.OrganizationID != nil:
+		return "an organization"
+	case invitation.AppID != nil:
+		return "an app as a collaborator"
+	default:
+		return ""
+	}
+}
+
+// sendInvitationEmail e-mails the invitation link. It is shared by every
+// controller that creates invitations.
+func sendInvitationEmail(mailerService services.MailerService, invitationURL string, invitation *models.Invitation, token string) error {
+	var htmlBody bytes.Buffer
+
+	tmpl, err := template.ParseFiles("templates/invitation_email.html")
+	if err != nil {
+		log.Printf("sendInvitationEmail: error parsing template: %s", err.Error())
+		return err
+	}
+
+	err = tmpl.Execute(&htmlBody, struct {
+		UserEmail      string
+		InvitationLink string
+		ExpiresAt      string
+		JoinTarget     string
+	}{
+		UserEmail:      invitation.Email,
+		InvitationLink: invitationURL + "?token=" + url.QueryEscape(token),
+		ExpiresAt:      invitation.ExpiresAt.UTC().Format(time.RFC1123),
+		JoinTarget:     invitationJoinTarget(invitation),
+	})
+	if err != nil {
+		log.Printf("sendInvitationEmail: error executing template: %s", err.Error())
+		return err
+	}
+
+	err = mailerService.SendEmail("", invitation.Email, "You have been invited", "", htmlBody.String())
+	if err != nil {
+		log.Printf("sendInvitationEmail: error sending email: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+type createInvitationDTO struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// CreateInvitation records an invitation and e-mails its link. Inviting with
+// a role also requires the permission to assign roles.
+func (ic *InvitationController) CreateInvitation(c *gin.Context) {
+	value, exists := c.Get("claims")
+	if !exists {
+		log.Print("CreateInvitation: claims value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+	claims := value.(*services.TokenClaims)
+
+	var invitationDTO createInvitationDTO
+
+	err := c.ShouldBindJSON(&invitationDTO)
+	if err != nil {
+		log.Printf("CreateInvitation: error during binding createInvitationDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	if invitationDTO.Role != "" && !claims.HasPermission(utils.PermissionRolesWrite) {
+		log.Printf("CreateInvitation: user %d cannot invite with role %s", claims.UserID, invitationDTO.Role)
+		c.JSON(http.StatusForbidden, utils.GetErrorResponse(utils.ErrForbidden))
+		return
+	}
+
+	invitationToken, err := ic.invitationService.CreateInvitation(claims.UserID, invitationDTO.Email, invitationDTO.Role)
+	if err != nil {
+		log.Printf("CreateInvitation: error creating invitation: %s", err.Error())
+		status, err := invitationErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	err = sendInvitationEmail(ic.mailerService, ic.invitationURL, invitationToken.Invitation, invitationToken.Token)
+	if err != nil {
+		log.Printf("CreateInvitation: error sending invitation email: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusCreated, map[string]any{
+		"data": invitationToken.Invitation,
+	})
+}
+
+func (ic *InvitationController) ListInvitations(c *gin.Context) {
+	page, pageSize, err := getPagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(err))
+		return
+	}
+
+	invitations, total, err := ic.invitationService.ListInvitations(page, pageSize)
+	if err != nil {
+		log.Printf("ListInvitations: error listing invitations: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": map[string]any{
+			"invitations": invitations,
+			"page":        page,
+			"page_size":   pageSize,
+			"total":       total,
+		},
+	})
+}
+
+func (ic *InvitationController) RevokeInvitation(c *gin.Context) {
+	invitationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Printf("RevokeInvitation: invalid invitationID: %s", err.Error())
+		c.JSON(http.StatusNotFound, utils.GetErrorResponse(utils.ErrInvitationNotFound))
+		return
+	}
+
+	err = ic.invitationService.RevokeInvitation(invitationID)
+	if err != nil {
+		log.Printf("RevokeInvitation: error revoking invitation: %s", err.Error())
+		status, err := invitationErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"message": "invitation revoked",
+	})
+}
+
+type acceptInvitationDTO struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// AcceptInvitation creates the invitee's account. The account is active
+// right away and can log in with the chosen password.
+func (ic *InvitationController) AcceptInvitation(c *gin.Context) {
+	var acceptDTO acceptInvitationDTO
+
+	err := c.ShouldBindJSON(&acceptDTO)
+	if err != nil {
+		log.Printf("AcceptInvitation: error during binding acceptInvitationDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	user, err := ic.invitationService.AcceptInvitation(acceptDTO.Token, acceptDTO.Password)
+	if err != nil {
+		log.Printf("AcceptInvitation: error accepting invitation: %s", err.Error())
+
+		var policyErr *utils.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			c.JSON(http.StatusBadRequest, utils.GetPasswordPolicyErrorResponse(policyErr))
+			return
+		}
+
+		status, err := invitationErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, map[string]any{
+		"data": user,
+	})
+}
+
+type joinWithInvitationDTO struct {
+	Token string `json:"token"`
+}
+
+// JoinWithInvitation lets a logged-in user join the organization or app of
+// an invitation sent to their e-mail.
+func (ic *InvitationController) JoinWithInvitation(c *gin.Context) {
+	var joinDTO joinWithInvitationDTO
+
+	err := c.ShouldBindJSON(&joinDTO)
+	if err != nil {
+		log.Printf("JoinWithInvitation: error during binding joinWithInvitationDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("JoinWithInvitation: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	invitation, err := ic.invitationService.JoinWithInvitation(joinDTO.Token, userID.(models.UserID))
+	if err != nil {
+		log.Printf("JoinWithInvitation: error accepting invitation: %s", err.Error())
+		status, err := invitationErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": map[string]any{
+			"invitation": invitation,
+		},
+	})
+}
@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IMFAController interface {
+	EnrollTOTP(c *gin.Context)
+	ConfirmTOTP(c *gin.Context)
+	DisableTOTP(c *gin.Context)
+	RegenerateRecoveryCodes(c *gin.Context)
+}
+
+type MFAController struct {
+	mfaService  services.IMFAService
+	userService services.IUserService
+}
+
+func NewMFAController(mfaService services.IMFAService, userService services.IUserService) IMFAController {
+	return &MFAController{
+		mfaService:  mfaService,
+		userService: userService,
+	}
+}
+
+func (mc *MFAController) EnrollTOTP(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("EnrollTOTP: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	user, err := mc.userService.GetUserByID(userID.(models.UserID))
+	if err != nil {
+		log.Printf("EnrollTOTP: error getting user: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	secret, uri, err := mc.mfaService.BeginTOTPEnrollment(user)
+	if err != nil {
+		log.Printf("EnrollTOTP: error starting enrollment: %s", err.Error())
+
+		if errors.Is(err, utils.ErrMFAAlreadyEnabled) {
+			c.JSON(http.StatusConflict, utils.GetErrorResponse(utils.ErrMFAAlreadyEnabled))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusCreated, map[string]any{
+		"message": "scan the uri with your authenticator app and confirm with the first code",
+		"data": map[string]any{
+			"secret":      secret,
+			"otpauth_uri": uri,
+		},
+	})
+}
+
+type totpCodeDTO struct {
+	Code string `json:"code"`
+}
+
+func (mc *MFAController) ConfirmTOTP(c *gin.Context) {
+	var codeDTO totpCodeDTO
+
+	err := c.ShouldBindJSON(&codeDTO)
+	if err != nil {
+		log.Printf("ConfirmTOTP: error during binding totpCodeDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("ConfirmTOTP: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	recoveryCodes, err := mc.mfaService.ConfirmTOTPEnrollment(userID.(models.UserID), codeDTO.Code)
+	if err != nil {
+		log.Printf("ConfirmTOTP: error confirming enrollment: %s", err.Error())
+
+		if errors.Is(err, utils.ErrMFACodeInvalid) {
+			c.JSON(http.StatusUnprocessableEntity, utils.GetErrorResponse(utils.ErrMFACodeInvalid))
+			return
+		}
+
+		if errors.Is(err, utils.ErrMFAEnrollmentNotFound) {
+			c.JSON(http.StatusNotFound, utils.GetErrorResponse(utils.ErrMFAEnrollmentNotFound))
+			return
+		}
+
+		if errors.Is(err, utils.ErrMFAAlreadyEnabled) {
+			c.JSON(http.StatusConflict, utils.GetErrorResponse(utils.ErrMFAAlreadyEnabled))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "mfa enabled, store the recovery codes in a safe place. they will not be shown again.",
+		"data": map[string]any{
+			"recovery_codes": recoveryCodes,
+		},
+	})
+}
+
+func (mc *MFAController) DisableTOTP(c *gin.Context) {
+	var codeDTO totpCodeDTO
+
+	err := c.ShouldBindJSON(&codeDTO)
+	if err != nil {
+		log.Printf("DisableTOTP: error during binding totpCodeDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("DisableTOTP: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	err = mc.mfaService.DisableTOTP(userID.(models.UserID), codeDTO.Code)
+	if err != nil {
+		log.Printf("DisableTOTP: error disabling mfa: %s", err.Error())
+
+		if errors.Is(err, utils.ErrMFACodeInvalid) || errors.Is(err, utils.ErrMFACodeReused) {
+			c.JSON(http.StatusUnprocessableEntity, utils.GetErrorResponse(err))
+			return
+		}
+
+		if errors.Is(err, utils.ErrMFANotEnabled) {
+			c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrMFANotEnabled))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"message": "mfa disabled",
+	})
+}
+
+func (mc *MFAController) RegenerateRecoveryCodes(c *gin.Context) {
+	var codeDTO totpCodeDTO
+
+	err := c.ShouldBindJSON(&codeDTO)
+	if err != nil {
+		log.Printf("RegenerateRecoveryCodes: error during binding totpCodeDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("RegenerateRecoveryCodes: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	recoveryCodes, err := mc.mfaService.RegenerateRecoveryCodes(userID.(models.UserID), codeDTO.Code)
+	if err != nil {
+		log.Printf("RegenerateRecoveryCodes: error regenerating recovery codes: %s", err.Error())
+
+		if errors.Is(err, utils.ErrMFACodeInvalid) || errors.Is(err, utils.ErrMFACodeReused) {
+			c.JSON(http.StatusUnprocessableEntity, utils.GetErrorResponse(err))
+			return
+		}
+
+		if errors.Is(err, utils.ErrMFANotEnabled) {
+			c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrMFANotEnabled))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "recovery codes regenerated, the previous ones are no longer valid.",
+		"data": map[string]any{
+			"recovery_codes": recoveryCodes,
+		},
+	})
+}
@@ -0,0 +1,346 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IOrganizationController interface {
+	CreateOrganization(c *gin.Context)
+	ListOrganizations(c *gin.Context)
+	GetOrganization(c *gin.Context)
+	GetMembers(c *gin.Context)
+	AddMember(c *gin.Context)
+	UpdateMember(c *gin.Context)
+	RemoveMember(c *gin.Context)
+	SwitchOrganization(c *gin.Context)
+}
+
+type OrganizationController struct {
+	organizationService services.IOrganizationService
+	jwtService          services.IJWTService
+	mailerService       services.MailerService
+	invitationURL       string
+}
+
+func NewOrganizationController(
+	organizationService services.IOrganizationService,
+	jwtService services.IJWTService,
+	mailerService services.MailerService,
+	invitationURL string,
+) IOrganizationController {
+	return &OrganizationController{
+		organizationService: organizationService,
+		jwtService:          jwtService,
+		mailerService:       mailerService,
+		invitationURL:       invitationURL,
+	}
+}
+
+// organizationErrorStatus maps errors of the organization operations to a
+// response.
+func organizationErrorStatus(err error) (int, error) {
+	switch {
+	case errors.Is(err, utils.ErrOrganizationNotFound), errors.Is(err, utils.ErrOrganizationMemberNotFound):
+		return http.StatusNotFound, err
+	case errors.Is(err, utils.ErrOrganizationNameInvalid), errors.Is(err, utils.ErrOrganizationRoleInvalid), errors.Is(err, utils.ErrInvalidEmail):
+		return http.StatusBadRequest, err
+	case errors.Is(err, utils.ErrForbidden):
+		return http.StatusForbidden, err
+	case errors.Is(err, utils.ErrOrganizationMemberAlreadyExists), errors.Is(err, utils.ErrOrganizationLastOwner):
+		return http.StatusConflict, err
+	default:
+		return http.StatusInternalServerError, utils.ErrInternalServerError
+	}
+}
+
+// getOrganizationRequest reads the authenticated user and the :id path
+// parameter. It writes the error response itself and returns ok=false on
+// failure.
+func getOrganizationRequest(c *gin.Context, caller string) (userID models.UserID, organizationID models.OrganizationID, ok bool) {
+	value, exists := c.Get("userID")
+	if !exists {
+		log.Printf("%s: userID value do not exists in context", caller)
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return 0, 0, false
+	}
+
+	organizationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Printf("%s: invalid organizationID: %s", caller, err.Error())
+		c.JSON(http.StatusNotFound, utils.GetErrorResponse(utils.ErrOrganizationNotFound))
+		return 0, 0, false
+	}
+
+	return value.(models.UserID), organizationID, true
+}
+
+type createOrganizationDTO struct {
+	Name string `json:"name"`
+}
+
+func (oc *OrganizationController) CreateOrganization(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("CreateOrganization: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	var organizationDTO createOrganizationDTO
+
+	err := c.ShouldBindJSON(&organizationDTO)
+	if err != nil {
+		log.Printf("CreateOrganization: error during binding createOrganizationDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	organization, err := oc.organizationService.CreateOrganization(userID.(models.UserID), organizationDTO.Name)
+	if err != nil {
+		log.Printf("CreateOrganization: error creating organization: %s", err.Error())
+		status, err := organizationErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, map[string]any{
+		"data": organization,
+	})
+}
+
+func (oc *OrganizationController) ListOrganizations(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("ListOrganizations: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	organizations, err := oc.organizationService.GetUserOrganizations(userID.(models.UserID))
+	if err != nil {
+		log.Printf("ListOrganizations: error getting organizations: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": organizations,
+	})
+}
+
+func (oc *OrganizationController) GetOrganization(c *gin.Context) {
+	userID, organizationID, ok := getOrganizationRequest(c, "GetOrganization")
+	if !ok {
+		return
+	}
+
+	organization, err := oc.organizationService.GetOrganization(organizationID, userID)
+	if err != nil {
+		log.Printf("GetOrganization: error getting organization: %s", err.Error())
+		status, err := organizationErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": organization,
+	})
+}
+
+func (oc *OrganizationController) GetMembers(c *gin.Context) {
+	userID, organizationID, ok := getOrganizationRequest(c, "GetMembers")
+	if !ok {
+		return
+	}
+
+	members, err := oc.organizationService.GetMembers(organizationID, userID)
+	if err != nil {
+		log.Printf("GetMembers: error getting members: %s", err.Error())
+		status, err := organizationErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": members,
+	})
+}
+
+type addOrganizationMemberDTO struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// AddMember e-mails an invitation into the organization. It answers 202
+// whether or not the e-mail has an account.
+func (oc *OrganizationController) AddMember(c *gin.Context) {
+	userID, organizationID, ok := getOrganizationRequest(c, "AddMember")
+	if !ok {
+		return
+	}
+
+	var memberDTO addOrganizationMemberDTO
+
+	err := c.ShouldBindJSON(&memberDTO)
+	if err != nil {
+		log.Printf("AddMember: error during binding addOrganizationMemberDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	if memberDTO.Role == "" {
+		memberDTO.Role = utils.OrganizationRoleMember
+	}
+
+	invitation, err := oc.organizationService.AddMember(organizationID, userID, memberDTO.Email, memberDTO.Role)
+	if err != nil {
+		log.Printf("AddMember: error adding member: %s", err.Error())
+		status, err := organizationErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	err = sendInvitationEmail(oc.mailerService, oc.invitationURL, invitation.Invitation, invitation.Token)
+	if err != nil {
+		log.Printf("AddMember: error sending invitation email: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, map[string]any{
+		"data": map[string]any{
+			"invitation": invitation.Invitation,
+		},
+	})
+}
+
+type updateOrganizationMemberDTO struct {
+	Role string `json:"role"`
+}
+
+func (oc *OrganizationController) UpdateMember(c *gin.Context) {
+	userID, organizationID, ok := getOrganizationRequest(c, "UpdateMember")
+	if !ok {
+		return
+	}
+
+	memberID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		log.Printf("UpdateMember: invalid userID: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrInvalidUserID))
+		return
+	}
+
+	var memberDTO updateOrganizationMemberDTO
+
+	err = c.ShouldBindJSON(&memberDTO)
+	if err != nil {
+		log.Printf("UpdateMember: error during binding updateOrganizationMemberDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	err = oc.organizationService.UpdateMemberRole(organizationID, userID, memberID, memberDTO.Role)
+	if err != nil {
+		log.Printf("UpdateMember: error updating member: %s", err.Error())
+		status, err := organizationErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"message": "member updated",
+	})
+}
+
+// RemoveMember removes a member. Members may remove themselves to leave the
+// organization.
+func (oc *OrganizationController) RemoveMember(c *gin.Context) {
+	userID, organizationID, ok := getOrganizationRequest(c, "RemoveMember")
+	if !ok {
+		return
+	}
+
+	memberID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		log.Printf("RemoveMember: invalid userID: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrInvalidUserID))
+		return
+	}
+
+	err = oc.organizationService.RemoveMember(organizationID, userID, memberID)
+	if err != nil {
+		log.Printf("RemoveMember: error removing member: %s", err.Error())
+		status, err := organizationErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"message": "member removed",
+	})
+}
+
+type switchOrganizationDTO struct {
+	OrganizationID *models.OrganizationID `json:"organization_id"`
+}
+
+// SwitchOrganization changes the organization the user acts in and returns
+// an access token carrying it. A null organization_id switches back to the
+// personal context. Refreshed tokens keep the choice.
+func (oc *OrganizationController) SwitchOrganization(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("SwitchOrganization: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	var switchDTO switchOrganizationDTO
+
+	err := c.ShouldBindJSON(&switchDTO)
+	if err != nil {
+		log.Printf("SwitchOrganization: error during binding switchOrganizationDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	err = oc.organizationService.SwitchOrganization(userID.(models.UserID), switchDTO.OrganizationID)
+	if err != nil {
+		log.Printf("SwitchOrganization: error switching organization: %s", err.Error())
+		status, err := organizationErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	accessToken, err := oc.jwtService.GenerateToken(userID.(models.UserID))
+	if err != nil {
+		log.Printf("SwitchOrganization: error generating token: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": map[string]any{
+			"access_token":    accessToken,
+			"organization_id": switchDTO.OrganizationID,
+		},
+	})
+}
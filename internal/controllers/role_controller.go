@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IRoleController interface {
+	ListRoles(c *gin.Context)
+	CreateRole(c *gin.Context)
+	DeleteRole(c *gin.Context)
+	ListPermissions(c *gin.Context)
+	GetUserRoles(c *gin.Context)
+	AssignUserRole(c *gin.Context)
+	RemoveUserRole(c *gin.Context)
+}
+
+type RoleController struct {
+	rbacService services.IRBACService
+}
+
+func NewRoleController(rbacService services.IRBACService) IRoleController {
+	return &RoleController{
+		rbacService: rbacService,
+	}
+}
+
+// roleErrorStatus maps errors of the role operations to a response.
+func roleErrorStatus(err error) (int, error) {
+	switch {
+	case errors.Is(err, utils.ErrRoleNotFound), errors.Is(err, utils.ErrUserNotFound):
+		return http.StatusNotFound, err
+	case errors.Is(err, utils.ErrRoleNameInvalid), errors.Is(err, utils.ErrPermissionNotFound):
+		return http.StatusBadRequest, err
+	case errors.Is(err, utils.ErrRoleAlreadyExists), errors.Is(err, utils.ErrRoleProtected):
+		return http.StatusConflict, err
+	default:
+		return http.StatusInternalServerError, utils.ErrInternalServerError
+	}
+}
+
+func (rc *RoleController) ListRoles(c *gin.Context) {
+	roles, err := rc.rbacService.ListRoles()
+	if err != nil {
+		log.Printf("ListRoles: error listing roles: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": roles,
+	})
+}
+
+type createRoleDTO struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+func (rc *RoleController) CreateRole(c *gin.Context) {
+	var roleDTO createRoleDTO
+
+	err := c.ShouldBindJSON(&roleDTO)
+	if err != nil {
+		log.Printf("CreateRole: error during binding createRoleDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	role, err := rc.rbacService.CreateRole(roleDTO.Name, roleDTO.Description, roleDTO.Permissions)
+	if err != nil {
+		log.Printf("CreateRole: error creating role: %s", err.Error())
+		status, err := roleErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, map[string]any{
+		"data": role,
+	})
+}
+
+func (rc *RoleController) DeleteRole(c *gin.Context) {
+	err := rc.rbacService.DeleteRole(c.Param("name"))
+	if err != nil {
+		log.Printf("DeleteRole: error deleting role: %s", err.Error())
+		status, err := roleErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"message": "role deleted",
+	})
+}
+
+func (rc *RoleController) ListPermissions(c *gin.Context) {
+	permissions, err := rc.rbacService.ListPermissions()
+	if err != nil {
+		log.Printf("ListPermissions: error listing permissions: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": permissions,
+	})
+}
+
+func (rc *RoleController) GetUserRoles(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Printf("GetUserRoles: invalid userID: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrInvalidUserID))
+		return
+	}
+
+	roles, err := rc.rbacService.GetUserRoles(userID)
+	if err != nil {
+		log.Printf("GetUserRoles: error getting user roles: %s", err.Error())
+		status, err := roleErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": roles,
+	})
+}
+
+type assignRoleDTO struct {
+	Role string `json:"role"`
+}
+
+func (rc *RoleController) AssignUserRole(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Printf("AssignUserRole: invalid userID: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrInvalidUserID))
+		return
+	}
+
+	var roleDTO assignRoleDTO
+
+	err = c.ShouldBindJSON(&roleDTO)
+	if err != nil {
+		log.Printf("AssignUserRole: error during binding assignRoleDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	err = rc.rbacService.AssignRole(userID, roleDTO.Role)
+	if err != nil {
+		log.Printf("AssignUserRole: error assigning role: %s", err.Error())
+		status, err := roleErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"message": "role assigned",
+	})
+}
+
+func (rc *RoleController) RemoveUserRole(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Printf("RemoveUserRole: invalid userID: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrInvalidUserID))
+		return
+	}
+
+	err = rc.rbacService.RemoveRole(userID, c.Param("role"))
+	if err != nil {
+		log.Printf("RemoveUserRole: error removing role: %s", err.Error())
+		status, err := roleErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"message": "role removed",
+	})
+}
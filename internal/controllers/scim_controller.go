@@ -0,0 +1,374 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type ISCIMController interface {
+	ListUsers(c *gin.Context)
+	GetUser(c *gin.Context)
+	CreateUser(c *gin.Context)
+	ReplaceUser(c *gin.Context)
+	PatchUser(c *gin.Context)
+	DeleteUser(c *gin.Context)
+	ListGroups(c *gin.Context)
+	GetGroup(c *gin.Context)
+	CreateGroup(c *gin.Context)
+	ReplaceGroup(c *gin.Context)
+	PatchGroup(c *gin.Context)
+	DeleteGroup(c *gin.Context)
+}
+
+type SCIMController struct {
+	scimService services.ISCIMService
+	baseURL     string
+}
+
+func NewSCIMController(scimService services.ISCIMService, baseURL string) ISCIMController {
+	return &SCIMController{
+		scimService: scimService,
+		baseURL:     baseURL,
+	}
+}
+
+// scimMaxCount caps the page size of SCIM listings.
+const scimMaxCount = 100
+
+// scimErrorStatus maps errors of the SCIM operations to a status and a SCIM
+// error type.
+func scimErrorStatus(err error) (int, string, error) {
+	switch {
+	case errors.Is(err, utils.ErrUserNotFound), errors.Is(err, utils.ErrRoleNotFound):
+		return http.StatusNotFound, "", err
+	case errors.Is(err, utils.ErrUserEmailAlreadyExists), errors.Is(err, utils.ErrRoleAlreadyExists):
+		return http.StatusConflict, utils.SCIMErrorUniqueness, err
+	case errors.Is(err, utils.ErrSCIMFilterInvalid):
+		return http.StatusBadRequest, utils.SCIMErrorInvalidFilter, err
+	case errors.Is(err, utils.ErrSCIMPatchInvalid):
+		return http.StatusBadRequest, utils.SCIMErrorInvalidSyntax, err
+	case errors.Is(err, utils.ErrSCIMGroupProtected):
+		return http.StatusForbidden, "", err
+	case errors.Is(err, utils.ErrSCIMGroupRenameNotSupported), errors.Is(err, utils.ErrRoleProtected):
+		return http.StatusBadRequest, utils.SCIMErrorMutability, err
+	case errors.Is(err, utils.ErrInvalidEmail), errors.Is(err, utils.ErrPasswordTooShort), errors.Is(err, utils.ErrPasswordPolicy),
+		errors.Is(err, utils.ErrDisplayNameInvalid), errors.Is(err, utils.ErrLocaleInvalid), errors.Is(err, utils.ErrTimezoneInvalid),
+		errors.Is(err, utils.ErrRoleNameInvalid), errors.Is(err, utils.ErrSCIMGroupMemberInvalid):
+		return http.StatusBadRequest, utils.SCIMErrorInvalidValue, err
+	default:
+		return http.StatusInternalServerError, "", utils.ErrInternalServerError
+	}
+}
+
+// scimJSON writes body with the SCIM media type.
+func scimJSON(c *gin.Context, status int, body any) {
+	c.Header("Content-Type", "application/scim+json")
+	c.JSON(status, body)
+}
+
+func scimAbort(c *gin.Context, caller string, err error) {
+	log.Printf("%s: %s", caller, err.Error())
+
+	status, scimType, err := scimErrorStatus(err)
+	scimJSON(c, status, utils.GetSCIMErrorResponse(status, scimType, err))
+}
+
+func scimBindError(c *gin.Context, caller string, err error) {
+	log.Printf("%s: error during binding request: %s", caller, err.Error())
+	scimJSON(c, http.StatusBadRequest, utils.GetSCIMErrorResponse(
+		http.StatusBadRequest,
+		utils.SCIMErrorInvalidSyntax,
+		fmt.Errorf("error parsing request body: %w", err),
+	))
+}
+
+// scimPagination reads the 1-based startIndex and the count of a listing.
+// Out of range values are clamped, as RFC 7644 asks.
+func scimPagination(c *gin.Context) (startIndex int, count int) {
+	startIndex, err := strconv.Atoi(c.DefaultQuery("startIndex", "1"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+
+	count, err = strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(scimMaxCount)))
+	if err != nil || count > scimMaxCount {
+		count = scimMaxCount
+	}
+	if count < 0 {
+		count = 0
+	}
+
+	return startIndex, count
+}
+
+func scimListResponse(resources any, total int, startIndex int, itemsPerPage int) models.SCIMListResponse {
+	return models.SCIMListResponse{
+		Schemas:      []string{utils.SCIMSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: itemsPerPage,
+		Resources:    resources,
+	}
+}
+
+func (sc *SCIMController) userLocation(user *models.SCIMUser) string {
+	location := sc.baseURL + "/scim/v2/Users/" + user.ID
+	user.Meta.Location = location
+	return location
+}
+
+func (sc *SCIMController) groupLocation(group *models.SCIMGroup) string {
+	location := sc.baseURL + "/scim/v2/Groups/" + group.ID
+	group.Meta.Location = location
+	return location
+}
+
+// getSCIMID reads the numeric id of the resource in the path. Ids that are
+// not numbers belong to no resource.
+func getSCIMID(c *gin.Context, caller string, notFound error) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		scimAbort(c, caller, notFound)
+		return 0, false
+	}
+
+	return id, true
+}
+
+func (sc *SCIMController) ListUsers(c *gin.Context) {
+	startIndex, count := scimPagination(c)
+
+	users, total, err := sc.scimService.GetUsers(c.Query("filter"), startIndex, count)
+	if err != nil {
+		scimAbort(c, "ListUsers", err)
+		return
+	}
+
+	for i := range users {
+		sc.userLocation(&users[i])
+	}
+
+	scimJSON(c, http.StatusOK, scimListResponse(users, total, startIndex, len(users)))
+}
+
+func (sc *SCIMController) GetUser(c *gin.Context) {
+	userID, ok := getSCIMID(c, "GetUser", utils.ErrUserNotFound)
+	if !ok {
+		return
+	}
+
+	user, err := sc.scimService.GetUser(userID)
+	if err != nil {
+		scimAbort(c, "GetUser", err)
+		return
+	}
+
+	sc.userLocation(user)
+	scimJSON(c, http.StatusOK, user)
+}
+
+func (sc *SCIMController) CreateUser(c *gin.Context) {
+	var resource models.SCIMUser
+
+	err := c.ShouldBindJSON(&resource)
+	if err != nil {
+		scimBindError(c, "CreateUser", err)
+		return
+	}
+
+	user, err := sc.scimService.CreateUser(&resource)
+	if err != nil {
+		scimAbort(c, "CreateUser", err)
+		return
+	}
+
+	c.Header("Location", sc.userLocation(user))
+	scimJSON(c, http.StatusCreated, user)
+}
+
+func (sc *SCIMController) ReplaceUser(c *gin.Context) {
+	userID, ok := getSCIMID(c, "ReplaceUser", utils.ErrUserNotFound)
+	if !ok {
+		return
+	}
+
+	var resource models.SCIMUser
+
+	err := c.ShouldBindJSON(&resource)
+	if err != nil {
+		scimBindError(c, "ReplaceUser", err)
+		return
+	}
+
+	user, err := sc.scimService.ReplaceUser(userID, &resource)
+	if err != nil {
+		scimAbort(c, "ReplaceUser", err)
+		return
+	}
+
+	sc.userLocation(user)
+	scimJSON(c, http.StatusOK, user)
+}
+
+func (sc *SCIMController) PatchUser(c *gin.Context) {
+	userID, ok := getSCIMID(c, "PatchUser", utils.ErrUserNotFound)
+	if !ok {
+		return
+	}
+
+	var patch models.SCIMPatchRequest
+
+	err := c.ShouldBindJSON(&patch)
+	if err != nil {
+		scimBindError(c, "PatchUser", err)
+		return
+	}
+
+	user, err := sc.scimService.PatchUser(userID, patch.Operations)
+	if err != nil {
+		scimAbort(c, "PatchUser", err)
+		return
+	}
+
+	sc.userLocation(user)
+	scimJSON(c, http.StatusOK, user)
+}
+
+// DeleteUser deprovisions the user: they are kept, inactive and without
+// sessions.
+func (sc *SCIMController) DeleteUser(c *gin.Context) {
+	userID, ok := getSCIMID(c, "DeleteUser", utils.ErrUserNotFound)
+	if !ok {
+		return
+	}
+
+	err := sc.scimService.DeprovisionUser(userID)
+	if err != nil {
+		scimAbort(c, "DeleteUser", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (sc *SCIMController) ListGroups(c *gin.Context) {
+	startIndex, count := scimPagination(c)
+
+	groups, total, err := sc.scimService.GetGroups(c.Query("filter"), startIndex, count)
+	if err != nil {
+		scimAbort(c, "ListGroups", err)
+		return
+	}
+
+	for i := range groups {
+		sc.groupLocation(&groups[i])
+	}
+
+	scimJSON(c, http.StatusOK, scimListResponse(groups, total, startIndex, len(groups)))
+}
+
+func (sc *SCIMController) GetGroup(c *gin.Context) {
+	roleID, ok := getSCIMID(c, "GetGroup", utils.ErrRoleNotFound)
+	if !ok {
+		return
+	}
+
+	group, err := sc.scimService.GetGroup(roleID)
+	if err != nil {
+		scimAbort(c, "GetGroup", err)
+		return
+	}
+
+	sc.groupLocation(group)
+	scimJSON(c, http.StatusOK, group)
+}
+
+func (sc *SCIMController) CreateGroup(c *gin.Context) {
+	var resource models.SCIMGroup
+
+	err := c.ShouldBindJSON(&resource)
+	if err != nil {
+		scimBindError(c, "CreateGroup", err)
+		return
+	}
+
+	group, err := sc.scimService.CreateGroup(&resource)
+	if err != nil {
+		scimAbort(c, "CreateGroup", err)
+		return
+	}
+
+	c.Header("Location", sc.groupLocation(group))
+	scimJSON(c, http.StatusCreated, group)
+}
+
+func (sc *SCIMController) ReplaceGroup(c *gin.Context) {
+	roleID, ok := getSCIMID(c, "ReplaceGroup", utils.ErrRoleNotFound)
+	if !ok {
+		return
+	}
+
+	var resource models.SCIMGroup
+
+	err := c.ShouldBindJSON(&resource)
+	if err != nil {
+		scimBindError(c, "ReplaceGroup", err)
+		return
+	}
+
+	group, err := sc.scimService.ReplaceGroup(roleID, &resource)
+	if err != nil {
+		scimAbort(c, "ReplaceGroup", err)
+		return
+	}
+
+	sc.groupLocation(group)
+	scimJSON(c, http.StatusOK, group)
+}
+
+func (sc *SCIMController) PatchGroup(c *gin.Context) {
+	roleID, ok := getSCIMID(c, "PatchGroup", utils.ErrRoleNotFound)
+	if !ok {
+		return
+	}
+
+	var patch models.SCIMPatchRequest
+
+	err := c.ShouldBindJSON(&patch)
+	if err != nil {
+		scimBindError(c, "PatchGroup", err)
+		return
+	}
+
+	group, err := sc.scimService.PatchGroup(roleID, patch.Operations)
+	if err != nil {
+		scimAbort(c, "PatchGroup", err)
+		return
+	}
+
+	sc.groupLocation(group)
+	scimJSON(c, http.StatusOK, group)
+}
+
+func (sc *SCIMController) DeleteGroup(c *gin.Context) {
+	roleID, ok := getSCIMID(c, "DeleteGroup", utils.ErrRoleNotFound)
+	if !ok {
+		return
+	}
+
+	err := sc.scimService.DeleteGroup(roleID)
+	if err != nil {
+		scimAbort(c, "DeleteGroup", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -18,23 +18,40 @@ import (
 type IUserController interface {
 	CreateUser(c *gin.Context)
 	VerifyUser(c *gin.Context)
+	GetMe(c *gin.Context)
+	UpdateMe(c *gin.Context)
+	DeleteMe(c *gin.Context)
+	CancelDeletion(c *gin.Context)
+	GetLogins(c *gin.Context)
 }
 
 type UserController struct {
 	userService                   services.IUserService
 	emailVerificationTokenService services.IEmailVerificationTokenService
 	mailerService                 services.MailerService
+	accountDeletionService        services.IAccountDeletionService
+	mfaService                    services.IMFAService
+	passwordPolicyService         services.IPasswordPolicyService
+	loginHistoryService           services.ILoginHistoryService
 }
 
 func NewUserController(
 	userService services.IUserService,
 	evtService services.IEmailVerificationTokenService,
 	mailerService services.MailerService,
+	accountDeletionService services.IAccountDeletionService,
+	mfaService services.IMFAService,
+	passwordPolicyService services.IPasswordPolicyService,
+	loginHistoryService services.ILoginHistoryService,
 ) IUserController {
 	return &UserController{
 		userService:                   userService,
 		emailVerificationTokenService: evtService,
 		mailerService:                 mailerService,
+		accountDeletionService:        accountDeletionService,
+		mfaService:                    mfaService,
+		passwordPolicyService:         passwordPolicyService,
+		loginHistoryService:           loginHistoryService,
 	}
 }
 
@@ -94,6 +111,18 @@ func (ac *UserController) CreateUser(c *gin.Context) {
 		return
 	}
 
+	err = ac.passwordPolicyService.Validate(user.Password, user.Email)
+	if err != nil {
+		var policyErr *utils.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			c.JSON(http.StatusBadRequest, utils.GetPasswordPolicyErrorResponse(policyErr))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
 	err = ac.userService.CreateUser(user)
 	if err != nil {
 		log.Printf("CreateUser: error creating user: %s", err.Error())
@@ -186,3 +215,219 @@ func (ac *UserController) VerifyUser(c *gin.Context) {
 		"message": "user activated",
 	})
 }
+
+func (ac *UserController) GetMe(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("GetMe: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	user, err := ac.userService.GetUserByID(userID.(models.UserID))
+	if err != nil {
+		log.Printf("GetMe: error getting user: %s", err.Error())
+
+		if errors.Is(err, utils.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, utils.GetErrorResponse(utils.ErrUserNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	mfaEnabled, err := ac.mfaService.IsMFAEnabled(user.ID)
+	if err != nil {
+		log.Printf("GetMe: error checking mfa: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	mfa := map[string]any{
+		"enabled": mfaEnabled,
+	}
+
+	if mfaEnabled {
+		remaining, err := ac.mfaService.CountRecoveryCodes(user.ID)
+		if err != nil {
+			log.Printf("GetMe: error counting recovery codes: %s", err.Error())
+			c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+			return
+		}
+
+		mfa["recovery_codes_remaining"] = remaining
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": map[string]any{
+			"user": user,
+			"mfa":  mfa,
+		},
+	})
+}
+
+func (ac *UserController) UpdateMe(c *gin.Context) {
+	var profile models.UserProfile
+
+	err := c.ShouldBindJSON(&profile)
+	if err != nil {
+		log.Printf("UpdateMe: error during binding profile: %s", err.Error())
+
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("UpdateMe: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	user, err := ac.userService.UpdateProfile(userID.(models.UserID), &profile)
+	if err != nil {
+		log.Printf("UpdateMe: error updating profile: %s", err.Error())
+
+		if errors.Is(err, utils.ErrDisplayNameInvalid) ||
+			errors.Is(err, utils.ErrLocaleInvalid) ||
+			errors.Is(err, utils.ErrTimezoneInvalid) ||
+			errors.Is(err, utils.ErrAvatarURLInvalid) {
+			c.JSON(http.StatusBadRequest, utils.GetErrorResponse(err))
+			return
+		}
+
+		if errors.Is(err, utils.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, utils.GetErrorResponse(utils.ErrUserNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": map[string]any{
+			"user": user,
+		},
+	})
+}
+
+type deleteMeDTO struct {
+	Password string `json:"password"`
+}
+
+func (ac *UserController) DeleteMe(c *gin.Context) {
+	var deleteDTO deleteMeDTO
+
+	err := c.ShouldBindJSON(&deleteDTO)
+	if err != nil {
+		log.Printf("DeleteMe: error during binding deleteMeDTO: %s", err.Error())
+
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("DeleteMe: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	scheduledAt, err := ac.accountDeletionService.RequestDeletion(userID.(models.UserID), deleteDTO.Password)
+	if err != nil {
+		log.Printf("DeleteMe: error requesting deletion: %s", err.Error())
+
+		if errors.Is(err, utils.ErrPasswordIncorrect) {
+			c.JSON(http.StatusUnprocessableEntity, utils.GetErrorResponse(utils.ErrPasswordIncorrect))
+			return
+		}
+
+		if errors.Is(err, utils.ErrUserDeletionAlreadyScheduled) {
+			c.JSON(http.StatusConflict, utils.GetErrorResponse(utils.ErrUserDeletionAlreadyScheduled))
+			return
+		}
+
+		if errors.Is(err, utils.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, utils.GetErrorResponse(utils.ErrUserNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, map[string]any{
+		"message": "account scheduled for deletion",
+		"data": map[string]any{
+			"deletion_scheduled_at": scheduledAt,
+		},
+	})
+}
+
+func (ac *UserController) CancelDeletion(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("CancelDeletion: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	err := ac.accountDeletionService.CancelDeletion(userID.(models.UserID))
+	if err != nil {
+		log.Printf("CancelDeletion: error cancelling deletion: %s", err.Error())
+
+		if errors.Is(err, utils.ErrUserDeletionNotScheduled) {
+			c.JSON(http.StatusConflict, utils.GetErrorResponse(utils.ErrUserDeletionNotScheduled))
+			return
+		}
+
+		if errors.Is(err, utils.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, utils.GetErrorResponse(utils.ErrUserNotFound))
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]string{
+		"message": "account deletion cancelled",
+	})
+}
+
+func (ac *UserController) GetLogins(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("GetLogins: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	page, pageSize, err := getPagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(err))
+		return
+	}
+
+	events, total, err := ac.loginHistoryService.GetLoginHistory(userID.(models.UserID), page, pageSize)
+	if err != nil {
+		log.Printf("GetLogins: error getting login history: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": map[string]any{
+			"logins":    events,
+			"page":      page,
+			"page_size": pageSize,
+			"total":     total,
+		},
+	})
+}
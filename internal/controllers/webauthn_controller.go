@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IWebAuthnController interface {
+	BeginRegistration(c *gin.Context)
+	FinishRegistration(c *gin.Context)
+	GetCredentials(c *gin.Context)
+	DeleteCredential(c *gin.Context)
+}
+
+type WebAuthnController struct {
+	webAuthnService services.IWebAuthnService
+	userService     services.IUserService
+}
+
+func NewWebAuthnController(webAuthnService services.IWebAuthnService, userService services.IUserService) IWebAuthnController {
+	return &WebAuthnController{
+		webAuthnService: webAuthnService,
+		userService:     userService,
+	}
+}
+
+// webAuthnCredentialDTO is the JSON serialization of a PublicKeyCredential
+// as produced by the browser, with binary fields in base64url.
+type webAuthnCredentialDTO struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AttestationObject string `json:"attestationObject"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// decodeBase64URL accepts base64url with or without padding, since browser
+// helpers disagree on it.
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+func (dto *webAuthnCredentialDTO) toAttestation() (*services.WebAuthnAttestation, error) {
+	clientDataJSON, err := decodeBase64URL(dto.Response.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("invalid clientDataJSON: %w", err)
+	}
+
+	attestationObject, err := decodeBase64URL(dto.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestationObject: %w", err)
+	}
+
+	return &services.WebAuthnAttestation{
+		ClientDataJSON:    clientDataJSON,
+		AttestationObject: attestationObject,
+	}, nil
+}
+
+func (dto *webAuthnCredentialDTO) toAssertion() (*services.WebAuthnAssertion, error) {
+	rawID := dto.RawID
+	if rawID == "" {
+		rawID = dto.ID
+	}
+
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"rawId", rawID},
+		{"clientDataJSON", dto.Response.ClientDataJSON},
+		{"authenticatorData", dto.Response.AuthenticatorData},
+		{"signature", dto.Response.Signature},
+		{"userHandle", dto.Response.UserHandle},
+	}
+
+	decoded := make([][]byte, len(fields))
+	for i, field := range fields {
+		value, err := decodeBase64URL(field.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", field.name, err)
+		}
+		decoded[i] = value
+	}
+
+	return &services.WebAuthnAssertion{
+		CredentialID:      decoded[0],
+		ClientDataJSON:    decoded[1],
+		AuthenticatorData: decoded[2],
+		Signature:         decoded[3],
+		UserHandle:        decoded[4],
+	}, nil
+}
+
+// webAuthnErrorStatus maps WebAuthn ceremony errors to response status codes.
+func webAuthnErrorStatus(err error) (int, error) {
+	switch {
+	case errors.Is(err, utils.ErrWebAuthnChallengeInvalid):
+		return http.StatusBadRequest, utils.ErrWebAuthnChallengeInvalid
+	case errors.Is(err, utils.ErrWebAuthnVerificationFailed):
+		return http.StatusUnauthorized, utils.ErrWebAuthnVerificationFailed
+	case errors.Is(err, utils.ErrWebAuthnCredentialNotFound):
+		return http.StatusUnauthorized, utils.ErrWebAuthnCredentialNotFound
+	case errors.Is(err, utils.ErrWebAuthnCredentialAlreadyRegistered):
+		return http.StatusConflict, utils.ErrWebAuthnCredentialAlreadyRegistered
+	default:
+		return http.StatusInternalServerError, utils.ErrInternalServerError
+	}
+}
+
+// BeginRegistration starts a passkey registration. The body must carry the
+// user's password or a current TOTP code.
+func (wc *WebAuthnController) BeginRegistration(c *gin.Context) {
+	var reauthDTO webAuthnReauthenticationDTO
+
+	err := c.ShouldBindJSON(&reauthDTO)
+	if err != nil {
+		log.Printf("BeginRegistration: error during binding webAuthnReauthenticationDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("BeginRegistration: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	user, err := wc.userService.GetUserByID(userID.(models.UserID))
+	if err != nil {
+		log.Printf("BeginRegistration: error getting user: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	options, err := wc.webAuthnService.BeginRegistration(user, services.WebAuthnReauthentication{
+		Password: reauthDTO.Password,
+		Code:     reauthDTO.Code,
+	})
+	if err != nil {
+		log.Printf("BeginRegistration: error starting registration: %s", err.Error())
+		status, err := webAuthnReauthenticationErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": map[string]any{
+			"public_key": options,
+		},
+	})
+}
+
+type finishRegistrationDTO struct {
+	Name       string                `json:"name"`
+	Credential webAuthnCredentialDTO `json:"credential"`
+}
+
+func (wc *WebAuthnController) FinishRegistration(c *gin.Context) {
+	var registrationDTO finishRegistrationDTO
+
+	err := c.ShouldBindJSON(&registrationDTO)
+	if err != nil {
+		log.Printf("FinishRegistration: error during binding finishRegistrationDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	attestation, err := registrationDTO.Credential.toAttestation()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("FinishRegistration: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	credential, err := wc.webAuthnService.FinishRegistration(userID.(models.UserID), registrationDTO.Name, attestation)
+	if err != nil {
+		log.Printf("FinishRegistration: error finishing registration: %s", err.Error())
+		status, err := webAuthnErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, map[string]any{
+		"message": "passkey registered",
+		"data": map[string]any{
+			"credential": credential,
+		},
+	})
+}
+
+func (wc *WebAuthnController) GetCredentials(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("GetCredentials: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	credentials, err := wc.webAuthnService.GetCredentials(userID.(models.UserID))
+	if err != nil {
+		log.Printf("GetCredentials: error getting credentials: %s", err.Error())
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"data": map[string]any{
+			"credentials": credentials,
+		},
+	})
+}
+
+type webAuthnReauthenticationDTO struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// webAuthnReauthenticationErrorStatus maps a failed password or TOTP check
+// to its HTTP status.
+func webAuthnReauthenticationErrorStatus(err error) (int, error) {
+	switch {
+	case errors.Is(err, utils.ErrPasswordIncorrect), errors.Is(err, utils.ErrMFACodeInvalid), errors.Is(err, utils.ErrMFACodeReused):
+		return http.StatusUnprocessableEntity, err
+	case errors.Is(err, utils.ErrReauthenticationRequired), errors.Is(err, utils.ErrMFANotEnabled):
+		return http.StatusBadRequest, err
+	default:
+		return http.StatusInternalServerError, utils.ErrInternalServerError
+	}
+}
+
+// DeleteCredential removes a passkey. The body must carry the user's
+// password or a current TOTP code.
+func (wc *WebAuthnController) DeleteCredential(c *gin.Context) {
+	var deleteDTO webAuthnReauthenticationDTO
+
+	err := c.ShouldBindJSON(&deleteDTO)
+	if err != nil {
+		log.Printf("DeleteCredential: error during binding webAuthnReauthenticationDTO: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(
+			fmt.Errorf("error parsing request body: %w", err),
+		))
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Printf("DeleteCredential: error converting credential id: %s", err.Error())
+		c.JSON(http.StatusBadRequest, utils.GetErrorResponse(utils.ErrWebAuthnCredentialNotFound))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		log.Print("DeleteCredential: userID value do not exists in context")
+		c.JSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+		return
+	}
+
+	err = wc.webAuthnService.DeleteCredential(userID.(models.UserID), id, services.WebAuthnReauthentication{
+		Password: deleteDTO.Password,
+		Code:     deleteDTO.Code,
+	})
+	if err != nil {
+		log.Printf("DeleteCredential: error deleting credential: %s", err.Error())
+
+		if errors.Is(err, utils.ErrWebAuthnCredentialNotFound) {
+			c.JSON(http.StatusNotFound, utils.GetErrorResponse(utils.ErrWebAuthnCredentialNotFound))
+			return
+		}
+
+		status, err := webAuthnReauthenticationErrorStatus(err)
+		c.JSON(status, utils.GetErrorResponse(err))
+		return
+	}
+
+	c.String(http.StatusOK, "")
+}
@@ -0,0 +1,41 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+)
+
+type AccountDeletionJob struct {
+	accountDeletionService services.IAccountDeletionService
+	interval               time.Duration
+}
+
+func NewAccountDeletionJob(accountDeletionService services.IAccountDeletionService, interval time.Duration) *AccountDeletionJob {
+	return &AccountDeletionJob{
+		accountDeletionService: accountDeletionService,
+		interval:               interval,
+	}
+}
+
+func (j *AccountDeletionJob) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Print("AccountDeletionJob: stopped")
+				return
+			case <-ticker.C:
+				_, err := j.accountDeletionService.PurgeDueAccounts()
+				if err != nil {
+					log.Printf("AccountDeletionJob: error purging accounts: %s", err.Error())
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,9 @@
+package jobs
+
+import "context"
+
+// Job is a background task started alongside the HTTP server. Start must not
+// block; the job stops when ctx is cancelled.
+type Job interface {
+	Start(ctx context.Context)
+}
@@ -0,0 +1,46 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+)
+
+// RateLimitCleanupJob removes buckets that have not been used for maxAge.
+// By then they would have refilled completely, so dropping them changes
+// nothing for the client.
+type RateLimitCleanupJob struct {
+	rateLimitRepository repositories.RateLimitRepository
+	interval            time.Duration
+	maxAge              time.Duration
+}
+
+func NewRateLimitCleanupJob(rateLimitRepository repositories.RateLimitRepository, interval time.Duration, maxAge time.Duration) *RateLimitCleanupJob {
+	return &RateLimitCleanupJob{
+		rateLimitRepository: rateLimitRepository,
+		interval:            interval,
+		maxAge:              maxAge,
+	}
+}
+
+func (j *RateLimitCleanupJob) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Print("RateLimitCleanupJob: stopped")
+				return
+			case <-ticker.C:
+				err := j.rateLimitRepository.DeleteStaleRateLimitBuckets(time.Now().Add(-j.maxAge))
+				if err != nil {
+					log.Printf("RateLimitCleanupJob: error deleting stale buckets: %s", err.Error())
+				}
+			}
+		}
+	}()
+}
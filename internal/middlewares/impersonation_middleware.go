@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IImpersonationMiddleware interface {
+	BlockImpersonation() gin.HandlerFunc
+}
+
+type ImpersonationMiddleware struct{}
+
+func NewImpersonationMiddleware() IImpersonationMiddleware {
+	return &ImpersonationMiddleware{}
+}
+
+// BlockImpersonation rejects requests made with an impersonation token. It
+// guards routes that change credentials, end sessions or expose data the
+// admin should not take away. It must run after IsAuthenticated.
+func (im *ImpersonationMiddleware) BlockImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("claims")
+		if !exists {
+			log.Print("BlockImpersonation: claims value do not exists in context")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+			return
+		}
+
+		claims := value.(*services.TokenClaims)
+		if claims.IsImpersonation() {
+			log.Printf("BlockImpersonation: user %d impersonating user %d blocked from %s %s", claims.Actor.UserID, claims.UserID, c.Request.Method, c.FullPath())
+			c.AbortWithStatusJSON(http.StatusForbidden, utils.GetErrorResponse(utils.ErrImpersonationForbidden))
+			return
+		}
+
+		c.Next()
+	}
+}
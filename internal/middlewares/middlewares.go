@@ -2,5 +2,9 @@ package middlewares
 
 type Middlewares struct {
 	AuthenticatedUserMiddleware IAuthenticatedUserMiddleware
+	PermissionMiddleware        IPermissionMiddleware
+	ImpersonationMiddleware     IImpersonationMiddleware
 	LoggerMiddleware            ILoggerMiddleware
+	RateLimitMiddleware         IRateLimitMiddleware
+	ProvisioningMiddleware      IProvisioningMiddleware
 }
@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IPermissionMiddleware interface {
+	RequirePermission(permission string) gin.HandlerFunc
+}
+
+type PermissionMiddleware struct{}
+
+func NewPermissionMiddleware() IPermissionMiddleware {
+	return &PermissionMiddleware{}
+}
+
+// RequirePermission lets the request through only when the access token
+// grants permission. It must run after IsAuthenticated.
+func (pm *PermissionMiddleware) RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("claims")
+		if !exists {
+			log.Print("RequirePermission: claims value do not exists in context")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, utils.GetErrorResponse(utils.ErrInternalServerError))
+			return
+		}
+
+		claims := value.(*services.TokenClaims)
+		if !claims.HasPermission(permission) {
+			log.Printf("RequirePermission: user %d lacks permission %s", claims.UserID, permission)
+			c.AbortWithStatusJSON(http.StatusForbidden, utils.GetErrorResponse(utils.ErrForbidden))
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package middlewares
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IProvisioningMiddleware interface {
+	RequireProvisioningToken() gin.HandlerFunc
+}
+
+type ProvisioningMiddleware struct {
+	tokenHash [sha256.Size]byte
+	enabled   bool
+}
+
+// NewProvisioningMiddleware returns the middleware guarding the SCIM
+// endpoints with the bearer token identity providers are configured with.
+// An empty token disables provisioning: every request is rejected.
+func NewProvisioningMiddleware(token string) IProvisioningMiddleware {
+	return &ProvisioningMiddleware{
+		tokenHash: sha256.Sum256([]byte(token)),
+		enabled:   token != "",
+	}
+}
+
+func (pm *ProvisioningMiddleware) RequireProvisioningToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authorization := c.Request.Header.Values("Authorization")
+
+		if !pm.enabled || len(authorization) != 1 || !strings.HasPrefix(authorization[0], "Bearer ") {
+			log.Print("RequireProvisioningToken: provisioning token missing or provisioning disabled")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, utils.GetSCIMErrorResponse(http.StatusUnauthorized, "", utils.ErrSCIMTokenInvalid))
+			return
+		}
+
+		// Hashing both sides makes the comparison constant time regardless
+		// of the token lengths.
+		tokenHash := sha256.Sum256([]byte(strings.TrimPrefix(authorization[0], "Bearer ")))
+		if subtle.ConstantTimeCompare(tokenHash[:], pm.tokenHash[:]) != 1 {
+			log.Print("RequireProvisioningToken: invalid provisioning token")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, utils.GetSCIMErrorResponse(http.StatusUnauthorized, "", utils.ErrSCIMTokenInvalid))
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,149 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IRateLimitMiddleware interface {
+	Limit(route string) gin.HandlerFunc
+}
+
+// RateLimitKeyFunc extracts what a rule counts requests by. An empty key
+// skips the rule for that request.
+type RateLimitKeyFunc func(c *gin.Context) string
+
+type RateLimitRule struct {
+	Name  string
+	Key   RateLimitKeyFunc
+	Limit models.RateLimit
+}
+
+type RateLimitMiddleware struct {
+	rateLimitRepository repositories.RateLimitRepository
+	rules               map[string][]RateLimitRule
+}
+
+// NewRateLimitMiddleware takes the rules of every limited route, keyed by the
+// route name later passed to Limit.
+func NewRateLimitMiddleware(rateLimitRepository repositories.RateLimitRepository, rules map[string][]RateLimitRule) IRateLimitMiddleware {
+	return &RateLimitMiddleware{
+		rateLimitRepository: rateLimitRepository,
+		rules:               rules,
+	}
+}
+
+// KeyByIP counts requests per client IP.
+func KeyByIP(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// maxAccountKeyBodySize bounds how much of the request body KeyByAccount
+// reads to find the e-mail.
+const maxAccountKeyBodySize = 64 << 10
+
+// KeyByAccount counts requests per account: the authenticated user when there
+// is one, otherwise the "email" field of the JSON body.
+func KeyByAccount(c *gin.Context) string {
+	if userID, exists := c.Get("userID"); exists {
+		return fmt.Sprintf("user:%v", userID)
+	}
+
+	if c.Request.Body == nil {
+		return ""
+	}
+
+	peeked, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAccountKeyBodySize))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(peeked), c.Request.Body))
+	if err != nil {
+		return ""
+	}
+
+	var body struct {
+		Email string `json:"email"`
+	}
+
+	err = json.Unmarshal(peeked, &body)
+	if err != nil || body.Email == "" {
+		return ""
+	}
+
+	return "email:" + strings.ToLower(strings.TrimSpace(body.Email))
+}
+
+// KeyByPoolAccount counts requests per account of the user pool named by the
+// :clientId path parameter. Pool accounts are separate from the global ones
+// and from other pools, so they must not share a login budget.
+func KeyByPoolAccount(c *gin.Context) string {
+	key := KeyByAccount(c)
+	if key == "" {
+		return ""
+	}
+
+	return "pool:" + c.Param("clientId") + ":" + key
+}
+
+func setRateLimitHeaders(c *gin.Context, result *models.RateLimitResult) {
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// Limit returns a handler enforcing the rules registered for route. Every
+// rule must allow the request. Store errors let the request through, so an
+// unavailable store does not take the login endpoints down.
+func (rlm *RateLimitMiddleware) Limit(route string) gin.HandlerFunc {
+	rules := rlm.rules[route]
+
+	return func(c *gin.Context) {
+		var tightest *models.RateLimitResult
+
+		for _, rule := range rules {
+			key := rule.Key(c)
+			if key == "" {
+				continue
+			}
+
+			result, err := rlm.rateLimitRepository.TakeRateLimitToken(route+":"+rule.Name+":"+key, rule.Limit, time.Now())
+			if err != nil {
+				log.Printf("Limit: error taking rate limit token: %s", err.Error())
+				continue
+			}
+
+			if !result.Allowed {
+				log.Printf("Limit: %s rate limit exceeded for %s", route, rule.Name)
+				setRateLimitHeaders(c, result)
+				c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, utils.GetErrorResponse(utils.ErrTooManyRequests))
+				return
+			}
+
+			if tightest == nil || result.Remaining < tightest.Remaining {
+				tightest = result
+			}
+		}
+
+		if tightest != nil {
+			setRateLimitHeaders(c, tightest)
+		}
+
+		c.Next()
+	}
+}
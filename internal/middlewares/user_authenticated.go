@@ -54,8 +54,13 @@ func (aum *AuthenticatedUserMiddleware) IsAuthenticated() gin.HandlerFunc {
 			return
 		}
 
-		log.Printf("user %d is authenticated", claims.UserID)
+		if claims.IsImpersonation() {
+			log.Printf("user %d is authenticated, impersonated by user %d", claims.UserID, claims.Actor.UserID)
+		} else {
+			log.Printf("user %d is authenticated", claims.UserID)
+		}
 		c.Set("userID", claims.UserID)
+		c.Set("claims", claims)
 		c.Next()
 	}
 }
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// AccountLockout tracks failed password logins of a user. A user without
+// failed attempts has no stored lockout. Logins for e-mails without an
+// account are tracked the same way, keyed by EmailHash instead of UserID.
+type AccountLockout struct {
+	UserID          UserID
+	EmailHash       string
+	FailedAttempts  int
+	LastFailedAt    *time.Time
+	LockedUntil     *time.Time
+	UnlockTokenHash string
+}
+
+func (al *AccountLockout) IsLocked(now time.Time) bool {
+	return al.LockedUntil != nil && now.Before(*al.LockedUntil)
+}
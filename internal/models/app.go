@@ -9,15 +9,38 @@ import (
 type AppID = int
 type AppName = string
 type AppDescription = string
+type AppRole = string
 
 type App struct {
 	ID          AppID          `json:"id"`
 	Name        AppName        `json:"name"`
 	Description AppDescription `json:"description"`
-	UserID      UserID         `json:"user_id"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   time.Time      `json:"deleted_at,omitempty"`
+	UserID      UserID         `json:"user_id,omitempty"`
+	// OrganizationID is set on apps owned by an organization; UserID is
+	// then the member who created the app, or zero once that user was
+	// deleted.
+	OrganizationID *OrganizationID `json:"organization_id,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	DeletedAt      time.Time       `json:"deleted_at,omitempty"`
+}
+
+// AppMember is a collaborator of an app and the role they hold on it.
+type AppMember struct {
+	AppID     AppID     `json:"app_id"`
+	UserID    UserID    `json:"user_id"`
+	Email     UserEmail `json:"email"`
+	Role      AppRole   `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AppFilter selects apps in admin listings. Deleted lists the soft-deleted
+// apps instead of the live ones; a zero UserID matches every owner.
+type AppFilter struct {
+	UserID  UserID
+	Deleted bool
+	Limit   int
+	Offset  int
 }
 
 func NewApp(name, description string, userID UserID) (*App, error) {
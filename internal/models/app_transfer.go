@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+type AppTransferID = int
+
+// AppTransferStatus is derived from the transfer timestamps, see GetStatus.
+type AppTransferStatus = string
+
+const (
+	AppTransferStatusPending   AppTransferStatus = "pending"
+	AppTransferStatusAccepted  AppTransferStatus = "accepted"
+	AppTransferStatusCancelled AppTransferStatus = "cancelled"
+	AppTransferStatusExpired   AppTransferStatus = "expired"
+)
+
+// AppTransfer hands an app over to another user or organization
+// (ToOrganizationID) once the recipient accepts it. Users are addressed by
+// ToEmail; ToUserID is only set when the e-mail belongs to an active account
+// and is never shown, so owners cannot tell whether it does. The previous
+// owner is kept, so accepted transfers are the ownership history of the app.
+// Only the SHA-256 hash of the token e-mailed to the recipient is stored.
+type AppTransfer struct {
+	ID                 AppTransferID   `json:"id"`
+	AppID              AppID           `json:"app_id"`
+	FromUserID         UserID          `json:"from_user_id,omitempty"`
+	FromOrganizationID *OrganizationID `json:"from_organization_id,omitempty"`
+	ToEmail            UserEmail       `json:"to_email,omitempty"`
+	ToUserID           *UserID         `json:"-"`
+	ToOrganizationID   *OrganizationID `json:"to_organization_id,omitempty"`
+	TokenHash          string          `json:"-"`
+	InitiatedBy        UserID          `json:"initiated_by"`
+	CreatedAt          time.Time       `json:"created_at"`
+	ExpiresAt          time.Time       `json:"expires_at"`
+	AcceptedAt         *time.Time      `json:"accepted_at,omitempty"`
+	AcceptedBy         *UserID         `json:"accepted_by,omitempty"`
+	CancelledAt        *time.Time      `json:"cancelled_at,omitempty"`
+
+	// Status is filled from GetStatus when transfers are returned to
+	// clients.
+	Status AppTransferStatus `json:"status"`
+}
+
+func (t *AppTransfer) GetStatus(now time.Time) AppTransferStatus {
+	switch {
+	case t.AcceptedAt != nil:
+		return AppTransferStatusAccepted
+	case t.CancelledAt != nil:
+		return AppTransferStatusCancelled
+	case !now.Before(t.ExpiresAt):
+		return AppTransferStatusExpired
+	default:
+		return AppTransferStatusPending
+	}
+}
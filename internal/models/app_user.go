@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+type AppUserID = int
+type AppClientID = string
+
+// AppUserPool gives an app its own end users, kept apart from the global
+// users and from the pools of other apps. ClientID is the public identifier
+// the app's clients use to sign up, log in and refresh tokens.
+type AppUserPool struct {
+	AppID     AppID       `json:"app_id"`
+	ClientID  AppClientID `json:"client_id"`
+	Enabled   bool        `json:"enabled"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// AppUser is an end user of an app user pool. E-mails are unique per pool
+// only.
+type AppUser struct {
+	ID          AppUserID  `json:"id"`
+	AppID       AppID      `json:"app_id"`
+	Email       UserEmail  `json:"email"`
+	Password    string     `json:"-"`
+	Status      UserStatus `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+type AppUserRefreshToken struct {
+	ID        RefreshTokenID
+	Content   RefreshTokenContent
+	Status    RefreshTokenStatus
+	AppUserID AppUserID
+	CreatedAt time.Time
+}
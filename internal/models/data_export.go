@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+type DataExportID = int
+type DataExportContent = string
+
+type DataExport struct {
+	ID        DataExportID
+	Content   DataExportContent
+	UserID    UserID
+	Archive   []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type EmailOTPCodeID = int
+
+type EmailOTPCode struct {
+	ID        EmailOTPCodeID
+	UserID    UserID
+	CodeHash  string
+	Attempts  int
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type ImpersonationEventID = int
+
+// ImpersonationEvent records an admin (the actor) obtaining an access token
+// for another user (the target). The e-mails are a snapshot taken at that
+// time: events outlive their users, whose IDs are then cleared.
+type ImpersonationEvent struct {
+	ID           ImpersonationEventID `json:"id"`
+	ActorUserID  *UserID              `json:"actor_user_id"`
+	TargetUserID *UserID              `json:"target_user_id"`
+	ActorEmail   UserEmail            `json:"actor_email"`
+	TargetEmail  UserEmail            `json:"target_email"`
+	Reason       string               `json:"reason"`
+	IPAddress    string               `json:"ip_address"`
+	UserAgent    string               `json:"user_agent"`
+	CreatedAt    time.Time            `json:"created_at"`
+	ExpiresAt    time.Time            `json:"expires_at"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+type InvitationID = int
+
+// InvitationStatus is derived from the invitation timestamps, see GetStatus.
+type InvitationStatus = string
+
+const (
+	InvitationStatusPending  InvitationStatus = "pending"
+	InvitationStatusAccepted InvitationStatus = "accepted"
+	InvitationStatusRevoked  InvitationStatus = "revoked"
+	InvitationStatusExpired  InvitationStatus = "expired"
+)
+
+// Invitation lets the owner of Email create an active account with a
+// password of their choice or, for organization and app invitations, join
+// with an existing account. Only the SHA-256 hash of the token is stored.
+type Invitation struct {
+	ID        InvitationID `json:"id"`
+	Email     UserEmail    `json:"email"`
+	TokenHash string       `json:"-"`
+	InvitedBy UserID       `json:"invited_by"`
+	RoleName  RoleName     `json:"role,omitempty"`
+	// OrganizationID, when set, makes the invitee a member of the
+	// organization with OrganizationRole on acceptance.
+	OrganizationID   *OrganizationID  `json:"organization_id,omitempty"`
+	OrganizationRole OrganizationRole `json:"organization_role,omitempty"`
+	// AppID, when set, makes the invitee a collaborator of the app with
+	// AppRole on acceptance.
+	AppID      *AppID     `json:"app_id,omitempty"`
+	AppRole    AppRole    `json:"app_role,omitempty"`
+	UserID     *UserID    `json:"user_id,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+
+	// Status is filled from GetStatus when invitations are returned to
+	// clients.
+	Status InvitationStatus `json:"status"`
+}
+
+func (i *Invitation) GetStatus(now time.Time) InvitationStatus {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationStatusAccepted
+	case i.RevokedAt != nil:
+		return InvitationStatusRevoked
+	case !now.Before(i.ExpiresAt):
+		return InvitationStatusExpired
+	default:
+		return InvitationStatusPending
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type LoginEventID = int
+
+// LoginEvent is one login attempt of a user, successful or not.
+type LoginEvent struct {
+	ID            LoginEventID `json:"id"`
+	UserID        UserID       `json:"-"`
+	Method        string       `json:"method"`
+	Success       bool         `json:"success"`
+	FailureReason string       `json:"failure_reason,omitempty"`
+	IPAddress     string       `json:"ip_address"`
+	UserAgent     string       `json:"user_agent"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type MagicLinkTokenID = int
+type MagicLinkTokenContent = string
+
+type MagicLinkToken struct {
+	ID        MagicLinkTokenID
+	Content   MagicLinkTokenContent
+	UserID    UserID
+	Binding   string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+func (mlt *MagicLinkToken) IsUsed() bool {
+	return mlt.UsedAt != nil
+}
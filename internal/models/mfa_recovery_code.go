@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type MFARecoveryCodeID = int
+type MFARecoveryCodeContent = string
+
+type MFARecoveryCode struct {
+	ID        MFARecoveryCodeID
+	Content   MFARecoveryCodeContent
+	UserID    UserID
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
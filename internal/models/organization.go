@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type OrganizationID = int
+type OrganizationRole = string
+
+type Organization struct {
+	ID        OrganizationID `json:"id"`
+	Name      string         `json:"name"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// OrganizationMembership is an organization as seen by one of its members.
+type OrganizationMembership struct {
+	Organization Organization     `json:"organization"`
+	Role         OrganizationRole `json:"role"`
+	Active       bool             `json:"active"`
+}
+
+type OrganizationMember struct {
+	OrganizationID OrganizationID   `json:"organization_id"`
+	UserID         UserID           `json:"user_id"`
+	Email          UserEmail        `json:"email"`
+	Role           OrganizationRole `json:"role"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type PasswordResetTokenID = int
+type PasswordResetTokenContent = string
+
+type PasswordResetToken struct {
+	ID        PasswordResetTokenID
+	Content   PasswordResetTokenContent
+	UserID    UserID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+func (prt *PasswordResetToken) IsUsed() bool {
+	return prt.UsedAt != nil
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// RateLimit describes a token bucket: it holds up to Burst tokens and
+// refills one token every Interval / Burst, so Burst requests are allowed per
+// Interval on average.
+type RateLimit struct {
+	Burst    int
+	Interval time.Duration
+}
+
+// rate is the refill rate in tokens per second.
+func (rl RateLimit) rate() float64 {
+	return float64(rl.Burst) / rl.Interval.Seconds()
+}
+
+type RateLimitBucket struct {
+	Key       string
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// NewRateLimitBucket returns a full bucket.
+func NewRateLimitBucket(key string, limit RateLimit, now time.Time) *RateLimitBucket {
+	return &RateLimitBucket{
+		Key:       key,
+		Tokens:    float64(limit.Burst),
+		UpdatedAt: now,
+	}
+}
+
+// Take refills the bucket for the time elapsed since its last update and
+// takes one token from it if there is one.
+func (b *RateLimitBucket) Take(limit RateLimit, now time.Time) *RateLimitResult {
+	rate := limit.rate()
+
+	elapsed := now.Sub(b.UpdatedAt).Seconds()
+	if elapsed > 0 {
+		b.Tokens = math.Min(float64(limit.Burst), b.Tokens+elapsed*rate)
+		b.UpdatedAt = now
+	}
+
+	result := &RateLimitResult{
+		Limit: limit.Burst,
+	}
+
+	if b.Tokens >= 1 {
+		b.Tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.Tokens) / rate)
+	}
+
+	result.Remaining = int(math.Floor(b.Tokens))
+	result.Reset = secondsToDuration((float64(limit.Burst) - b.Tokens) / rate)
+
+	return result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type RefreshTokenID = int
 type RefreshTokenContent = string
 type RefreshTokenStatus = string
@@ -8,8 +10,9 @@ var RefreshTokenStatusActive RefreshTokenStatus = "active"
 var RefreshTokenStatusInactive RefreshTokenStatus = "inactive"
 
 type RefreshToken struct {
-	ID      RefreshTokenID
-	Content RefreshTokenContent
-	Status  RefreshTokenStatus
-	UserID  UserID
+	ID        RefreshTokenID
+	Content   RefreshTokenContent
+	Status    RefreshTokenStatus
+	UserID    UserID
+	CreatedAt time.Time
 }
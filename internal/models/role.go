@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type RoleID = int
+type RoleName = string
+
+type Role struct {
+	ID          RoleID    `json:"id"`
+	Name        RoleName  `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// RoleMember is a user holding a role.
+type RoleMember struct {
+	UserID UserID    `json:"user_id"`
+	Email  UserEmail `json:"email"`
+}
+
+type PermissionID = int
+
+type Permission struct {
+	ID          PermissionID `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+}
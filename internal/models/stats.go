@@ -0,0 +1,23 @@
+package models
+
+// Stats counts the main resources of the service, as printed by the admin
+// tool.
+type Stats struct {
+	Users         UserStats `json:"users"`
+	Apps          AppStats  `json:"apps"`
+	Organizations int       `json:"organizations"`
+	// ActiveRefreshTokens counts the refresh tokens not revoked yet.
+	ActiveRefreshTokens int `json:"active_refresh_tokens"`
+}
+
+type UserStats struct {
+	Total    int `json:"total"`
+	Active   int `json:"active"`
+	Pending  int `json:"pending"`
+	Inactive int `json:"inactive"`
+}
+
+type AppStats struct {
+	Total   int `json:"total"`
+	Deleted int `json:"deleted"`
+}
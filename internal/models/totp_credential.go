@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+type TOTPCredential struct {
+	UserID          UserID
+	Secret          string
+	ConfirmedAt     *time.Time
+	LastUsedCounter int64
+	CreatedAt       time.Time
+}
+
+func (tc *TOTPCredential) IsConfirmed() bool {
+	return tc.ConfirmedAt != nil
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"strings"
+	"time"
 
 	"github.com/pedrotunin/go-jwt-auth/internal/validators"
 )
@@ -12,10 +13,19 @@ type UserPassword = string
 type UserStatus = string
 
 type User struct {
-	ID       UserID
-	Email    UserEmail
-	Password UserPassword
-	Status   UserStatus
+	ID          UserID       `json:"id"`
+	Email       UserEmail    `json:"email"`
+	Password    UserPassword `json:"-"`
+	Status      UserStatus   `json:"status"`
+	DisplayName string       `json:"display_name"`
+	Locale      string       `json:"locale"`
+	Timezone    string       `json:"timezone"`
+	AvatarURL   string       `json:"avatar_url"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	LastLoginAt *time.Time   `json:"last_login_at"`
+
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
 }
 
 func NewUser(email, password string) (*User, error) {
@@ -34,3 +44,68 @@ func NewUser(email, password string) (*User, error) {
 		Password: password,
 	}, nil
 }
+
+// UserProfile holds the fields a user is allowed to change on their own
+// account. Nil fields are left untouched.
+type UserProfile struct {
+	DisplayName *string `json:"display_name"`
+	Locale      *string `json:"locale"`
+	Timezone    *string `json:"timezone"`
+	AvatarURL   *string `json:"avatar_url"`
+}
+
+func (p *UserProfile) Validate() error {
+	if p.DisplayName != nil {
+		if err := validators.IsValidDisplayName(*p.DisplayName); err != nil {
+			return err
+		}
+	}
+
+	if p.Locale != nil {
+		if err := validators.IsValidLocale(*p.Locale); err != nil {
+			return err
+		}
+	}
+
+	if p.Timezone != nil {
+		if err := validators.IsValidTimezone(*p.Timezone); err != nil {
+			return err
+		}
+	}
+
+	if p.AvatarURL != nil {
+		if err := validators.IsValidAvatarURL(*p.AvatarURL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Apply copies the non-nil profile fields into the user.
+func (p *UserProfile) Apply(u *User) {
+	if p.DisplayName != nil {
+		u.DisplayName = strings.TrimSpace(*p.DisplayName)
+	}
+
+	if p.Locale != nil {
+		u.Locale = *p.Locale
+	}
+
+	if p.Timezone != nil {
+		u.Timezone = *p.Timezone
+	}
+
+	if p.AvatarURL != nil {
+		u.AvatarURL = *p.AvatarURL
+	}
+}
+
+// UserFilter selects users in admin listings. Empty fields match every user;
+// Email matches any part of the address, case-insensitively.
+type UserFilter struct {
+	Status UserStatus
+	Email  string
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type WebAuthnCredentialID = int
+
+type WebAuthnCredential struct {
+	ID           WebAuthnCredentialID `json:"id"`
+	UserID       UserID               `json:"-"`
+	CredentialID string               `json:"credential_id"`
+	PublicKey    []byte               `json:"-"`
+	SignCount    uint32               `json:"-"`
+	Name         string               `json:"name"`
+	CreatedAt    time.Time            `json:"created_at"`
+	LastUsedAt   *time.Time           `json:"last_used_at"`
+}
+
+type WebAuthnCeremony = string
+
+const (
+	WebAuthnCeremonyRegistration   WebAuthnCeremony = "registration"
+	WebAuthnCeremonyAuthentication WebAuthnCeremony = "authentication"
+)
+
+type WebAuthnChallenge struct {
+	ID        int
+	Challenge string
+	UserID    *UserID
+	Ceremony  WebAuthnCeremony
+	ExpiresAt time.Time
+}
@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type AccountLockoutRepository interface {
+	GetAccountLockout(userID models.UserID) (*models.AccountLockout, error)
+	GetAccountLockoutByUnlockToken(unlockTokenHash string) (*models.AccountLockout, error)
+	RecordFailedLogin(userID models.UserID, now time.Time, windowStart time.Time) (*models.AccountLockout, error)
+	LockAccount(userID models.UserID, lockedUntil time.Time, unlockTokenHash string) error
+	ResetAccountLockout(userID models.UserID) error
+	GetEmailLockout(emailHash string) (*models.AccountLockout, error)
+	RecordFailedEmailLogin(emailHash string, now time.Time, windowStart time.Time) (*models.AccountLockout, error)
+	LockEmail(emailHash string, lockedUntil time.Time) error
+	ResetEmailLockout(emailHash string) error
+	DeleteStaleEmailLockouts(windowStart time.Time) error
+}
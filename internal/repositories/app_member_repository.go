@@ -0,0 +1,12 @@
+package repositories
+
+import "github.com/pedrotunin/go-jwt-auth/internal/models"
+
+type AppMemberRepository interface {
+	GetAppMembers(appID models.AppID) ([]models.AppMember, error)
+	GetAppMember(appID models.AppID, userID models.UserID) (*models.AppMember, error)
+	GetAppMembershipsByUserID(userID models.UserID) ([]models.AppMember, error)
+	AddAppMember(appID models.AppID, userID models.UserID, role models.AppRole) error
+	RemoveAppMember(appID models.AppID, userID models.UserID) error
+	CountAppOwners(appID models.AppID) (int, error)
+}
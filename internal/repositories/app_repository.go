@@ -4,8 +4,13 @@ import "github.com/pedrotunin/go-jwt-auth/internal/models"
 
 type AppRepository interface {
 	GetAppsByUserID(userID models.UserID) ([]models.App, error)
+	GetAppsByOrganizationID(organizationID models.OrganizationID) ([]models.App, error)
 	GetAppByID(appID models.AppID) (*models.App, error)
+	GetAllAppsByUserID(userID models.UserID) ([]models.App, error)
 	CreateApp(*models.App) error
 	UpdateApp(app *models.App) error
 	DeleteAppByID(appID models.AppID) error
+	SearchApps(filter models.AppFilter) ([]models.App, error)
+	RestoreAppByID(appID models.AppID) error
+	TransferApp(transfer *models.AppTransfer, acceptedBy models.UserID) error
 }
@@ -0,0 +1,12 @@
+package repositories
+
+import "github.com/pedrotunin/go-jwt-auth/internal/models"
+
+type AppTransferRepository interface {
+	CreateAppTransfer(transfer *models.AppTransfer) error
+	GetAppTransferByID(id models.AppTransferID) (*models.AppTransfer, error)
+	GetAppTransferByTokenHash(tokenHash string) (*models.AppTransfer, error)
+	GetAppTransfersByAppID(appID models.AppID) ([]models.AppTransfer, error)
+	GetIncomingAppTransfers(userID models.UserID) ([]models.AppTransfer, error)
+	CancelAppTransfer(appID models.AppID, id models.AppTransferID) error
+}
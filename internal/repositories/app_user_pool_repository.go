@@ -0,0 +1,11 @@
+package repositories
+
+import (
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type AppUserPoolRepository interface {
+	GetUserPoolByAppID(appID models.AppID) (*models.AppUserPool, error)
+	GetUserPoolByClientID(clientID models.AppClientID) (*models.AppUserPool, error)
+	SaveUserPool(pool *models.AppUserPool) error
+}
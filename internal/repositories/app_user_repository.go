@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type AppUserRepository interface {
+	CreateAppUser(user *models.AppUser) error
+	GetAppUserByID(appID models.AppID, appUserID models.AppUserID) (*models.AppUser, error)
+	GetAppUserByEmail(appID models.AppID, email models.UserEmail) (*models.AppUser, error)
+	UpdateAppUserLastLogin(appUserID models.AppUserID, at time.Time) error
+	CreateAppUserRefreshToken(token *models.AppUserRefreshToken) error
+	GetAppUserRefreshTokenByContent(content models.RefreshTokenContent) (*models.AppUserRefreshToken, error)
+	InvalidateAppUserRefreshTokenByContent(content models.RefreshTokenContent) error
+}
@@ -0,0 +1,13 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type DataExportRepository interface {
+	CreateDataExport(export *models.DataExport) error
+	GetDataExportByContent(content models.DataExportContent) (*models.DataExport, error)
+	DeleteExpiredDataExports(now time.Time) error
+}
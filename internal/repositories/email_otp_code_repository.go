@@ -0,0 +1,14 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type EmailOTPCodeRepository interface {
+	CreateEmailOTPCode(code *models.EmailOTPCode) error
+	GetActiveEmailOTPCodeByUserID(userID models.UserID, now time.Time) (*models.EmailOTPCode, error)
+	IncrementEmailOTPAttempts(id models.EmailOTPCodeID, maxAttempts int) error
+	UseEmailOTPCode(id models.EmailOTPCodeID) error
+}
@@ -5,5 +5,6 @@ import "github.com/pedrotunin/go-jwt-auth/internal/models"
 type EmailVerificationTokenRepository interface {
 	CreateVerificationToken(*models.EmailVerificationToken) error
 	GetVerificationTokenByContent(content models.EmailVerificationTokenContent) (*models.EmailVerificationToken, error)
+	GetVerificationTokensByUserID(userID models.UserID) ([]models.EmailVerificationToken, error)
 	SetTokenToUsed(*models.EmailVerificationToken) error
 }
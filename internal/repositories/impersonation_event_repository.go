@@ -0,0 +1,11 @@
+package repositories
+
+import (
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type ImpersonationEventRepository interface {
+	CreateImpersonationEvent(event *models.ImpersonationEvent) error
+	GetImpersonationEventsByTargetUserID(userID models.UserID, limit int, offset int) ([]models.ImpersonationEvent, error)
+	CountImpersonationEventsByTargetUserID(userID models.UserID) (int, error)
+}
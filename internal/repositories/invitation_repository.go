@@ -0,0 +1,15 @@
+package repositories
+
+import (
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type InvitationRepository interface {
+	CreateInvitation(invitation *models.Invitation) error
+	GetInvitationByTokenHash(tokenHash string) (*models.Invitation, error)
+	GetInvitations(limit int, offset int) ([]models.Invitation, error)
+	CountInvitations() (int, error)
+	GetInvitationsByUser(userID models.UserID, email models.UserEmail) ([]models.Invitation, error)
+	RevokeInvitation(id models.InvitationID) error
+	AcceptInvitation(id models.InvitationID, userID models.UserID) error
+}
@@ -0,0 +1,12 @@
+package repositories
+
+import (
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type LoginEventRepository interface {
+	CreateLoginEvent(event *models.LoginEvent) error
+	GetLoginEventsByUserID(userID models.UserID, limit int, offset int) ([]models.LoginEvent, error)
+	CountLoginEventsByUserID(userID models.UserID) (int, error)
+	GetKnownLoginDevice(userID models.UserID, ipAddress string, userAgent string) (hasLogins bool, known bool, err error)
+}
@@ -0,0 +1,14 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type MagicLinkTokenRepository interface {
+	CreateMagicLinkToken(token *models.MagicLinkToken) error
+	GetMagicLinkTokenByContent(content models.MagicLinkTokenContent) (*models.MagicLinkToken, error)
+	UseMagicLinkToken(id models.MagicLinkTokenID) error
+	DeleteExpiredMagicLinkTokens(now time.Time) error
+}
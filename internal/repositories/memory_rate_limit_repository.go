@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type MemoryRateLimitRepository struct {
+	mu      sync.Mutex
+	buckets map[string]*models.RateLimitBucket
+}
+
+func NewMemoryRateLimitRepository() *MemoryRateLimitRepository {
+	return &MemoryRateLimitRepository{
+		buckets: map[string]*models.RateLimitBucket{},
+	}
+}
+
+func (repo *MemoryRateLimitRepository) TakeRateLimitToken(key string, limit models.RateLimit, now time.Time) (*models.RateLimitResult, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	bucket, ok := repo.buckets[key]
+	if !ok {
+		bucket = models.NewRateLimitBucket(key, limit, now)
+		repo.buckets[key] = bucket
+	}
+
+	return bucket.Take(limit, now), nil
+}
+
+func (repo *MemoryRateLimitRepository) DeleteStaleRateLimitBuckets(before time.Time) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for key, bucket := range repo.buckets {
+		if bucket.UpdatedAt.Before(before) {
+			delete(repo.buckets, key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,9 @@
+package repositories
+
+import "time"
+
+type MFAChallengeRepository interface {
+	GetMFAChallengeFailures(challengeID string) (int, error)
+	RecordMFAChallengeFailure(challengeID string, expiresAt time.Time) (failures int, err error)
+	DeleteExpiredMFAChallenges(now time.Time) error
+}
@@ -0,0 +1,12 @@
+package repositories
+
+import (
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type MFARecoveryCodeRepository interface {
+	ReplaceRecoveryCodes(userID models.UserID, contents []models.MFARecoveryCodeContent) error
+	UseRecoveryCode(userID models.UserID, content models.MFARecoveryCodeContent) error
+	CountUnusedRecoveryCodes(userID models.UserID) (int, error)
+	DeleteRecoveryCodes(userID models.UserID) error
+}
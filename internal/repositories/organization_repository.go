@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type OrganizationRepository interface {
+	CreateOrganization(organization *models.Organization, ownerID models.UserID) error
+	GetOrganizationByID(organizationID models.OrganizationID) (*models.Organization, error)
+	GetOrganizationsByUserID(userID models.UserID) ([]models.OrganizationMembership, error)
+	GetOrganizationMembers(organizationID models.OrganizationID) ([]models.OrganizationMember, error)
+	GetOrganizationMember(organizationID models.OrganizationID, userID models.UserID) (*models.OrganizationMember, error)
+	AddOrganizationMember(organizationID models.OrganizationID, userID models.UserID, role models.OrganizationRole) error
+	UpdateOrganizationMemberRole(organizationID models.OrganizationID, userID models.UserID, role models.OrganizationRole) error
+	RemoveOrganizationMember(organizationID models.OrganizationID, userID models.UserID) error
+	CountOrganizationOwners(organizationID models.OrganizationID) (int, error)
+	SetActiveOrganization(userID models.UserID, organizationID *models.OrganizationID) error
+	GetActiveOrganizationID(userID models.UserID) (*models.OrganizationID, error)
+}
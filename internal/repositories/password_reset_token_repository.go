@@ -0,0 +1,14 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type PasswordResetTokenRepository interface {
+	CreatePasswordResetToken(token *models.PasswordResetToken) error
+	GetPasswordResetTokenByContent(content models.PasswordResetTokenContent) (*models.PasswordResetToken, error)
+	UsePasswordResetToken(id models.PasswordResetTokenID) error
+	DeleteExpiredPasswordResetTokens(now time.Time) error
+}
@@ -0,0 +1,419 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type PSQLAccountLockoutRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLAccountLockoutRepository(db *sql.DB) *PSQLAccountLockoutRepository {
+	return &PSQLAccountLockoutRepository{
+		db: db,
+	}
+}
+
+func scanAccountLockout(row rowScanner) (*models.AccountLockout, error) {
+	var lockout models.AccountLockout
+	var lastFailedAt, lockedUntil sql.NullTime
+	var unlockTokenHash sql.NullString
+
+	err := row.Scan(&lockout.UserID, &lockout.FailedAttempts, &lastFailedAt, &lockedUntil, &unlockTokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastFailedAt.Valid {
+		lockout.LastFailedAt = &lastFailedAt.Time
+	}
+
+	if lockedUntil.Valid {
+		lockout.LockedUntil = &lockedUntil.Time
+	}
+
+	lockout.UnlockTokenHash = unlockTokenHash.String
+	return &lockout, nil
+}
+
+// GetAccountLockout returns the user's lockout state. Users without failed
+// attempts get an empty state instead of an error.
+func (repo *PSQLAccountLockoutRepository) GetAccountLockout(userID models.UserID) (*models.AccountLockout, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetAccountLockout: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT user_id, failed_attempts, last_failed_at, locked_until, unlock_token_hash FROM account_lockouts WHERE user_id=$1;")
+	if err != nil {
+		log.Printf("GetAccountLockout: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	lockout, err := scanAccountLockout(stmt.QueryRow(userID))
+	if err != nil {
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return &models.AccountLockout{UserID: userID}, nil
+		}
+
+		log.Printf("GetAccountLockout: error executing query: %s", err.Error())
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetAccountLockout: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return lockout, nil
+}
+
+func (repo *PSQLAccountLockoutRepository) GetAccountLockoutByUnlockToken(unlockTokenHash string) (*models.AccountLockout, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetAccountLockoutByUnlockToken: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT user_id, failed_attempts, last_failed_at, locked_until, unlock_token_hash FROM account_lockouts WHERE unlock_token_hash=$1;")
+	if err != nil {
+		log.Printf("GetAccountLockoutByUnlockToken: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	lockout, err := scanAccountLockout(stmt.QueryRow(unlockTokenHash))
+	if err != nil {
+		log.Printf("GetAccountLockoutByUnlockToken: error executing query: %s", err.Error())
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrUnlockTokenInvalid
+		}
+
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetAccountLockoutByUnlockToken: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return lockout, nil
+}
+
+// RecordFailedLogin atomically counts a failed login. Failures older than
+// windowStart are forgotten and the count starts again from one.
+func (repo *PSQLAccountLockoutRepository) RecordFailedLogin(userID models.UserID, now time.Time, windowStart time.Time) (*models.AccountLockout, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("RecordFailedLogin: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	query := `INSERT INTO account_lockouts (user_id, failed_attempts, last_failed_at) VALUES ($1, 1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET
+			failed_attempts = CASE WHEN account_lockouts.last_failed_at < $3 THEN 1 ELSE account_lockouts.failed_attempts + 1 END,
+			last_failed_at = EXCLUDED.last_failed_at
+		RETURNING user_id, failed_attempts, last_failed_at, locked_until, unlock_token_hash;`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		log.Printf("RecordFailedLogin: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	lockout, err := scanAccountLockout(stmt.QueryRow(userID, now, windowStart))
+	if err != nil {
+		log.Printf("RecordFailedLogin: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("RecordFailedLogin: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return lockout, nil
+}
+
+func (repo *PSQLAccountLockoutRepository) LockAccount(userID models.UserID, lockedUntil time.Time, unlockTokenHash string) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("LockAccount: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE account_lockouts SET locked_until=$2, unlock_token_hash=$3 WHERE user_id=$1;")
+	if err != nil {
+		log.Printf("LockAccount: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(userID, lockedUntil, unlockTokenHash)
+	if err != nil {
+		log.Printf("LockAccount: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("LockAccount: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("LockAccount: account %d locked", userID)
+	return nil
+}
+
+func (repo *PSQLAccountLockoutRepository) ResetAccountLockout(userID models.UserID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("ResetAccountLockout: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("DELETE FROM account_lockouts WHERE user_id=$1;")
+	if err != nil {
+		log.Printf("ResetAccountLockout: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(userID)
+	if err != nil {
+		log.Printf("ResetAccountLockout: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("ResetAccountLockout: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+func scanEmailLockout(row rowScanner) (*models.AccountLockout, error) {
+	var lockout models.AccountLockout
+	var lastFailedAt, lockedUntil sql.NullTime
+
+	err := row.Scan(&lockout.EmailHash, &lockout.FailedAttempts, &lastFailedAt, &lockedUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastFailedAt.Valid {
+		lockout.LastFailedAt = &lastFailedAt.Time
+	}
+
+	if lockedUntil.Valid {
+		lockout.LockedUntil = &lockedUntil.Time
+	}
+
+	return &lockout, nil
+}
+
+// GetEmailLockout returns the lockout state of an e-mail without an account.
+// E-mails without failed attempts get an empty state instead of an error.
+func (repo *PSQLAccountLockoutRepository) GetEmailLockout(emailHash string) (*models.AccountLockout, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetEmailLockout: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT email_hash, failed_attempts, last_failed_at, locked_until FROM email_lockouts WHERE email_hash=$1;")
+	if err != nil {
+		log.Printf("GetEmailLockout: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	lockout, err := scanEmailLockout(stmt.QueryRow(emailHash))
+	if err != nil {
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return &models.AccountLockout{EmailHash: emailHash}, nil
+		}
+
+		log.Printf("GetEmailLockout: error executing query: %s", err.Error())
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetEmailLockout: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return lockout, nil
+}
+
+// RecordFailedEmailLogin atomically counts a failed login for an e-mail
+// without an account, like RecordFailedLogin does for users.
+func (repo *PSQLAccountLockoutRepository) RecordFailedEmailLogin(emailHash string, now time.Time, windowStart time.Time) (*models.AccountLockout, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("RecordFailedEmailLogin: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	query := `INSERT INTO email_lockouts (email_hash, failed_attempts, last_failed_at) VALUES ($1, 1, $2)
+		ON CONFLICT (email_hash) DO UPDATE SET
+			failed_attempts = CASE WHEN email_lockouts.last_failed_at < $3 THEN 1 ELSE email_lockouts.failed_attempts + 1 END,
+			last_failed_at = EXCLUDED.last_failed_at
+		RETURNING email_hash, failed_attempts, last_failed_at, locked_until;`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		log.Printf("RecordFailedEmailLogin: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	lockout, err := scanEmailLockout(stmt.QueryRow(emailHash, now, windowStart))
+	if err != nil {
+		log.Printf("RecordFailedEmailLogin: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("RecordFailedEmailLogin: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return lockout, nil
+}
+
+func (repo *PSQLAccountLockoutRepository) LockEmail(emailHash string, lockedUntil time.Time) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("LockEmail: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE email_lockouts SET locked_until=$2 WHERE email_hash=$1;")
+	if err != nil {
+		log.Printf("LockEmail: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(emailHash, lockedUntil)
+	if err != nil {
+		log.Printf("LockEmail: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("LockEmail: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+func (repo *PSQLAccountLockoutRepository) ResetEmailLockout(emailHash string) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("ResetEmailLockout: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("DELETE FROM email_lockouts WHERE email_hash=$1;")
+	if err != nil {
+		log.Printf("ResetEmailLockout: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(emailHash)
+	if err != nil {
+		log.Printf("ResetEmailLockout: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("ResetEmailLockout: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// DeleteStaleEmailLockouts forgets e-mails whose last failure is older than
+// windowStart; any lock they had has run out by then.
+func (repo *PSQLAccountLockoutRepository) DeleteStaleEmailLockouts(windowStart time.Time) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("DeleteStaleEmailLockouts: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("DELETE FROM email_lockouts WHERE last_failed_at < $1;")
+	if err != nil {
+		log.Printf("DeleteStaleEmailLockouts: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(windowStart)
+	if err != nil {
+		log.Printf("DeleteStaleEmailLockouts: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("DeleteStaleEmailLockouts: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,292 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/lib/pq"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type PSQLAppMemberRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLAppMemberRepository(db *sql.DB) *PSQLAppMemberRepository {
+	return &PSQLAppMemberRepository{
+		db: db,
+	}
+}
+
+func (repo *PSQLAppMemberRepository) GetAppMembers(appID models.AppID) ([]models.AppMember, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetAppMembers: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare(`SELECT m.app_id, m.user_id, u.email, m.role, m.created_at FROM app_members m
+		JOIN users u ON u.id = m.user_id WHERE m.app_id=$1 ORDER BY m.created_at, m.user_id;`)
+	if err != nil {
+		log.Printf("GetAppMembers: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(appID)
+	if err != nil {
+		log.Printf("GetAppMembers: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.AppMember{}
+
+	for rows.Next() {
+		var member models.AppMember
+
+		err := rows.Scan(&member.AppID, &member.UserID, &member.Email, &member.Role, &member.CreatedAt)
+		if err != nil {
+			log.Printf("GetAppMembers: error scanning row: %s", err.Error())
+			tx.Rollback()
+			return nil, err
+		}
+
+		members = append(members, member)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("GetAppMembers: error during iterating rows: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetAppMembers: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (repo *PSQLAppMemberRepository) GetAppMember(appID models.AppID, userID models.UserID) (*models.AppMember, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetAppMember: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare(`SELECT m.app_id, m.user_id, u.email, m.role, m.created_at FROM app_members m
+		JOIN users u ON u.id = m.user_id WHERE m.app_id=$1 AND m.user_id=$2;`)
+	if err != nil {
+		log.Printf("GetAppMember: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var member models.AppMember
+	err = stmt.QueryRow(appID, userID).Scan(&member.AppID, &member.UserID, &member.Email, &member.Role, &member.CreatedAt)
+	if err != nil {
+		log.Printf("GetAppMember: error executing query: %s", err.Error())
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrAppMemberNotFound
+		}
+
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetAppMember: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// GetAppMembershipsByUserID returns the user's memberships in every app.
+func (repo *PSQLAppMemberRepository) GetAppMembershipsByUserID(userID models.UserID) ([]models.AppMember, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetAppMembershipsByUserID: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare(`SELECT m.app_id, m.user_id, u.email, m.role, m.created_at FROM app_members m
+		JOIN users u ON u.id = m.user_id WHERE m.user_id=$1 ORDER BY m.created_at, m.app_id;`)
+	if err != nil {
+		log.Printf("GetAppMembershipsByUserID: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(userID)
+	if err != nil {
+		log.Printf("GetAppMembershipsByUserID: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.AppMember{}
+
+	for rows.Next() {
+		var member models.AppMember
+
+		err := rows.Scan(&member.AppID, &member.UserID, &member.Email, &member.Role, &member.CreatedAt)
+		if err != nil {
+			log.Printf("GetAppMembershipsByUserID: error scanning row: %s", err.Error())
+			tx.Rollback()
+			return nil, err
+		}
+
+		members = append(members, member)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("GetAppMembershipsByUserID: error during iterating rows: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetAppMembershipsByUserID: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (repo *PSQLAppMemberRepository) AddAppMember(appID models.AppID, userID models.UserID, role models.AppRole) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("AddAppMember: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO app_members (app_id, user_id, role) VALUES ($1, $2, $3);")
+	if err != nil {
+		log.Printf("AddAppMember: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(appID, userID, role)
+	if err != nil {
+		log.Printf("AddAppMember: error executing query: %s", err.Error())
+		tx.Rollback()
+
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code {
+			case "23505":
+				return utils.ErrAppMemberAlreadyExists
+			case "23503":
+				return utils.ErrAppNotFound
+			}
+		}
+
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("AddAppMember: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+func (repo *PSQLAppMemberRepository) RemoveAppMember(appID models.AppID, userID models.UserID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("RemoveAppMember: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("DELETE FROM app_members WHERE app_id=$1 AND user_id=$2;")
+	if err != nil {
+		log.Printf("RemoveAppMember: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(appID, userID)
+	if err != nil {
+		log.Printf("RemoveAppMember: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return utils.ErrAppMemberNotFound
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("RemoveAppMember: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+func (repo *PSQLAppMemberRepository) CountAppOwners(appID models.AppID) (int, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CountAppOwners: error creating transaction: %s", err.Error())
+		return 0, err
+	}
+
+	stmt, err := tx.Prepare("SELECT COUNT(*) FROM app_members WHERE app_id=$1 AND role=$2;")
+	if err != nil {
+		log.Printf("CountAppOwners: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var count int
+	err = stmt.QueryRow(appID, utils.AppRoleOwner).Scan(&count)
+	if err != nil {
+		log.Printf("CountAppOwners: error executing query: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CountAppOwners: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"log"
 	"time"
 
@@ -20,31 +21,36 @@ func NewPSQLAppRepository(db *sql.DB) *PSQLAppRepository {
 	}
 }
 
+// GetAppsByUserID returns the personal apps the user is a member of, the ones
+// not owned by an organization.
 func (repo *PSQLAppRepository) GetAppsByUserID(userID models.UserID) ([]models.App, error) {
+	return repo.queryApps("GetAppsByUserID", `SELECT a.id, a.name, a.description, a.user_id, a.organization_id, a.created_at, a.updated_at FROM apps a
+		JOIN app_members m ON m.app_id = a.id WHERE m.user_id=$1 AND a.organization_id IS NULL AND a.deleted_at IS NULL ORDER BY a.id;`, userID)
+}
+
+func (repo *PSQLAppRepository) GetAppsByOrganizationID(organizationID models.OrganizationID) ([]models.App, error) {
+	return repo.queryApps("GetAppsByOrganizationID", "SELECT id, name, description, user_id, organization_id, created_at, updated_at FROM apps WHERE organization_id=$1 AND deleted_at IS NULL;", organizationID)
+}
+
+func (repo *PSQLAppRepository) queryApps(caller string, query string, args ...any) ([]models.App, error) {
 	tx, err := repo.db.Begin()
 	if err != nil {
-		log.Printf("GetAppsByUserID: error creating transaction: %s", err.Error())
+		log.Printf("%s: error creating transaction: %s", caller, err.Error())
 		return nil, err
 	}
 
-	query := "SELECT id, name, description, user_id, created_at, updated_at FROM apps WHERE user_id=$1 AND deleted_at IS NULL;"
 	stmt, err := tx.Prepare(query)
 	if err != nil {
-		log.Printf("GetAppsByUserID: error creating statement: %s", err.Error())
+		log.Printf("%s: error creating statement: %s", caller, err.Error())
 		tx.Rollback()
 		return nil, err
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.Query(userID)
+	rows, err := stmt.Query(args...)
 	if err != nil {
-		log.Printf("GetAppsByUserID: error executing query: %s", err.Error())
+		log.Printf("%s: error executing query: %s", caller, err.Error())
 		tx.Rollback()
-
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, utils.ErrAppNotFound
-		}
-
 		return nil, err
 	}
 	defer rows.Close()
@@ -52,45 +58,66 @@ func (repo *PSQLAppRepository) GetAppsByUserID(userID models.UserID) ([]models.A
 	apps := []models.App{}
 
 	for rows.Next() {
-		var id, userId int
+		var id int
+		var userId, organizationID sql.NullInt64
 		var name, description string
 		var createdAt, updatedAt time.Time
 
-		err := rows.Scan(&id, &name, &description, &userId, &createdAt, &updatedAt)
+		err := rows.Scan(&id, &name, &description, &userId, &organizationID, &createdAt, &updatedAt)
 		if err != nil {
 			tx.Rollback()
 			return nil, err
 		}
 
 		apps = append(apps, models.App{
-			ID:          id,
-			Name:        name,
-			Description: description,
-			UserID:      userId,
-			CreatedAt:   createdAt,
-			UpdatedAt:   updatedAt,
+			ID:             id,
+			Name:           name,
+			Description:    description,
+			UserID:         models.UserID(userId.Int64),
+			OrganizationID: appOrganizationID(organizationID),
+			CreatedAt:      createdAt,
+			UpdatedAt:      updatedAt,
 		})
 
 	}
 
 	err = rows.Err()
 	if err != nil {
-		log.Printf("GetAppsByUserID: error during iterating rows: %s", err.Error())
+		log.Printf("%s: error during iterating rows: %s", caller, err.Error())
 		tx.Rollback()
 		return nil, err
 	}
 
 	err = tx.Commit()
 	if err != nil {
-		log.Printf("GetAppsByUserID: error during commmit: %s", err.Error())
+		log.Printf("%s: error during commmit: %s", caller, err.Error())
 		tx.Rollback()
 		return nil, err
 	}
 
-	log.Printf("GetAppsByUserID: got apps")
+	log.Printf("%s: got apps", caller)
 	return apps, nil
 }
 
+func appOrganizationID(organizationID sql.NullInt64) *models.OrganizationID {
+	if !organizationID.Valid {
+		return nil
+	}
+
+	id := models.OrganizationID(organizationID.Int64)
+	return &id
+}
+
+// appUserID stores the zero UserID of an organization app whose creator was
+// deleted as NULL.
+func appUserID(userID models.UserID) any {
+	if userID == 0 {
+		return nil
+	}
+
+	return userID
+}
+
 func (repo *PSQLAppRepository) GetAppByID(appID models.AppID) (*models.App, error) {
 	tx, err := repo.db.Begin()
 	if err != nil {
@@ -98,7 +125,7 @@ func (repo *PSQLAppRepository) GetAppByID(appID models.AppID) (*models.App, erro
 		return nil, err
 	}
 
-	query := "SELECT id, name, description, user_id, created_at, updated_at FROM apps WHERE id=$1 AND deleted_at IS NULL;"
+	query := "SELECT id, name, description, user_id, organization_id, created_at, updated_at FROM apps WHERE id=$1 AND deleted_at IS NULL;"
 	stmt, err := tx.Prepare(query)
 	if err != nil {
 		log.Printf("GetAppByID: error creating statement: %s", err.Error())
@@ -107,10 +134,11 @@ func (repo *PSQLAppRepository) GetAppByID(appID models.AppID) (*models.App, erro
 	}
 	defer stmt.Close()
 
-	var id, userId int
+	var id int
+	var userId, organizationID sql.NullInt64
 	var name, description string
 	var createdAt, updatedAt time.Time
-	err = stmt.QueryRow(appID).Scan(&id, &name, &description, &userId, &createdAt, &updatedAt)
+	err = stmt.QueryRow(appID).Scan(&id, &name, &description, &userId, &organizationID, &createdAt, &updatedAt)
 	if err != nil {
 		log.Printf("GetAppByID: error executing query: %s", err.Error())
 		tx.Rollback()
@@ -130,12 +158,13 @@ func (repo *PSQLAppRepository) GetAppByID(appID models.AppID) (*models.App, erro
 	}
 
 	app := models.App{
-		ID:          id,
-		Name:        name,
-		Description: description,
-		UserID:      userId,
-		CreatedAt:   createdAt,
-		UpdatedAt:   updatedAt,
+		ID:             id,
+		Name:           name,
+		Description:    description,
+		UserID:         models.UserID(userId.Int64),
+		OrganizationID: appOrganizationID(organizationID),
+		CreatedAt:      createdAt,
+		UpdatedAt:      updatedAt,
 	}
 
 	log.Printf("GetAppByID: got app")
@@ -150,7 +179,7 @@ func (repo *PSQLAppRepository) CreateApp(app *models.App) error {
 		return err
 	}
 
-	stmt, err := tx.Prepare("INSERT INTO apps (name, description, user_id) VALUES ($1, $2, $3);")
+	stmt, err := tx.Prepare("INSERT INTO apps (name, description, user_id, organization_id) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at;")
 	if err != nil {
 		log.Printf("CreateApp: error creating statement: %s", err.Error())
 		tx.Rollback()
@@ -158,13 +187,23 @@ func (repo *PSQLAppRepository) CreateApp(app *models.App) error {
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(app.Name, app.Description, app.UserID)
+	err = stmt.QueryRow(app.Name, app.Description, app.UserID, app.OrganizationID).Scan(&app.ID, &app.CreatedAt, &app.UpdatedAt)
 	if err != nil {
 		log.Printf("CreateApp: error executing query: %s", err.Error())
 		tx.Rollback()
 		return err
 	}
 
+	_, err = tx.Exec(
+		"INSERT INTO app_members (app_id, user_id, role) VALUES ($1, $2, $3);",
+		app.ID, app.UserID, utils.AppRoleOwner,
+	)
+	if err != nil {
+		log.Printf("CreateApp: error adding owner: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		log.Printf("CreateApp: error during commmit: %s", err.Error())
@@ -242,3 +281,297 @@ func (repo *PSQLAppRepository) DeleteAppByID(appID models.AppID) error {
 	return nil
 
 }
+
+// TransferApp hands the app over to the recipient of the transfer and marks
+// the transfer as accepted by acceptedBy, in one transaction. Apps
+// transferred to a user become their personal apps; apps transferred to an
+// organization are then attributed to acceptedBy. The previous owner loses
+// their membership and the new one becomes an owner. It fails with
+// ErrAppTransferInvalid when the transfer is no longer pending or the app
+// changed owner since it was created.
+func (repo *PSQLAppRepository) TransferApp(transfer *models.AppTransfer, acceptedBy models.UserID) error {
+	userID := acceptedBy
+	if transfer.ToUserID != nil {
+		userID = *transfer.ToUserID
+	}
+
+	now := time.Now()
+
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("TransferApp: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	res, err := tx.Exec(
+		"UPDATE app_transfers SET accepted_at=$1, accepted_by=$2 WHERE id=$3 AND accepted_at IS NULL AND cancelled_at IS NULL AND expires_at > $1;",
+		now, acceptedBy, transfer.ID,
+	)
+	if err != nil {
+		log.Printf("TransferApp: error accepting transfer: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return utils.ErrAppTransferInvalid
+	}
+
+	res, err = tx.Exec(
+		"UPDATE apps SET user_id=$1, organization_id=$2, updated_at=$3 WHERE id=$4 AND user_id IS NOT DISTINCT FROM $5 AND organization_id IS NOT DISTINCT FROM $6 AND deleted_at IS NULL;",
+		userID, transfer.ToOrganizationID, now, transfer.AppID, appUserID(transfer.FromUserID), transfer.FromOrganizationID,
+	)
+	if err != nil {
+		log.Printf("TransferApp: error updating app: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	affected, err = res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return utils.ErrAppTransferInvalid
+	}
+
+	_, err = tx.Exec("DELETE FROM app_members WHERE app_id=$1 AND user_id=$2;", transfer.AppID, transfer.FromUserID)
+	if err != nil {
+		log.Printf("TransferApp: error removing previous owner: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO app_members (app_id, user_id, role) VALUES ($1, $2, $3) ON CONFLICT (app_id, user_id) DO UPDATE SET role=EXCLUDED.role;",
+		transfer.AppID, userID, utils.AppRoleOwner,
+	)
+	if err != nil {
+		log.Printf("TransferApp: error adding new owner: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("TransferApp: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("TransferApp: app %d transferred by transfer %d", transfer.AppID, transfer.ID)
+	return nil
+}
+
+// GetAllAppsByUserID returns every app owned by the user, soft-deleted ones
+// included.
+func (repo *PSQLAppRepository) GetAllAppsByUserID(userID models.UserID) ([]models.App, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetAllAppsByUserID: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	query := "SELECT id, name, description, user_id, organization_id, created_at, updated_at, deleted_at FROM apps WHERE user_id=$1;"
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		log.Printf("GetAllAppsByUserID: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(userID)
+	if err != nil {
+		log.Printf("GetAllAppsByUserID: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	apps := []models.App{}
+
+	for rows.Next() {
+		var id int
+		var userId, organizationID sql.NullInt64
+		var name, description string
+		var createdAt, updatedAt time.Time
+		var deletedAt sql.NullTime
+
+		err := rows.Scan(&id, &name, &description, &userId, &organizationID, &createdAt, &updatedAt, &deletedAt)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		apps = append(apps, models.App{
+			ID:             id,
+			Name:           name,
+			Description:    description,
+			UserID:         models.UserID(userId.Int64),
+			OrganizationID: appOrganizationID(organizationID),
+			CreatedAt:      createdAt,
+			UpdatedAt:      updatedAt,
+			DeletedAt:      deletedAt.Time,
+		})
+
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("GetAllAppsByUserID: error during iterating rows: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetAllAppsByUserID: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	log.Printf("GetAllAppsByUserID: got apps")
+	return apps, nil
+}
+
+// SearchApps returns a page of the apps matching filter, oldest first.
+func (repo *PSQLAppRepository) SearchApps(filter models.AppFilter) ([]models.App, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("SearchApps: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	where := " WHERE deleted_at IS NULL"
+	if filter.Deleted {
+		where = " WHERE deleted_at IS NOT NULL"
+	}
+
+	args := []any{}
+	if filter.UserID != 0 {
+		args = append(args, filter.UserID)
+		where += fmt.Sprintf(" AND user_id=$%d", len(args))
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	query := fmt.Sprintf(
+		"SELECT id, name, description, user_id, organization_id, created_at, updated_at, deleted_at FROM apps%s ORDER BY id LIMIT $%d OFFSET $%d;",
+		where, len(args)-1, len(args),
+	)
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		log.Printf("SearchApps: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		log.Printf("SearchApps: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	apps := []models.App{}
+
+	for rows.Next() {
+		var id int
+		var userId, organizationID sql.NullInt64
+		var name, description string
+		var createdAt, updatedAt time.Time
+		var deletedAt sql.NullTime
+
+		err := rows.Scan(&id, &name, &description, &userId, &organizationID, &createdAt, &updatedAt, &deletedAt)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		apps = append(apps, models.App{
+			ID:             id,
+			Name:           name,
+			Description:    description,
+			UserID:         models.UserID(userId.Int64),
+			OrganizationID: appOrganizationID(organizationID),
+			CreatedAt:      createdAt,
+			UpdatedAt:      updatedAt,
+			DeletedAt:      deletedAt.Time,
+		})
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("SearchApps: error during iterating rows: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("SearchApps: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	log.Printf("SearchApps: got apps")
+	return apps, nil
+}
+
+// RestoreAppByID undoes the soft delete of the app. It fails with
+// ErrDeletedAppNotFound when no deleted app has that id.
+func (repo *PSQLAppRepository) RestoreAppByID(appID models.AppID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("RestoreAppByID: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE apps SET deleted_at=NULL, updated_at=$1 WHERE id=$2 AND deleted_at IS NOT NULL;")
+	if err != nil {
+		log.Printf("RestoreAppByID: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(time.Now(), appID)
+	if err != nil {
+		log.Printf("RestoreAppByID: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return utils.ErrDeletedAppNotFound
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("RestoreAppByID: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("RestoreAppByID: app %d restored", appID)
+	return nil
+}
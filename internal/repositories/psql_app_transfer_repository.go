@@ -0,0 +1,266 @@
+package repositories
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+const appTransferColumns = "id, app_id, from_user_id, from_organization_id, to_email, to_user_id, to_organization_id, token_hash, initiated_by, created_at, expires_at, accepted_at, accepted_by, cancelled_at"
+
+type PSQLAppTransferRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLAppTransferRepository(db *sql.DB) *PSQLAppTransferRepository {
+	return &PSQLAppTransferRepository{
+		db: db,
+	}
+}
+
+// CreateAppTransfer stores the transfer and cancels the pending transfers
+// of the same app, so an app is only ever offered to one recipient.
+func (repo *PSQLAppTransferRepository) CreateAppTransfer(transfer *models.AppTransfer) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CreateAppTransfer: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE app_transfers SET cancelled_at=$1 WHERE app_id=$2 AND accepted_at IS NULL AND cancelled_at IS NULL;",
+		time.Now(), transfer.AppID,
+	)
+	if err != nil {
+		log.Printf("CreateAppTransfer: error cancelling previous transfers: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO app_transfers (app_id, from_user_id, from_organization_id, to_email, to_user_id, to_organization_id, token_hash, initiated_by, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at;")
+	if err != nil {
+		log.Printf("CreateAppTransfer: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(
+		transfer.AppID,
+		appUserID(transfer.FromUserID),
+		transfer.FromOrganizationID,
+		transfer.ToEmail,
+		transfer.ToUserID,
+		transfer.ToOrganizationID,
+		transfer.TokenHash,
+		transfer.InitiatedBy,
+		transfer.ExpiresAt,
+	).Scan(&transfer.ID, &transfer.CreatedAt)
+	if err != nil {
+		log.Printf("CreateAppTransfer: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CreateAppTransfer: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("CreateAppTransfer: app transfer created")
+	return nil
+}
+
+func scanAppTransfer(row rowScanner) (*models.AppTransfer, error) {
+	var transfer models.AppTransfer
+	var fromUserID, fromOrganizationID, toUserID, toOrganizationID, acceptedBy sql.NullInt64
+	var acceptedAt, cancelledAt sql.NullTime
+
+	err := row.Scan(
+		&transfer.ID,
+		&transfer.AppID,
+		&fromUserID,
+		&fromOrganizationID,
+		&transfer.ToEmail,
+		&toUserID,
+		&toOrganizationID,
+		&transfer.TokenHash,
+		&transfer.InitiatedBy,
+		&transfer.CreatedAt,
+		&transfer.ExpiresAt,
+		&acceptedAt,
+		&acceptedBy,
+		&cancelledAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer.FromUserID = models.UserID(fromUserID.Int64)
+	transfer.FromOrganizationID = appOrganizationID(fromOrganizationID)
+	transfer.ToOrganizationID = appOrganizationID(toOrganizationID)
+	if toUserID.Valid {
+		id := models.UserID(toUserID.Int64)
+		transfer.ToUserID = &id
+	}
+	if acceptedBy.Valid {
+		id := models.UserID(acceptedBy.Int64)
+		transfer.AcceptedBy = &id
+	}
+	if acceptedAt.Valid {
+		transfer.AcceptedAt = &acceptedAt.Time
+	}
+	if cancelledAt.Valid {
+		transfer.CancelledAt = &cancelledAt.Time
+	}
+
+	return &transfer, nil
+}
+
+func (repo *PSQLAppTransferRepository) queryAppTransfers(caller string, query string, args ...any) ([]models.AppTransfer, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("%s: error creating transaction: %s", caller, err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		log.Printf("%s: error creating statement: %s", caller, err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		log.Printf("%s: error executing query: %s", caller, err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := []models.AppTransfer{}
+
+	for rows.Next() {
+		transfer, err := scanAppTransfer(rows)
+		if err != nil {
+			log.Printf("%s: error scanning row: %s", caller, err.Error())
+			tx.Rollback()
+			return nil, err
+		}
+
+		transfers = append(transfers, *transfer)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("%s: error during iterating rows: %s", caller, err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("%s: error during commmit: %s", caller, err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+func (repo *PSQLAppTransferRepository) GetAppTransferByID(id models.AppTransferID) (*models.AppTransfer, error) {
+	transfers, err := repo.queryAppTransfers("GetAppTransferByID", "SELECT "+appTransferColumns+" FROM app_transfers WHERE id=$1;", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(transfers) == 0 {
+		return nil, utils.ErrAppTransferNotFound
+	}
+
+	return &transfers[0], nil
+}
+
+func (repo *PSQLAppTransferRepository) GetAppTransferByTokenHash(tokenHash string) (*models.AppTransfer, error) {
+	transfers, err := repo.queryAppTransfers("GetAppTransferByTokenHash", "SELECT "+appTransferColumns+" FROM app_transfers WHERE token_hash=$1;", tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(transfers) == 0 {
+		return nil, utils.ErrAppTransferInvalid
+	}
+
+	return &transfers[0], nil
+}
+
+// GetAppTransfersByAppID returns every transfer of the app, newest first.
+func (repo *PSQLAppTransferRepository) GetAppTransfersByAppID(appID models.AppID) ([]models.AppTransfer, error) {
+	return repo.queryAppTransfers("GetAppTransfersByAppID", "SELECT "+appTransferColumns+" FROM app_transfers WHERE app_id=$1 ORDER BY created_at DESC, id DESC;", appID)
+}
+
+// GetIncomingAppTransfers returns the pending transfers the user may accept:
+// the ones addressed to them and the ones addressed to organizations they
+// own or administer.
+func (repo *PSQLAppTransferRepository) GetIncomingAppTransfers(userID models.UserID) ([]models.AppTransfer, error) {
+	return repo.queryAppTransfers(
+		"GetIncomingAppTransfers",
+		"SELECT "+appTransferColumns+` FROM app_transfers WHERE accepted_at IS NULL AND cancelled_at IS NULL AND expires_at > $2
+		AND (to_user_id=$1 OR to_organization_id IN (SELECT organization_id FROM organization_members WHERE user_id=$1 AND role IN ($3, $4)))
+		ORDER BY created_at DESC, id DESC;`,
+		userID, time.Now(), utils.OrganizationRoleOwner, utils.OrganizationRoleAdmin,
+	)
+}
+
+// CancelAppTransfer cancels a pending transfer of the app. It fails with
+// ErrAppTransferNotFound when the app has no pending transfer with that ID.
+func (repo *PSQLAppTransferRepository) CancelAppTransfer(appID models.AppID, id models.AppTransferID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CancelAppTransfer: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE app_transfers SET cancelled_at=$1 WHERE id=$2 AND app_id=$3 AND accepted_at IS NULL AND cancelled_at IS NULL;")
+	if err != nil {
+		log.Printf("CancelAppTransfer: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(time.Now(), id, appID)
+	if err != nil {
+		log.Printf("CancelAppTransfer: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return utils.ErrAppTransferNotFound
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CancelAppTransfer: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("CancelAppTransfer: app transfer %d cancelled", id)
+	return nil
+}
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type PSQLAppUserPoolRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLAppUserPoolRepository(db *sql.DB) *PSQLAppUserPoolRepository {
+	return &PSQLAppUserPoolRepository{
+		db: db,
+	}
+}
+
+func scanAppUserPool(row rowScanner) (*models.AppUserPool, error) {
+	var pool models.AppUserPool
+
+	err := row.Scan(&pool.AppID, &pool.ClientID, &pool.Enabled, &pool.CreatedAt, &pool.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pool, nil
+}
+
+// queryAppUserPool runs a query returning a single user pool, mapping a
+// missing row to ErrAppUserPoolNotFound.
+func (repo *PSQLAppUserPoolRepository) queryAppUserPool(caller string, query string, args ...any) (*models.AppUserPool, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("%s: error creating transaction: %s", caller, err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		log.Printf("%s: error creating statement: %s", caller, err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	pool, err := scanAppUserPool(stmt.QueryRow(args...))
+	if err != nil {
+		log.Printf("%s: error executing query: %s", caller, err.Error())
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrAppUserPoolNotFound
+		}
+
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("%s: error during commmit: %s", caller, err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+func (repo *PSQLAppUserPoolRepository) GetUserPoolByAppID(appID models.AppID) (*models.AppUserPool, error) {
+	return repo.queryAppUserPool(
+		"GetUserPoolByAppID",
+		"SELECT app_id, client_id, enabled, created_at, updated_at FROM app_user_pools WHERE app_id=$1;",
+		appID,
+	)
+}
+
+// GetUserPoolByClientID returns the pool with clientID only while it is
+// enabled and its app is not deleted.
+func (repo *PSQLAppUserPoolRepository) GetUserPoolByClientID(clientID models.AppClientID) (*models.AppUserPool, error) {
+	return repo.queryAppUserPool(
+		"GetUserPoolByClientID",
+		`SELECT p.app_id, p.client_id, p.enabled, p.created_at, p.updated_at FROM app_user_pools p
+		JOIN apps a ON a.id = p.app_id WHERE p.client_id=$1 AND p.enabled AND a.deleted_at IS NULL;`,
+		clientID,
+	)
+}
+
+// SaveUserPool creates the pool of the app or updates whether it is enabled.
+// The client ID of an existing pool is kept, so it is read back into pool.
+func (repo *PSQLAppUserPoolRepository) SaveUserPool(pool *models.AppUserPool) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("SaveUserPool: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO app_user_pools (app_id, client_id, enabled) VALUES ($1, $2, $3)
+		ON CONFLICT (app_id) DO UPDATE SET enabled=EXCLUDED.enabled, updated_at=$4
+		RETURNING client_id, created_at, updated_at;`)
+	if err != nil {
+		log.Printf("SaveUserPool: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(pool.AppID, pool.ClientID, pool.Enabled, time.Now()).Scan(&pool.ClientID, &pool.CreatedAt, &pool.UpdatedAt)
+	if err != nil {
+		log.Printf("SaveUserPool: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("SaveUserPool: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("SaveUserPool: user pool of app %d saved", pool.AppID)
+	return nil
+}
@@ -0,0 +1,287 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type PSQLAppUserRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLAppUserRepository(db *sql.DB) *PSQLAppUserRepository {
+	return &PSQLAppUserRepository{
+		db: db,
+	}
+}
+
+const appUserColumns = "id, app_id, email, password, status, created_at, updated_at, last_login_at"
+
+func scanAppUser(row rowScanner) (*models.AppUser, error) {
+	var user models.AppUser
+	var lastLoginAt sql.NullTime
+
+	err := row.Scan(
+		&user.ID,
+		&user.AppID,
+		&user.Email,
+		&user.Password,
+		&user.Status,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&lastLoginAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastLoginAt.Valid {
+		user.LastLoginAt = &lastLoginAt.Time
+	}
+
+	return &user, nil
+}
+
+// queryAppUser runs a query returning a single app user, mapping a missing
+// row to ErrUserNotFound.
+func (repo *PSQLAppUserRepository) queryAppUser(caller string, query string, args ...any) (*models.AppUser, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("%s: error creating transaction: %s", caller, err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		log.Printf("%s: error creating statement: %s", caller, err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	user, err := scanAppUser(stmt.QueryRow(args...))
+	if err != nil {
+		log.Printf("%s: error executing query: %s", caller, err.Error())
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrUserNotFound
+		}
+
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("%s: error during commmit: %s", caller, err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (repo *PSQLAppUserRepository) CreateAppUser(user *models.AppUser) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CreateAppUser: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO app_users (app_id, email, password, status) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at;")
+	if err != nil {
+		log.Printf("CreateAppUser: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(user.AppID, user.Email, user.Password, user.Status).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		log.Printf("CreateAppUser: error executing query: %s", err.Error())
+		tx.Rollback()
+
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code {
+			case "23505":
+				return utils.ErrUserEmailAlreadyExists
+			case "23503":
+				return utils.ErrAppUserPoolNotFound
+			}
+		}
+
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CreateAppUser: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("CreateAppUser: user %d created in the pool of app %d", user.ID, user.AppID)
+	return nil
+}
+
+func (repo *PSQLAppUserRepository) GetAppUserByID(appID models.AppID, appUserID models.AppUserID) (*models.AppUser, error) {
+	return repo.queryAppUser(
+		"GetAppUserByID",
+		"SELECT "+appUserColumns+" FROM app_users WHERE app_id=$1 AND id=$2;",
+		appID,
+		appUserID,
+	)
+}
+
+func (repo *PSQLAppUserRepository) GetAppUserByEmail(appID models.AppID, email models.UserEmail) (*models.AppUser, error) {
+	return repo.queryAppUser(
+		"GetAppUserByEmail",
+		"SELECT "+appUserColumns+" FROM app_users WHERE app_id=$1 AND email=$2;",
+		appID,
+		email,
+	)
+}
+
+func (repo *PSQLAppUserRepository) UpdateAppUserLastLogin(appUserID models.AppUserID, at time.Time) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("UpdateAppUserLastLogin: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE app_users SET last_login_at=$1 WHERE id=$2;")
+	if err != nil {
+		log.Printf("UpdateAppUserLastLogin: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(at, appUserID)
+	if err != nil {
+		log.Printf("UpdateAppUserLastLogin: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("UpdateAppUserLastLogin: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("UpdateAppUserLastLogin: last login updated")
+	return nil
+}
+
+func (repo *PSQLAppUserRepository) CreateAppUserRefreshToken(token *models.AppUserRefreshToken) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CreateAppUserRefreshToken: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO app_user_refresh_tokens (content, status, app_user_id) VALUES ($1, $2, $3);")
+	if err != nil {
+		log.Printf("CreateAppUserRefreshToken: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(token.Content, token.Status, token.AppUserID)
+	if err != nil {
+		log.Printf("CreateAppUserRefreshToken: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CreateAppUserRefreshToken: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("CreateAppUserRefreshToken: refresh token created")
+	return nil
+}
+
+func (repo *PSQLAppUserRepository) GetAppUserRefreshTokenByContent(content models.RefreshTokenContent) (*models.AppUserRefreshToken, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetAppUserRefreshTokenByContent: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT id, content, status, app_user_id, created_at FROM app_user_refresh_tokens WHERE content=$1;")
+	if err != nil {
+		log.Printf("GetAppUserRefreshTokenByContent: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var token models.AppUserRefreshToken
+	err = stmt.QueryRow(content).Scan(&token.ID, &token.Content, &token.Status, &token.AppUserID, &token.CreatedAt)
+	if err != nil {
+		log.Printf("GetAppUserRefreshTokenByContent: error executing query: %s", err.Error())
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrRefreshTokenNotFound
+		}
+
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetAppUserRefreshTokenByContent: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (repo *PSQLAppUserRepository) InvalidateAppUserRefreshTokenByContent(content models.RefreshTokenContent) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("InvalidateAppUserRefreshTokenByContent: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE app_user_refresh_tokens SET status='inactive' WHERE content=$1;")
+	if err != nil {
+		log.Printf("InvalidateAppUserRefreshTokenByContent: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(content)
+	if err != nil {
+		log.Printf("InvalidateAppUserRefreshTokenByContent: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("InvalidateAppUserRefreshTokenByContent: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("InvalidateAppUserRefreshTokenByContent: invalidated refresh token")
+	return nil
+}
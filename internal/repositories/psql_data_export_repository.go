@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type PSQLDataExportRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLDataExportRepository(db *sql.DB) *PSQLDataExportRepository {
+	return &PSQLDataExportRepository{
+		db: db,
+	}
+}
+
+func (repo *PSQLDataExportRepository) CreateDataExport(export *models.DataExport) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CreateDataExport: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO data_exports (content, user_id, archive, expires_at) VALUES ($1, $2, $3, $4) RETURNING id;")
+	if err != nil {
+		log.Printf("CreateDataExport: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(export.Content, export.UserID, export.Archive, export.ExpiresAt).Scan(&export.ID)
+	if err != nil {
+		log.Printf("CreateDataExport: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CreateDataExport: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("CreateDataExport: data export created")
+	return nil
+}
+
+func (repo *PSQLDataExportRepository) GetDataExportByContent(content models.DataExportContent) (*models.DataExport, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetDataExportByContent: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT id, content, user_id, archive, created_at, expires_at FROM data_exports WHERE content=$1;")
+	if err != nil {
+		log.Printf("GetDataExportByContent: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var export models.DataExport
+	err = stmt.QueryRow(content).Scan(&export.ID, &export.Content, &export.UserID, &export.Archive, &export.CreatedAt, &export.ExpiresAt)
+	if err != nil {
+		log.Printf("GetDataExportByContent: error executing query: %s", err.Error())
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrDataExportNotFound
+		}
+
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetDataExportByContent: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	log.Printf("GetDataExportByContent: data export found")
+	return &export, nil
+}
+
+func (repo *PSQLDataExportRepository) DeleteExpiredDataExports(now time.Time) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("DeleteExpiredDataExports: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("DELETE FROM data_exports WHERE expires_at <= $1;")
+	if err != nil {
+		log.Printf("DeleteExpiredDataExports: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(now)
+	if err != nil {
+		log.Printf("DeleteExpiredDataExports: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("DeleteExpiredDataExports: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("DeleteExpiredDataExports: expired data exports deleted")
+	return nil
+}
@@ -0,0 +1,201 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type PSQLEmailOTPCodeRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLEmailOTPCodeRepository(db *sql.DB) *PSQLEmailOTPCodeRepository {
+	return &PSQLEmailOTPCodeRepository{
+		db: db,
+	}
+}
+
+// CreateEmailOTPCode stores a new code for the user. Previous codes of the
+// user and expired codes of everyone are removed, so only the latest code
+// sent to an address can be used.
+func (repo *PSQLEmailOTPCodeRepository) CreateEmailOTPCode(code *models.EmailOTPCode) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CreateEmailOTPCode: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	deleteStmt, err := tx.Prepare("DELETE FROM email_otp_codes WHERE user_id=$1 OR expires_at < CURRENT_TIMESTAMP;")
+	if err != nil {
+		log.Printf("CreateEmailOTPCode: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer deleteStmt.Close()
+
+	_, err = deleteStmt.Exec(code.UserID)
+	if err != nil {
+		log.Printf("CreateEmailOTPCode: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO email_otp_codes (user_id, code_hash, expires_at) VALUES ($1, $2, $3) RETURNING id;")
+	if err != nil {
+		log.Printf("CreateEmailOTPCode: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(code.UserID, code.CodeHash, code.ExpiresAt).Scan(&code.ID)
+	if err != nil {
+		log.Printf("CreateEmailOTPCode: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CreateEmailOTPCode: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("CreateEmailOTPCode: email otp code created")
+	return nil
+}
+
+func (repo *PSQLEmailOTPCodeRepository) GetActiveEmailOTPCodeByUserID(userID models.UserID, now time.Time) (*models.EmailOTPCode, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetActiveEmailOTPCodeByUserID: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	query := `SELECT id, user_id, code_hash, attempts, created_at, expires_at FROM email_otp_codes
+		WHERE user_id=$1 AND used_at IS NULL AND expires_at > $2 ORDER BY created_at DESC LIMIT 1;`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		log.Printf("GetActiveEmailOTPCodeByUserID: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var code models.EmailOTPCode
+	err = stmt.QueryRow(userID, now).Scan(&code.ID, &code.UserID, &code.CodeHash, &code.Attempts, &code.CreatedAt, &code.ExpiresAt)
+	if err != nil {
+		log.Printf("GetActiveEmailOTPCodeByUserID: error executing query: %s", err.Error())
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrEmailOTPInvalid
+		}
+
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetActiveEmailOTPCodeByUserID: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &code, nil
+}
+
+// IncrementEmailOTPAttempts counts a verification attempt against the code.
+// It fails with ErrEmailOTPAttemptsExceeded once maxAttempts were used, and
+// runs before the comparison so parallel guesses are counted too.
+func (repo *PSQLEmailOTPCodeRepository) IncrementEmailOTPAttempts(id models.EmailOTPCodeID, maxAttempts int) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("IncrementEmailOTPAttempts: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE email_otp_codes SET attempts=attempts+1 WHERE id=$1 AND attempts < $2 AND used_at IS NULL;")
+	if err != nil {
+		log.Printf("IncrementEmailOTPAttempts: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(id, maxAttempts)
+	if err != nil {
+		log.Printf("IncrementEmailOTPAttempts: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return utils.ErrEmailOTPAttemptsExceeded
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("IncrementEmailOTPAttempts: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+func (repo *PSQLEmailOTPCodeRepository) UseEmailOTPCode(id models.EmailOTPCodeID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("UseEmailOTPCode: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE email_otp_codes SET used_at=CURRENT_TIMESTAMP WHERE id=$1 AND used_at IS NULL;")
+	if err != nil {
+		log.Printf("UseEmailOTPCode: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(id)
+	if err != nil {
+		log.Printf("UseEmailOTPCode: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return utils.ErrEmailOTPInvalid
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("UseEmailOTPCode: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
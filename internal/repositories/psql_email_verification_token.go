@@ -135,3 +135,59 @@ func (repo *PSQLEmailVerificationTokenRepository) SetTokenToUsed(token *models.E
 	log.Printf("SetTokenToUsed: token set to used")
 	return nil
 }
+
+func (repo *PSQLEmailVerificationTokenRepository) GetVerificationTokensByUserID(userID models.UserID) ([]models.EmailVerificationToken, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetVerificationTokensByUserID: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	query := "SELECT id, content, user_id, created_at, expires_at, is_used FROM email_verification_tokens WHERE user_id=$1 ORDER BY created_at DESC;"
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		log.Printf("GetVerificationTokensByUserID: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(userID)
+	if err != nil {
+		log.Printf("GetVerificationTokensByUserID: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.EmailVerificationToken{}
+
+	for rows.Next() {
+		var token models.EmailVerificationToken
+
+		err := rows.Scan(&token.ID, &token.Content, &token.UserID, &token.CreatedAt, &token.ExpiresAt, &token.IsUsed)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("GetVerificationTokensByUserID: error during iterating rows: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetVerificationTokensByUserID: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	log.Printf("GetVerificationTokensByUserID: got email verification tokens")
+	return tokens, nil
+}
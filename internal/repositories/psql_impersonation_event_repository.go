@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type PSQLImpersonationEventRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLImpersonationEventRepository(db *sql.DB) *PSQLImpersonationEventRepository {
+	return &PSQLImpersonationEventRepository{
+		db: db,
+	}
+}
+
+func (repo *PSQLImpersonationEventRepository) CreateImpersonationEvent(event *models.ImpersonationEvent) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CreateImpersonationEvent: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO impersonation_events (actor_user_id, target_user_id, actor_email, target_email, reason, ip_address, user_agent, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id;")
+	if err != nil {
+		log.Printf("CreateImpersonationEvent: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(
+		event.ActorUserID,
+		event.TargetUserID,
+		event.ActorEmail,
+		event.TargetEmail,
+		event.Reason,
+		event.IPAddress,
+		event.UserAgent,
+		event.CreatedAt,
+		event.ExpiresAt,
+	).Scan(&event.ID)
+	if err != nil {
+		log.Printf("CreateImpersonationEvent: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CreateImpersonationEvent: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// GetImpersonationEventsByTargetUserID returns a page of the impersonations
+// of the user, newest first.
+func (repo *PSQLImpersonationEventRepository) GetImpersonationEventsByTargetUserID(userID models.UserID, limit int, offset int) ([]models.ImpersonationEvent, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetImpersonationEventsByTargetUserID: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT id, actor_user_id, target_user_id, actor_email, target_email, reason, ip_address, user_agent, created_at, expires_at FROM impersonation_events WHERE target_user_id=$1 ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3;")
+	if err != nil {
+		log.Printf("GetImpersonationEventsByTargetUserID: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(userID, limit, offset)
+	if err != nil {
+		log.Printf("GetImpersonationEventsByTargetUserID: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.ImpersonationEvent{}
+
+	for rows.Next() {
+		var event models.ImpersonationEvent
+		var actorUserID, targetUserID sql.NullInt64
+
+		err := rows.Scan(
+			&event.ID,
+			&actorUserID,
+			&targetUserID,
+			&event.ActorEmail,
+			&event.TargetEmail,
+			&event.Reason,
+			&event.IPAddress,
+			&event.UserAgent,
+			&event.CreatedAt,
+			&event.ExpiresAt,
+		)
+		if err != nil {
+			log.Printf("GetImpersonationEventsByTargetUserID: error scanning row: %s", err.Error())
+			tx.Rollback()
+			return nil, err
+		}
+
+		if actorUserID.Valid {
+			id := models.UserID(actorUserID.Int64)
+			event.ActorUserID = &id
+		}
+		if targetUserID.Valid {
+			id := models.UserID(targetUserID.Int64)
+			event.TargetUserID = &id
+		}
+
+		events = append(events, event)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("GetImpersonationEventsByTargetUserID: error during iterating rows: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetImpersonationEventsByTargetUserID: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (repo *PSQLImpersonationEventRepository) CountImpersonationEventsByTargetUserID(userID models.UserID) (int, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CountImpersonationEventsByTargetUserID: error creating transaction: %s", err.Error())
+		return 0, err
+	}
+
+	stmt, err := tx.Prepare("SELECT COUNT(*) FROM impersonation_events WHERE target_user_id=$1;")
+	if err != nil {
+		log.Printf("CountImpersonationEventsByTargetUserID: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var count int
+	err = stmt.QueryRow(userID).Scan(&count)
+	if err != nil {
+		log.Printf("CountImpersonationEventsByTargetUserID: error executing query: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CountImpersonationEventsByTargetUserID: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,395 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type PSQLInvitationRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLInvitationRepository(db *sql.DB) *PSQLInvitationRepository {
+	return &PSQLInvitationRepository{
+		db: db,
+	}
+}
+
+// CreateInvitation stores the invitation and revokes the pending invitations
+// previously sent to the same e-mail for the same organization or app, or
+// for neither, so only the newest link works.
+func (repo *PSQLInvitationRepository) CreateInvitation(invitation *models.Invitation) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CreateInvitation: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE invitations SET revoked_at=$1 WHERE LOWER(email)=LOWER($2) AND organization_id IS NOT DISTINCT FROM $3 AND app_id IS NOT DISTINCT FROM $4 AND accepted_at IS NULL AND revoked_at IS NULL;",
+		time.Now(), invitation.Email, invitation.OrganizationID, invitation.AppID,
+	)
+	if err != nil {
+		log.Printf("CreateInvitation: error revoking previous invitations: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO invitations (email, token_hash, invited_by, role_name, organization_id, organization_role, app_id, app_role, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at;")
+	if err != nil {
+		log.Printf("CreateInvitation: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(
+		invitation.Email,
+		invitation.TokenHash,
+		invitation.InvitedBy,
+		invitation.RoleName,
+		invitation.OrganizationID,
+		invitation.OrganizationRole,
+		invitation.AppID,
+		invitation.AppRole,
+		invitation.ExpiresAt,
+	).Scan(&invitation.ID, &invitation.CreatedAt)
+	if err != nil {
+		log.Printf("CreateInvitation: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CreateInvitation: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("CreateInvitation: invitation created")
+	return nil
+}
+
+func scanInvitation(row rowScanner) (*models.Invitation, error) {
+	var invitation models.Invitation
+	var userID, organizationID, appID sql.NullInt64
+	var acceptedAt, revokedAt sql.NullTime
+
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.Email,
+		&invitation.TokenHash,
+		&invitation.InvitedBy,
+		&invitation.RoleName,
+		&organizationID,
+		&invitation.OrganizationRole,
+		&appID,
+		&invitation.AppRole,
+		&userID,
+		&invitation.CreatedAt,
+		&invitation.ExpiresAt,
+		&acceptedAt,
+		&revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if userID.Valid {
+		id := models.UserID(userID.Int64)
+		invitation.UserID = &id
+	}
+	if organizationID.Valid {
+		id := models.OrganizationID(organizationID.Int64)
+		invitation.OrganizationID = &id
+	}
+	if appID.Valid {
+		id := models.AppID(appID.Int64)
+		invitation.AppID = &id
+	}
+	if acceptedAt.Valid {
+		invitation.AcceptedAt = &acceptedAt.Time
+	}
+	if revokedAt.Valid {
+		invitation.RevokedAt = &revokedAt.Time
+	}
+
+	return &invitation, nil
+}
+
+func (repo *PSQLInvitationRepository) GetInvitationByTokenHash(tokenHash string) (*models.Invitation, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetInvitationByTokenHash: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT id, email, token_hash, invited_by, role_name, organization_id, organization_role, app_id, app_role, user_id, created_at, expires_at, accepted_at, revoked_at FROM invitations WHERE token_hash=$1;")
+	if err != nil {
+		log.Printf("GetInvitationByTokenHash: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	invitation, err := scanInvitation(stmt.QueryRow(tokenHash))
+	if err != nil {
+		log.Printf("GetInvitationByTokenHash: error executing query: %s", err.Error())
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrInvitationInvalid
+		}
+
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetInvitationByTokenHash: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// GetInvitations returns a page of invitations, newest first.
+func (repo *PSQLInvitationRepository) GetInvitations(limit int, offset int) ([]models.Invitation, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetInvitations: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT id, email, token_hash, invited_by, role_name, organization_id, organization_role, app_id, app_role, user_id, created_at, expires_at, accepted_at, revoked_at FROM invitations ORDER BY created_at DESC, id DESC LIMIT $1 OFFSET $2;")
+	if err != nil {
+		log.Printf("GetInvitations: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(limit, offset)
+	if err != nil {
+		log.Printf("GetInvitations: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []models.Invitation{}
+
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			log.Printf("GetInvitations: error scanning row: %s", err.Error())
+			tx.Rollback()
+			return nil, err
+		}
+
+		invitations = append(invitations, *invitation)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("GetInvitations: error during iterating rows: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetInvitations: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+// GetInvitationsByUser returns the invitations sent to the user, by e-mail
+// or accepted by them, and the ones they sent, newest first.
+func (repo *PSQLInvitationRepository) GetInvitationsByUser(userID models.UserID, email models.UserEmail) ([]models.Invitation, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetInvitationsByUser: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare(`SELECT id, email, token_hash, invited_by, role_name, organization_id, organization_role, app_id, app_role, user_id, created_at, expires_at, accepted_at, revoked_at FROM invitations
+		WHERE user_id=$1 OR invited_by=$1 OR LOWER(email)=LOWER($2) ORDER BY created_at DESC, id DESC;`)
+	if err != nil {
+		log.Printf("GetInvitationsByUser: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(userID, email)
+	if err != nil {
+		log.Printf("GetInvitationsByUser: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []models.Invitation{}
+
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			log.Printf("GetInvitationsByUser: error scanning row: %s", err.Error())
+			tx.Rollback()
+			return nil, err
+		}
+
+		invitations = append(invitations, *invitation)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("GetInvitationsByUser: error during iterating rows: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetInvitationsByUser: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+func (repo *PSQLInvitationRepository) CountInvitations() (int, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CountInvitations: error creating transaction: %s", err.Error())
+		return 0, err
+	}
+
+	stmt, err := tx.Prepare("SELECT COUNT(*) FROM invitations;")
+	if err != nil {
+		log.Printf("CountInvitations: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var count int
+	err = stmt.QueryRow().Scan(&count)
+	if err != nil {
+		log.Printf("CountInvitations: error executing query: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CountInvitations: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// RevokeInvitation revokes a pending invitation. It fails with
+// ErrInvitationNotFound when there is no pending invitation with that ID.
+func (repo *PSQLInvitationRepository) RevokeInvitation(id models.InvitationID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("RevokeInvitation: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE invitations SET revoked_at=$1 WHERE id=$2 AND accepted_at IS NULL AND revoked_at IS NULL;")
+	if err != nil {
+		log.Printf("RevokeInvitation: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(time.Now(), id)
+	if err != nil {
+		log.Printf("RevokeInvitation: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return utils.ErrInvitationNotFound
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("RevokeInvitation: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("RevokeInvitation: invitation %d revoked", id)
+	return nil
+}
+
+// AcceptInvitation marks the invitation as accepted by userID. It fails with
+// ErrInvitationInvalid when the invitation was accepted or revoked meanwhile.
+func (repo *PSQLInvitationRepository) AcceptInvitation(id models.InvitationID, userID models.UserID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("AcceptInvitation: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE invitations SET accepted_at=$1, user_id=$2 WHERE id=$3 AND accepted_at IS NULL AND revoked_at IS NULL;")
+	if err != nil {
+		log.Printf("AcceptInvitation: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(time.Now(), userID, id)
+	if err != nil {
+		log.Printf("AcceptInvitation: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return utils.ErrInvitationInvalid
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("AcceptInvitation: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package repositories
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type PSQLLoginEventRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLLoginEventRepository(db *sql.DB) *PSQLLoginEventRepository {
+	return &PSQLLoginEventRepository{
+		db: db,
+	}
+}
+
+func (repo *PSQLLoginEventRepository) CreateLoginEvent(event *models.LoginEvent) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CreateLoginEvent: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO login_events (user_id, method, success, failure_reason, ip_address, user_agent, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id;")
+	if err != nil {
+		log.Printf("CreateLoginEvent: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	var failureReason sql.NullString
+	if event.FailureReason != "" {
+		failureReason = sql.NullString{String: event.FailureReason, Valid: true}
+	}
+
+	err = stmt.QueryRow(event.UserID, event.Method, event.Success, failureReason, event.IPAddress, event.UserAgent, event.CreatedAt).Scan(&event.ID)
+	if err != nil {
+		log.Printf("CreateLoginEvent: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CreateLoginEvent: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// GetLoginEventsByUserID returns a page of the user's login events, newest
+// first.
+func (repo *PSQLLoginEventRepository) GetLoginEventsByUserID(userID models.UserID, limit int, offset int) ([]models.LoginEvent, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetLoginEventsByUserID: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT id, user_id, method, success, failure_reason, ip_address, user_agent, created_at FROM login_events WHERE user_id=$1 ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3;")
+	if err != nil {
+		log.Printf("GetLoginEventsByUserID: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(userID, limit, offset)
+	if err != nil {
+		log.Printf("GetLoginEventsByUserID: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.LoginEvent{}
+
+	for rows.Next() {
+		var event models.LoginEvent
+		var failureReason sql.NullString
+
+		err := rows.Scan(&event.ID, &event.UserID, &event.Method, &event.Success, &failureReason, &event.IPAddress, &event.UserAgent, &event.CreatedAt)
+		if err != nil {
+			log.Printf("GetLoginEventsByUserID: error scanning row: %s", err.Error())
+			tx.Rollback()
+			return nil, err
+		}
+
+		event.FailureReason = failureReason.String
+		events = append(events, event)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("GetLoginEventsByUserID: error during iterating rows: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetLoginEventsByUserID: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (repo *PSQLLoginEventRepository) CountLoginEventsByUserID(userID models.UserID) (int, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CountLoginEventsByUserID: error creating transaction: %s", err.Error())
+		return 0, err
+	}
+
+	stmt, err := tx.Prepare("SELECT COUNT(*) FROM login_events WHERE user_id=$1;")
+	if err != nil {
+		log.Printf("CountLoginEventsByUserID: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var count int
+	err = stmt.QueryRow(userID).Scan(&count)
+	if err != nil {
+		log.Printf("CountLoginEventsByUserID: error executing query: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CountLoginEventsByUserID: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// GetKnownLoginDevice reports whether the user ever logged in successfully
+// and whether one of those logins came from the given IP and user agent.
+func (repo *PSQLLoginEventRepository) GetKnownLoginDevice(userID models.UserID, ipAddress string, userAgent string) (bool, bool, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetKnownLoginDevice: error creating transaction: %s", err.Error())
+		return false, false, err
+	}
+
+	stmt, err := tx.Prepare("SELECT COUNT(*) > 0, COUNT(*) FILTER (WHERE ip_address=$2 AND user_agent=$3) > 0 FROM login_events WHERE user_id=$1 AND success;")
+	if err != nil {
+		log.Printf("GetKnownLoginDevice: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return false, false, err
+	}
+	defer stmt.Close()
+
+	var hasLogins, known bool
+	err = stmt.QueryRow(userID, ipAddress, userAgent).Scan(&hasLogins, &known)
+	if err != nil {
+		log.Printf("GetKnownLoginDevice: error executing query: %s", err.Error())
+		tx.Rollback()
+		return false, false, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetKnownLoginDevice: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return false, false, err
+	}
+
+	return hasLogins, known, nil
+}
@@ -0,0 +1,175 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type PSQLMagicLinkTokenRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLMagicLinkTokenRepository(db *sql.DB) *PSQLMagicLinkTokenRepository {
+	return &PSQLMagicLinkTokenRepository{
+		db: db,
+	}
+}
+
+func (repo *PSQLMagicLinkTokenRepository) CreateMagicLinkToken(token *models.MagicLinkToken) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CreateMagicLinkToken: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO magic_link_tokens (content, user_id, binding, expires_at) VALUES ($1, $2, $3, $4) RETURNING id;")
+	if err != nil {
+		log.Printf("CreateMagicLinkToken: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(token.Content, token.UserID, token.Binding, token.ExpiresAt).Scan(&token.ID)
+	if err != nil {
+		log.Printf("CreateMagicLinkToken: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CreateMagicLinkToken: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("CreateMagicLinkToken: magic link token created")
+	return nil
+}
+
+func (repo *PSQLMagicLinkTokenRepository) GetMagicLinkTokenByContent(content models.MagicLinkTokenContent) (*models.MagicLinkToken, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetMagicLinkTokenByContent: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT id, content, user_id, binding, created_at, expires_at, used_at FROM magic_link_tokens WHERE content=$1;")
+	if err != nil {
+		log.Printf("GetMagicLinkTokenByContent: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var token models.MagicLinkToken
+	var usedAt sql.NullTime
+	err = stmt.QueryRow(content).Scan(&token.ID, &token.Content, &token.UserID, &token.Binding, &token.CreatedAt, &token.ExpiresAt, &usedAt)
+	if err != nil {
+		log.Printf("GetMagicLinkTokenByContent: error executing query: %s", err.Error())
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrMagicLinkInvalid
+		}
+
+		return nil, err
+	}
+
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetMagicLinkTokenByContent: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// UseMagicLinkToken marks the token as used. It fails with
+// ErrMagicLinkInvalid when the token was already used, so two concurrent
+// requests with the same link cannot both log in.
+func (repo *PSQLMagicLinkTokenRepository) UseMagicLinkToken(id models.MagicLinkTokenID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("UseMagicLinkToken: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE magic_link_tokens SET used_at=CURRENT_TIMESTAMP WHERE id=$1 AND used_at IS NULL;")
+	if err != nil {
+		log.Printf("UseMagicLinkToken: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(id)
+	if err != nil {
+		log.Printf("UseMagicLinkToken: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return utils.ErrMagicLinkInvalid
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("UseMagicLinkToken: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+func (repo *PSQLMagicLinkTokenRepository) DeleteExpiredMagicLinkTokens(now time.Time) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("DeleteExpiredMagicLinkTokens: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("DELETE FROM magic_link_tokens WHERE expires_at < $1;")
+	if err != nil {
+		log.Printf("DeleteExpiredMagicLinkTokens: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(now)
+	if err != nil {
+		log.Printf("DeleteExpiredMagicLinkTokens: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("DeleteExpiredMagicLinkTokens: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
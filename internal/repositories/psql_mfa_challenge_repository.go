@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+type PSQLMFAChallengeRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLMFAChallengeRepository(db *sql.DB) *PSQLMFAChallengeRepository {
+	return &PSQLMFAChallengeRepository{
+		db: db,
+	}
+}
+
+// GetMFAChallengeFailures returns how many wrong second factors were sent
+// with the mfa_token challengeID. Tokens without failures have no row.
+func (repo *PSQLMFAChallengeRepository) GetMFAChallengeFailures(challengeID string) (int, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetMFAChallengeFailures: error creating transaction: %s", err.Error())
+		return 0, err
+	}
+
+	stmt, err := tx.Prepare("SELECT failed_attempts FROM mfa_challenge_failures WHERE challenge_id=$1;")
+	if err != nil {
+		log.Printf("GetMFAChallengeFailures: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var failures int
+	err = stmt.QueryRow(challengeID).Scan(&failures)
+	if err != nil {
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+
+		log.Printf("GetMFAChallengeFailures: error executing query: %s", err.Error())
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetMFAChallengeFailures: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	return failures, nil
+}
+
+// RecordMFAChallengeFailure atomically counts a failure of the challenge and
+// returns the new count. expiresAt is when the row may be cleaned up.
+func (repo *PSQLMFAChallengeRepository) RecordMFAChallengeFailure(challengeID string, expiresAt time.Time) (int, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("RecordMFAChallengeFailure: error creating transaction: %s", err.Error())
+		return 0, err
+	}
+
+	query := `INSERT INTO mfa_challenge_failures (challenge_id, failed_attempts, expires_at) VALUES ($1, 1, $2)
+		ON CONFLICT (challenge_id) DO UPDATE SET failed_attempts = mfa_challenge_failures.failed_attempts + 1
+		RETURNING failed_attempts;`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		log.Printf("RecordMFAChallengeFailure: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var failures int
+	err = stmt.QueryRow(challengeID, expiresAt).Scan(&failures)
+	if err != nil {
+		log.Printf("RecordMFAChallengeFailure: error executing query: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("RecordMFAChallengeFailure: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	return failures, nil
+}
+
+func (repo *PSQLMFAChallengeRepository) DeleteExpiredMFAChallenges(now time.Time) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("DeleteExpiredMFAChallenges: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("DELETE FROM mfa_challenge_failures WHERE expires_at < $1;")
+	if err != nil {
+		log.Printf("DeleteExpiredMFAChallenges: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(now)
+	if err != nil {
+		log.Printf("DeleteExpiredMFAChallenges: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("DeleteExpiredMFAChallenges: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
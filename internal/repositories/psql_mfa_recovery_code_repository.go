@@ -0,0 +1,174 @@
+package repositories
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type PSQLMFARecoveryCodeRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLMFARecoveryCodeRepository(db *sql.DB) *PSQLMFARecoveryCodeRepository {
+	return &PSQLMFARecoveryCodeRepository{
+		db: db,
+	}
+}
+
+// ReplaceRecoveryCodes discards every recovery code of the user and stores the
+// new set in the same transaction.
+func (repo *PSQLMFARecoveryCodeRepository) ReplaceRecoveryCodes(userID models.UserID, contents []models.MFARecoveryCodeContent) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("ReplaceRecoveryCodes: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM mfa_recovery_codes WHERE user_id=$1;", userID)
+	if err != nil {
+		log.Printf("ReplaceRecoveryCodes: error deleting old codes: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO mfa_recovery_codes (content, user_id) VALUES ($1, $2);")
+	if err != nil {
+		log.Printf("ReplaceRecoveryCodes: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, content := range contents {
+		_, err = stmt.Exec(content, userID)
+		if err != nil {
+			log.Printf("ReplaceRecoveryCodes: error executing query: %s", err.Error())
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("ReplaceRecoveryCodes: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("ReplaceRecoveryCodes: recovery codes replaced")
+	return nil
+}
+
+func (repo *PSQLMFARecoveryCodeRepository) UseRecoveryCode(userID models.UserID, content models.MFARecoveryCodeContent) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("UseRecoveryCode: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE mfa_recovery_codes SET used_at=$1 WHERE user_id=$2 AND content=$3 AND used_at IS NULL;")
+	if err != nil {
+		log.Printf("UseRecoveryCode: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(time.Now(), userID, content)
+	if err != nil {
+		log.Printf("UseRecoveryCode: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return utils.ErrRecoveryCodeInvalid
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("UseRecoveryCode: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("UseRecoveryCode: recovery code used")
+	return nil
+}
+
+func (repo *PSQLMFARecoveryCodeRepository) CountUnusedRecoveryCodes(userID models.UserID) (int, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CountUnusedRecoveryCodes: error creating transaction: %s", err.Error())
+		return 0, err
+	}
+
+	stmt, err := tx.Prepare("SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id=$1 AND used_at IS NULL;")
+	if err != nil {
+		log.Printf("CountUnusedRecoveryCodes: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var count int
+	err = stmt.QueryRow(userID).Scan(&count)
+	if err != nil {
+		log.Printf("CountUnusedRecoveryCodes: error executing query: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CountUnusedRecoveryCodes: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (repo *PSQLMFARecoveryCodeRepository) DeleteRecoveryCodes(userID models.UserID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("DeleteRecoveryCodes: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("DELETE FROM mfa_recovery_codes WHERE user_id=$1;")
+	if err != nil {
+		log.Printf("DeleteRecoveryCodes: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(userID)
+	if err != nil {
+		log.Printf("DeleteRecoveryCodes: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("DeleteRecoveryCodes: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("DeleteRecoveryCodes: recovery codes deleted")
+	return nil
+}
@@ -0,0 +1,485 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/lib/pq"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type PSQLOrganizationRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLOrganizationRepository(db *sql.DB) *PSQLOrganizationRepository {
+	return &PSQLOrganizationRepository{
+		db: db,
+	}
+}
+
+// CreateOrganization stores the organization and makes ownerID its owner.
+func (repo *PSQLOrganizationRepository) CreateOrganization(organization *models.Organization, ownerID models.UserID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CreateOrganization: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO organizations (name) VALUES ($1) RETURNING id, created_at, updated_at;")
+	if err != nil {
+		log.Printf("CreateOrganization: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(organization.Name).Scan(&organization.ID, &organization.CreatedAt, &organization.UpdatedAt)
+	if err != nil {
+		log.Printf("CreateOrganization: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, $3);",
+		organization.ID, ownerID, utils.OrganizationRoleOwner,
+	)
+	if err != nil {
+		log.Printf("CreateOrganization: error adding owner: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CreateOrganization: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("CreateOrganization: organization created")
+	return nil
+}
+
+func (repo *PSQLOrganizationRepository) GetOrganizationByID(organizationID models.OrganizationID) (*models.Organization, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetOrganizationByID: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT id, name, created_at, updated_at FROM organizations WHERE id=$1;")
+	if err != nil {
+		log.Printf("GetOrganizationByID: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var organization models.Organization
+	err = stmt.QueryRow(organizationID).Scan(&organization.ID, &organization.Name, &organization.CreatedAt, &organization.UpdatedAt)
+	if err != nil {
+		log.Printf("GetOrganizationByID: error executing query: %s", err.Error())
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrOrganizationNotFound
+		}
+
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetOrganizationByID: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &organization, nil
+}
+
+func (repo *PSQLOrganizationRepository) GetOrganizationsByUserID(userID models.UserID) ([]models.OrganizationMembership, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetOrganizationsByUserID: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare(`SELECT o.id, o.name, o.created_at, o.updated_at, m.role, m.active FROM organizations o
+		JOIN organization_members m ON m.organization_id = o.id WHERE m.user_id=$1 ORDER BY o.name, o.id;`)
+	if err != nil {
+		log.Printf("GetOrganizationsByUserID: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(userID)
+	if err != nil {
+		log.Printf("GetOrganizationsByUserID: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := []models.OrganizationMembership{}
+
+	for rows.Next() {
+		var membership models.OrganizationMembership
+
+		err := rows.Scan(
+			&membership.Organization.ID,
+			&membership.Organization.Name,
+			&membership.Organization.CreatedAt,
+			&membership.Organization.UpdatedAt,
+			&membership.Role,
+			&membership.Active,
+		)
+		if err != nil {
+			log.Printf("GetOrganizationsByUserID: error scanning row: %s", err.Error())
+			tx.Rollback()
+			return nil, err
+		}
+
+		memberships = append(memberships, membership)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("GetOrganizationsByUserID: error during iterating rows: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetOrganizationsByUserID: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return memberships, nil
+}
+
+func (repo *PSQLOrganizationRepository) GetOrganizationMembers(organizationID models.OrganizationID) ([]models.OrganizationMember, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetOrganizationMembers: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare(`SELECT m.organization_id, m.user_id, u.email, m.role, m.created_at FROM organization_members m
+		JOIN users u ON u.id = m.user_id WHERE m.organization_id=$1 ORDER BY m.created_at, m.user_id;`)
+	if err != nil {
+		log.Printf("GetOrganizationMembers: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(organizationID)
+	if err != nil {
+		log.Printf("GetOrganizationMembers: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.OrganizationMember{}
+
+	for rows.Next() {
+		var member models.OrganizationMember
+
+		err := rows.Scan(&member.OrganizationID, &member.UserID, &member.Email, &member.Role, &member.CreatedAt)
+		if err != nil {
+			log.Printf("GetOrganizationMembers: error scanning row: %s", err.Error())
+			tx.Rollback()
+			return nil, err
+		}
+
+		members = append(members, member)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("GetOrganizationMembers: error during iterating rows: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetOrganizationMembers: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (repo *PSQLOrganizationRepository) GetOrganizationMember(organizationID models.OrganizationID, userID models.UserID) (*models.OrganizationMember, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetOrganizationMember: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare(`SELECT m.organization_id, m.user_id, u.email, m.role, m.created_at FROM organization_members m
+		JOIN users u ON u.id = m.user_id WHERE m.organization_id=$1 AND m.user_id=$2;`)
+	if err != nil {
+		log.Printf("GetOrganizationMember: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var member models.OrganizationMember
+	err = stmt.QueryRow(organizationID, userID).Scan(&member.OrganizationID, &member.UserID, &member.Email, &member.Role, &member.CreatedAt)
+	if err != nil {
+		log.Printf("GetOrganizationMember: error executing query: %s", err.Error())
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrOrganizationMemberNotFound
+		}
+
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetOrganizationMember: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+func (repo *PSQLOrganizationRepository) AddOrganizationMember(organizationID models.OrganizationID, userID models.UserID, role models.OrganizationRole) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("AddOrganizationMember: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, $3);")
+	if err != nil {
+		log.Printf("AddOrganizationMember: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(organizationID, userID, role)
+	if err != nil {
+		log.Printf("AddOrganizationMember: error executing query: %s", err.Error())
+		tx.Rollback()
+
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code {
+			case "23505":
+				return utils.ErrOrganizationMemberAlreadyExists
+			case "23503":
+				return utils.ErrOrganizationNotFound
+			}
+		}
+
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("AddOrganizationMember: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+func (repo *PSQLOrganizationRepository) UpdateOrganizationMemberRole(organizationID models.OrganizationID, userID models.UserID, role models.OrganizationRole) error {
+	return repo.execMemberUpdate(
+		"UpdateOrganizationMemberRole",
+		"UPDATE organization_members SET role=$3 WHERE organization_id=$1 AND user_id=$2;",
+		organizationID, userID, role,
+	)
+}
+
+func (repo *PSQLOrganizationRepository) RemoveOrganizationMember(organizationID models.OrganizationID, userID models.UserID) error {
+	return repo.execMemberUpdate(
+		"RemoveOrganizationMember",
+		"DELETE FROM organization_members WHERE organization_id=$1 AND user_id=$2;",
+		organizationID, userID,
+	)
+}
+
+// execMemberUpdate runs a statement on one membership and fails with
+// ErrOrganizationMemberNotFound when it touched no row.
+func (repo *PSQLOrganizationRepository) execMemberUpdate(caller string, query string, args ...any) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("%s: error creating transaction: %s", caller, err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		log.Printf("%s: error creating statement: %s", caller, err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(args...)
+	if err != nil {
+		log.Printf("%s: error executing query: %s", caller, err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return utils.ErrOrganizationMemberNotFound
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("%s: error during commmit: %s", caller, err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+func (repo *PSQLOrganizationRepository) CountOrganizationOwners(organizationID models.OrganizationID) (int, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CountOrganizationOwners: error creating transaction: %s", err.Error())
+		return 0, err
+	}
+
+	stmt, err := tx.Prepare("SELECT COUNT(*) FROM organization_members WHERE organization_id=$1 AND role=$2;")
+	if err != nil {
+		log.Printf("CountOrganizationOwners: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var count int
+	err = stmt.QueryRow(organizationID, utils.OrganizationRoleOwner).Scan(&count)
+	if err != nil {
+		log.Printf("CountOrganizationOwners: error executing query: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CountOrganizationOwners: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// SetActiveOrganization makes organizationID the user's active organization,
+// or clears it when organizationID is nil. It fails with
+// ErrOrganizationMemberNotFound when the user is not a member.
+func (repo *PSQLOrganizationRepository) SetActiveOrganization(userID models.UserID, organizationID *models.OrganizationID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("SetActiveOrganization: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE organization_members SET active=FALSE WHERE user_id=$1 AND active;", userID)
+	if err != nil {
+		log.Printf("SetActiveOrganization: error clearing active organization: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	if organizationID != nil {
+		res, err := tx.Exec("UPDATE organization_members SET active=TRUE WHERE user_id=$1 AND organization_id=$2;", userID, *organizationID)
+		if err != nil {
+			log.Printf("SetActiveOrganization: error setting active organization: %s", err.Error())
+			tx.Rollback()
+			return err
+		}
+
+		affected, err := res.RowsAffected()
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if affected == 0 {
+			tx.Rollback()
+			return utils.ErrOrganizationMemberNotFound
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("SetActiveOrganization: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// GetActiveOrganizationID returns the user's active organization, or nil when
+// they act on their own.
+func (repo *PSQLOrganizationRepository) GetActiveOrganizationID(userID models.UserID) (*models.OrganizationID, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetActiveOrganizationID: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT organization_id FROM organization_members WHERE user_id=$1 AND active;")
+	if err != nil {
+		log.Printf("GetActiveOrganizationID: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var organizationID models.OrganizationID
+	err = stmt.QueryRow(userID).Scan(&organizationID)
+	if err != nil {
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		log.Printf("GetActiveOrganizationID: error executing query: %s", err.Error())
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetActiveOrganizationID: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &organizationID, nil
+}
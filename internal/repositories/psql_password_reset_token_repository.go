@@ -0,0 +1,175 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type PSQLPasswordResetTokenRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLPasswordResetTokenRepository(db *sql.DB) *PSQLPasswordResetTokenRepository {
+	return &PSQLPasswordResetTokenRepository{
+		db: db,
+	}
+}
+
+func (repo *PSQLPasswordResetTokenRepository) CreatePasswordResetToken(token *models.PasswordResetToken) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CreatePasswordResetToken: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO password_reset_tokens (content, user_id, expires_at) VALUES ($1, $2, $3) RETURNING id;")
+	if err != nil {
+		log.Printf("CreatePasswordResetToken: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(token.Content, token.UserID, token.ExpiresAt).Scan(&token.ID)
+	if err != nil {
+		log.Printf("CreatePasswordResetToken: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CreatePasswordResetToken: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("CreatePasswordResetToken: password reset token created")
+	return nil
+}
+
+func (repo *PSQLPasswordResetTokenRepository) GetPasswordResetTokenByContent(content models.PasswordResetTokenContent) (*models.PasswordResetToken, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetPasswordResetTokenByContent: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT id, content, user_id, created_at, expires_at, used_at FROM password_reset_tokens WHERE content=$1;")
+	if err != nil {
+		log.Printf("GetPasswordResetTokenByContent: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var token models.PasswordResetToken
+	var usedAt sql.NullTime
+	err = stmt.QueryRow(content).Scan(&token.ID, &token.Content, &token.UserID, &token.CreatedAt, &token.ExpiresAt, &usedAt)
+	if err != nil {
+		log.Printf("GetPasswordResetTokenByContent: error executing query: %s", err.Error())
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrPasswordResetTokenInvalid
+		}
+
+		return nil, err
+	}
+
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetPasswordResetTokenByContent: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// UsePasswordResetToken marks the token as used. It fails with
+// ErrPasswordResetTokenInvalid when the token was already used, so two concurrent
+// requests with the same token cannot both reset the password.
+func (repo *PSQLPasswordResetTokenRepository) UsePasswordResetToken(id models.PasswordResetTokenID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("UsePasswordResetToken: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE password_reset_tokens SET used_at=CURRENT_TIMESTAMP WHERE id=$1 AND used_at IS NULL;")
+	if err != nil {
+		log.Printf("UsePasswordResetToken: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(id)
+	if err != nil {
+		log.Printf("UsePasswordResetToken: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return utils.ErrPasswordResetTokenInvalid
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("UsePasswordResetToken: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+func (repo *PSQLPasswordResetTokenRepository) DeleteExpiredPasswordResetTokens(now time.Time) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("DeleteExpiredPasswordResetTokens: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("DELETE FROM password_reset_tokens WHERE expires_at < $1;")
+	if err != nil {
+		log.Printf("DeleteExpiredPasswordResetTokens: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(now)
+	if err != nil {
+		log.Printf("DeleteExpiredPasswordResetTokens: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("DeleteExpiredPasswordResetTokens: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type PSQLRateLimitRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLRateLimitRepository(db *sql.DB) *PSQLRateLimitRepository {
+	return &PSQLRateLimitRepository{
+		db: db,
+	}
+}
+
+// TakeRateLimitToken locks the bucket row for the duration of the
+// transaction, so concurrent requests on any instance are counted correctly.
+func (repo *PSQLRateLimitRepository) TakeRateLimitToken(key string, limit models.RateLimit, now time.Time) (*models.RateLimitResult, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("TakeRateLimitToken: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	insertStmt, err := tx.Prepare("INSERT INTO rate_limit_buckets (key, tokens, updated_at) VALUES ($1, $2, $3) ON CONFLICT (key) DO NOTHING;")
+	if err != nil {
+		log.Printf("TakeRateLimitToken: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer insertStmt.Close()
+
+	_, err = insertStmt.Exec(key, limit.Burst, now)
+	if err != nil {
+		log.Printf("TakeRateLimitToken: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	selectStmt, err := tx.Prepare("SELECT key, tokens, updated_at FROM rate_limit_buckets WHERE key=$1 FOR UPDATE;")
+	if err != nil {
+		log.Printf("TakeRateLimitToken: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer selectStmt.Close()
+
+	var bucket models.RateLimitBucket
+	err = selectStmt.QueryRow(key).Scan(&bucket.Key, &bucket.Tokens, &bucket.UpdatedAt)
+	if err != nil {
+		log.Printf("TakeRateLimitToken: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	result := bucket.Take(limit, now)
+
+	updateStmt, err := tx.Prepare("UPDATE rate_limit_buckets SET tokens=$2, updated_at=$3 WHERE key=$1;")
+	if err != nil {
+		log.Printf("TakeRateLimitToken: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer updateStmt.Close()
+
+	_, err = updateStmt.Exec(key, bucket.Tokens, bucket.UpdatedAt)
+	if err != nil {
+		log.Printf("TakeRateLimitToken: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("TakeRateLimitToken: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (repo *PSQLRateLimitRepository) DeleteStaleRateLimitBuckets(before time.Time) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("DeleteStaleRateLimitBuckets: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("DELETE FROM rate_limit_buckets WHERE updated_at < $1;")
+	if err != nil {
+		log.Printf("DeleteStaleRateLimitBuckets: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(before)
+	if err != nil {
+		log.Printf("DeleteStaleRateLimitBuckets: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("DeleteStaleRateLimitBuckets: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
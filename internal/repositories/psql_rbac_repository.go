@@ -0,0 +1,491 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/lib/pq"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+// roleColumns selects a role with the names of its permissions. Queries using
+// it must group by r.id.
+const roleColumns = "r.id, r.name, r.description, r.created_at, COALESCE(ARRAY_AGG(p.name ORDER BY p.name) FILTER (WHERE p.name IS NOT NULL), '{}')"
+
+const roleJoins = " LEFT JOIN role_permissions rp ON rp.role_id = r.id LEFT JOIN permissions p ON p.id = rp.permission_id"
+
+type PSQLRBACRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLRBACRepository(db *sql.DB) *PSQLRBACRepository {
+	return &PSQLRBACRepository{
+		db: db,
+	}
+}
+
+func scanRole(row rowScanner) (*models.Role, error) {
+	var role models.Role
+
+	err := row.Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt, pq.Array(&role.Permissions))
+	if err != nil {
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+func (repo *PSQLRBACRepository) queryRoles(caller string, query string, args ...any) ([]models.Role, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("%s: error creating transaction: %s", caller, err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		log.Printf("%s: error creating statement: %s", caller, err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		log.Printf("%s: error executing query: %s", caller, err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			log.Printf("%s: error scanning row: %s", caller, err.Error())
+			tx.Rollback()
+			return nil, err
+		}
+
+		roles = append(roles, *role)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("%s: error during iterating rows: %s", caller, err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("%s: error during commmit: %s", caller, err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+func (repo *PSQLRBACRepository) GetRoles() ([]models.Role, error) {
+	return repo.queryRoles("GetRoles", "SELECT "+roleColumns+" FROM roles r"+roleJoins+" GROUP BY r.id ORDER BY r.name;")
+}
+
+func (repo *PSQLRBACRepository) GetRoleByName(name models.RoleName) (*models.Role, error) {
+	roles, err := repo.queryRoles("GetRoleByName", "SELECT "+roleColumns+" FROM roles r"+roleJoins+" WHERE r.name=$1 GROUP BY r.id;", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(roles) == 0 {
+		return nil, utils.ErrRoleNotFound
+	}
+
+	return &roles[0], nil
+}
+
+func (repo *PSQLRBACRepository) GetUserRoles(userID models.UserID) ([]models.Role, error) {
+	return repo.queryRoles("GetUserRoles", "SELECT "+roleColumns+" FROM user_roles ur JOIN roles r ON r.id = ur.role_id"+roleJoins+" WHERE ur.user_id=$1 GROUP BY r.id ORDER BY r.name;", userID)
+}
+
+func (repo *PSQLRBACRepository) GetRoleMembers(roleID models.RoleID) ([]models.RoleMember, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetRoleMembers: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT u.id, u.email FROM user_roles ur JOIN users u ON u.id = ur.user_id WHERE ur.role_id=$1 ORDER BY u.id;")
+	if err != nil {
+		log.Printf("GetRoleMembers: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(roleID)
+	if err != nil {
+		log.Printf("GetRoleMembers: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.RoleMember{}
+
+	for rows.Next() {
+		var member models.RoleMember
+
+		err := rows.Scan(&member.UserID, &member.Email)
+		if err != nil {
+			log.Printf("GetRoleMembers: error scanning row: %s", err.Error())
+			tx.Rollback()
+			return nil, err
+		}
+
+		members = append(members, member)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("GetRoleMembers: error during iterating rows: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetRoleMembers: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// CreateRole creates the role with its permissions. It fails with
+// ErrPermissionNotFound when one of the permissions does not exist.
+func (repo *PSQLRBACRepository) CreateRole(role *models.Role) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CreateRole: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	err = tx.QueryRow("INSERT INTO roles (name, description) VALUES ($1, $2) RETURNING id, created_at;", role.Name, role.Description).Scan(&role.ID, &role.CreatedAt)
+	if err != nil {
+		log.Printf("CreateRole: error inserting role: %s", err.Error())
+		tx.Rollback()
+
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return utils.ErrRoleAlreadyExists
+		}
+
+		return err
+	}
+
+	res, err := tx.Exec("INSERT INTO role_permissions (role_id, permission_id) SELECT $1, id FROM permissions WHERE name = ANY($2);", role.ID, pq.Array(role.Permissions))
+	if err != nil {
+		log.Printf("CreateRole: error inserting role permissions: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if int(affected) != len(role.Permissions) {
+		tx.Rollback()
+		return utils.ErrPermissionNotFound
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CreateRole: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("CreateRole: role %s created", role.Name)
+	return nil
+}
+
+func (repo *PSQLRBACRepository) DeleteRole(id models.RoleID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("DeleteRole: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("DELETE FROM roles WHERE id=$1;")
+	if err != nil {
+		log.Printf("DeleteRole: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(id)
+	if err != nil {
+		log.Printf("DeleteRole: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("DeleteRole: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+func (repo *PSQLRBACRepository) GetPermissions() ([]models.Permission, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetPermissions: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT id, name, description FROM permissions ORDER BY name;")
+	if err != nil {
+		log.Printf("GetPermissions: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query()
+	if err != nil {
+		log.Printf("GetPermissions: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []models.Permission{}
+
+	for rows.Next() {
+		var permission models.Permission
+
+		err := rows.Scan(&permission.ID, &permission.Name, &permission.Description)
+		if err != nil {
+			log.Printf("GetPermissions: error scanning row: %s", err.Error())
+			tx.Rollback()
+			return nil, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("GetPermissions: error during iterating rows: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetPermissions: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// GetUserPermissions returns the names of every permission granted to the
+// user through any of their roles.
+func (repo *PSQLRBACRepository) GetUserPermissions(userID models.UserID) ([]string, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetUserPermissions: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT DISTINCT p.name FROM user_roles ur JOIN role_permissions rp ON rp.role_id = ur.role_id JOIN permissions p ON p.id = rp.permission_id WHERE ur.user_id=$1 ORDER BY p.name;")
+	if err != nil {
+		log.Printf("GetUserPermissions: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(userID)
+	if err != nil {
+		log.Printf("GetUserPermissions: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []string{}
+
+	for rows.Next() {
+		var permission string
+
+		err := rows.Scan(&permission)
+		if err != nil {
+			log.Printf("GetUserPermissions: error scanning row: %s", err.Error())
+			tx.Rollback()
+			return nil, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("GetUserPermissions: error during iterating rows: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetUserPermissions: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func (repo *PSQLRBACRepository) AssignUserRole(userID models.UserID, roleID models.RoleID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("AssignUserRole: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO user_roles (user_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING;")
+	if err != nil {
+		log.Printf("AssignUserRole: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(userID, roleID)
+	if err != nil {
+		log.Printf("AssignUserRole: error executing query: %s", err.Error())
+		tx.Rollback()
+
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return utils.ErrUserNotFound
+		}
+
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("AssignUserRole: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+func (repo *PSQLRBACRepository) RemoveUserRole(userID models.UserID, roleID models.RoleID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("RemoveUserRole: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("DELETE FROM user_roles WHERE user_id=$1 AND role_id=$2;")
+	if err != nil {
+		log.Printf("RemoveUserRole: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(userID, roleID)
+	if err != nil {
+		log.Printf("RemoveUserRole: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("RemoveUserRole: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// UpdateRoleMembers assigns the role to added and removes it from removed in
+// a single transaction, so a failure leaves the members unchanged.
+func (repo *PSQLRBACRepository) UpdateRoleMembers(roleID models.RoleID, added []models.UserID, removed []models.UserID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("UpdateRoleMembers: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	removeStmt, err := tx.Prepare("DELETE FROM user_roles WHERE user_id=$1 AND role_id=$2;")
+	if err != nil {
+		log.Printf("UpdateRoleMembers: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer removeStmt.Close()
+
+	for _, userID := range removed {
+		_, err = removeStmt.Exec(userID, roleID)
+		if err != nil {
+			log.Printf("UpdateRoleMembers: error removing user %d: %s", userID, err.Error())
+			tx.Rollback()
+			return err
+		}
+	}
+
+	addStmt, err := tx.Prepare("INSERT INTO user_roles (user_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING;")
+	if err != nil {
+		log.Printf("UpdateRoleMembers: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer addStmt.Close()
+
+	for _, userID := range added {
+		_, err = addStmt.Exec(userID, roleID)
+		if err != nil {
+			log.Printf("UpdateRoleMembers: error assigning user %d: %s", userID, err.Error())
+			tx.Rollback()
+
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+				return utils.ErrUserNotFound
+			}
+
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("UpdateRoleMembers: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
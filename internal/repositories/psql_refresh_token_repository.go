@@ -164,3 +164,58 @@ func (repo *PSQLRefreshTokenRepository) InvalidateRefreshTokensByUserID(userID m
 	return nil
 
 }
+
+func (repo *PSQLRefreshTokenRepository) GetRefreshTokensByUserID(userID models.UserID) ([]models.RefreshToken, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetRefreshTokensByUserID: error creating transaction: %s", err.Error())
+		return nil, fmt.Errorf("GetRefreshTokensByUserID: error creating transaction: %w", err)
+	}
+
+	stmt, err := tx.Prepare("SELECT id, content, user_id, status, created_at FROM refresh_tokens WHERE user_id=$1 ORDER BY created_at DESC;")
+	if err != nil {
+		log.Printf("GetRefreshTokensByUserID: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, fmt.Errorf("GetRefreshTokensByUserID: error creating prepared statement: %w", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(userID)
+	if err != nil {
+		log.Printf("GetRefreshTokensByUserID: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.RefreshToken{}
+
+	for rows.Next() {
+		var token models.RefreshToken
+
+		err := rows.Scan(&token.ID, &token.Content, &token.UserID, &token.Status, &token.CreatedAt)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("GetRefreshTokensByUserID: error during iterating rows: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetRefreshTokensByUserID: error during commit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	log.Printf("GetRefreshTokensByUserID: got refresh tokens")
+	return tokens, nil
+}
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type PSQLStatsRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLStatsRepository(db *sql.DB) *PSQLStatsRepository {
+	return &PSQLStatsRepository{
+		db: db,
+	}
+}
+
+// GetStats counts every resource in a single query, so the numbers are
+// consistent with each other.
+func (repo *PSQLStatsRepository) GetStats() (*models.Stats, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetStats: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare(`SELECT
+		(SELECT COUNT(*) FROM users),
+		(SELECT COUNT(*) FROM users WHERE status='active'),
+		(SELECT COUNT(*) FROM users WHERE status='pending'),
+		(SELECT COUNT(*) FROM users WHERE status='inactive'),
+		(SELECT COUNT(*) FROM apps),
+		(SELECT COUNT(*) FROM apps WHERE deleted_at IS NOT NULL),
+		(SELECT COUNT(*) FROM organizations),
+		(SELECT COUNT(*) FROM refresh_tokens WHERE status='active');`)
+	if err != nil {
+		log.Printf("GetStats: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var stats models.Stats
+	err = stmt.QueryRow().Scan(
+		&stats.Users.Total,
+		&stats.Users.Active,
+		&stats.Users.Pending,
+		&stats.Users.Inactive,
+		&stats.Apps.Total,
+		&stats.Apps.Deleted,
+		&stats.Organizations,
+		&stats.ActiveRefreshTokens,
+	)
+	if err != nil {
+		log.Printf("GetStats: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetStats: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &stats, nil
+}
@@ -0,0 +1,224 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type PSQLTOTPCredentialRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLTOTPCredentialRepository(db *sql.DB) *PSQLTOTPCredentialRepository {
+	return &PSQLTOTPCredentialRepository{
+		db: db,
+	}
+}
+
+// SaveTOTPCredential stores a new, unconfirmed credential, replacing any
+// previous unconfirmed enrollment of the user.
+func (repo *PSQLTOTPCredentialRepository) SaveTOTPCredential(credential *models.TOTPCredential) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("SaveTOTPCredential: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	query := `INSERT INTO totp_credentials (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret=EXCLUDED.secret, confirmed_at=NULL, last_used_counter=0, created_at=CURRENT_TIMESTAMP
+		WHERE totp_credentials.confirmed_at IS NULL;`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		log.Printf("SaveTOTPCredential: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(credential.UserID, credential.Secret)
+	if err != nil {
+		log.Printf("SaveTOTPCredential: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return utils.ErrMFAAlreadyEnabled
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("SaveTOTPCredential: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("SaveTOTPCredential: totp credential saved")
+	return nil
+}
+
+func (repo *PSQLTOTPCredentialRepository) GetTOTPCredentialByUserID(userID models.UserID) (*models.TOTPCredential, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetTOTPCredentialByUserID: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT user_id, secret, confirmed_at, last_used_counter, created_at FROM totp_credentials WHERE user_id=$1;")
+	if err != nil {
+		log.Printf("GetTOTPCredentialByUserID: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var credential models.TOTPCredential
+	var confirmedAt sql.NullTime
+	err = stmt.QueryRow(userID).Scan(&credential.UserID, &credential.Secret, &confirmedAt, &credential.LastUsedCounter, &credential.CreatedAt)
+	if err != nil {
+		log.Printf("GetTOTPCredentialByUserID: error executing query: %s", err.Error())
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrMFANotEnabled
+		}
+
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetTOTPCredentialByUserID: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	if confirmedAt.Valid {
+		credential.ConfirmedAt = &confirmedAt.Time
+	}
+
+	return &credential, nil
+}
+
+func (repo *PSQLTOTPCredentialRepository) ConfirmTOTPCredential(userID models.UserID, counter int64) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("ConfirmTOTPCredential: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE totp_credentials SET confirmed_at=$1, last_used_counter=$2 WHERE user_id=$3 AND confirmed_at IS NULL;")
+	if err != nil {
+		log.Printf("ConfirmTOTPCredential: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(time.Now(), counter, userID)
+	if err != nil {
+		log.Printf("ConfirmTOTPCredential: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("ConfirmTOTPCredential: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("ConfirmTOTPCredential: totp credential confirmed")
+	return nil
+}
+
+// UseTOTPCounter records counter as the last accepted time step. It fails
+// with ErrMFACodeReused when that step, or a later one, was already used.
+func (repo *PSQLTOTPCredentialRepository) UseTOTPCounter(userID models.UserID, counter int64) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("UseTOTPCounter: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE totp_credentials SET last_used_counter=$1 WHERE user_id=$2 AND last_used_counter < $1;")
+	if err != nil {
+		log.Printf("UseTOTPCounter: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(counter, userID)
+	if err != nil {
+		log.Printf("UseTOTPCounter: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return utils.ErrMFACodeReused
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("UseTOTPCounter: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+func (repo *PSQLTOTPCredentialRepository) DeleteTOTPCredential(userID models.UserID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("DeleteTOTPCredential: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("DELETE FROM totp_credentials WHERE user_id=$1;")
+	if err != nil {
+		log.Printf("DeleteTOTPCredential: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(userID)
+	if err != nil {
+		log.Printf("DeleteTOTPCredential: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("DeleteTOTPCredential: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("DeleteTOTPCredential: totp credential deleted")
+	return nil
+}
@@ -5,11 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/pedrotunin/go-jwt-auth/internal/models"
 	"github.com/pedrotunin/go-jwt-auth/internal/utils"
 )
 
+const userColumns = "id, email, password, status, display_name, locale, timezone, avatar_url, created_at, updated_at, last_login_at, deletion_scheduled_at"
+
 type PSQLUserRepository struct {
 	db *sql.DB
 }
@@ -20,6 +25,43 @@ func NewPSQLUserRepository(db *sql.DB) *PSQLUserRepository {
 	}
 }
 
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	var lastLoginAt, deletionScheduledAt sql.NullTime
+
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.Password,
+		&user.Status,
+		&user.DisplayName,
+		&user.Locale,
+		&user.Timezone,
+		&user.AvatarURL,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&lastLoginAt,
+		&deletionScheduledAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastLoginAt.Valid {
+		user.LastLoginAt = &lastLoginAt.Time
+	}
+
+	if deletionScheduledAt.Valid {
+		user.DeletionScheduledAt = &deletionScheduledAt.Time
+	}
+
+	return &user, nil
+}
+
 func (repo *PSQLUserRepository) GetUserByEmail(email models.UserEmail) (*models.User, error) {
 	tx, err := repo.db.Begin()
 	if err != nil {
@@ -27,7 +69,7 @@ func (repo *PSQLUserRepository) GetUserByEmail(email models.UserEmail) (*models.
 		return nil, fmt.Errorf("GetUserByEmail: error creating transaction: %w", err)
 	}
 
-	stmt, err := tx.Prepare("SELECT id, email, password, status FROM users WHERE email=$1;")
+	stmt, err := tx.Prepare("SELECT " + userColumns + " FROM users WHERE email=$1;")
 	if err != nil {
 		log.Printf("GetUserByEmail: error creating statement: %s", err.Error())
 
@@ -36,9 +78,7 @@ func (repo *PSQLUserRepository) GetUserByEmail(email models.UserEmail) (*models.
 	}
 	defer stmt.Close()
 
-	var resId int
-	var resEmail, resPassword, resStatus string
-	err = stmt.QueryRow(email).Scan(&resId, &resEmail, &resPassword, &resStatus)
+	user, err := scanUser(stmt.QueryRow(email))
 	if err != nil {
 		log.Printf("GetUserByEmail: error executing query: %s", err.Error())
 
@@ -58,12 +98,46 @@ func (repo *PSQLUserRepository) GetUserByEmail(email models.UserEmail) (*models.
 	}
 
 	log.Print("GetUserByEmail: user found in users table")
-	return &models.User{
-		ID:       resId,
-		Email:    resEmail,
-		Password: resPassword,
-		Status:   resStatus,
-	}, nil
+	return user, nil
+}
+
+func (repo *PSQLUserRepository) GetUserByID(userID models.UserID) (*models.User, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetUserByID: error creating transaction: %s", err.Error())
+		return nil, fmt.Errorf("GetUserByID: error creating transaction: %w", err)
+	}
+
+	stmt, err := tx.Prepare("SELECT " + userColumns + " FROM users WHERE id=$1;")
+	if err != nil {
+		log.Printf("GetUserByID: error creating statement: %s", err.Error())
+
+		tx.Rollback()
+		return nil, fmt.Errorf("GetUserByID: error creating prepared statement: %w", err)
+	}
+	defer stmt.Close()
+
+	user, err := scanUser(stmt.QueryRow(userID))
+	if err != nil {
+		log.Printf("GetUserByID: error executing query: %s", err.Error())
+
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrUserNotFound
+		}
+
+		return nil, fmt.Errorf("GetUserByID: error scanning query result: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetUserByID: error during commit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	log.Print("GetUserByID: user found in users table")
+	return user, nil
 }
 
 func (repo *PSQLUserRepository) CreateUser(u *models.User) (id int, err error) {
@@ -79,7 +153,14 @@ func (repo *PSQLUserRepository) CreateUser(u *models.User) (id int, err error) {
 		return -1, err
 	}
 
-	stmt, err := tx.Prepare("INSERT INTO users (email, password) VALUES ($1, $2) RETURNING id;")
+	// Users are pending until they verify their e-mail, unless the caller
+	// creates them with another status.
+	status := u.Status
+	if status == "" {
+		status = utils.UserStatusPending
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO users (email, password, status) VALUES ($1, $2, $3) RETURNING id;")
 	if err != nil {
 		log.Printf("CreateUser: error starting statement: %s", err.Error())
 		tx.Rollback()
@@ -88,7 +169,7 @@ func (repo *PSQLUserRepository) CreateUser(u *models.User) (id int, err error) {
 	defer stmt.Close()
 
 	var insertedID int
-	err = stmt.QueryRow(u.Email, u.Password).Scan(&insertedID)
+	err = stmt.QueryRow(u.Email, u.Password, status).Scan(&insertedID)
 	if err != nil {
 		log.Printf("CreateUser: error executing query: %s", err.Error())
 		tx.Rollback()
@@ -113,7 +194,7 @@ func (repo *PSQLUserRepository) ActivateUser(userID models.UserID) error {
 		return err
 	}
 
-	stmt, err := tx.Prepare("UPDATE users SET status='active' WHERE id=$1;")
+	stmt, err := tx.Prepare("UPDATE users SET status='active', updated_at=$1 WHERE id=$2;")
 	if err != nil {
 		log.Printf("ActivateUser: error creating statement: %s", err.Error())
 		tx.Rollback()
@@ -121,7 +202,7 @@ func (repo *PSQLUserRepository) ActivateUser(userID models.UserID) error {
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(userID)
+	_, err = stmt.Exec(time.Now(), userID)
 	if err != nil {
 		log.Printf("ActivateUser: error executing query: %s", err.Error())
 		tx.Rollback()
@@ -138,3 +219,526 @@ func (repo *PSQLUserRepository) ActivateUser(userID models.UserID) error {
 	log.Printf("ActivateUser: user activated")
 	return nil
 }
+
+func (repo *PSQLUserRepository) UpdateUserPassword(userID models.UserID, hash string) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("UpdateUserPassword: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE users SET password=$1, updated_at=$2 WHERE id=$3;")
+	if err != nil {
+		log.Printf("UpdateUserPassword: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(hash, time.Now(), userID)
+	if err != nil {
+		log.Printf("UpdateUserPassword: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("UpdateUserPassword: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("UpdateUserPassword: password updated")
+	return nil
+}
+
+func (repo *PSQLUserRepository) UpdateUserEmail(userID models.UserID, email models.UserEmail) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("UpdateUserEmail: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE users SET email=$1, updated_at=$2 WHERE id=$3;")
+	if err != nil {
+		log.Printf("UpdateUserEmail: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(email, time.Now(), userID)
+	if err != nil {
+		log.Printf("UpdateUserEmail: error executing query: %s", err.Error())
+		tx.Rollback()
+
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return utils.ErrUserEmailAlreadyExists
+		}
+
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("UpdateUserEmail: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("UpdateUserEmail: email updated")
+	return nil
+}
+
+func (repo *PSQLUserRepository) UpdateUserStatus(userID models.UserID, status models.UserStatus) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("UpdateUserStatus: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE users SET status=$1, updated_at=$2 WHERE id=$3;")
+	if err != nil {
+		log.Printf("UpdateUserStatus: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(status, time.Now(), userID)
+	if err != nil {
+		log.Printf("UpdateUserStatus: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return utils.ErrUserNotFound
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("UpdateUserStatus: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("UpdateUserStatus: user status updated to %s", status)
+	return nil
+}
+
+// userFilterWhere builds the WHERE clause and its arguments for filter.
+func userFilterWhere(filter models.UserFilter) (string, []any) {
+	conditions := []string{}
+	args := []any{}
+
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status=$%d", len(args)))
+	}
+
+	if filter.Email != "" {
+		args = append(args, "%"+escapeLike(strings.ToLower(filter.Email))+"%")
+		conditions = append(conditions, fmt.Sprintf("LOWER(email) LIKE $%d", len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
+
+// SearchUsers returns a page of the users matching filter, oldest first.
+func (repo *PSQLUserRepository) SearchUsers(filter models.UserFilter) ([]models.User, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("SearchUsers: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	where, args := userFilterWhere(filter)
+	args = append(args, filter.Limit, filter.Offset)
+
+	stmt, err := tx.Prepare(fmt.Sprintf("SELECT "+userColumns+" FROM users%s ORDER BY id LIMIT $%d OFFSET $%d;", where, len(args)-1, len(args)))
+	if err != nil {
+		log.Printf("SearchUsers: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		log.Printf("SearchUsers: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			log.Printf("SearchUsers: error scanning row: %s", err.Error())
+			tx.Rollback()
+			return nil, err
+		}
+
+		users = append(users, *user)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("SearchUsers: error during iterating rows: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("SearchUsers: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (repo *PSQLUserRepository) CountUsers(filter models.UserFilter) (int, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CountUsers: error creating transaction: %s", err.Error())
+		return 0, err
+	}
+
+	where, args := userFilterWhere(filter)
+
+	stmt, err := tx.Prepare("SELECT COUNT(*) FROM users" + where + ";")
+	if err != nil {
+		log.Printf("CountUsers: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var count int
+	err = stmt.QueryRow(args...).Scan(&count)
+	if err != nil {
+		log.Printf("CountUsers: error executing query: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CountUsers: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (repo *PSQLUserRepository) UpdateUserProfile(u *models.User) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("UpdateUserProfile: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE users SET display_name=$1, locale=$2, timezone=$3, avatar_url=$4, updated_at=$5 WHERE id=$6;")
+	if err != nil {
+		log.Printf("UpdateUserProfile: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	u.UpdatedAt = time.Now()
+
+	_, err = stmt.Exec(u.DisplayName, u.Locale, u.Timezone, u.AvatarURL, u.UpdatedAt, u.ID)
+	if err != nil {
+		log.Printf("UpdateUserProfile: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("UpdateUserProfile: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("UpdateUserProfile: user profile updated")
+	return nil
+}
+
+func (repo *PSQLUserRepository) UpdateLastLogin(userID models.UserID, at time.Time) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("UpdateLastLogin: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE users SET last_login_at=$1 WHERE id=$2;")
+	if err != nil {
+		log.Printf("UpdateLastLogin: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(at, userID)
+	if err != nil {
+		log.Printf("UpdateLastLogin: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("UpdateLastLogin: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("UpdateLastLogin: last login updated")
+	return nil
+}
+
+func (repo *PSQLUserRepository) SetDeletionScheduledAt(userID models.UserID, at *time.Time) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("SetDeletionScheduledAt: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE users SET deletion_scheduled_at=$1, updated_at=$2 WHERE id=$3;")
+	if err != nil {
+		log.Printf("SetDeletionScheduledAt: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(at, time.Now(), userID)
+	if err != nil {
+		log.Printf("SetDeletionScheduledAt: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("SetDeletionScheduledAt: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("SetDeletionScheduledAt: user deletion schedule updated")
+	return nil
+}
+
+func (repo *PSQLUserRepository) GetUserIDsDueForDeletion(now time.Time) ([]models.UserID, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetUserIDsDueForDeletion: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT id FROM users WHERE deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= $1;")
+	if err != nil {
+		log.Printf("GetUserIDsDueForDeletion: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(now)
+	if err != nil {
+		log.Printf("GetUserIDsDueForDeletion: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []models.UserID{}
+
+	for rows.Next() {
+		var id int
+
+		err := rows.Scan(&id)
+		if err != nil {
+			log.Printf("GetUserIDsDueForDeletion: error scanning row: %s", err.Error())
+			tx.Rollback()
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("GetUserIDsDueForDeletion: error during iterating rows: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetUserIDsDueForDeletion: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// DeleteUser removes the user and every row that references it, except the
+// impersonation audit trail and organization apps: those outlive the user
+// and only lose its ID.
+func (repo *PSQLUserRepository) DeleteUser(userID models.UserID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("DeleteUser: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	queries := []string{
+		"DELETE FROM refresh_tokens WHERE user_id=$1;",
+		"DELETE FROM email_verification_tokens WHERE user_id=$1;",
+		"DELETE FROM data_exports WHERE user_id=$1;",
+		"DELETE FROM totp_credentials WHERE user_id=$1;",
+		"DELETE FROM mfa_recovery_codes WHERE user_id=$1;",
+		"DELETE FROM webauthn_credentials WHERE user_id=$1;",
+		"DELETE FROM webauthn_challenges WHERE user_id=$1;",
+		"DELETE FROM magic_link_tokens WHERE user_id=$1;",
+		"DELETE FROM email_otp_codes WHERE user_id=$1;",
+		"DELETE FROM account_lockouts WHERE user_id=$1;",
+		"DELETE FROM login_events WHERE user_id=$1;",
+		"DELETE FROM password_reset_tokens WHERE user_id=$1;",
+		"DELETE FROM user_roles WHERE user_id=$1;",
+		"DELETE FROM invitations WHERE invited_by=$1 OR user_id=$1;",
+		"DELETE FROM organization_members WHERE user_id=$1;",
+		"DELETE FROM app_members WHERE user_id=$1;",
+		"DELETE FROM app_transfers WHERE from_user_id=$1 OR to_user_id=$1 OR initiated_by=$1;",
+		"DELETE FROM apps WHERE user_id=$1 AND organization_id IS NULL;",
+		"UPDATE apps SET user_id=NULL WHERE user_id=$1;",
+		"DELETE FROM users WHERE id=$1;",
+	}
+
+	for _, query := range queries {
+		_, err = tx.Exec(query, userID)
+		if err != nil {
+			log.Printf("DeleteUser: error executing query: %s", err.Error())
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("DeleteUser: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("DeleteUser: user deleted")
+	return nil
+}
+
+// AnonymizeUser keeps the user row for referential history but strips every
+// piece of personal data from it and from the rows that reference it.
+func (repo *PSQLUserRepository) AnonymizeUser(userID models.UserID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("AnonymizeUser: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	now := time.Now()
+
+	_, err = tx.Exec(
+		`UPDATE users SET email=$1, password='', status=$2, display_name='', locale='', timezone='',
+		avatar_url='', last_login_at=NULL, deletion_scheduled_at=NULL, updated_at=$3 WHERE id=$4;`,
+		fmt.Sprintf("deleted-user-%d@deleted.invalid", userID), utils.UserStatusInactive, now, userID,
+	)
+	if err != nil {
+		log.Printf("AnonymizeUser: error anonymizing user: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	queries := []string{
+		"DELETE FROM refresh_tokens WHERE user_id=$1;",
+		"DELETE FROM email_verification_tokens WHERE user_id=$1;",
+		"DELETE FROM data_exports WHERE user_id=$1;",
+		"DELETE FROM totp_credentials WHERE user_id=$1;",
+		"DELETE FROM mfa_recovery_codes WHERE user_id=$1;",
+		"DELETE FROM webauthn_credentials WHERE user_id=$1;",
+		"DELETE FROM webauthn_challenges WHERE user_id=$1;",
+		"DELETE FROM magic_link_tokens WHERE user_id=$1;",
+		"DELETE FROM email_otp_codes WHERE user_id=$1;",
+		"DELETE FROM account_lockouts WHERE user_id=$1;",
+		"DELETE FROM login_events WHERE user_id=$1;",
+		"DELETE FROM password_reset_tokens WHERE user_id=$1;",
+		"DELETE FROM user_roles WHERE user_id=$1;",
+		"DELETE FROM invitations WHERE user_id=$1;",
+		"DELETE FROM organization_members WHERE user_id=$1;",
+		"DELETE FROM app_members WHERE user_id=$1;",
+	}
+
+	for _, query := range queries {
+		_, err = tx.Exec(query, userID)
+		if err != nil {
+			log.Printf("AnonymizeUser: error executing query: %s", err.Error())
+			tx.Rollback()
+			return err
+		}
+	}
+
+	_, err = tx.Exec(
+		"UPDATE apps SET name='deleted', description='', updated_at=$1, deleted_at=COALESCE(deleted_at, $1) WHERE user_id=$2 AND organization_id IS NULL;",
+		now, userID,
+	)
+	if err != nil {
+		log.Printf("AnonymizeUser: error anonymizing apps: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	// Organization apps belong to the organization: they stay as they are
+	// and only forget who created them.
+	_, err = tx.Exec("UPDATE apps SET user_id=NULL WHERE user_id=$1;", userID)
+	if err != nil {
+		log.Printf("AnonymizeUser: error detaching organization apps: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("AnonymizeUser: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("AnonymizeUser: user anonymized")
+	return nil
+}
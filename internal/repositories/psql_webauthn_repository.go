@@ -0,0 +1,354 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type PSQLWebAuthnCredentialRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLWebAuthnCredentialRepository(db *sql.DB) *PSQLWebAuthnCredentialRepository {
+	return &PSQLWebAuthnCredentialRepository{
+		db: db,
+	}
+}
+
+func (repo *PSQLWebAuthnCredentialRepository) CreateWebAuthnCredential(credential *models.WebAuthnCredential) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CreateWebAuthnCredential: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO webauthn_credentials (user_id, credential_id, public_key, sign_count, name) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at;")
+	if err != nil {
+		log.Printf("CreateWebAuthnCredential: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(
+		credential.UserID,
+		credential.CredentialID,
+		credential.PublicKey,
+		int64(credential.SignCount),
+		credential.Name,
+	).Scan(&credential.ID, &credential.CreatedAt)
+	if err != nil {
+		log.Printf("CreateWebAuthnCredential: error executing query: %s", err.Error())
+		tx.Rollback()
+
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return utils.ErrWebAuthnCredentialAlreadyRegistered
+		}
+
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CreateWebAuthnCredential: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("CreateWebAuthnCredential: webauthn credential created")
+	return nil
+}
+
+func scanWebAuthnCredential(row rowScanner) (*models.WebAuthnCredential, error) {
+	var credential models.WebAuthnCredential
+	var signCount int64
+	var lastUsedAt sql.NullTime
+
+	err := row.Scan(
+		&credential.ID,
+		&credential.UserID,
+		&credential.CredentialID,
+		&credential.PublicKey,
+		&signCount,
+		&credential.Name,
+		&credential.CreatedAt,
+		&lastUsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	credential.SignCount = uint32(signCount)
+	if lastUsedAt.Valid {
+		credential.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return &credential, nil
+}
+
+func (repo *PSQLWebAuthnCredentialRepository) GetWebAuthnCredentialByCredentialID(credentialID string) (*models.WebAuthnCredential, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetWebAuthnCredentialByCredentialID: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT id, user_id, credential_id, public_key, sign_count, name, created_at, last_used_at FROM webauthn_credentials WHERE credential_id=$1;")
+	if err != nil {
+		log.Printf("GetWebAuthnCredentialByCredentialID: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	credential, err := scanWebAuthnCredential(stmt.QueryRow(credentialID))
+	if err != nil {
+		log.Printf("GetWebAuthnCredentialByCredentialID: error executing query: %s", err.Error())
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrWebAuthnCredentialNotFound
+		}
+
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetWebAuthnCredentialByCredentialID: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return credential, nil
+}
+
+func (repo *PSQLWebAuthnCredentialRepository) GetWebAuthnCredentialsByUserID(userID models.UserID) ([]models.WebAuthnCredential, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("GetWebAuthnCredentialsByUserID: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("SELECT id, user_id, credential_id, public_key, sign_count, name, created_at, last_used_at FROM webauthn_credentials WHERE user_id=$1 ORDER BY created_at;")
+	if err != nil {
+		log.Printf("GetWebAuthnCredentialsByUserID: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(userID)
+	if err != nil {
+		log.Printf("GetWebAuthnCredentialsByUserID: error executing query: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	credentials := []models.WebAuthnCredential{}
+
+	for rows.Next() {
+		credential, err := scanWebAuthnCredential(rows)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		credentials = append(credentials, *credential)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Printf("GetWebAuthnCredentialsByUserID: error during iterating rows: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("GetWebAuthnCredentialsByUserID: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+func (repo *PSQLWebAuthnCredentialRepository) UpdateWebAuthnSignCount(id models.WebAuthnCredentialID, signCount uint32) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("UpdateWebAuthnSignCount: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE webauthn_credentials SET sign_count=$1, last_used_at=$2 WHERE id=$3;")
+	if err != nil {
+		log.Printf("UpdateWebAuthnSignCount: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(int64(signCount), time.Now(), id)
+	if err != nil {
+		log.Printf("UpdateWebAuthnSignCount: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("UpdateWebAuthnSignCount: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+func (repo *PSQLWebAuthnCredentialRepository) DeleteWebAuthnCredential(id models.WebAuthnCredentialID, userID models.UserID) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("DeleteWebAuthnCredential: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	stmt, err := tx.Prepare("DELETE FROM webauthn_credentials WHERE id=$1 AND user_id=$2;")
+	if err != nil {
+		log.Printf("DeleteWebAuthnCredential: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(id, userID)
+	if err != nil {
+		log.Printf("DeleteWebAuthnCredential: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return utils.ErrWebAuthnCredentialNotFound
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("DeleteWebAuthnCredential: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	log.Printf("DeleteWebAuthnCredential: webauthn credential deleted")
+	return nil
+}
+
+type PSQLWebAuthnChallengeRepository struct {
+	db *sql.DB
+}
+
+func NewPSQLWebAuthnChallengeRepository(db *sql.DB) *PSQLWebAuthnChallengeRepository {
+	return &PSQLWebAuthnChallengeRepository{
+		db: db,
+	}
+}
+
+func (repo *PSQLWebAuthnChallengeRepository) CreateWebAuthnChallenge(challenge *models.WebAuthnChallenge) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("CreateWebAuthnChallenge: error creating transaction: %s", err.Error())
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM webauthn_challenges WHERE expires_at <= $1;", time.Now())
+	if err != nil {
+		log.Printf("CreateWebAuthnChallenge: error deleting expired challenges: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO webauthn_challenges (challenge, user_id, ceremony, expires_at) VALUES ($1, $2, $3, $4) RETURNING id;")
+	if err != nil {
+		log.Printf("CreateWebAuthnChallenge: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(challenge.Challenge, challenge.UserID, challenge.Ceremony, challenge.ExpiresAt).Scan(&challenge.ID)
+	if err != nil {
+		log.Printf("CreateWebAuthnChallenge: error executing query: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("CreateWebAuthnChallenge: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// ConsumeWebAuthnChallenge deletes and returns the challenge, so each one can
+// only be answered once.
+func (repo *PSQLWebAuthnChallengeRepository) ConsumeWebAuthnChallenge(challenge string, ceremony models.WebAuthnCeremony) (*models.WebAuthnChallenge, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		log.Printf("ConsumeWebAuthnChallenge: error creating transaction: %s", err.Error())
+		return nil, err
+	}
+
+	stmt, err := tx.Prepare("DELETE FROM webauthn_challenges WHERE challenge=$1 AND ceremony=$2 RETURNING id, challenge, user_id, ceremony, expires_at;")
+	if err != nil {
+		log.Printf("ConsumeWebAuthnChallenge: error creating statement: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var result models.WebAuthnChallenge
+	var userID sql.NullInt64
+	err = stmt.QueryRow(challenge, ceremony).Scan(&result.ID, &result.Challenge, &userID, &result.Ceremony, &result.ExpiresAt)
+	if err != nil {
+		log.Printf("ConsumeWebAuthnChallenge: error executing query: %s", err.Error())
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrWebAuthnChallengeInvalid
+		}
+
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("ConsumeWebAuthnChallenge: error during commmit: %s", err.Error())
+		tx.Rollback()
+		return nil, err
+	}
+
+	if userID.Valid {
+		id := models.UserID(userID.Int64)
+		result.UserID = &id
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,14 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+// RateLimitRepository stores token buckets. The in-memory implementation
+// suits a single instance; the Postgres one shares buckets between instances.
+type RateLimitRepository interface {
+	TakeRateLimitToken(key string, limit models.RateLimit, now time.Time) (*models.RateLimitResult, error)
+	DeleteStaleRateLimitBuckets(before time.Time) error
+}
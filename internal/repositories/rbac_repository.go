@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type RBACRepository interface {
+	GetRoles() ([]models.Role, error)
+	GetRoleByName(name models.RoleName) (*models.Role, error)
+	CreateRole(role *models.Role) error
+	DeleteRole(id models.RoleID) error
+	GetPermissions() ([]models.Permission, error)
+	GetUserRoles(userID models.UserID) ([]models.Role, error)
+	GetRoleMembers(roleID models.RoleID) ([]models.RoleMember, error)
+	GetUserPermissions(userID models.UserID) ([]string, error)
+	AssignUserRole(userID models.UserID, roleID models.RoleID) error
+	RemoveUserRole(userID models.UserID, roleID models.RoleID) error
+	UpdateRoleMembers(roleID models.RoleID, added []models.UserID, removed []models.UserID) error
+}
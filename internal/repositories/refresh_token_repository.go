@@ -7,6 +7,7 @@ import (
 type RefreshTokenRepository interface {
 	CreateRefreshToken(token *models.RefreshToken) error
 	GetRefreshTokenByContent(content models.RefreshTokenContent) (*models.RefreshToken, error)
+	GetRefreshTokensByUserID(userID models.UserID) ([]models.RefreshToken, error)
 	InvalidateRefreshTokenByContent(content models.RefreshTokenContent) error
 	InvalidateRefreshTokensByUserID(userID models.UserID) error
 }
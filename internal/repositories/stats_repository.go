@@ -0,0 +1,7 @@
+package repositories
+
+import "github.com/pedrotunin/go-jwt-auth/internal/models"
+
+type StatsRepository interface {
+	GetStats() (*models.Stats, error)
+}
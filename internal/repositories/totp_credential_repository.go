@@ -0,0 +1,13 @@
+package repositories
+
+import (
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type TOTPCredentialRepository interface {
+	SaveTOTPCredential(credential *models.TOTPCredential) error
+	GetTOTPCredentialByUserID(userID models.UserID) (*models.TOTPCredential, error)
+	ConfirmTOTPCredential(userID models.UserID, counter int64) error
+	UseTOTPCounter(userID models.UserID, counter int64) error
+	DeleteTOTPCredential(userID models.UserID) error
+}
@@ -1,11 +1,25 @@
 package repositories
 
 import (
+	"time"
+
 	"github.com/pedrotunin/go-jwt-auth/internal/models"
 )
 
 type UserRepository interface {
 	GetUserByEmail(email models.UserEmail) (*models.User, error)
+	GetUserByID(userID models.UserID) (*models.User, error)
 	CreateUser(u *models.User) (id int, err error)
 	ActivateUser(userID models.UserID) error
+	UpdateUserProfile(u *models.User) error
+	UpdateLastLogin(userID models.UserID, at time.Time) error
+	UpdateUserPassword(userID models.UserID, hash string) error
+	UpdateUserEmail(userID models.UserID, email models.UserEmail) error
+	UpdateUserStatus(userID models.UserID, status models.UserStatus) error
+	SearchUsers(filter models.UserFilter) ([]models.User, error)
+	CountUsers(filter models.UserFilter) (int, error)
+	SetDeletionScheduledAt(userID models.UserID, at *time.Time) error
+	GetUserIDsDueForDeletion(now time.Time) ([]models.UserID, error)
+	DeleteUser(userID models.UserID) error
+	AnonymizeUser(userID models.UserID) error
 }
@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+type WebAuthnCredentialRepository interface {
+	CreateWebAuthnCredential(credential *models.WebAuthnCredential) error
+	GetWebAuthnCredentialByCredentialID(credentialID string) (*models.WebAuthnCredential, error)
+	GetWebAuthnCredentialsByUserID(userID models.UserID) ([]models.WebAuthnCredential, error)
+	UpdateWebAuthnSignCount(id models.WebAuthnCredentialID, signCount uint32) error
+	DeleteWebAuthnCredential(id models.WebAuthnCredentialID, userID models.UserID) error
+}
+
+type WebAuthnChallengeRepository interface {
+	CreateWebAuthnChallenge(challenge *models.WebAuthnChallenge) error
+	ConsumeWebAuthnChallenge(challenge string, ceremony models.WebAuthnCeremony) (*models.WebAuthnChallenge, error)
+}
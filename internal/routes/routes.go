@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/pedrotunin/go-jwt-auth/internal/controllers"
 	"github.com/pedrotunin/go-jwt-auth/internal/middlewares"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
 )
 
 type Routes struct {
@@ -19,15 +20,84 @@ func (r *Routes) Setup() {
 	{
 		users := v1.Group("/users")
 		{
-			users.POST("/", r.Controllers.UserController.CreateUser)
+			users.POST("/", r.Middlewares.RateLimitMiddleware.Limit("signup"), r.Controllers.UserController.CreateUser)
 			users.GET("/:id/verify", r.Controllers.UserController.VerifyUser)
+			users.GET("/me", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Controllers.UserController.GetMe)
+			users.PATCH("/me", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Controllers.UserController.UpdateMe)
+			users.DELETE("/me", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.UserController.DeleteMe)
+			users.POST("/me/cancel-deletion", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.UserController.CancelDeletion)
+			users.GET("/me/logins", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Controllers.UserController.GetLogins)
+			users.POST("/me/export", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.DataExportController.Create)
+			users.POST("/me/mfa/totp", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.MFAController.EnrollTOTP)
+			users.POST("/me/mfa/totp/confirm", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.MFAController.ConfirmTOTP)
+			users.DELETE("/me/mfa/totp", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.MFAController.DisableTOTP)
+			users.POST("/me/mfa/recovery-codes", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.MFAController.RegenerateRecoveryCodes)
+			users.POST("/me/webauthn/register/begin", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.WebAuthnController.BeginRegistration)
+			users.POST("/me/webauthn/register/finish", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.WebAuthnController.FinishRegistration)
+			users.GET("/me/webauthn/credentials", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Controllers.WebAuthnController.GetCredentials)
+			users.DELETE("/me/webauthn/credentials/:id", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.WebAuthnController.DeleteCredential)
+			users.PUT("/me/organization", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.OrganizationController.SwitchOrganization)
+		}
+
+		exports := v1.Group("/exports")
+		{
+			exports.GET("/:token", r.Controllers.DataExportController.Download)
 		}
 
 		auth := v1.Group("/auth")
 		{
-			auth.POST("/login", r.Controllers.AuthController.Login)
-			auth.POST("/logout", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Controllers.AuthController.Logout)
-			auth.POST("/refresh", r.Controllers.AuthController.Refresh)
+			auth.POST("/login", r.Middlewares.RateLimitMiddleware.Limit("login"), r.Controllers.AuthController.Login)
+			auth.POST("/logout", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.AuthController.Logout)
+			auth.POST("/refresh", r.Middlewares.RateLimitMiddleware.Limit("refresh"), r.Controllers.AuthController.Refresh)
+			auth.POST("/mfa/verify", r.Middlewares.RateLimitMiddleware.Limit("login"), r.Controllers.AuthController.VerifyMFA)
+			auth.POST("/webauthn/begin", r.Middlewares.RateLimitMiddleware.Limit("login"), r.Controllers.AuthController.BeginWebAuthnLogin)
+			auth.POST("/webauthn/finish", r.Middlewares.RateLimitMiddleware.Limit("login"), r.Controllers.AuthController.FinishWebAuthnLogin)
+			auth.POST("/magic-link", r.Middlewares.RateLimitMiddleware.Limit("email"), r.Controllers.AuthController.RequestMagicLink)
+			auth.GET("/magic-link/:token", r.Controllers.AuthController.OpenMagicLink)
+			auth.POST("/magic-link/verify", r.Middlewares.RateLimitMiddleware.Limit("login"), r.Controllers.AuthController.ConsumeMagicLink)
+			auth.POST("/email-otp", r.Middlewares.RateLimitMiddleware.Limit("email"), r.Controllers.AuthController.RequestEmailOTP)
+			auth.POST("/email-otp/verify", r.Middlewares.RateLimitMiddleware.Limit("login"), r.Controllers.AuthController.VerifyEmailOTP)
+			auth.GET("/unlock/:token", r.Controllers.AuthController.Unlock)
+			auth.POST("/password-reset", r.Middlewares.RateLimitMiddleware.Limit("login"), r.Controllers.AuthController.ResetPassword)
+		}
+
+		admin := v1.Group("/admin", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Middlewares.ImpersonationMiddleware.BlockImpersonation())
+		{
+			admin.GET("/users", r.Middlewares.PermissionMiddleware.RequirePermission(utils.PermissionUsersRead), r.Controllers.AdminController.ListUsers)
+			admin.GET("/users/:id", r.Middlewares.PermissionMiddleware.RequirePermission(utils.PermissionUsersRead), r.Controllers.AdminController.GetUser)
+			admin.POST("/users/:id/deactivate", r.Middlewares.PermissionMiddleware.RequirePermission(utils.PermissionUsersWrite), r.Controllers.AdminController.DeactivateUser)
+			admin.POST("/users/:id/reactivate", r.Middlewares.PermissionMiddleware.RequirePermission(utils.PermissionUsersWrite), r.Controllers.AdminController.ReactivateUser)
+			admin.POST("/users/:id/verify", r.Middlewares.PermissionMiddleware.RequirePermission(utils.PermissionUsersWrite), r.Controllers.AdminController.VerifyUser)
+			admin.POST("/users/:id/password-reset", r.Middlewares.PermissionMiddleware.RequirePermission(utils.PermissionUsersWrite), r.Controllers.AdminController.ResetUserPassword)
+			admin.POST("/users/:id/impersonate", r.Middlewares.PermissionMiddleware.RequirePermission(utils.PermissionUsersImpersonate), r.Controllers.AdminController.ImpersonateUser)
+			admin.GET("/users/:id/impersonations", r.Middlewares.PermissionMiddleware.RequirePermission(utils.PermissionUsersRead), r.Controllers.AdminController.GetUserImpersonations)
+			admin.GET("/users/:id/roles", r.Middlewares.PermissionMiddleware.RequirePermission(utils.PermissionRolesRead), r.Controllers.RoleController.GetUserRoles)
+			admin.POST("/users/:id/roles", r.Middlewares.PermissionMiddleware.RequirePermission(utils.PermissionRolesWrite), r.Controllers.RoleController.AssignUserRole)
+			admin.DELETE("/users/:id/roles/:role", r.Middlewares.PermissionMiddleware.RequirePermission(utils.PermissionRolesWrite), r.Controllers.RoleController.RemoveUserRole)
+			admin.GET("/roles", r.Middlewares.PermissionMiddleware.RequirePermission(utils.PermissionRolesRead), r.Controllers.RoleController.ListRoles)
+			admin.POST("/roles", r.Middlewares.PermissionMiddleware.RequirePermission(utils.PermissionRolesWrite), r.Controllers.RoleController.CreateRole)
+			admin.DELETE("/roles/:name", r.Middlewares.PermissionMiddleware.RequirePermission(utils.PermissionRolesWrite), r.Controllers.RoleController.DeleteRole)
+			admin.GET("/permissions", r.Middlewares.PermissionMiddleware.RequirePermission(utils.PermissionRolesRead), r.Controllers.RoleController.ListPermissions)
+		}
+
+		invitations := v1.Group("/invitations")
+		{
+			invitations.POST("/", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Middlewares.PermissionMiddleware.RequirePermission(utils.PermissionUsersInvite), r.Controllers.InvitationController.CreateInvitation)
+			invitations.GET("/", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Middlewares.PermissionMiddleware.RequirePermission(utils.PermissionUsersInvite), r.Controllers.InvitationController.ListInvitations)
+			invitations.DELETE("/:id", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Middlewares.PermissionMiddleware.RequirePermission(utils.PermissionUsersInvite), r.Controllers.InvitationController.RevokeInvitation)
+			invitations.POST("/accept", r.Middlewares.RateLimitMiddleware.Limit("signup"), r.Controllers.InvitationController.AcceptInvitation)
+			invitations.POST("/join", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated(), r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.InvitationController.JoinWithInvitation)
+		}
+
+		orgs := v1.Group("/orgs", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated())
+		{
+			orgs.POST("/", r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.OrganizationController.CreateOrganization)
+			orgs.GET("/", r.Controllers.OrganizationController.ListOrganizations)
+			orgs.GET("/:id", r.Controllers.OrganizationController.GetOrganization)
+			orgs.GET("/:id/members", r.Controllers.OrganizationController.GetMembers)
+			orgs.POST("/:id/members", r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.OrganizationController.AddMember)
+			orgs.PATCH("/:id/members/:userId", r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.OrganizationController.UpdateMember)
+			orgs.DELETE("/:id/members/:userId", r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.OrganizationController.RemoveMember)
 		}
 
 		apps := v1.Group("/apps", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated())
@@ -37,8 +107,46 @@ func (r *Routes) Setup() {
 			apps.POST("/", r.Controllers.AppController.Create)
 			apps.PUT("/:id", r.Controllers.AppController.Update)
 			apps.DELETE("/:id", r.Controllers.AppController.DeleteByID)
+			apps.GET("/:id/members", r.Controllers.AppController.GetMembers)
+			apps.POST("/:id/members", r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.AppController.AddMember)
+			apps.DELETE("/:id/members/:userId", r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.AppController.RemoveMember)
+			apps.POST("/:id/transfers", r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.AppTransferController.CreateTransfer)
+			apps.GET("/:id/transfers", r.Controllers.AppTransferController.ListAppTransfers)
+			apps.DELETE("/:id/transfers/:transferId", r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.AppTransferController.CancelTransfer)
+			apps.GET("/:id/user-pool", r.Controllers.AppUserPoolController.GetUserPool)
+			apps.PUT("/:id/user-pool", r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.AppUserPoolController.ConfigureUserPool)
+		}
+
+		appTransfers := v1.Group("/app-transfers", r.Middlewares.AuthenticatedUserMiddleware.IsAuthenticated())
+		{
+			appTransfers.GET("/", r.Controllers.AppTransferController.ListIncomingTransfers)
+			appTransfers.POST("/accept", r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.AppTransferController.AcceptTransferByToken)
+			appTransfers.POST("/:id/accept", r.Middlewares.ImpersonationMiddleware.BlockImpersonation(), r.Controllers.AppTransferController.AcceptTransfer)
+		}
+
+		pools := v1.Group("/pools")
+		{
+			pools.POST("/:clientId/signup", r.Middlewares.RateLimitMiddleware.Limit("signup"), r.Controllers.AppUserPoolController.SignUp)
+			pools.POST("/:clientId/login", r.Middlewares.RateLimitMiddleware.Limit("pool-login"), r.Controllers.AppUserPoolController.Login)
+			pools.POST("/:clientId/refresh", r.Middlewares.RateLimitMiddleware.Limit("refresh"), r.Controllers.AppUserPoolController.Refresh)
 		}
 
 	}
 
+	scim := r.Router.Group("/scim/v2", r.Middlewares.ProvisioningMiddleware.RequireProvisioningToken())
+	{
+		scim.GET("/Users", r.Controllers.SCIMController.ListUsers)
+		scim.GET("/Users/:id", r.Controllers.SCIMController.GetUser)
+		scim.POST("/Users", r.Controllers.SCIMController.CreateUser)
+		scim.PUT("/Users/:id", r.Controllers.SCIMController.ReplaceUser)
+		scim.PATCH("/Users/:id", r.Controllers.SCIMController.PatchUser)
+		scim.DELETE("/Users/:id", r.Controllers.SCIMController.DeleteUser)
+		scim.GET("/Groups", r.Controllers.SCIMController.ListGroups)
+		scim.GET("/Groups/:id", r.Controllers.SCIMController.GetGroup)
+		scim.POST("/Groups", r.Controllers.SCIMController.CreateGroup)
+		scim.PUT("/Groups/:id", r.Controllers.SCIMController.ReplaceGroup)
+		scim.PATCH("/Groups/:id", r.Controllers.SCIMController.PatchGroup)
+		scim.DELETE("/Groups/:id", r.Controllers.SCIMController.DeleteGroup)
+	}
+
 }
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IAccountDeletionService interface {
+	RequestDeletion(userID models.UserID, password string) (scheduledAt time.Time, err error)
+	CancelDeletion(userID models.UserID) error
+	PurgeDueAccounts() (purged int, err error)
+}
+
+type AccountDeletionService struct {
+	userRepository         repositories.UserRepository
+	refreshTokenRepository repositories.RefreshTokenRepository
+	hashService            IHashService
+	gracePeriod            time.Duration
+	mode                   string
+}
+
+func NewAccountDeletionService(
+	userRepository repositories.UserRepository,
+	refreshTokenRepository repositories.RefreshTokenRepository,
+	hashService IHashService,
+	gracePeriod time.Duration,
+	mode string,
+) IAccountDeletionService {
+	return &AccountDeletionService{
+		userRepository:         userRepository,
+		refreshTokenRepository: refreshTokenRepository,
+		hashService:            hashService,
+		gracePeriod:            gracePeriod,
+		mode:                   mode,
+	}
+}
+
+func (ads *AccountDeletionService) RequestDeletion(userID models.UserID, password string) (time.Time, error) {
+	user, err := ads.userRepository.GetUserByID(userID)
+	if err != nil {
+		log.Printf("RequestDeletion: error getting user: %s", err.Error())
+		return time.Time{}, err
+	}
+
+	if user.DeletionScheduledAt != nil {
+		return time.Time{}, utils.ErrUserDeletionAlreadyScheduled
+	}
+
+	err = ads.hashService.ComparePassword(password, user.Password)
+	if err != nil {
+		log.Printf("RequestDeletion: error comparing password and hash: %s", err.Error())
+		return time.Time{}, utils.ErrPasswordIncorrect
+	}
+
+	scheduledAt := time.Now().Add(ads.gracePeriod)
+
+	err = ads.userRepository.SetDeletionScheduledAt(userID, &scheduledAt)
+	if err != nil {
+		log.Printf("RequestDeletion: error scheduling deletion: %s", err.Error())
+		return time.Time{}, err
+	}
+
+	err = ads.refreshTokenRepository.InvalidateRefreshTokensByUserID(userID)
+	if err != nil {
+		log.Printf("RequestDeletion: error invalidating refresh tokens: %s", err.Error())
+		return time.Time{}, err
+	}
+
+	log.Printf("RequestDeletion: user %d scheduled for deletion at %s", userID, scheduledAt)
+	return scheduledAt, nil
+}
+
+func (ads *AccountDeletionService) CancelDeletion(userID models.UserID) error {
+	user, err := ads.userRepository.GetUserByID(userID)
+	if err != nil {
+		log.Printf("CancelDeletion: error getting user: %s", err.Error())
+		return err
+	}
+
+	if user.DeletionScheduledAt == nil {
+		return utils.ErrUserDeletionNotScheduled
+	}
+
+	err = ads.userRepository.SetDeletionScheduledAt(userID, nil)
+	if err != nil {
+		log.Printf("CancelDeletion: error cancelling deletion: %s", err.Error())
+		return err
+	}
+
+	log.Printf("CancelDeletion: user %d deletion cancelled", userID)
+	return nil
+}
+
+// PurgeDueAccounts deletes or anonymizes, depending on the configured mode,
+// every account whose grace period is over.
+func (ads *AccountDeletionService) PurgeDueAccounts() (int, error) {
+	ids, err := ads.userRepository.GetUserIDsDueForDeletion(time.Now())
+	if err != nil {
+		log.Printf("PurgeDueAccounts: error getting users due for deletion: %s", err.Error())
+		return 0, err
+	}
+
+	purged := 0
+
+	for _, id := range ids {
+		if ads.mode == utils.AccountDeletionModeAnonymize {
+			err = ads.userRepository.AnonymizeUser(id)
+		} else {
+			err = ads.userRepository.DeleteUser(id)
+		}
+
+		if err != nil {
+			log.Printf("PurgeDueAccounts: error purging user %d: %s", id, err.Error())
+			continue
+		}
+
+		purged++
+	}
+
+	log.Printf("PurgeDueAccounts: purged %d of %d accounts", purged, len(ids))
+	return purged, nil
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IAccountLockoutService interface {
+	CheckAllowed(userID models.UserID) (retryAfter time.Duration, err error)
+	RecordFailure(userID models.UserID) (lock *AccountLock, err error)
+	RecordSuccess(userID models.UserID) error
+	Unlock(unlockToken string) error
+	CheckAllowedEmail(email string) (retryAfter time.Duration, err error)
+	RecordEmailFailure(email string) error
+}
+
+// AccountLock is returned by RecordFailure when the failure locked the
+// account. UnlockToken is meant to be e-mailed to the owner.
+type AccountLock struct {
+	LockedUntil time.Time
+	UnlockToken string
+}
+
+type AccountLockoutConfig struct {
+	// Threshold is the number of failures that locks the account.
+	Threshold int
+	// Duration is how long the account stays locked. It is also how long
+	// failures are remembered.
+	Duration time.Duration
+	// BackoffBase is the wait after the first failure; it doubles with every
+	// further failure. Zero disables backoff.
+	BackoffBase time.Duration
+}
+
+type AccountLockoutService struct {
+	accountLockoutRepository repositories.AccountLockoutRepository
+	hashService              IHashService
+	config                   AccountLockoutConfig
+}
+
+func NewAccountLockoutService(
+	accountLockoutRepository repositories.AccountLockoutRepository,
+	hashService IHashService,
+	config AccountLockoutConfig,
+) IAccountLockoutService {
+	return &AccountLockoutService{
+		accountLockoutRepository: accountLockoutRepository,
+		hashService:              hashService,
+		config:                   config,
+	}
+}
+
+// backoff returns how long to wait after failures consecutive failures.
+func (als *AccountLockoutService) backoff(failures int) time.Duration {
+	if als.config.BackoffBase <= 0 || failures <= 0 {
+		return 0
+	}
+
+	delay := als.config.BackoffBase
+	for i := 1; i < failures; i++ {
+		delay *= 2
+		if delay >= als.config.Duration {
+			return als.config.Duration
+		}
+	}
+
+	return delay
+}
+
+// CheckAllowed tells whether a password may be checked for the user right
+// now. It is meant to run before the password hash is compared, so locked
+// accounts cost no hashing.
+func (als *AccountLockoutService) CheckAllowed(userID models.UserID) (time.Duration, error) {
+	lockout, err := als.accountLockoutRepository.GetAccountLockout(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return als.checkLockout(lockout, func() error {
+		return als.accountLockoutRepository.ResetAccountLockout(userID)
+	})
+}
+
+// checkLockout applies the lock and backoff rules to a stored lockout. reset
+// forgets the lockout once its lock has run out.
+func (als *AccountLockoutService) checkLockout(lockout *models.AccountLockout, reset func() error) (time.Duration, error) {
+	now := time.Now()
+
+	if lockout.IsLocked(now) {
+		return lockout.LockedUntil.Sub(now), utils.ErrAccountLocked
+	}
+
+	// The lock has run out: start over with a clean slate.
+	if lockout.LockedUntil != nil {
+		return 0, reset()
+	}
+
+	if lockout.LastFailedAt != nil {
+		allowedAt := lockout.LastFailedAt.Add(als.backoff(lockout.FailedAttempts))
+		if now.Before(allowedAt) {
+			return allowedAt.Sub(now), utils.ErrLoginThrottled
+		}
+	}
+
+	return 0, nil
+}
+
+func (als *AccountLockoutService) RecordFailure(userID models.UserID) (*AccountLock, error) {
+	now := time.Now()
+
+	lockout, err := als.accountLockoutRepository.RecordFailedLogin(userID, now, now.Add(-als.config.Duration))
+	if err != nil {
+		return nil, err
+	}
+
+	if als.config.Threshold <= 0 || lockout.FailedAttempts < als.config.Threshold {
+		return nil, nil
+	}
+
+	unlockToken, err := utils.GetRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	unlockTokenHash, err := als.hashService.HashSHA256(unlockToken)
+	if err != nil {
+		return nil, err
+	}
+
+	lockedUntil := now.Add(als.config.Duration)
+
+	err = als.accountLockoutRepository.LockAccount(userID, lockedUntil, unlockTokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("RecordFailure: account %d locked after %d failed logins", userID, lockout.FailedAttempts)
+	return &AccountLock{
+		LockedUntil: lockedUntil,
+		UnlockToken: unlockToken,
+	}, nil
+}
+
+func (als *AccountLockoutService) RecordSuccess(userID models.UserID) error {
+	return als.accountLockoutRepository.ResetAccountLockout(userID)
+}
+
+func (als *AccountLockoutService) Unlock(unlockToken string) error {
+	unlockTokenHash, err := als.hashService.HashSHA256(unlockToken)
+	if err != nil {
+		return err
+	}
+
+	lockout, err := als.accountLockoutRepository.GetAccountLockoutByUnlockToken(unlockTokenHash)
+	if err != nil {
+		return err
+	}
+
+	err = als.accountLockoutRepository.ResetAccountLockout(lockout.UserID)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Unlock: account %d unlocked", lockout.UserID)
+	return nil
+}
+
+// emailHash keys the lockout of an e-mail without an account, so the table
+// does not keep the e-mails people tried.
+func (als *AccountLockoutService) emailHash(email string) (string, error) {
+	return als.hashService.HashSHA256(strings.ToLower(strings.TrimSpace(email)))
+}
+
+// CheckAllowedEmail is CheckAllowed for e-mails without an account. Applying
+// the same rules to them keeps login responses from telling whether an
+// account exists.
+func (als *AccountLockoutService) CheckAllowedEmail(email string) (time.Duration, error) {
+	emailHash, err := als.emailHash(email)
+	if err != nil {
+		return 0, err
+	}
+
+	lockout, err := als.accountLockoutRepository.GetEmailLockout(emailHash)
+	if err != nil {
+		return 0, err
+	}
+
+	return als.checkLockout(lockout, func() error {
+		return als.accountLockoutRepository.ResetEmailLockout(emailHash)
+	})
+}
+
+// RecordEmailFailure is RecordFailure for e-mails without an account. There
+// is nobody to send an unlock link to, so the lock simply runs out.
+func (als *AccountLockoutService) RecordEmailFailure(email string) error {
+	emailHash, err := als.emailHash(email)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	windowStart := now.Add(-als.config.Duration)
+
+	err = als.accountLockoutRepository.DeleteStaleEmailLockouts(windowStart)
+	if err != nil {
+		return err
+	}
+
+	lockout, err := als.accountLockoutRepository.RecordFailedEmailLogin(emailHash, now, windowStart)
+	if err != nil {
+		return err
+	}
+
+	if als.config.Threshold <= 0 || lockout.FailedAttempts < als.config.Threshold {
+		return nil
+	}
+
+	err = als.accountLockoutRepository.LockEmail(emailHash, now.Add(als.config.Duration))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("RecordEmailFailure: unknown e-mail locked after %d failed logins", lockout.FailedAttempts)
+	return nil
+}
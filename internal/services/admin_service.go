@@ -0,0 +1,139 @@
+package services
+
+import (
+	"log"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IAdminService interface {
+	ListUsers(filter models.UserFilter) (users []models.User, total int, err error)
+	GetUser(userID models.UserID) (*models.User, error)
+	DeactivateUser(userID models.UserID) error
+	ReactivateUser(userID models.UserID) error
+	VerifyUser(userID models.UserID) error
+	RevokeSessions(userID models.UserID) error
+}
+
+type AdminService struct {
+	userRepository         repositories.UserRepository
+	refreshTokenRepository repositories.RefreshTokenRepository
+}
+
+func NewAdminService(
+	userRepository repositories.UserRepository,
+	refreshTokenRepository repositories.RefreshTokenRepository,
+) IAdminService {
+	return &AdminService{
+		userRepository:         userRepository,
+		refreshTokenRepository: refreshTokenRepository,
+	}
+}
+
+func (as *AdminService) ListUsers(filter models.UserFilter) ([]models.User, int, error) {
+	total, err := as.userRepository.CountUsers(filter)
+	if err != nil {
+		log.Printf("ListUsers: error counting users: %s", err.Error())
+		return nil, 0, err
+	}
+
+	users, err := as.userRepository.SearchUsers(filter)
+	if err != nil {
+		log.Printf("ListUsers: error searching users: %s", err.Error())
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+func (as *AdminService) GetUser(userID models.UserID) (*models.User, error) {
+	user, err := as.userRepository.GetUserByID(userID)
+	if err != nil {
+		log.Printf("GetUser: error getting user: %s", err.Error())
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// DeactivateUser blocks the user from logging in and revokes their sessions.
+func (as *AdminService) DeactivateUser(userID models.UserID) error {
+	err := as.userRepository.UpdateUserStatus(userID, utils.UserStatusInactive)
+	if err != nil {
+		log.Printf("DeactivateUser: error updating user status: %s", err.Error())
+		return err
+	}
+
+	err = as.refreshTokenRepository.InvalidateRefreshTokensByUserID(userID)
+	if err != nil {
+		log.Printf("DeactivateUser: error revoking sessions: %s", err.Error())
+		return err
+	}
+
+	log.Printf("DeactivateUser: user %d deactivated", userID)
+	return nil
+}
+
+func (as *AdminService) ReactivateUser(userID models.UserID) error {
+	user, err := as.userRepository.GetUserByID(userID)
+	if err != nil {
+		log.Printf("ReactivateUser: error getting user: %s", err.Error())
+		return err
+	}
+
+	if user.Status != utils.UserStatusInactive {
+		return utils.ErrUserNotInactive
+	}
+
+	err = as.userRepository.UpdateUserStatus(userID, utils.UserStatusActive)
+	if err != nil {
+		log.Printf("ReactivateUser: error updating user status: %s", err.Error())
+		return err
+	}
+
+	log.Printf("ReactivateUser: user %d reactivated", userID)
+	return nil
+}
+
+// VerifyUser activates a pending user without the e-mail verification step.
+func (as *AdminService) VerifyUser(userID models.UserID) error {
+	user, err := as.userRepository.GetUserByID(userID)
+	if err != nil {
+		log.Printf("VerifyUser: error getting user: %s", err.Error())
+		return err
+	}
+
+	if user.Status != utils.UserStatusPending {
+		return utils.ErrUserNotPending
+	}
+
+	err = as.userRepository.ActivateUser(userID)
+	if err != nil {
+		log.Printf("VerifyUser: error activating user: %s", err.Error())
+		return err
+	}
+
+	log.Printf("VerifyUser: user %d verified", userID)
+	return nil
+}
+
+// RevokeSessions invalidates every refresh token of the user, logging them
+// out everywhere once their access tokens expire.
+func (as *AdminService) RevokeSessions(userID models.UserID) error {
+	_, err := as.userRepository.GetUserByID(userID)
+	if err != nil {
+		log.Printf("RevokeSessions: error getting user: %s", err.Error())
+		return err
+	}
+
+	err = as.refreshTokenRepository.InvalidateRefreshTokensByUserID(userID)
+	if err != nil {
+		log.Printf("RevokeSessions: error revoking sessions: %s", err.Error())
+		return err
+	}
+
+	log.Printf("RevokeSessions: sessions of user %d revoked", userID)
+	return nil
+}
@@ -1,6 +1,9 @@
 package services
 
 import (
+	"log"
+	"strings"
+
 	"github.com/pedrotunin/go-jwt-auth/internal/models"
 	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
 	"github.com/pedrotunin/go-jwt-auth/internal/utils"
@@ -9,19 +12,48 @@ import (
 
 type IAppService interface {
 	GetUserApps(userID models.UserID) ([]models.App, error)
+	GetOrganizationApps(organizationID models.OrganizationID) ([]models.App, error)
 	GetAppByID(appID models.AppID) (*models.App, error)
 	CreateApp(app *models.App) error
 	UpdateApp(app *models.App) error
 	DeleteApp(appID models.AppID) error
+	ListApps(filter models.AppFilter) ([]models.App, error)
+	RestoreApp(appID models.AppID) error
+	GetAppRole(appID models.AppID, userID models.UserID) (models.AppRole, error)
+	GetAppMembers(appID models.AppID) ([]models.AppMember, error)
+	AddAppMember(appID models.AppID, inviterID models.UserID, email string, role models.AppRole) (*InvitationToken, error)
+	RemoveAppMember(app *models.App, userID models.UserID) error
+}
+
+// appRoleRanks orders the app roles, each one granting everything the lower
+// ones do: viewers read the app, editors also update it and owners also
+// delete it and manage its members.
+var appRoleRanks = map[models.AppRole]int{
+	utils.AppRoleViewer: 1,
+	utils.AppRoleEditor: 2,
+	utils.AppRoleOwner:  3,
+}
+
+// HasAppRole reports whether role grants at least the rights of required.
+func HasAppRole(role models.AppRole, required models.AppRole) bool {
+	return appRoleRanks[role] > 0 && appRoleRanks[role] >= appRoleRanks[required]
 }
 
 type AppService struct {
-	appRepository repositories.AppRepository
+	appRepository       repositories.AppRepository
+	appMemberRepository repositories.AppMemberRepository
+	invitationService   IInvitationService
 }
 
-func NewAppService(repository repositories.AppRepository) IAppService {
+func NewAppService(
+	repository repositories.AppRepository,
+	appMemberRepository repositories.AppMemberRepository,
+	invitationService IInvitationService,
+) IAppService {
 	return &AppService{
-		appRepository: repository,
+		appRepository:       repository,
+		appMemberRepository: appMemberRepository,
+		invitationService:   invitationService,
 	}
 }
 
@@ -33,6 +65,14 @@ func (as *AppService) GetUserApps(userID models.UserID) ([]models.App, error) {
 	return apps, nil
 }
 
+func (as *AppService) GetOrganizationApps(organizationID models.OrganizationID) ([]models.App, error) {
+	apps, err := as.appRepository.GetAppsByOrganizationID(organizationID)
+	if err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
+
 func (as *AppService) GetAppByID(appID models.AppID) (*models.App, error) {
 	app, err := as.appRepository.GetAppByID(appID)
 	if err != nil {
@@ -76,3 +116,105 @@ func (as *AppService) DeleteApp(appID models.AppID) error {
 	}
 	return nil
 }
+
+func (as *AppService) ListApps(filter models.AppFilter) ([]models.App, error) {
+	apps, err := as.appRepository.SearchApps(filter)
+	if err != nil {
+		log.Printf("ListApps: error searching apps: %s", err.Error())
+		return nil, err
+	}
+
+	return apps, nil
+}
+
+// RestoreApp brings back a soft-deleted app, with the members it had.
+func (as *AppService) RestoreApp(appID models.AppID) error {
+	err := as.appRepository.RestoreAppByID(appID)
+	if err != nil {
+		log.Printf("RestoreApp: error restoring app: %s", err.Error())
+		return err
+	}
+
+	log.Printf("RestoreApp: app %d restored", appID)
+	return nil
+}
+
+// GetAppRole returns the role userID holds on the app, or
+// ErrAppMemberNotFound when they are not a collaborator.
+func (as *AppService) GetAppRole(appID models.AppID, userID models.UserID) (models.AppRole, error) {
+	member, err := as.appMemberRepository.GetAppMember(appID, userID)
+	if err != nil {
+		return "", err
+	}
+
+	return member.Role, nil
+}
+
+func (as *AppService) GetAppMembers(appID models.AppID) ([]models.AppMember, error) {
+	return as.appMemberRepository.GetAppMembers(appID)
+}
+
+// AddAppMember invites email to collaborate on the app with role and returns
+// the invitation, meant to be e-mailed. Nobody becomes a collaborator without
+// accepting, and the result is the same whether or not the e-mail has an
+// account. E-mails of current collaborators get ErrAppMemberAlreadyExists;
+// their role is changed by removing and inviting them again.
+func (as *AppService) AddAppMember(appID models.AppID, inviterID models.UserID, email string, role models.AppRole) (*InvitationToken, error) {
+	if _, ok := appRoleRanks[role]; !ok {
+		return nil, utils.ErrAppRoleInvalid
+	}
+
+	email = strings.TrimSpace(email)
+
+	// Collaborators are listed to app owners anyway, so this reveals nothing.
+	members, err := as.appMemberRepository.GetAppMembers(appID)
+	if err != nil {
+		log.Printf("AddAppMember: error getting app members: %s", err.Error())
+		return nil, err
+	}
+
+	for _, member := range members {
+		if strings.EqualFold(member.Email, email) {
+			return nil, utils.ErrAppMemberAlreadyExists
+		}
+	}
+
+	invitation, err := as.invitationService.CreateAppInvitation(inviterID, email, appID, role)
+	if err != nil {
+		log.Printf("AddAppMember: error creating invitation: %s", err.Error())
+		return nil, err
+	}
+
+	log.Printf("AddAppMember: user %d invited a collaborator to app %d as %s", inviterID, appID, role)
+	return invitation, nil
+}
+
+// RemoveAppMember removes a collaborator. Personal apps keep at least one
+// owner; organization apps are still managed by the organization's owners
+// and admins without one.
+func (as *AppService) RemoveAppMember(app *models.App, userID models.UserID) error {
+	member, err := as.appMemberRepository.GetAppMember(app.ID, userID)
+	if err != nil {
+		return err
+	}
+
+	if app.OrganizationID == nil && member.Role == utils.AppRoleOwner {
+		owners, err := as.appMemberRepository.CountAppOwners(app.ID)
+		if err != nil {
+			return err
+		}
+
+		if owners <= 1 {
+			return utils.ErrAppLastOwner
+		}
+	}
+
+	err = as.appMemberRepository.RemoveAppMember(app.ID, userID)
+	if err != nil {
+		log.Printf("RemoveAppMember: error removing member: %s", err.Error())
+		return err
+	}
+
+	log.Printf("RemoveAppMember: user %d removed from app %d", userID, app.ID)
+	return nil
+}
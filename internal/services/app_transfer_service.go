@@ -0,0 +1,250 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IAppTransferService interface {
+	CreateTransfer(app *models.App, initiatorID models.UserID, email string, organizationID *models.OrganizationID) (*AppTransferToken, error)
+	GetAppTransfers(appID models.AppID) ([]models.AppTransfer, error)
+	CancelTransfer(appID models.AppID, transferID models.AppTransferID) error
+	GetIncomingTransfers(userID models.UserID) ([]models.AppTransfer, error)
+	AcceptTransfer(transferID models.AppTransferID, userID models.UserID) (*models.AppTransfer, error)
+	AcceptTransferByToken(token string, userID models.UserID) (*models.AppTransfer, error)
+}
+
+// AppTransferToken is a freshly created transfer and the token of its link,
+// meant to be e-mailed to RecipientEmails: the recipient user, or the owners
+// and admins of the recipient organization.
+type AppTransferToken struct {
+	Transfer        *models.AppTransfer
+	Token           string
+	RecipientEmails []string
+}
+
+type AppTransferService struct {
+	appTransferRepository  repositories.AppTransferRepository
+	appRepository          repositories.AppRepository
+	userRepository         repositories.UserRepository
+	organizationRepository repositories.OrganizationRepository
+	hashService            IHashService
+	appTransferTTL         time.Duration
+}
+
+func NewAppTransferService(
+	appTransferRepository repositories.AppTransferRepository,
+	appRepository repositories.AppRepository,
+	userRepository repositories.UserRepository,
+	organizationRepository repositories.OrganizationRepository,
+	hashService IHashService,
+	appTransferTTL time.Duration,
+) IAppTransferService {
+	return &AppTransferService{
+		appTransferRepository:  appTransferRepository,
+		appRepository:          appRepository,
+		userRepository:         userRepository,
+		organizationRepository: organizationRepository,
+		hashService:            hashService,
+		appTransferTTL:         appTransferTTL,
+	}
+}
+
+// CreateTransfer offers app to the active user with email or, when
+// organizationID is set instead, to that organization. It replaces any
+// pending transfer of the app. An e-mail without an active account gets a
+// transfer nobody can accept and no recipients to e-mail, so the result does
+// not tell whether the account exists. Permission checks are up to the
+// caller.
+func (ats *AppTransferService) CreateTransfer(app *models.App, initiatorID models.UserID, email string, organizationID *models.OrganizationID) (*AppTransferToken, error) {
+	email = strings.TrimSpace(email)
+	if (email == "") == (organizationID == nil) {
+		return nil, utils.ErrAppTransferRecipientInvalid
+	}
+
+	transfer := &models.AppTransfer{
+		AppID:              app.ID,
+		FromUserID:         app.UserID,
+		FromOrganizationID: app.OrganizationID,
+		InitiatedBy:        initiatorID,
+	}
+
+	var recipientEmails []string
+
+	if organizationID != nil {
+		if app.OrganizationID != nil && *app.OrganizationID == *organizationID {
+			return nil, utils.ErrAppTransferRecipientInvalid
+		}
+
+		_, err := ats.organizationRepository.GetOrganizationByID(*organizationID)
+		if err != nil {
+			return nil, err
+		}
+
+		members, err := ats.organizationRepository.GetOrganizationMembers(*organizationID)
+		if err != nil {
+			log.Printf("CreateTransfer: error getting organization members: %s", err.Error())
+			return nil, err
+		}
+
+		for _, member := range members {
+			if canManageMembers(member.Role) {
+				recipientEmails = append(recipientEmails, member.Email)
+			}
+		}
+
+		transfer.ToOrganizationID = organizationID
+	} else {
+		transfer.ToEmail = email
+
+		user, err := ats.userRepository.GetUserByEmail(email)
+		if err != nil && !errors.Is(err, utils.ErrUserNotFound) {
+			log.Printf("CreateTransfer: error getting user: %s", err.Error())
+			return nil, err
+		}
+
+		if user != nil && app.OrganizationID == nil && app.UserID == user.ID {
+			return nil, utils.ErrAppTransferRecipientInvalid
+		}
+
+		if user != nil && user.Status == utils.UserStatusActive {
+			recipientEmails = []string{user.Email}
+			transfer.ToUserID = &user.ID
+		}
+	}
+
+	value, err := utils.GetRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer.TokenHash, err = ats.hashService.HashSHA256(value)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer.ExpiresAt = time.Now().Add(ats.appTransferTTL)
+
+	err = ats.appTransferRepository.CreateAppTransfer(transfer)
+	if err != nil {
+		log.Printf("CreateTransfer: error saving transfer: %s", err.Error())
+		return nil, err
+	}
+
+	transfer.Status = transfer.GetStatus(time.Now())
+
+	log.Printf("CreateTransfer: user %d offered app %d for transfer", initiatorID, app.ID)
+	return &AppTransferToken{
+		Transfer:        transfer,
+		Token:           value,
+		RecipientEmails: recipientEmails,
+	}, nil
+}
+
+// GetAppTransfers returns every transfer of the app, newest first, accepted
+// ones being its ownership history.
+func (ats *AppTransferService) GetAppTransfers(appID models.AppID) ([]models.AppTransfer, error) {
+	transfers, err := ats.appTransferRepository.GetAppTransfersByAppID(appID)
+	if err != nil {
+		log.Printf("GetAppTransfers: error getting transfers: %s", err.Error())
+		return nil, err
+	}
+
+	return withAppTransferStatus(transfers), nil
+}
+
+func (ats *AppTransferService) CancelTransfer(appID models.AppID, transferID models.AppTransferID) error {
+	return ats.appTransferRepository.CancelAppTransfer(appID, transferID)
+}
+
+// GetIncomingTransfers returns the pending transfers userID may accept.
+func (ats *AppTransferService) GetIncomingTransfers(userID models.UserID) ([]models.AppTransfer, error) {
+	transfers, err := ats.appTransferRepository.GetIncomingAppTransfers(userID)
+	if err != nil {
+		log.Printf("GetIncomingTransfers: error getting transfers: %s", err.Error())
+		return nil, err
+	}
+
+	return withAppTransferStatus(transfers), nil
+}
+
+func (ats *AppTransferService) AcceptTransfer(transferID models.AppTransferID, userID models.UserID) (*models.AppTransfer, error) {
+	transfer, err := ats.appTransferRepository.GetAppTransferByID(transferID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ats.acceptTransfer(transfer, userID)
+}
+
+// AcceptTransferByToken accepts the transfer of an e-mailed link. The token
+// alone is not enough: userID must still be the recipient.
+func (ats *AppTransferService) AcceptTransferByToken(token string, userID models.UserID) (*models.AppTransfer, error) {
+	tokenHash, err := ats.hashService.HashSHA256(token)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer, err := ats.appTransferRepository.GetAppTransferByTokenHash(tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return ats.acceptTransfer(transfer, userID)
+}
+
+// acceptTransfer lets the recipient user, or an owner or admin of the
+// recipient organization, take the app over.
+func (ats *AppTransferService) acceptTransfer(transfer *models.AppTransfer, userID models.UserID) (*models.AppTransfer, error) {
+	switch transfer.GetStatus(time.Now()) {
+	case models.AppTransferStatusExpired:
+		return nil, utils.ErrAppTransferExpired
+	case models.AppTransferStatusAccepted, models.AppTransferStatusCancelled:
+		return nil, utils.ErrAppTransferInvalid
+	}
+
+	if transfer.ToOrganizationID == nil && (transfer.ToUserID == nil || *transfer.ToUserID != userID) {
+		return nil, utils.ErrForbidden
+	}
+
+	if transfer.ToOrganizationID != nil {
+		member, err := ats.organizationRepository.GetOrganizationMember(*transfer.ToOrganizationID, userID)
+		if err != nil && !errors.Is(err, utils.ErrOrganizationMemberNotFound) {
+			log.Printf("acceptTransfer: error getting organization member: %s", err.Error())
+			return nil, err
+		}
+
+		if member == nil || !canManageMembers(member.Role) {
+			return nil, utils.ErrForbidden
+		}
+	}
+
+	err := ats.appRepository.TransferApp(transfer, userID)
+	if err != nil {
+		log.Printf("acceptTransfer: error transferring app: %s", err.Error())
+		return nil, err
+	}
+
+	now := time.Now()
+	transfer.AcceptedAt = &now
+	transfer.AcceptedBy = &userID
+	transfer.Status = transfer.GetStatus(now)
+
+	log.Printf("acceptTransfer: user %d accepted the transfer of app %d", userID, transfer.AppID)
+	return transfer, nil
+}
+
+func withAppTransferStatus(transfers []models.AppTransfer) []models.AppTransfer {
+	now := time.Now()
+	for i := range transfers {
+		transfers[i].Status = transfers[i].GetStatus(now)
+	}
+
+	return transfers
+}
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IAppUserPoolService interface {
+	GetUserPool(appID models.AppID) (*models.AppUserPool, error)
+	ConfigureUserPool(appID models.AppID, enabled bool) (*models.AppUserPool, error)
+	SignUp(clientID models.AppClientID, email string, password string) (*models.AppUser, error)
+	Login(clientID models.AppClientID, email string, password string) (*AppUserTokens, error)
+	Refresh(clientID models.AppClientID, refreshToken string) (*AppUserTokens, error)
+}
+
+// AppUserTokens is the token pair issued to an app user.
+type AppUserTokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// AppUserTokenClaims are the claims of app user tokens. The subject is the
+// app user ID and the audience is the client ID of the pool, so services of
+// the app validate tokens by checking the audience is their own client ID.
+type AppUserTokenClaims struct {
+	AppID models.AppID `json:"app"`
+	jwt.RegisteredClaims
+}
+
+type AppUserPoolService struct {
+	appUserPoolRepository repositories.AppUserPoolRepository
+	appUserRepository     repositories.AppUserRepository
+	hashService           IHashService
+	passwordPolicyService IPasswordPolicyService
+	tokenSecret           string
+	refreshTokenSecret    string
+	tokenTTL              time.Duration
+	refreshTokenTTL       time.Duration
+}
+
+func NewAppUserPoolService(
+	appUserPoolRepository repositories.AppUserPoolRepository,
+	appUserRepository repositories.AppUserRepository,
+	hashService IHashService,
+	passwordPolicyService IPasswordPolicyService,
+	tokenSecret, refreshTokenSecret string,
+	tokenTTL, refreshTokenTTL time.Duration,
+) IAppUserPoolService {
+	return &AppUserPoolService{
+		appUserPoolRepository: appUserPoolRepository,
+		appUserRepository:     appUserRepository,
+		hashService:           hashService,
+		passwordPolicyService: passwordPolicyService,
+		tokenSecret:           deriveSecret(tokenSecret, "app_user_access"),
+		refreshTokenSecret:    deriveSecret(refreshTokenSecret, "app_user_refresh"),
+		tokenTTL:              tokenTTL,
+		refreshTokenTTL:       refreshTokenTTL,
+	}
+}
+
+func (ups *AppUserPoolService) GetUserPool(appID models.AppID) (*models.AppUserPool, error) {
+	return ups.appUserPoolRepository.GetUserPoolByAppID(appID)
+}
+
+// ConfigureUserPool enables or disables the user pool of the app, creating
+// it with a new client ID the first time. Disabling a pool keeps its users,
+// but they cannot sign up, log in or refresh tokens until it is enabled again.
+func (ups *AppUserPoolService) ConfigureUserPool(appID models.AppID, enabled bool) (*models.AppUserPool, error) {
+	clientID, err := utils.GetRandomString(16)
+	if err != nil {
+		log.Printf("ConfigureUserPool: error generating client id: %s", err.Error())
+		return nil, err
+	}
+
+	pool := &models.AppUserPool{
+		AppID:    appID,
+		ClientID: clientID,
+		Enabled:  enabled,
+	}
+
+	err = ups.appUserPoolRepository.SaveUserPool(pool)
+	if err != nil {
+		log.Printf("ConfigureUserPool: error saving user pool: %s", err.Error())
+		return nil, err
+	}
+
+	log.Printf("ConfigureUserPool: user pool of app %d enabled: %t", appID, enabled)
+	return pool, nil
+}
+
+// SignUp registers an active user in the pool of clientID. Pool users do not
+// go through e-mail verification; apps needing it verify e-mails themselves.
+func (ups *AppUserPoolService) SignUp(clientID models.AppClientID, email string, password string) (*models.AppUser, error) {
+	pool, err := ups.appUserPoolRepository.GetUserPoolByClientID(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := models.NewUser(email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ups.passwordPolicyService.Validate(u.Password, u.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := ups.hashService.HashPassword(u.Password)
+	if err != nil {
+		log.Printf("SignUp: error hashing password: %s", err.Error())
+		return nil, err
+	}
+
+	user := &models.AppUser{
+		AppID:    pool.AppID,
+		Email:    u.Email,
+		Password: hashedPassword,
+		Status:   utils.UserStatusActive,
+	}
+
+	err = ups.appUserRepository.CreateAppUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("SignUp: user %d signed up to the pool of app %d", user.ID, pool.AppID)
+	return user, nil
+}
+
+// Login checks the credentials of a pool user and issues a token pair for
+// the pool. Unknown e-mails and wrong passwords get the same error and cost
+// the same hash comparison.
+func (ups *AppUserPoolService) Login(clientID models.AppClientID, email string, password string) (*AppUserTokens, error) {
+	pool, err := ups.appUserPoolRepository.GetUserPoolByClientID(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := models.NewUser(email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := ups.appUserRepository.GetAppUserByEmail(pool.AppID, u.Email)
+	if errors.Is(err, utils.ErrUserNotFound) {
+		ups.hashService.CompareDummyPassword(u.Password)
+		return nil, utils.ErrEmailPasswordIncorrect
+	}
+	if err != nil {
+		log.Printf("Login: error getting app user: %s", err.Error())
+		return nil, err
+	}
+
+	err = ups.hashService.ComparePassword(u.Password, user.Password)
+	if err != nil {
+		return nil, utils.ErrEmailPasswordIncorrect
+	}
+
+	if user.Status != utils.UserStatusActive {
+		return nil, utils.ErrUserInactive
+	}
+
+	err = ups.appUserRepository.UpdateAppUserLastLogin(user.ID, time.Now())
+	if err != nil {
+		log.Printf("Login: error recording login: %s", err.Error())
+	}
+
+	return ups.issueTokens(pool, user.ID)
+}
+
+// Refresh rotates a refresh token of the pool of clientID: the token is
+// invalidated and a new pair is issued.
+func (ups *AppUserPoolService) Refresh(clientID models.AppClientID, refreshToken string) (*AppUserTokens, error) {
+	pool, err := ups.appUserPoolRepository.GetUserPoolByClientID(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := ups.parseToken(refreshToken, ups.refreshTokenSecret, clientID)
+	if err != nil {
+		log.Printf("Refresh: error parsing refresh token: %s", err.Error())
+		return nil, utils.ErrRefreshTokenInvalid
+	}
+
+	if claims.AppID != pool.AppID {
+		return nil, utils.ErrRefreshTokenInvalid
+	}
+
+	hashToken, err := ups.hashService.HashSHA256(refreshToken)
+	if err != nil {
+		log.Printf("Refresh: error hashing refresh token: %s", err.Error())
+		return nil, err
+	}
+
+	storedToken, err := ups.appUserRepository.GetAppUserRefreshTokenByContent(hashToken)
+	if errors.Is(err, utils.ErrRefreshTokenNotFound) {
+		return nil, utils.ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if storedToken.Status != models.RefreshTokenStatusActive || strconv.Itoa(storedToken.AppUserID) != claims.Subject {
+		return nil, utils.ErrRefreshTokenInvalid
+	}
+
+	user, err := ups.appUserRepository.GetAppUserByID(pool.AppID, storedToken.AppUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Status != utils.UserStatusActive {
+		return nil, utils.ErrUserInactive
+	}
+
+	err = ups.appUserRepository.InvalidateAppUserRefreshTokenByContent(hashToken)
+	if err != nil {
+		log.Printf("Refresh: error invalidating refresh token: %s", err.Error())
+		return nil, err
+	}
+
+	return ups.issueTokens(pool, user.ID)
+}
+
+func (ups *AppUserPoolService) signToken(pool *models.AppUserPool, appUserID models.AppUserID, ttl time.Duration, secret string) (string, error) {
+	now := time.Now()
+
+	// A random token ID keeps tokens issued within the same second apart, so
+	// a rotated refresh token never comes back as its successor.
+	tokenID, err := utils.GetRandomString(16)
+	if err != nil {
+		return "", err
+	}
+
+	claims := &AppUserTokenClaims{
+		AppID: pool.AppID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Issuer:    "jwt_auth",
+			Subject:   strconv.Itoa(appUserID),
+			Audience:  jwt.ClaimStrings{pool.ClientID},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+func (ups *AppUserPoolService) issueTokens(pool *models.AppUserPool, appUserID models.AppUserID) (*AppUserTokens, error) {
+	accessToken, err := ups.signToken(pool, appUserID, ups.tokenTTL, ups.tokenSecret)
+	if err != nil {
+		log.Printf("issueTokens: error creating token: %s", err.Error())
+		return nil, err
+	}
+
+	refreshToken, err := ups.signToken(pool, appUserID, ups.refreshTokenTTL, ups.refreshTokenSecret)
+	if err != nil {
+		log.Printf("issueTokens: error creating refresh token: %s", err.Error())
+		return nil, err
+	}
+
+	hashToken, err := ups.hashService.HashSHA256(refreshToken)
+	if err != nil {
+		log.Printf("issueTokens: error hashing refresh token: %s", err.Error())
+		return nil, err
+	}
+
+	err = ups.appUserRepository.CreateAppUserRefreshToken(&models.AppUserRefreshToken{
+		Content:   hashToken,
+		Status:    models.RefreshTokenStatusActive,
+		AppUserID: appUserID,
+	})
+	if err != nil {
+		log.Printf("issueTokens: error creating refresh token in database: %s", err.Error())
+		return nil, err
+	}
+
+	log.Printf("issueTokens: tokens issued to user %d of app %d", appUserID, pool.AppID)
+	return &AppUserTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (ups *AppUserPoolService) parseToken(tokenString string, secret string, clientID models.AppClientID) (*AppUserTokenClaims, error) {
+	claims := AppUserTokenClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+
+		return []byte(secret), nil
+	}, jwt.WithAudience(clientID))
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, utils.ErrTokenInvalid
+	}
+
+	return &claims, nil
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IBreachedPasswordChecker tells whether a password appears in a list of
+// passwords exposed in data breaches. Lists are local files, so passwords
+// never leave the server.
+type IBreachedPasswordChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// NewBreachedPasswordChecker loads the list at path. A directory is read as
+// Have I Been Pwned range files: one file per 5 character SHA-1 prefix
+// (e.g. "21BD1" or "21BD1.txt") with "<suffix>:<count>" lines, as produced
+// by the HIBP downloader. A regular file is read as "<sha1>[:<count>]" lines
+// and kept in memory.
+func NewBreachedPasswordChecker(path string) (IBreachedPasswordChecker, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return &HIBPRangeDirectoryChecker{dir: path}, nil
+	}
+
+	checker, err := loadHIBPHashListChecker(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return checker, nil
+}
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// hashFromLine returns the hash part of a "<hash>:<count>" line.
+func hashFromLine(line string) string {
+	hash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+	return strings.ToUpper(hash)
+}
+
+type HIBPRangeDirectoryChecker struct {
+	dir string
+}
+
+func (c *HIBPRangeDirectoryChecker) openRange(prefix string) (*os.File, error) {
+	f, err := os.Open(filepath.Join(c.dir, prefix+".txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		f, err = os.Open(filepath.Join(c.dir, prefix))
+	}
+	return f, err
+}
+
+func (c *HIBPRangeDirectoryChecker) IsBreached(password string) (bool, error) {
+	hash := sha1Hex(password)
+	prefix, suffix := hash[:5], hash[5:]
+
+	f, err := c.openRange(prefix)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if hashFromLine(scanner.Text()) == suffix {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+type HIBPHashListChecker struct {
+	hashes map[string]struct{}
+}
+
+func loadHIBPHashListChecker(path string) (*HIBPHashListChecker, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hashes := map[string]struct{}{}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		hash := hashFromLine(scanner.Text())
+		if len(hash) == sha1.Size*2 {
+			hashes[hash] = struct{}{}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("loaded %d breached password hashes", len(hashes))
+	return &HIBPHashListChecker{hashes: hashes}, nil
+}
+
+func (c *HIBPHashListChecker) IsBreached(password string) (bool, error) {
+	_, found := c.hashes[sha1Hex(password)]
+	return found, nil
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IDataExportService interface {
+	CreateExport(userID models.UserID) (token string, expiresAt time.Time, err error)
+	GetExport(token string) (*models.DataExport, error)
+}
+
+type DataExportService struct {
+	dataExportRepository             repositories.DataExportRepository
+	userRepository                   repositories.UserRepository
+	appRepository                    repositories.AppRepository
+	refreshTokenRepository           repositories.RefreshTokenRepository
+	emailVerificationTokenRepository repositories.EmailVerificationTokenRepository
+	loginEventRepository             repositories.LoginEventRepository
+	totpCredentialRepository         repositories.TOTPCredentialRepository
+	recoveryCodeRepository           repositories.MFARecoveryCodeRepository
+	webAuthnCredentialRepository     repositories.WebAuthnCredentialRepository
+	organizationRepository           repositories.OrganizationRepository
+	invitationRepository             repositories.InvitationRepository
+	appMemberRepository              repositories.AppMemberRepository
+	hashService                      IHashService
+	linkTTL                          time.Duration
+}
+
+func NewDataExportService(
+	dataExportRepository repositories.DataExportRepository,
+	userRepository repositories.UserRepository,
+	appRepository repositories.AppRepository,
+	refreshTokenRepository repositories.RefreshTokenRepository,
+	emailVerificationTokenRepository repositories.EmailVerificationTokenRepository,
+	loginEventRepository repositories.LoginEventRepository,
+	totpCredentialRepository repositories.TOTPCredentialRepository,
+	recoveryCodeRepository repositories.MFARecoveryCodeRepository,
+	webAuthnCredentialRepository repositories.WebAuthnCredentialRepository,
+	organizationRepository repositories.OrganizationRepository,
+	invitationRepository repositories.InvitationRepository,
+	appMemberRepository repositories.AppMemberRepository,
+	hashService IHashService,
+	linkTTL time.Duration,
+) IDataExportService {
+	return &DataExportService{
+		dataExportRepository:             dataExportRepository,
+		userRepository:                   userRepository,
+		appRepository:                    appRepository,
+		refreshTokenRepository:           refreshTokenRepository,
+		emailVerificationTokenRepository: emailVerificationTokenRepository,
+		loginEventRepository:             loginEventRepository,
+		totpCredentialRepository:         totpCredentialRepository,
+		recoveryCodeRepository:           recoveryCodeRepository,
+		webAuthnCredentialRepository:     webAuthnCredentialRepository,
+		organizationRepository:           organizationRepository,
+		invitationRepository:             invitationRepository,
+		appMemberRepository:              appMemberRepository,
+		hashService:                      hashService,
+		linkTTL:                          linkTTL,
+	}
+}
+
+type exportSession struct {
+	ID        models.RefreshTokenID     `json:"id"`
+	Status    models.RefreshTokenStatus `json:"status"`
+	CreatedAt time.Time                 `json:"created_at"`
+}
+
+type exportEmailVerification struct {
+	ID        models.EmailVerificationTokenID `json:"id"`
+	CreatedAt time.Time                       `json:"created_at"`
+	ExpiresAt time.Time                       `json:"expires_at"`
+	IsUsed    bool                            `json:"is_used"`
+}
+
+type exportMFA struct {
+	TOTPEnabled         bool       `json:"totp_enabled"`
+	TOTPCreatedAt       *time.Time `json:"totp_created_at,omitempty"`
+	TOTPConfirmedAt     *time.Time `json:"totp_confirmed_at,omitempty"`
+	UnusedRecoveryCodes int        `json:"unused_recovery_codes"`
+}
+
+// buildArchive collects everything we store about the user into a zip
+// archive with one JSON document per data set. Secrets such as the password
+// hash and token contents are never included.
+func (des *DataExportService) buildArchive(userID models.UserID) ([]byte, error) {
+	user, err := des.userRepository.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	apps, err := des.appRepository.GetAllAppsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshTokens, err := des.refreshTokenRepository.GetRefreshTokensByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]exportSession, 0, len(refreshTokens))
+	for _, rt := range refreshTokens {
+		sessions = append(sessions, exportSession{
+			ID:        rt.ID,
+			Status:    rt.Status,
+			CreatedAt: rt.CreatedAt,
+		})
+	}
+
+	evTokens, err := des.emailVerificationTokenRepository.GetVerificationTokensByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	verifications := make([]exportEmailVerification, 0, len(evTokens))
+	for _, evt := range evTokens {
+		verifications = append(verifications, exportEmailVerification{
+			ID:        evt.ID,
+			CreatedAt: evt.CreatedAt,
+			ExpiresAt: evt.ExpiresAt,
+			IsUsed:    evt.IsUsed,
+		})
+	}
+
+	loginEventCount, err := des.loginEventRepository.CountLoginEventsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	loginEvents, err := des.loginEventRepository.GetLoginEventsByUserID(userID, loginEventCount, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	mfa, err := des.buildMFAExport(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	webAuthnCredentials, err := des.webAuthnCredentialRepository.GetWebAuthnCredentialsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	organizations, err := des.organizationRepository.GetOrganizationsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations, err := des.invitationRepository.GetInvitationsByUser(userID, user.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range invitations {
+		invitations[i].Status = invitations[i].GetStatus(now)
+	}
+
+	appMemberships, err := des.appMemberRepository.GetAppMembershipsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"user.json", user},
+		{"apps.json", apps},
+		{"sessions.json", sessions},
+		{"email_verifications.json", verifications},
+		{"login_events.json", loginEvents},
+		{"mfa.json", mfa},
+		{"webauthn_credentials.json", webAuthnCredentials},
+		{"organizations.json", organizations},
+		{"invitations.json", invitations},
+		{"app_memberships.json", appMemberships},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for _, file := range files {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		err = encoder.Encode(file.data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = zw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// buildMFAExport describes the user's second factors without their secrets.
+func (des *DataExportService) buildMFAExport(userID models.UserID) (*exportMFA, error) {
+	var mfa exportMFA
+
+	credential, err := des.totpCredentialRepository.GetTOTPCredentialByUserID(userID)
+	if err != nil && !errors.Is(err, utils.ErrMFANotEnabled) {
+		return nil, err
+	}
+
+	if credential != nil {
+		mfa.TOTPEnabled = credential.IsConfirmed()
+		mfa.TOTPCreatedAt = &credential.CreatedAt
+		mfa.TOTPConfirmedAt = credential.ConfirmedAt
+	}
+
+	mfa.UnusedRecoveryCodes, err = des.recoveryCodeRepository.CountUnusedRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mfa, nil
+}
+
+func (des *DataExportService) CreateExport(userID models.UserID) (string, time.Time, error) {
+	err := des.dataExportRepository.DeleteExpiredDataExports(time.Now())
+	if err != nil {
+		log.Printf("CreateExport: error deleting expired exports: %s", err.Error())
+	}
+
+	archive, err := des.buildArchive(userID)
+	if err != nil {
+		log.Printf("CreateExport: error building archive: %s", err.Error())
+		return "", time.Time{}, err
+	}
+
+	token, err := utils.GetRandomString(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	tokenHash, err := des.hashService.HashSHA256(token)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	export := &models.DataExport{
+		Content:   tokenHash,
+		UserID:    userID,
+		Archive:   archive,
+		ExpiresAt: time.Now().Add(des.linkTTL),
+	}
+
+	err = des.dataExportRepository.CreateDataExport(export)
+	if err != nil {
+		log.Printf("CreateExport: error saving export: %s", err.Error())
+		return "", time.Time{}, err
+	}
+
+	log.Printf("CreateExport: export created for user %d", userID)
+	return token, export.ExpiresAt, nil
+}
+
+func (des *DataExportService) GetExport(token string) (*models.DataExport, error) {
+	tokenHash, err := des.hashService.HashSHA256(token)
+	if err != nil {
+		return nil, err
+	}
+
+	export, err := des.dataExportRepository.GetDataExportByContent(tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(export.ExpiresAt) {
+		return nil, utils.ErrDataExportExpired
+	}
+
+	return export, nil
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+const emailOTPDigits = 6
+
+type IEmailOTPService interface {
+	RequestCode(email string) (user *models.User, code string, expiresAt time.Time, err error)
+	VerifyCode(email string, code string) (*models.User, error)
+}
+
+type EmailOTPService struct {
+	emailOTPCodeRepository repositories.EmailOTPCodeRepository
+	userRepository         repositories.UserRepository
+	hashKey                string
+	codeTTL                time.Duration
+	maxAttempts            int
+}
+
+func NewEmailOTPService(
+	emailOTPCodeRepository repositories.EmailOTPCodeRepository,
+	userRepository repositories.UserRepository,
+	tokenSecret string,
+	codeTTL time.Duration,
+	maxAttempts int,
+) IEmailOTPService {
+	return &EmailOTPService{
+		emailOTPCodeRepository: emailOTPCodeRepository,
+		userRepository:         userRepository,
+		hashKey:                deriveSecret(tokenSecret, "email_otp"),
+		codeTTL:                codeTTL,
+		maxAttempts:            maxAttempts,
+	}
+}
+
+// hashCode keys the hash with a server secret: a plain SHA256 of a six digit
+// code could be reversed by trying all one million values.
+func (es *EmailOTPService) hashCode(userID models.UserID, code string) string {
+	mac := hmac.New(sha256.New, []byte(es.hashKey))
+	mac.Write([]byte(fmt.Sprintf("%d:%s", userID, code)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (es *EmailOTPService) RequestCode(email string) (*models.User, string, time.Time, error) {
+	user, err := es.userRepository.GetUserByEmail(email)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+
+	if user.Status == utils.UserStatusInactive {
+		return nil, "", time.Time{}, utils.ErrUserInactive
+	}
+
+	code, err := utils.GetRandomDigits(emailOTPDigits)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+
+	otp := &models.EmailOTPCode{
+		UserID:    user.ID,
+		CodeHash:  es.hashCode(user.ID, code),
+		ExpiresAt: time.Now().Add(es.codeTTL),
+	}
+
+	err = es.emailOTPCodeRepository.CreateEmailOTPCode(otp)
+	if err != nil {
+		log.Printf("RequestCode: error saving code: %s", err.Error())
+		return nil, "", time.Time{}, err
+	}
+
+	log.Printf("RequestCode: email otp created for user %d", user.ID)
+	return user, code, otp.ExpiresAt, nil
+}
+
+// VerifyCode checks code against the latest code sent to email. Unknown
+// addresses fail with the same ErrEmailOTPInvalid as wrong codes. Pending
+// users are activated, since the code proves they own the address.
+func (es *EmailOTPService) VerifyCode(email string, code string) (*models.User, error) {
+	user, err := es.userRepository.GetUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, utils.ErrUserNotFound) {
+			return nil, utils.ErrEmailOTPInvalid
+		}
+		return nil, err
+	}
+
+	otp, err := es.emailOTPCodeRepository.GetActiveEmailOTPCodeByUserID(user.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	err = es.emailOTPCodeRepository.IncrementEmailOTPAttempts(otp.ID, es.maxAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal([]byte(es.hashCode(user.ID, code)), []byte(otp.CodeHash)) {
+		return nil, utils.ErrEmailOTPInvalid
+	}
+
+	err = es.emailOTPCodeRepository.UseEmailOTPCode(otp.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Status == utils.UserStatusPending {
+		err = es.userRepository.ActivateUser(user.ID)
+		if err != nil {
+			log.Printf("VerifyCode: error activating user: %s", err.Error())
+			return nil, err
+		}
+
+		user.Status = utils.UserStatusActive
+		log.Printf("VerifyCode: user %d activated", user.ID)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+)
+
+type IEncryptionService interface {
+	Encrypt(plainText string) (cipherText string, err error)
+	Decrypt(cipherText string) (plainText string, err error)
+}
+
+// AESGCMEncryptionService encrypts small secrets (e.g. TOTP seeds) before
+// they are stored. The output is base64(nonce || ciphertext).
+type AESGCMEncryptionService struct {
+	aead cipher.AEAD
+}
+
+func NewAESGCMEncryptionService(key []byte) (IEncryptionService, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AESGCMEncryptionService{
+		aead: aead,
+	}, nil
+}
+
+func (es *AESGCMEncryptionService) Encrypt(plainText string) (string, error) {
+	nonce := make([]byte, es.aead.NonceSize())
+	_, err := io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return "", err
+	}
+
+	sealed := es.aead.Seal(nonce, nonce, []byte(plainText), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (es *AESGCMEncryptionService) Decrypt(cipherText string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	nonceSize := es.aead.NonceSize()
+	if len(data) < nonceSize {
+		return "", errors.New("cipher text too short")
+	}
+
+	plain, err := es.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plain), nil
+}
@@ -1,29 +1,72 @@
 package services
 
 import (
+	"crypto/hmac"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"log"
+	"strings"
+	"sync"
 
 	"github.com/alexedwards/argon2id"
 	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type IHashService interface {
 	HashArgon2id(text string) (hash string, err error)
 	CompareArgon2id(text string, hashedText string) error
+	HashPassword(password string) (hash string, err error)
+	ComparePassword(password string, hashedPassword string) error
+	CompareDummyPassword(password string)
+	NeedsRehash(hashedPassword string) bool
 	HashSHA256(text string) (hash string, err error)
 }
 
+// pepperPrefix marks a password hash whose input was peppered; the pepper
+// version follows it up to the next "$", then the Argon2id hash itself.
+const pepperPrefix = "$pepper$v="
+
+// PasswordPepper is a server-side secret mixed into passwords before hashing.
+// Version is stored with every hash so old peppers keep verifying after a
+// rotation.
+type PasswordPepper struct {
+	Version string
+	Secret  []byte
+}
+
 type HashService struct {
+	params  *argon2id.Params
+	peppers map[string][]byte
+	current string
+
+	dummyHashOnce sync.Once
+	dummyHash     string
 }
 
-func NewHashService() IHashService {
-	return &HashService{}
+// NewHashService creates a hash service using params for new Argon2id hashes.
+// The first pepper, if any, is used for new password hashes; the rest are only
+// accepted for verification.
+func NewHashService(params *argon2id.Params, peppers ...PasswordPepper) IHashService {
+	hs := &HashService{
+		params:  params,
+		peppers: make(map[string][]byte, len(peppers)),
+	}
+
+	for i, pepper := range peppers {
+		if i == 0 {
+			hs.current = pepper.Version
+		}
+		hs.peppers[pepper.Version] = pepper.Secret
+	}
+
+	return hs
 }
 
 func (hs *HashService) HashArgon2id(text string) (hash string, err error) {
-	hash, err = argon2id.CreateHash(text, argon2id.DefaultParams)
+	hash, err = argon2id.CreateHash(text, hs.params)
 	if err != nil {
 		log.Printf("Hash: error creating hash: %s", err.Error())
 		return "", err
@@ -47,6 +90,101 @@ func (hs *HashService) CompareArgon2id(text string, hashedText string) error {
 	return nil
 }
 
+// HashPassword hashes a user password with Argon2id, mixing in the current
+// pepper when one is configured.
+func (hs *HashService) HashPassword(password string) (hash string, err error) {
+	if hs.current == "" {
+		return hs.HashArgon2id(password)
+	}
+
+	hash, err = hs.HashArgon2id(pepperPassword(hs.peppers[hs.current], password))
+	if err != nil {
+		return "", err
+	}
+
+	return pepperPrefix + hs.current + hash, nil
+}
+
+// ComparePassword verifies a stored password hash, accepting peppered and
+// plain Argon2id hashes as well as legacy bcrypt hashes from migrated users.
+func (hs *HashService) ComparePassword(password string, hashedPassword string) error {
+	if version, hash, ok := splitPepperedHash(hashedPassword); ok {
+		secret, found := hs.peppers[version]
+		if !found {
+			log.Printf("ComparePassword: pepper version %q not configured", version)
+			return utils.ErrPasswordPepperUnknown
+		}
+
+		return hs.CompareArgon2id(pepperPassword(secret, password), hash)
+	}
+
+	if !isBcryptHash(hashedPassword) {
+		return hs.CompareArgon2id(password, hashedPassword)
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		log.Printf("ComparePassword: password do not match with bcrypt hash")
+		return utils.ErrPasswordsNotMatch
+	}
+	if err != nil {
+		log.Printf("ComparePassword: error comparing bcrypt hash: %s", err.Error())
+		return err
+	}
+
+	log.Printf("ComparePassword: password and bcrypt hash match")
+	return nil
+}
+
+// CompareDummyPassword runs a password comparison against a throwaway hash
+// made with the current parameters and pepper, so requests for unknown
+// accounts cost the same as requests for real ones.
+func (hs *HashService) CompareDummyPassword(password string) {
+	hs.dummyHashOnce.Do(func() {
+		hash, err := hs.HashPassword("dummy-password")
+		if err != nil {
+			log.Printf("CompareDummyPassword: error creating dummy hash: %s", err.Error())
+			return
+		}
+		hs.dummyHash = hash
+	})
+
+	if hs.dummyHash == "" {
+		return
+	}
+
+	_ = hs.ComparePassword(password, hs.dummyHash)
+}
+
+// NeedsRehash reports whether a stored hash was produced by a legacy
+// algorithm, with a pepper other than the current one, or with Argon2id
+// parameters weaker than the current ones.
+func (hs *HashService) NeedsRehash(hashedPassword string) bool {
+	if isBcryptHash(hashedPassword) {
+		return true
+	}
+
+	version, hash, ok := splitPepperedHash(hashedPassword)
+	if version != hs.current {
+		return true
+	}
+	if ok {
+		hashedPassword = hash
+	}
+
+	params, _, _, err := argon2id.DecodeHash(hashedPassword)
+	if err != nil {
+		log.Printf("NeedsRehash: error decoding hash: %s", err.Error())
+		return false
+	}
+
+	return params.Memory < hs.params.Memory ||
+		params.Iterations < hs.params.Iterations ||
+		params.Parallelism < hs.params.Parallelism ||
+		params.SaltLength < hs.params.SaltLength ||
+		params.KeyLength < hs.params.KeyLength
+}
+
 func (hs *HashService) HashSHA256(text string) (hash string, err error) {
 	h := sha256.New()
 	h.Write([]byte(text))
@@ -56,3 +194,30 @@ func (hs *HashService) HashSHA256(text string) (hash string, err error) {
 
 	return hashString, nil
 }
+
+func pepperPassword(secret []byte, password string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(password))
+
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func splitPepperedHash(hash string) (version string, argon2idHash string, ok bool) {
+	rest, found := strings.CutPrefix(hash, pepperPrefix)
+	if !found {
+		return "", hash, false
+	}
+
+	i := strings.Index(rest, "$")
+	if i <= 0 {
+		return "", hash, false
+	}
+
+	return rest[:i], rest[i:], true
+}
+
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") ||
+		strings.HasPrefix(hash, "$2b$") ||
+		strings.HasPrefix(hash, "$2y$")
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IImpersonationService interface {
+	Impersonate(actorID models.UserID, targetID models.UserID, reason string, ipAddress string, userAgent string) (*Impersonation, error)
+	GetImpersonations(targetID models.UserID, page int, pageSize int) (events []models.ImpersonationEvent, total int, err error)
+}
+
+// Impersonation is returned by Impersonate. Token is an access token for
+// User that expires at ExpiresAt and cannot be refreshed.
+type Impersonation struct {
+	Token     string
+	ExpiresAt time.Time
+	User      *models.User
+}
+
+const maxImpersonationReasonLength = 500
+
+type ImpersonationService struct {
+	impersonationEventRepository repositories.ImpersonationEventRepository
+	userRepository               repositories.UserRepository
+	jwtService                   IJWTService
+	tokenTTL                     time.Duration
+}
+
+func NewImpersonationService(
+	impersonationEventRepository repositories.ImpersonationEventRepository,
+	userRepository repositories.UserRepository,
+	jwtService IJWTService,
+	tokenTTL time.Duration,
+) IImpersonationService {
+	return &ImpersonationService{
+		impersonationEventRepository: impersonationEventRepository,
+		userRepository:               userRepository,
+		jwtService:                   jwtService,
+		tokenTTL:                     tokenTTL,
+	}
+}
+
+// Impersonate issues an access token for targetID on behalf of actorID. The
+// impersonation is recorded before the token is handed out, so no token
+// exists without an audit entry.
+func (is *ImpersonationService) Impersonate(actorID models.UserID, targetID models.UserID, reason string, ipAddress string, userAgent string) (*Impersonation, error) {
+	if actorID == targetID {
+		return nil, utils.ErrImpersonationSelf
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" || len(reason) > maxImpersonationReasonLength {
+		return nil, utils.ErrImpersonationReasonInvalid
+	}
+
+	user, err := is.userRepository.GetUserByID(targetID)
+	if err != nil {
+		log.Printf("Impersonate: error getting user: %s", err.Error())
+		return nil, err
+	}
+
+	if user.Status != utils.UserStatusActive {
+		return nil, utils.ErrUserInactive
+	}
+
+	actor, err := is.userRepository.GetUserByID(actorID)
+	if err != nil {
+		log.Printf("Impersonate: error getting actor: %s", err.Error())
+		return nil, err
+	}
+
+	token, expiresAt, err := is.jwtService.GenerateImpersonationToken(targetID, actorID, is.tokenTTL)
+	if err != nil {
+		log.Printf("Impersonate: error generating token: %s", err.Error())
+		return nil, err
+	}
+
+	err = is.impersonationEventRepository.CreateImpersonationEvent(&models.ImpersonationEvent{
+		ActorUserID:  &actorID,
+		TargetUserID: &targetID,
+		ActorEmail:   actor.Email,
+		TargetEmail:  user.Email,
+		Reason:       reason,
+		IPAddress:    ipAddress,
+		UserAgent:    userAgent,
+		CreatedAt:    time.Now(),
+		ExpiresAt:    expiresAt,
+	})
+	if err != nil {
+		log.Printf("Impersonate: error recording impersonation: %s", err.Error())
+		return nil, err
+	}
+
+	log.Printf("Impersonate: user %d started impersonating user %d until %s", actorID, targetID, expiresAt.UTC().Format(time.RFC3339))
+	return &Impersonation{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User:      user,
+	}, nil
+}
+
+// GetImpersonations returns the page-th page (starting at 1) of the
+// impersonations of the user, newest first, and their total number.
+func (is *ImpersonationService) GetImpersonations(targetID models.UserID, page int, pageSize int) ([]models.ImpersonationEvent, int, error) {
+	total, err := is.impersonationEventRepository.CountImpersonationEventsByTargetUserID(targetID)
+	if err != nil {
+		log.Printf("GetImpersonations: error counting impersonation events: %s", err.Error())
+		return nil, 0, err
+	}
+
+	events, err := is.impersonationEventRepository.GetImpersonationEventsByTargetUserID(targetID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		log.Printf("GetImpersonations: error getting impersonation events: %s", err.Error())
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
@@ -0,0 +1,330 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+	"github.com/pedrotunin/go-jwt-auth/internal/validators"
+)
+
+type IInvitationService interface {
+	CreateInvitation(inviterID models.UserID, email string, roleName string) (*InvitationToken, error)
+	CreateOrganizationInvitation(inviterID models.UserID, email string, organizationID models.OrganizationID, role models.OrganizationRole) (*InvitationToken, error)
+	CreateAppInvitation(inviterID models.UserID, email string, appID models.AppID, role models.AppRole) (*InvitationToken, error)
+	ListInvitations(page int, pageSize int) (invitations []models.Invitation, total int, err error)
+	RevokeInvitation(id models.InvitationID) error
+	AcceptInvitation(token string, password string) (*models.User, error)
+	JoinWithInvitation(token string, userID models.UserID) (*models.Invitation, error)
+}
+
+// InvitationToken is a freshly created invitation and the token of its link,
+// meant to be e-mailed to the invitee.
+type InvitationToken struct {
+	Invitation *models.Invitation
+	Token      string
+}
+
+type InvitationService struct {
+	invitationRepository   repositories.InvitationRepository
+	userRepository         repositories.UserRepository
+	rbacRepository         repositories.RBACRepository
+	organizationRepository repositories.OrganizationRepository
+	appMemberRepository    repositories.AppMemberRepository
+	hashService            IHashService
+	passwordPolicyService  IPasswordPolicyService
+	invitationTTL          time.Duration
+}
+
+func NewInvitationService(
+	invitationRepository repositories.InvitationRepository,
+	userRepository repositories.UserRepository,
+	rbacRepository repositories.RBACRepository,
+	organizationRepository repositories.OrganizationRepository,
+	appMemberRepository repositories.AppMemberRepository,
+	hashService IHashService,
+	passwordPolicyService IPasswordPolicyService,
+	invitationTTL time.Duration,
+) IInvitationService {
+	return &InvitationService{
+		invitationRepository:   invitationRepository,
+		userRepository:         userRepository,
+		rbacRepository:         rbacRepository,
+		organizationRepository: organizationRepository,
+		appMemberRepository:    appMemberRepository,
+		hashService:            hashService,
+		passwordPolicyService:  passwordPolicyService,
+		invitationTTL:          invitationTTL,
+	}
+}
+
+// CreateInvitation invites email to create an account. When roleName is set
+// the role is granted on acceptance. Inviting an e-mail again replaces its
+// pending invitation.
+func (is *InvitationService) CreateInvitation(inviterID models.UserID, email string, roleName string) (*InvitationToken, error) {
+	if roleName != "" {
+		_, err := is.rbacRepository.GetRoleByName(roleName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return is.createInvitation(&models.Invitation{
+		Email:     email,
+		InvitedBy: inviterID,
+		RoleName:  roleName,
+	})
+}
+
+// CreateOrganizationInvitation invites email to join the organization with
+// role. Registered users accept with JoinWithInvitation, anyone else creates
+// their account with AcceptInvitation. Both get the same invitation, so the
+// caller cannot tell whether the e-mail has an account. Permission checks are
+// up to the caller.
+func (is *InvitationService) CreateOrganizationInvitation(inviterID models.UserID, email string, organizationID models.OrganizationID, role models.OrganizationRole) (*InvitationToken, error) {
+	return is.createInvitation(&models.Invitation{
+		Email:            email,
+		InvitedBy:        inviterID,
+		OrganizationID:   &organizationID,
+		OrganizationRole: role,
+	})
+}
+
+// CreateAppInvitation invites email to collaborate on the app with role,
+// the same way CreateOrganizationInvitation does for organizations.
+func (is *InvitationService) CreateAppInvitation(inviterID models.UserID, email string, appID models.AppID, role models.AppRole) (*InvitationToken, error) {
+	return is.createInvitation(&models.Invitation{
+		Email:     email,
+		InvitedBy: inviterID,
+		AppID:     &appID,
+		AppRole:   role,
+	})
+}
+
+func (is *InvitationService) createInvitation(invitation *models.Invitation) (*InvitationToken, error) {
+	invitation.Email = strings.TrimSpace(invitation.Email)
+
+	err := validators.IsValidEmail(invitation.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	if invitation.OrganizationID == nil && invitation.AppID == nil {
+		_, err = is.userRepository.GetUserByEmail(invitation.Email)
+		if err == nil {
+			return nil, utils.ErrUserEmailAlreadyExists
+		}
+		if !errors.Is(err, utils.ErrUserNotFound) {
+			log.Printf("createInvitation: error getting user: %s", err.Error())
+			return nil, err
+		}
+	}
+
+	value, err := utils.GetRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation.TokenHash, err = is.hashService.HashSHA256(value)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation.ExpiresAt = time.Now().Add(is.invitationTTL)
+
+	err = is.invitationRepository.CreateInvitation(invitation)
+	if err != nil {
+		log.Printf("createInvitation: error saving invitation: %s", err.Error())
+		return nil, err
+	}
+
+	invitation.Status = invitation.GetStatus(time.Now())
+
+	log.Printf("createInvitation: user %d invited a new user", invitation.InvitedBy)
+	return &InvitationToken{
+		Invitation: invitation,
+		Token:      value,
+	}, nil
+}
+
+// ListInvitations returns the page-th page (starting at 1) of invitations,
+// newest first, and the total number of invitations.
+func (is *InvitationService) ListInvitations(page int, pageSize int) ([]models.Invitation, int, error) {
+	total, err := is.invitationRepository.CountInvitations()
+	if err != nil {
+		log.Printf("ListInvitations: error counting invitations: %s", err.Error())
+		return nil, 0, err
+	}
+
+	invitations, err := is.invitationRepository.GetInvitations(pageSize, (page-1)*pageSize)
+	if err != nil {
+		log.Printf("ListInvitations: error getting invitations: %s", err.Error())
+		return nil, 0, err
+	}
+
+	now := time.Now()
+	for i := range invitations {
+		invitations[i].Status = invitations[i].GetStatus(now)
+	}
+
+	return invitations, total, nil
+}
+
+func (is *InvitationService) RevokeInvitation(id models.InvitationID) error {
+	return is.invitationRepository.RevokeInvitation(id)
+}
+
+// getPendingInvitation returns the invitation of token when it can still be
+// accepted.
+func (is *InvitationService) getPendingInvitation(token string) (*models.Invitation, error) {
+	tokenHash, err := is.hashService.HashSHA256(token)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := is.invitationRepository.GetInvitationByTokenHash(tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	switch invitation.GetStatus(time.Now()) {
+	case models.InvitationStatusPending:
+	case models.InvitationStatusExpired:
+		return nil, utils.ErrInvitationExpired
+	default:
+		return nil, utils.ErrInvitationInvalid
+	}
+
+	return invitation, nil
+}
+
+// AcceptInvitation creates an active account for the invitee with password,
+// which must meet the password policy. The e-mail was proven by the link, so
+// no verification e-mail is needed.
+func (is *InvitationService) AcceptInvitation(token string, password string) (*models.User, error) {
+	invitation, err := is.getPendingInvitation(token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := models.NewUser(invitation.Email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	err = is.passwordPolicyService.Validate(user.Password, user.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Password, err = is.hashService.HashPassword(user.Password)
+	if err != nil {
+		log.Printf("AcceptInvitation: error hashing password: %s", err.Error())
+		return nil, err
+	}
+
+	// The unique e-mail makes a second acceptance of the same invitation fail
+	// here, before anything else is written.
+	user.ID, err = is.userRepository.CreateUser(user)
+	if err != nil {
+		log.Printf("AcceptInvitation: error creating user: %s", err.Error())
+		return nil, err
+	}
+
+	err = is.userRepository.ActivateUser(user.ID)
+	if err != nil {
+		log.Printf("AcceptInvitation: error activating user: %s", err.Error())
+		return nil, err
+	}
+	user.Status = utils.UserStatusActive
+
+	if invitation.RoleName != "" {
+		role, err := is.rbacRepository.GetRoleByName(invitation.RoleName)
+		if err == nil {
+			err = is.rbacRepository.AssignUserRole(user.ID, role.ID)
+		}
+		if err != nil {
+			// The account exists at this point; a role deleted after the
+			// invitation was sent must not make the invitee start over.
+			log.Printf("AcceptInvitation: error assigning role %s: %s", invitation.RoleName, err.Error())
+		}
+	}
+
+	err = is.join(invitation, user.ID)
+	if err != nil {
+		// As with roles, the account is kept when the organization or app
+		// is gone.
+		log.Printf("AcceptInvitation: error joining: %s", err.Error())
+	}
+
+	err = is.invitationRepository.AcceptInvitation(invitation.ID, user.ID)
+	if err != nil {
+		log.Printf("AcceptInvitation: error marking invitation as accepted: %s", err.Error())
+		return nil, err
+	}
+
+	log.Printf("AcceptInvitation: invitation %d accepted by user %d", invitation.ID, user.ID)
+	return user, nil
+}
+
+// join makes userID a member of the organization or app the invitation is
+// for, if any.
+func (is *InvitationService) join(invitation *models.Invitation, userID models.UserID) error {
+	if invitation.OrganizationID != nil {
+		return is.organizationRepository.AddOrganizationMember(*invitation.OrganizationID, userID, invitation.OrganizationRole)
+	}
+
+	if invitation.AppID != nil {
+		return is.appMemberRepository.AddAppMember(*invitation.AppID, userID, invitation.AppRole)
+	}
+
+	return nil
+}
+
+// JoinWithInvitation makes the registered user userID a member of the
+// organization or app of the invitation. The token alone is not enough: the
+// invitation must have been sent to the user's e-mail.
+func (is *InvitationService) JoinWithInvitation(token string, userID models.UserID) (*models.Invitation, error) {
+	invitation, err := is.getPendingInvitation(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if invitation.OrganizationID == nil && invitation.AppID == nil {
+		return nil, utils.ErrInvitationInvalid
+	}
+
+	user, err := is.userRepository.GetUserByID(userID)
+	if err != nil {
+		log.Printf("JoinWithInvitation: error getting user: %s", err.Error())
+		return nil, err
+	}
+
+	if !strings.EqualFold(user.Email, invitation.Email) {
+		return nil, utils.ErrForbidden
+	}
+
+	err = is.join(invitation, userID)
+	if err != nil {
+		log.Printf("JoinWithInvitation: error joining: %s", err.Error())
+		return nil, err
+	}
+
+	err = is.invitationRepository.AcceptInvitation(invitation.ID, userID)
+	if err != nil {
+		log.Printf("JoinWithInvitation: error marking invitation as accepted: %s", err.Error())
+		return nil, err
+	}
+
+	now := time.Now()
+	invitation.AcceptedAt = &now
+	invitation.UserID = &userID
+	invitation.Status = invitation.GetStatus(now)
+
+	log.Printf("JoinWithInvitation: invitation %d accepted by user %d", invitation.ID, userID)
+	return invitation, nil
+}
@@ -1,6 +1,9 @@
 package services
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"time"
@@ -18,34 +21,110 @@ type IJWTService interface {
 	ValidateRefreshToken(tokenString string) (*RefreshTokenClaims, error)
 	InvalidateRefreshToken(tokenString string) error
 	InvalidateRefreshTokensByUserID(userID models.UserID) error
+	GenerateMFAToken(userID models.UserID) (tokenString string, err error)
+	GenerateImpersonationToken(userID models.UserID, actorID models.UserID, ttl time.Duration) (tokenString string, expiresAt time.Time, err error)
+	ValidateMFAToken(tokenString string) (*MFATokenClaims, error)
 }
 
 type JWTService struct {
 	tokenSecret            string
 	refreshTokenSecret     string
+	mfaTokenSecret         string
+	tokenTTL               time.Duration
+	refreshTokenTTL        time.Duration
 	refreshTokenRepository repositories.RefreshTokenRepository
 	hashService            IHashService
+	rbacService            IRBACService
+	organizationService    IOrganizationService
 }
 
-func NewJWTService(tokenSecret, refreshTokenSecret string, repo repositories.RefreshTokenRepository, hashService IHashService) IJWTService {
+func NewJWTService(
+	tokenSecret, refreshTokenSecret string,
+	tokenTTL, refreshTokenTTL time.Duration,
+	repo repositories.RefreshTokenRepository,
+	hashService IHashService,
+	rbacService IRBACService,
+	organizationService IOrganizationService,
+) IJWTService {
 	return &JWTService{
 		tokenSecret:            tokenSecret,
 		refreshTokenSecret:     refreshTokenSecret,
+		mfaTokenSecret:         deriveSecret(tokenSecret, "mfa_challenge"),
+		tokenTTL:               tokenTTL,
+		refreshTokenTTL:        refreshTokenTTL,
 		refreshTokenRepository: repo,
 		hashService:            hashService,
+		rbacService:            rbacService,
+		organizationService:    organizationService,
 	}
 }
 
+// deriveSecret returns a signing key bound to purpose, so tokens issued for
+// one purpose can never be validated as another (e.g. an MFA challenge token
+// as an access token).
+func deriveSecret(secret, purpose string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 type TokenClaims struct {
-	UserID models.UserID `json:"uid"`
+	UserID      models.UserID `json:"uid"`
+	Permissions []string      `json:"perms,omitempty"`
+	// OrganizationID is the user's active organization, the tenant the
+	// request acts in. It is nil in the user's personal context.
+	OrganizationID *models.OrganizationID `json:"org,omitempty"`
+	// Actor is set on impersonation tokens and identifies the admin acting
+	// as UserID (RFC 8693 "act" claim).
+	Actor *ActorClaims `json:"act,omitempty"`
+	// Impersonated lets clients show an impersonation banner without
+	// inspecting Actor.
+	Impersonated bool `json:"imp,omitempty"`
 	jwt.RegisteredClaims
 }
 
+type ActorClaims struct {
+	UserID models.UserID `json:"uid"`
+}
+
+// IsImpersonation reports whether the token was issued to an admin acting as
+// another user.
+func (tc *TokenClaims) IsImpersonation() bool {
+	return tc.Actor != nil
+}
+
+// HasPermission reports whether the token grants permission.
+func (tc *TokenClaims) HasPermission(permission string) bool {
+	for _, p := range tc.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (js *JWTService) GenerateToken(userID models.UserID) (tokenString string, err error) {
-	expiration := time.Now().Add(5 * time.Minute)
+	expiration := time.Now().Add(js.tokenTTL)
+
+	// Permissions are resolved on every issuance, so role changes reach the
+	// user within one access token lifetime.
+	permissions, err := js.rbacService.GetUserPermissions(userID)
+	if err != nil {
+		log.Printf("GenerateToken: error resolving permissions: %s", err.Error())
+		return "", err
+	}
+
+	organizationID, err := js.organizationService.GetActiveOrganizationID(userID)
+	if err != nil {
+		log.Printf("GenerateToken: error resolving active organization: %s", err.Error())
+		return "", err
+	}
 
 	claims := &TokenClaims{
-		UserID: userID,
+		UserID:         userID,
+		Permissions:    permissions,
+		OrganizationID: organizationID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "jwt_auth",
 			ExpiresAt: jwt.NewNumericDate(expiration),
@@ -65,13 +144,56 @@ func (js *JWTService) GenerateToken(userID models.UserID) (tokenString string, e
 	return tokenString, nil
 }
 
+// GenerateImpersonationToken issues an access token for userID on behalf of
+// actorID. It carries the target's permissions, lives for ttl and has no
+// refresh token, so it cannot be extended.
+func (js *JWTService) GenerateImpersonationToken(userID models.UserID, actorID models.UserID, ttl time.Duration) (string, time.Time, error) {
+	expiration := time.Now().Add(ttl)
+
+	permissions, err := js.rbacService.GetUserPermissions(userID)
+	if err != nil {
+		log.Printf("GenerateImpersonationToken: error resolving permissions: %s", err.Error())
+		return "", time.Time{}, err
+	}
+
+	organizationID, err := js.organizationService.GetActiveOrganizationID(userID)
+	if err != nil {
+		log.Printf("GenerateImpersonationToken: error resolving active organization: %s", err.Error())
+		return "", time.Time{}, err
+	}
+
+	claims := &TokenClaims{
+		UserID:         userID,
+		Permissions:    permissions,
+		OrganizationID: organizationID,
+		Actor:          &ActorClaims{UserID: actorID},
+		Impersonated:   true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "jwt_auth",
+			ExpiresAt: jwt.NewNumericDate(expiration),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	tokenString, err := token.SignedString([]byte(js.tokenSecret))
+	if err != nil {
+		log.Printf("GenerateImpersonationToken: error creating token: %s", err.Error())
+		return "", time.Time{}, err
+	}
+
+	log.Printf("GenerateImpersonationToken: user %d impersonating user %d", actorID, userID)
+	return tokenString, expiration, nil
+}
+
 type RefreshTokenClaims struct {
 	UserID models.UserID `json:"uid"`
 	jwt.RegisteredClaims
 }
 
 func (js *JWTService) GenerateRefreshToken(userID models.UserID) (tokenString string, err error) {
-	expiration := time.Now().Add(7 * 24 * time.Hour)
+	expiration := time.Now().Add(js.refreshTokenTTL)
 
 	claims := &RefreshTokenClaims{
 		UserID: userID,
@@ -132,6 +254,13 @@ func (js *JWTService) ValidateToken(tokenString string) (*TokenClaims, error) {
 		return nil, utils.ErrTokenInvalid
 	}
 
+	// Tokens with an audience were issued to app user pools and are only
+	// valid for that app, never as global access tokens.
+	if len(claims.Audience) > 0 {
+		log.Print("ValidateToken: token issued for an app user pool")
+		return nil, utils.ErrTokenInvalid
+	}
+
 	log.Print("ValidateToken: token is valid")
 	return &claims, nil
 }
@@ -151,7 +280,7 @@ func (js *JWTService) ValidateRefreshToken(tokenString string) (*RefreshTokenCla
 		return nil, utils.ErrRefreshTokenInvalid
 	}
 
-	if !token.Valid {
+	if !token.Valid || len(claims.Audience) > 0 {
 		log.Print("ValidateRefreshToken: invalid refresh token")
 		return nil, utils.ErrRefreshTokenInvalid
 	}
@@ -202,3 +331,64 @@ func (js *JWTService) InvalidateRefreshTokensByUserID(userID models.UserID) erro
 
 	return nil
 }
+
+type MFATokenClaims struct {
+	UserID models.UserID `json:"uid"`
+	jwt.RegisteredClaims
+}
+
+func (js *JWTService) GenerateMFAToken(userID models.UserID) (tokenString string, err error) {
+	expiration := time.Now().Add(5 * time.Minute)
+
+	// The token ID lets failed attempts be counted per token.
+	tokenID, err := utils.GetRandomString(16)
+	if err != nil {
+		log.Printf("GenerateMFAToken: error creating token id: %s", err.Error())
+		return "", err
+	}
+
+	claims := &MFATokenClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Issuer:    "jwt_auth",
+			ExpiresAt: jwt.NewNumericDate(expiration),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	tokenString, err = token.SignedString([]byte(js.mfaTokenSecret))
+	if err != nil {
+		log.Printf("GenerateMFAToken: error creating token: %s", err.Error())
+		return "", err
+	}
+
+	log.Print("GenerateMFAToken: token created")
+	return tokenString, nil
+}
+
+func (js *JWTService) ValidateMFAToken(tokenString string) (*MFATokenClaims, error) {
+	claims := MFATokenClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+
+		return []byte(js.mfaTokenSecret), nil
+	})
+	if err != nil {
+		log.Printf("ValidateMFAToken: error parsing token: %s", err.Error())
+		return nil, utils.ErrMFATokenInvalid
+	}
+
+	if !token.Valid {
+		log.Print("ValidateMFAToken: invalid token")
+		return nil, utils.ErrMFATokenInvalid
+	}
+
+	log.Print("ValidateMFAToken: token is valid")
+	return &claims, nil
+}
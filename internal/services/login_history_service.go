@@ -0,0 +1,86 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+)
+
+type ILoginHistoryService interface {
+	RecordSuccess(userID models.UserID, method string, ipAddress string, userAgent string) (newDevice bool, err error)
+	RecordFailure(userID models.UserID, method string, reason string, ipAddress string, userAgent string) error
+	GetLoginHistory(userID models.UserID, page int, pageSize int) (events []models.LoginEvent, total int, err error)
+}
+
+type LoginHistoryService struct {
+	loginEventRepository repositories.LoginEventRepository
+}
+
+func NewLoginHistoryService(loginEventRepository repositories.LoginEventRepository) ILoginHistoryService {
+	return &LoginHistoryService{
+		loginEventRepository: loginEventRepository,
+	}
+}
+
+// RecordSuccess stores a successful login. newDevice is true when the user
+// logged in before, but never from this IP address and user agent.
+func (lhs *LoginHistoryService) RecordSuccess(userID models.UserID, method string, ipAddress string, userAgent string) (bool, error) {
+	hasLogins, known, err := lhs.loginEventRepository.GetKnownLoginDevice(userID, ipAddress, userAgent)
+	if err != nil {
+		log.Printf("RecordSuccess: error checking known devices: %s", err.Error())
+		return false, err
+	}
+
+	err = lhs.loginEventRepository.CreateLoginEvent(&models.LoginEvent{
+		UserID:    userID,
+		Method:    method,
+		Success:   true,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("RecordSuccess: error creating login event: %s", err.Error())
+		return false, err
+	}
+
+	return hasLogins && !known, nil
+}
+
+func (lhs *LoginHistoryService) RecordFailure(userID models.UserID, method string, reason string, ipAddress string, userAgent string) error {
+	err := lhs.loginEventRepository.CreateLoginEvent(&models.LoginEvent{
+		UserID:        userID,
+		Method:        method,
+		Success:       false,
+		FailureReason: reason,
+		IPAddress:     ipAddress,
+		UserAgent:     userAgent,
+		CreatedAt:     time.Now(),
+	})
+	if err != nil {
+		log.Printf("RecordFailure: error creating login event: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// GetLoginHistory returns the page-th page (starting at 1) of the user's
+// login events, newest first, and the total number of events.
+func (lhs *LoginHistoryService) GetLoginHistory(userID models.UserID, page int, pageSize int) ([]models.LoginEvent, int, error) {
+	total, err := lhs.loginEventRepository.CountLoginEventsByUserID(userID)
+	if err != nil {
+		log.Printf("GetLoginHistory: error counting login events: %s", err.Error())
+		return nil, 0, err
+	}
+
+	events, err := lhs.loginEventRepository.GetLoginEventsByUserID(userID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		log.Printf("GetLoginHistory: error getting login events: %s", err.Error())
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
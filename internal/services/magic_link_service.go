@@ -0,0 +1,194 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IMagicLinkService interface {
+	CreateMagicLink(email string) (*MagicLink, error)
+	ConsumeMagicLink(token string, binding string) (*models.User, error)
+}
+
+// MagicLink is a freshly issued link. Token goes in the e-mailed URL and
+// Binding, when browser binding is enabled, goes in a cookie of the browser
+// that requested it.
+type MagicLink struct {
+	User      *models.User
+	Token     string
+	Binding   string
+	ExpiresAt time.Time
+}
+
+type MagicLinkService struct {
+	magicLinkTokenRepository repositories.MagicLinkTokenRepository
+	userRepository           repositories.UserRepository
+	hashService              IHashService
+	signingKey               string
+	linkTTL                  time.Duration
+	bindToBrowser            bool
+}
+
+func NewMagicLinkService(
+	magicLinkTokenRepository repositories.MagicLinkTokenRepository,
+	userRepository repositories.UserRepository,
+	hashService IHashService,
+	tokenSecret string,
+	linkTTL time.Duration,
+	bindToBrowser bool,
+) IMagicLinkService {
+	return &MagicLinkService{
+		magicLinkTokenRepository: magicLinkTokenRepository,
+		userRepository:           userRepository,
+		hashService:              hashService,
+		signingKey:               deriveSecret(tokenSecret, "magic_link"),
+		linkTTL:                  linkTTL,
+		bindToBrowser:            bindToBrowser,
+	}
+}
+
+func (mls *MagicLinkService) sign(value string) string {
+	mac := hmac.New(sha256.New, []byte(mls.signingKey))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks the signature of a "<value>.<signature>" token and returns
+// the value. Forged or mangled links are rejected without a database lookup.
+func (mls *MagicLinkService) verify(token string) (string, bool) {
+	value, signature, found := strings.Cut(token, ".")
+	if !found {
+		return "", false
+	}
+
+	return value, hmac.Equal([]byte(signature), []byte(mls.sign(value)))
+}
+
+func (mls *MagicLinkService) CreateMagicLink(email string) (*MagicLink, error) {
+	user, err := mls.userRepository.GetUserByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Status == utils.UserStatusInactive {
+		return nil, utils.ErrUserInactive
+	}
+
+	err = mls.magicLinkTokenRepository.DeleteExpiredMagicLinkTokens(time.Now())
+	if err != nil {
+		log.Printf("CreateMagicLink: error deleting expired tokens: %s", err.Error())
+	}
+
+	value, err := utils.GetRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := mls.hashService.HashSHA256(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var binding, bindingHash string
+	if mls.bindToBrowser {
+		binding, err = utils.GetRandomString(32)
+		if err != nil {
+			return nil, err
+		}
+
+		bindingHash, err = mls.hashService.HashSHA256(binding)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	token := &models.MagicLinkToken{
+		Content:   content,
+		UserID:    user.ID,
+		Binding:   bindingHash,
+		ExpiresAt: time.Now().Add(mls.linkTTL),
+	}
+
+	err = mls.magicLinkTokenRepository.CreateMagicLinkToken(token)
+	if err != nil {
+		log.Printf("CreateMagicLink: error saving token: %s", err.Error())
+		return nil, err
+	}
+
+	log.Printf("CreateMagicLink: magic link created for user %d", user.ID)
+	return &MagicLink{
+		User:      user,
+		Token:     value + "." + mls.sign(value),
+		Binding:   binding,
+		ExpiresAt: token.ExpiresAt,
+	}, nil
+}
+
+// ConsumeMagicLink uses the link and returns its user. Pending users are
+// activated, since receiving the link proves they own the e-mail address.
+func (mls *MagicLinkService) ConsumeMagicLink(token string, binding string) (*models.User, error) {
+	value, ok := mls.verify(token)
+	if !ok {
+		return nil, utils.ErrMagicLinkInvalid
+	}
+
+	content, err := mls.hashService.HashSHA256(value)
+	if err != nil {
+		return nil, err
+	}
+
+	magicLinkToken, err := mls.magicLinkTokenRepository.GetMagicLinkTokenByContent(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if magicLinkToken.IsUsed() {
+		return nil, utils.ErrMagicLinkInvalid
+	}
+
+	if time.Now().After(magicLinkToken.ExpiresAt) {
+		return nil, utils.ErrMagicLinkExpired
+	}
+
+	if magicLinkToken.Binding != "" {
+		bindingHash, err := mls.hashService.HashSHA256(binding)
+		if err != nil {
+			return nil, err
+		}
+
+		if !hmac.Equal([]byte(bindingHash), []byte(magicLinkToken.Binding)) {
+			return nil, utils.ErrMagicLinkBrowserMismatch
+		}
+	}
+
+	err = mls.magicLinkTokenRepository.UseMagicLinkToken(magicLinkToken.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := mls.userRepository.GetUserByID(magicLinkToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Status == utils.UserStatusPending {
+		err = mls.userRepository.ActivateUser(user.ID)
+		if err != nil {
+			log.Printf("ConsumeMagicLink: error activating user: %s", err.Error())
+			return nil, err
+		}
+
+		user.Status = utils.UserStatusActive
+		log.Printf("ConsumeMagicLink: user %d activated", user.ID)
+	}
+
+	return user, nil
+}
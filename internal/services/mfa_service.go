@@ -0,0 +1,345 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IMFAService interface {
+	IsMFAEnabled(userID models.UserID) (bool, error)
+	GetMFAMethods(userID models.UserID) ([]string, error)
+	BeginTOTPEnrollment(user *models.User) (secret string, uri string, err error)
+	ConfirmTOTPEnrollment(userID models.UserID, code string) (recoveryCodes []string, err error)
+	DisableTOTP(userID models.UserID, code string) error
+	VerifyTOTP(userID models.UserID, code string) error
+	VerifyRecoveryCode(userID models.UserID, recoveryCode string) error
+	RegenerateRecoveryCodes(userID models.UserID, code string) (recoveryCodes []string, err error)
+	CountRecoveryCodes(userID models.UserID) (int, error)
+	CheckChallenge(challengeID string) error
+	RecordChallengeFailure(challengeID string, expiresAt time.Time) error
+}
+
+const recoveryCodeCount = 10
+
+// Second factors a user can complete the MFA step with.
+const (
+	MFAMethodTOTP     = "totp"
+	MFAMethodWebAuthn = "webauthn"
+)
+
+type MFAService struct {
+	totpCredentialRepository  repositories.TOTPCredentialRepository
+	mfaRecoveryCodeRepository repositories.MFARecoveryCodeRepository
+	webAuthnRepository        repositories.WebAuthnCredentialRepository
+	encryptionService         IEncryptionService
+	mfaChallengeRepository    repositories.MFAChallengeRepository
+	hashService               IHashService
+	issuer                    string
+	// maxChallengeAttempts is how many wrong second factors an mfa_token
+	// survives.
+	maxChallengeAttempts int
+}
+
+func NewMFAService(
+	totpCredentialRepository repositories.TOTPCredentialRepository,
+	mfaRecoveryCodeRepository repositories.MFARecoveryCodeRepository,
+	webAuthnRepository repositories.WebAuthnCredentialRepository,
+	mfaChallengeRepository repositories.MFAChallengeRepository,
+	encryptionService IEncryptionService,
+	hashService IHashService,
+	issuer string,
+	maxChallengeAttempts int,
+) IMFAService {
+	return &MFAService{
+		totpCredentialRepository:  totpCredentialRepository,
+		mfaRecoveryCodeRepository: mfaRecoveryCodeRepository,
+		webAuthnRepository:        webAuthnRepository,
+		encryptionService:         encryptionService,
+		mfaChallengeRepository:    mfaChallengeRepository,
+		hashService:               hashService,
+		issuer:                    issuer,
+		maxChallengeAttempts:      maxChallengeAttempts,
+	}
+}
+
+func (ms *MFAService) IsMFAEnabled(userID models.UserID) (bool, error) {
+	methods, err := ms.GetMFAMethods(userID)
+	if err != nil {
+		return false, err
+	}
+
+	return len(methods) > 0, nil
+}
+
+// GetMFAMethods lists the second factors the user has set up. An empty list
+// means MFA is not enabled.
+func (ms *MFAService) GetMFAMethods(userID models.UserID) ([]string, error) {
+	methods := []string{}
+
+	credential, err := ms.totpCredentialRepository.GetTOTPCredentialByUserID(userID)
+	if err != nil && !errors.Is(err, utils.ErrMFANotEnabled) {
+		return nil, err
+	}
+
+	if err == nil && credential.IsConfirmed() {
+		methods = append(methods, MFAMethodTOTP)
+	}
+
+	webAuthnCredentials, err := ms.webAuthnRepository.GetWebAuthnCredentialsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(webAuthnCredentials) > 0 {
+		methods = append(methods, MFAMethodWebAuthn)
+	}
+
+	return methods, nil
+}
+
+func (ms *MFAService) BeginTOTPEnrollment(user *models.User) (string, string, error) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	encrypted, err := ms.encryptionService.Encrypt(secret)
+	if err != nil {
+		log.Printf("BeginTOTPEnrollment: error encrypting secret: %s", err.Error())
+		return "", "", err
+	}
+
+	err = ms.totpCredentialRepository.SaveTOTPCredential(&models.TOTPCredential{
+		UserID: user.ID,
+		Secret: encrypted,
+	})
+	if err != nil {
+		log.Printf("BeginTOTPEnrollment: error saving credential: %s", err.Error())
+		return "", "", err
+	}
+
+	log.Printf("BeginTOTPEnrollment: enrollment started for user %d", user.ID)
+	return secret, utils.GetTOTPURI(ms.issuer, user.Email, secret), nil
+}
+
+// validateCode decrypts the user's secret and checks code against it,
+// returning the credential and the matched time step.
+func (ms *MFAService) validateCode(userID models.UserID, code string) (*models.TOTPCredential, int64, error) {
+	credential, err := ms.totpCredentialRepository.GetTOTPCredentialByUserID(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	secret, err := ms.encryptionService.Decrypt(credential.Secret)
+	if err != nil {
+		log.Printf("validateCode: error decrypting secret: %s", err.Error())
+		return nil, 0, err
+	}
+
+	counter, err := utils.ValidateTOTPCode(secret, code, time.Now())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return credential, counter, nil
+}
+
+func (ms *MFAService) ConfirmTOTPEnrollment(userID models.UserID, code string) ([]string, error) {
+	credential, counter, err := ms.validateCode(userID, code)
+	if err != nil {
+		if errors.Is(err, utils.ErrMFANotEnabled) {
+			return nil, utils.ErrMFAEnrollmentNotFound
+		}
+		return nil, err
+	}
+
+	if credential.IsConfirmed() {
+		return nil, utils.ErrMFAAlreadyEnabled
+	}
+
+	err = ms.totpCredentialRepository.ConfirmTOTPCredential(userID, counter)
+	if err != nil {
+		log.Printf("ConfirmTOTPEnrollment: error confirming credential: %s", err.Error())
+		return nil, err
+	}
+
+	recoveryCodes, err := ms.generateRecoveryCodes(userID)
+	if err != nil {
+		log.Printf("ConfirmTOTPEnrollment: error generating recovery codes: %s", err.Error())
+		return nil, err
+	}
+
+	log.Printf("ConfirmTOTPEnrollment: mfa enabled for user %d", userID)
+	return recoveryCodes, nil
+}
+
+func (ms *MFAService) DisableTOTP(userID models.UserID, code string) error {
+	err := ms.VerifyTOTP(userID, code)
+	if err != nil {
+		return err
+	}
+
+	err = ms.totpCredentialRepository.DeleteTOTPCredential(userID)
+	if err != nil {
+		log.Printf("DisableTOTP: error deleting credential: %s", err.Error())
+		return err
+	}
+
+	err = ms.mfaRecoveryCodeRepository.DeleteRecoveryCodes(userID)
+	if err != nil {
+		log.Printf("DisableTOTP: error deleting recovery codes: %s", err.Error())
+		return err
+	}
+
+	log.Printf("DisableTOTP: mfa disabled for user %d", userID)
+	return nil
+}
+
+func (ms *MFAService) VerifyTOTP(userID models.UserID, code string) error {
+	credential, counter, err := ms.validateCode(userID, code)
+	if err != nil {
+		return err
+	}
+
+	if !credential.IsConfirmed() {
+		return utils.ErrMFANotEnabled
+	}
+
+	err = ms.totpCredentialRepository.UseTOTPCounter(userID, counter)
+	if err != nil {
+		log.Printf("VerifyTOTP: error using totp counter: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// normalizeRecoveryCode makes the comparison tolerant to how users type the
+// code back (case, dashes and spaces).
+func normalizeRecoveryCode(recoveryCode string) string {
+	recoveryCode = strings.ToLower(recoveryCode)
+	recoveryCode = strings.ReplaceAll(recoveryCode, "-", "")
+	recoveryCode = strings.ReplaceAll(recoveryCode, " ", "")
+	return recoveryCode
+}
+
+// generateRecoveryCodes replaces the user's recovery codes with a new set and
+// returns them in plain text. Only their hashes are stored.
+func (ms *MFAService) generateRecoveryCodes(userID models.UserID) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]models.MFARecoveryCodeContent, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		random, err := utils.GetRandomString(5)
+		if err != nil {
+			return nil, err
+		}
+
+		hash, err := ms.hashService.HashSHA256(random)
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, random[:5]+"-"+random[5:])
+		hashes = append(hashes, hash)
+	}
+
+	err := ms.mfaRecoveryCodeRepository.ReplaceRecoveryCodes(userID, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (ms *MFAService) VerifyRecoveryCode(userID models.UserID, recoveryCode string) error {
+	enabled, err := ms.IsMFAEnabled(userID)
+	if err != nil {
+		return err
+	}
+
+	if !enabled {
+		return utils.ErrMFANotEnabled
+	}
+
+	hash, err := ms.hashService.HashSHA256(normalizeRecoveryCode(recoveryCode))
+	if err != nil {
+		return err
+	}
+
+	err = ms.mfaRecoveryCodeRepository.UseRecoveryCode(userID, hash)
+	if err != nil {
+		log.Printf("VerifyRecoveryCode: error using recovery code: %s", err.Error())
+		return err
+	}
+
+	log.Printf("VerifyRecoveryCode: recovery code used by user %d", userID)
+	return nil
+}
+
+func (ms *MFAService) RegenerateRecoveryCodes(userID models.UserID, code string) ([]string, error) {
+	err := ms.VerifyTOTP(userID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := ms.generateRecoveryCodes(userID)
+	if err != nil {
+		log.Printf("RegenerateRecoveryCodes: error generating recovery codes: %s", err.Error())
+		return nil, err
+	}
+
+	log.Printf("RegenerateRecoveryCodes: recovery codes regenerated for user %d", userID)
+	return recoveryCodes, nil
+}
+
+func (ms *MFAService) CountRecoveryCodes(userID models.UserID) (int, error) {
+	count, err := ms.mfaRecoveryCodeRepository.CountUnusedRecoveryCodes(userID)
+	if err != nil {
+		log.Printf("CountRecoveryCodes: error counting recovery codes: %s", err.Error())
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// CheckChallenge fails with ErrMFATokenInvalid once the mfa_token challengeID
+// ran out of attempts.
+func (ms *MFAService) CheckChallenge(challengeID string) error {
+	failures, err := ms.mfaChallengeRepository.GetMFAChallengeFailures(challengeID)
+	if err != nil {
+		return err
+	}
+
+	if failures >= ms.maxChallengeAttempts {
+		return utils.ErrMFATokenInvalid
+	}
+
+	return nil
+}
+
+// RecordChallengeFailure counts a wrong second factor sent with the mfa_token
+// challengeID, which expires at expiresAt.
+func (ms *MFAService) RecordChallengeFailure(challengeID string, expiresAt time.Time) error {
+	err := ms.mfaChallengeRepository.DeleteExpiredMFAChallenges(time.Now())
+	if err != nil {
+		log.Printf("RecordChallengeFailure: error deleting expired challenges: %s", err.Error())
+	}
+
+	failures, err := ms.mfaChallengeRepository.RecordMFAChallengeFailure(challengeID, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	if failures >= ms.maxChallengeAttempts {
+		log.Printf("RecordChallengeFailure: mfa token invalidated after %d failures", failures)
+	}
+
+	return nil
+}
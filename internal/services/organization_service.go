@@ -0,0 +1,245 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IOrganizationService interface {
+	CreateOrganization(userID models.UserID, name string) (*models.Organization, error)
+	GetUserOrganizations(userID models.UserID) ([]models.OrganizationMembership, error)
+	GetOrganization(organizationID models.OrganizationID, userID models.UserID) (*models.Organization, error)
+	GetMembers(organizationID models.OrganizationID, userID models.UserID) ([]models.OrganizationMember, error)
+	GetMemberRole(organizationID models.OrganizationID, userID models.UserID) (models.OrganizationRole, error)
+	AddMember(organizationID models.OrganizationID, actorID models.UserID, email string, role models.OrganizationRole) (*InvitationToken, error)
+	UpdateMemberRole(organizationID models.OrganizationID, actorID models.UserID, userID models.UserID, role models.OrganizationRole) error
+	RemoveMember(organizationID models.OrganizationID, actorID models.UserID, userID models.UserID) error
+	SwitchOrganization(userID models.UserID, organizationID *models.OrganizationID) error
+	GetActiveOrganizationID(userID models.UserID) (*models.OrganizationID, error)
+}
+
+type OrganizationService struct {
+	organizationRepository repositories.OrganizationRepository
+	invitationService      IInvitationService
+}
+
+func NewOrganizationService(
+	organizationRepository repositories.OrganizationRepository,
+	invitationService IInvitationService,
+) IOrganizationService {
+	return &OrganizationService{
+		organizationRepository: organizationRepository,
+		invitationService:      invitationService,
+	}
+}
+
+func isValidOrganizationRole(role models.OrganizationRole) bool {
+	return role == utils.OrganizationRoleOwner || role == utils.OrganizationRoleAdmin || role == utils.OrganizationRoleMember
+}
+
+// canManageMembers reports whether a member with role may invite, remove and
+// change the role of other members.
+func canManageMembers(role models.OrganizationRole) bool {
+	return role == utils.OrganizationRoleOwner || role == utils.OrganizationRoleAdmin
+}
+
+// CreateOrganization creates an organization owned by userID.
+func (orgs *OrganizationService) CreateOrganization(userID models.UserID, name string) (*models.Organization, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		return nil, utils.ErrOrganizationNameInvalid
+	}
+
+	organization := &models.Organization{Name: name}
+
+	err := orgs.organizationRepository.CreateOrganization(organization, userID)
+	if err != nil {
+		log.Printf("CreateOrganization: error creating organization: %s", err.Error())
+		return nil, err
+	}
+
+	log.Printf("CreateOrganization: user %d created organization %d", userID, organization.ID)
+	return organization, nil
+}
+
+func (orgs *OrganizationService) GetUserOrganizations(userID models.UserID) ([]models.OrganizationMembership, error) {
+	return orgs.organizationRepository.GetOrganizationsByUserID(userID)
+}
+
+// GetMemberRole returns the role of userID in the organization. Non-members
+// get ErrOrganizationNotFound, so organizations are invisible to outsiders.
+func (orgs *OrganizationService) GetMemberRole(organizationID models.OrganizationID, userID models.UserID) (models.OrganizationRole, error) {
+	member, err := orgs.organizationRepository.GetOrganizationMember(organizationID, userID)
+	if errors.Is(err, utils.ErrOrganizationMemberNotFound) {
+		return "", utils.ErrOrganizationNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return member.Role, nil
+}
+
+func (orgs *OrganizationService) GetOrganization(organizationID models.OrganizationID, userID models.UserID) (*models.Organization, error) {
+	_, err := orgs.GetMemberRole(organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return orgs.organizationRepository.GetOrganizationByID(organizationID)
+}
+
+func (orgs *OrganizationService) GetMembers(organizationID models.OrganizationID, userID models.UserID) ([]models.OrganizationMember, error) {
+	_, err := orgs.GetMemberRole(organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return orgs.organizationRepository.GetOrganizationMembers(organizationID)
+}
+
+// AddMember invites email into the organization with role and returns the
+// invitation, meant to be e-mailed. Nobody joins without accepting, and the
+// result is the same whether or not the e-mail has an account. Only owners
+// may invite owners.
+func (orgs *OrganizationService) AddMember(organizationID models.OrganizationID, actorID models.UserID, email string, role models.OrganizationRole) (*InvitationToken, error) {
+	if !isValidOrganizationRole(role) {
+		return nil, utils.ErrOrganizationRoleInvalid
+	}
+
+	actorRole, err := orgs.GetMemberRole(organizationID, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canManageMembers(actorRole) || (role == utils.OrganizationRoleOwner && actorRole != utils.OrganizationRoleOwner) {
+		return nil, utils.ErrForbidden
+	}
+
+	invitation, err := orgs.invitationService.CreateOrganizationInvitation(actorID, email, organizationID, role)
+	if err != nil {
+		log.Printf("AddMember: error creating invitation: %s", err.Error())
+		return nil, err
+	}
+
+	log.Printf("AddMember: user %d invited a member into organization %d as %s", actorID, organizationID, role)
+	return invitation, nil
+}
+
+// checkMemberChange loads the member userID and the role of actorID, and
+// verifies actorID may change or remove that member: managers may change
+// members, only owners may change owners and anyone may act on themselves.
+func (orgs *OrganizationService) checkMemberChange(organizationID models.OrganizationID, actorID models.UserID, userID models.UserID) (*models.OrganizationMember, models.OrganizationRole, error) {
+	actorRole, err := orgs.GetMemberRole(organizationID, actorID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	member, err := orgs.organizationRepository.GetOrganizationMember(organizationID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if actorID != userID {
+		if !canManageMembers(actorRole) || (member.Role == utils.OrganizationRoleOwner && actorRole != utils.OrganizationRoleOwner) {
+			return nil, "", utils.ErrForbidden
+		}
+	}
+
+	return member, actorRole, nil
+}
+
+// checkOwnerKept fails with ErrOrganizationLastOwner when member is the only
+// owner left.
+func (orgs *OrganizationService) checkOwnerKept(member *models.OrganizationMember) error {
+	if member.Role != utils.OrganizationRoleOwner {
+		return nil
+	}
+
+	owners, err := orgs.organizationRepository.CountOrganizationOwners(member.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	if owners <= 1 {
+		return utils.ErrOrganizationLastOwner
+	}
+
+	return nil
+}
+
+func (orgs *OrganizationService) UpdateMemberRole(organizationID models.OrganizationID, actorID models.UserID, userID models.UserID, role models.OrganizationRole) error {
+	if !isValidOrganizationRole(role) {
+		return utils.ErrOrganizationRoleInvalid
+	}
+
+	member, actorRole, err := orgs.checkMemberChange(organizationID, actorID, userID)
+	if err != nil {
+		return err
+	}
+
+	// Nobody promotes themselves, and only owners hand out ownership.
+	if (actorID == userID && !canManageMembers(actorRole)) || (role == utils.OrganizationRoleOwner && actorRole != utils.OrganizationRoleOwner) {
+		return utils.ErrForbidden
+	}
+
+	if role != utils.OrganizationRoleOwner {
+		err = orgs.checkOwnerKept(member)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = orgs.organizationRepository.UpdateOrganizationMemberRole(organizationID, userID, role)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("UpdateMemberRole: user %d made user %d %s of organization %d", actorID, userID, role, organizationID)
+	return nil
+}
+
+func (orgs *OrganizationService) RemoveMember(organizationID models.OrganizationID, actorID models.UserID, userID models.UserID) error {
+	member, _, err := orgs.checkMemberChange(organizationID, actorID, userID)
+	if err != nil {
+		return err
+	}
+
+	err = orgs.checkOwnerKept(member)
+	if err != nil {
+		return err
+	}
+
+	err = orgs.organizationRepository.RemoveOrganizationMember(organizationID, userID)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("RemoveMember: user %d removed user %d from organization %d", actorID, userID, organizationID)
+	return nil
+}
+
+// SwitchOrganization sets the organization the user acts in. A nil
+// organizationID switches back to the user's personal context.
+func (orgs *OrganizationService) SwitchOrganization(userID models.UserID, organizationID *models.OrganizationID) error {
+	err := orgs.organizationRepository.SetActiveOrganization(userID, organizationID)
+	if errors.Is(err, utils.ErrOrganizationMemberNotFound) {
+		return utils.ErrOrganizationNotFound
+	}
+	if err != nil {
+		log.Printf("SwitchOrganization: error setting active organization: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (orgs *OrganizationService) GetActiveOrganizationID(userID models.UserID) (*models.OrganizationID, error) {
+	return orgs.organizationRepository.GetActiveOrganizationID(userID)
+}
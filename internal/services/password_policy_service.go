@@ -0,0 +1,53 @@
+package services
+
+import (
+	"log"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+	"github.com/pedrotunin/go-jwt-auth/internal/validators"
+)
+
+type IPasswordPolicyService interface {
+	Validate(password string, email string) error
+}
+
+type PasswordPolicyService struct {
+	policy          validators.PasswordPolicy
+	breachedChecker IBreachedPasswordChecker
+}
+
+// NewPasswordPolicyService returns a service checking new passwords against
+// policy. breachedChecker may be nil to skip the breached password check.
+func NewPasswordPolicyService(policy validators.PasswordPolicy, breachedChecker IBreachedPasswordChecker) IPasswordPolicyService {
+	return &PasswordPolicyService{
+		policy:          policy,
+		breachedChecker: breachedChecker,
+	}
+}
+
+// Validate returns a *utils.PasswordPolicyError listing every violation, or
+// nil when the password is acceptable.
+func (pps *PasswordPolicyService) Validate(password string, email string) error {
+	violations := pps.policy.Check(password, email)
+
+	if pps.breachedChecker != nil {
+		breached, err := pps.breachedChecker.IsBreached(password)
+		if err != nil {
+			// Do not block signups because the list is unreadable.
+			log.Printf("Validate: error checking breached passwords: %s", err.Error())
+		}
+
+		if breached {
+			violations = append(violations, utils.PasswordViolation{
+				Code:    utils.PasswordViolationBreached,
+				Message: "password appeared in a data breach, choose a different one",
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &utils.PasswordPolicyError{Violations: violations}
+	}
+
+	return nil
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IPasswordResetService interface {
+	CreatePasswordReset(userID models.UserID) (*PasswordReset, error)
+	SendPasswordResetEmail(reset *PasswordReset) error
+	ResetPassword(token string, password string) error
+}
+
+// PasswordReset is a freshly issued reset token, meant to be e-mailed to User.
+// Link points to the password reset page with the token.
+type PasswordReset struct {
+	User      *models.User
+	Token     string
+	Link      string
+	ExpiresAt time.Time
+}
+
+type PasswordResetService struct {
+	passwordResetTokenRepository repositories.PasswordResetTokenRepository
+	userRepository               repositories.UserRepository
+	refreshTokenRepository       repositories.RefreshTokenRepository
+	hashService                  IHashService
+	passwordPolicyService        IPasswordPolicyService
+	mailerService                MailerService
+	resetURL                     string
+	tokenTTL                     time.Duration
+}
+
+func NewPasswordResetService(
+	passwordResetTokenRepository repositories.PasswordResetTokenRepository,
+	userRepository repositories.UserRepository,
+	refreshTokenRepository repositories.RefreshTokenRepository,
+	hashService IHashService,
+	passwordPolicyService IPasswordPolicyService,
+	mailerService MailerService,
+	resetURL string,
+	tokenTTL time.Duration,
+) IPasswordResetService {
+	return &PasswordResetService{
+		passwordResetTokenRepository: passwordResetTokenRepository,
+		userRepository:               userRepository,
+		refreshTokenRepository:       refreshTokenRepository,
+		hashService:                  hashService,
+		passwordPolicyService:        passwordPolicyService,
+		mailerService:                mailerService,
+		resetURL:                     resetURL,
+		tokenTTL:                     tokenTTL,
+	}
+}
+
+func (prs *PasswordResetService) CreatePasswordReset(userID models.UserID) (*PasswordReset, error) {
+	user, err := prs.userRepository.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = prs.passwordResetTokenRepository.DeleteExpiredPasswordResetTokens(time.Now())
+	if err != nil {
+		log.Printf("CreatePasswordReset: error deleting expired tokens: %s", err.Error())
+	}
+
+	value, err := utils.GetRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := prs.hashService.HashSHA256(value)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &models.PasswordResetToken{
+		Content:   content,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(prs.tokenTTL),
+	}
+
+	err = prs.passwordResetTokenRepository.CreatePasswordResetToken(token)
+	if err != nil {
+		log.Printf("CreatePasswordReset: error saving token: %s", err.Error())
+		return nil, err
+	}
+
+	log.Printf("CreatePasswordReset: password reset created for user %d", user.ID)
+	return &PasswordReset{
+		User:      user,
+		Token:     value,
+		Link:      prs.resetURL + "?token=" + url.QueryEscape(value),
+		ExpiresAt: token.ExpiresAt,
+	}, nil
+}
+
+// SendPasswordResetEmail e-mails the reset link to its user. The template is
+// read from the working directory, so callers must run from the repository
+// root.
+func (prs *PasswordResetService) SendPasswordResetEmail(reset *PasswordReset) error {
+	var htmlBody bytes.Buffer
+
+	tmpl, err := template.ParseFiles("templates/password_reset_email.html")
+	if err != nil {
+		log.Printf("SendPasswordResetEmail: error parsing template: %s", err.Error())
+		return err
+	}
+
+	err = tmpl.Execute(&htmlBody, struct {
+		UserEmail string
+		ResetLink string
+		ExpiresAt string
+	}{
+		UserEmail: reset.User.Email,
+		ResetLink: reset.Link,
+		ExpiresAt: reset.ExpiresAt.UTC().Format(time.RFC1123),
+	})
+	if err != nil {
+		log.Printf("SendPasswordResetEmail: error executing template: %s", err.Error())
+		return err
+	}
+
+	err = prs.mailerService.SendEmail("", reset.User.Email, "Reset your password", "", htmlBody.String())
+	if err != nil {
+		log.Printf("SendPasswordResetEmail: error sending email: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password for the owner of token and revokes their
+// sessions. The password is checked against the password policy first.
+func (prs *PasswordResetService) ResetPassword(token string, password string) error {
+	content, err := prs.hashService.HashSHA256(token)
+	if err != nil {
+		return err
+	}
+
+	resetToken, err := prs.passwordResetTokenRepository.GetPasswordResetTokenByContent(content)
+	if err != nil {
+		return err
+	}
+
+	if resetToken.IsUsed() {
+		return utils.ErrPasswordResetTokenInvalid
+	}
+
+	if time.Now().After(resetToken.ExpiresAt) {
+		return utils.ErrPasswordResetTokenExpired
+	}
+
+	user, err := prs.userRepository.GetUserByID(resetToken.UserID)
+	if err != nil {
+		return err
+	}
+
+	err = prs.passwordPolicyService.Validate(password, user.Email)
+	if err != nil {
+		return err
+	}
+
+	hash, err := prs.hashService.HashPassword(password)
+	if err != nil {
+		log.Printf("ResetPassword: error hashing password: %s", err.Error())
+		return err
+	}
+
+	err = prs.passwordResetTokenRepository.UsePasswordResetToken(resetToken.ID)
+	if err != nil {
+		return err
+	}
+
+	err = prs.userRepository.UpdateUserPassword(user.ID, hash)
+	if err != nil {
+		log.Printf("ResetPassword: error updating password: %s", err.Error())
+		return err
+	}
+
+	err = prs.refreshTokenRepository.InvalidateRefreshTokensByUserID(user.ID)
+	if err != nil {
+		log.Printf("ResetPassword: error revoking sessions: %s", err.Error())
+		return err
+	}
+
+	log.Printf("ResetPassword: password reset for user %d", user.ID)
+	return nil
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type IRBACService interface {
+	ListRoles() ([]models.Role, error)
+	ListPermissions() ([]models.Permission, error)
+	CreateRole(name string, description string, permissions []string) (*models.Role, error)
+	DeleteRole(name string) error
+	GetUserRoles(userID models.UserID) ([]models.Role, error)
+	GetUserPermissions(userID models.UserID) ([]string, error)
+	AssignRole(userID models.UserID, roleName string) error
+	RemoveRole(userID models.UserID, roleName string) error
+}
+
+var roleNameRegex = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+type RBACService struct {
+	rbacRepository repositories.RBACRepository
+	userRepository repositories.UserRepository
+	adminEmails    map[string]bool
+}
+
+// NewRBACService returns the role service. Users whose e-mail is in
+// adminEmails always hold the built-in admin role, which bootstraps the first
+// administrators before any role was assigned.
+func NewRBACService(
+	rbacRepository repositories.RBACRepository,
+	userRepository repositories.UserRepository,
+	adminEmails []string,
+) IRBACService {
+	emails := make(map[string]bool, len(adminEmails))
+	for _, email := range adminEmails {
+		emails[strings.ToLower(strings.TrimSpace(email))] = true
+	}
+
+	return &RBACService{
+		rbacRepository: rbacRepository,
+		userRepository: userRepository,
+		adminEmails:    emails,
+	}
+}
+
+func (rs *RBACService) ListRoles() ([]models.Role, error) {
+	return rs.rbacRepository.GetRoles()
+}
+
+func (rs *RBACService) ListPermissions() ([]models.Permission, error) {
+	return rs.rbacRepository.GetPermissions()
+}
+
+func (rs *RBACService) CreateRole(name string, description string, permissions []string) (*models.Role, error) {
+	if !roleNameRegex.MatchString(name) {
+		return nil, utils.ErrRoleNameInvalid
+	}
+
+	role := &models.Role{
+		Name:        name,
+		Description: description,
+		Permissions: uniqueStrings(permissions),
+	}
+
+	err := rs.rbacRepository.CreateRole(role)
+	if err != nil {
+		log.Printf("CreateRole: error creating role: %s", err.Error())
+		return nil, err
+	}
+
+	return role, nil
+}
+
+func (rs *RBACService) DeleteRole(name string) error {
+	if name == utils.RoleAdmin {
+		return utils.ErrRoleProtected
+	}
+
+	role, err := rs.rbacRepository.GetRoleByName(name)
+	if err != nil {
+		return err
+	}
+
+	err = rs.rbacRepository.DeleteRole(role.ID)
+	if err != nil {
+		log.Printf("DeleteRole: error deleting role: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (rs *RBACService) GetUserRoles(userID models.UserID) ([]models.Role, error) {
+	_, err := rs.userRepository.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return rs.rbacRepository.GetUserRoles(userID)
+}
+
+// GetUserPermissions resolves the permissions granted to the user by their
+// roles, plus those of the admin role for bootstrap administrators.
+func (rs *RBACService) GetUserPermissions(userID models.UserID) ([]string, error) {
+	permissions, err := rs.rbacRepository.GetUserPermissions(userID)
+	if err != nil {
+		log.Printf("GetUserPermissions: error getting permissions: %s", err.Error())
+		return nil, err
+	}
+
+	if len(rs.adminEmails) == 0 {
+		return permissions, nil
+	}
+
+	user, err := rs.userRepository.GetUserByID(userID)
+	if err != nil {
+		log.Printf("GetUserPermissions: error getting user: %s", err.Error())
+		return nil, err
+	}
+
+	if !rs.adminEmails[strings.ToLower(user.Email)] {
+		return permissions, nil
+	}
+
+	admin, err := rs.rbacRepository.GetRoleByName(utils.RoleAdmin)
+	if err != nil {
+		log.Printf("GetUserPermissions: error getting admin role: %s", err.Error())
+		return nil, err
+	}
+
+	return uniqueStrings(append(permissions, admin.Permissions...)), nil
+}
+
+func (rs *RBACService) AssignRole(userID models.UserID, roleName string) error {
+	role, err := rs.rbacRepository.GetRoleByName(roleName)
+	if err != nil {
+		return err
+	}
+
+	err = rs.rbacRepository.AssignUserRole(userID, role.ID)
+	if err != nil {
+		log.Printf("AssignRole: error assigning role: %s", err.Error())
+		return err
+	}
+
+	log.Printf("AssignRole: role %s assigned to user %d", role.Name, userID)
+	return nil
+}
+
+func (rs *RBACService) RemoveRole(userID models.UserID, roleName string) error {
+	role, err := rs.rbacRepository.GetRoleByName(roleName)
+	if err != nil {
+		return err
+	}
+
+	err = rs.rbacRepository.RemoveUserRole(userID, role.ID)
+	if err != nil {
+		log.Printf("RemoveRole: error removing role: %s", err.Error())
+		return err
+	}
+
+	log.Printf("RemoveRole: role %s removed from user %d", role.Name, userID)
+	return nil
+}
+
+// uniqueStrings returns values without duplicates, keeping the first
+// occurrence of each.
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := []string{}
+
+	for _, value := range values {
+		if seen[value] {
+			continue
+		}
+
+		seen[value] = true
+		unique = append(unique, value)
+	}
+
+	return unique
+}
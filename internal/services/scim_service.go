@@ -0,0 +1,919 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+	"github.com/pedrotunin/go-jwt-auth/internal/validators"
+)
+
+type ISCIMService interface {
+	GetUsers(filter string, startIndex int, count int) (users []models.SCIMUser, total int, err error)
+	GetUser(userID models.UserID) (*models.SCIMUser, error)
+	CreateUser(resource *models.SCIMUser) (*models.SCIMUser, error)
+	ReplaceUser(userID models.UserID, resource *models.SCIMUser) (*models.SCIMUser, error)
+	PatchUser(userID models.UserID, operations []models.SCIMPatchOperation) (*models.SCIMUser, error)
+	DeprovisionUser(userID models.UserID) error
+	GetGroups(filter string, startIndex int, count int) (groups []models.SCIMGroup, total int, err error)
+	GetGroup(roleID models.RoleID) (*models.SCIMGroup, error)
+	CreateGroup(resource *models.SCIMGroup) (*models.SCIMGroup, error)
+	ReplaceGroup(roleID models.RoleID, resource *models.SCIMGroup) (*models.SCIMGroup, error)
+	PatchGroup(roleID models.RoleID, operations []models.SCIMPatchOperation) (*models.SCIMGroup, error)
+	DeleteGroup(roleID models.RoleID) error
+}
+
+// scimFilterRegex matches the only filter supported, `<attribute> eq "<value>"`.
+var scimFilterRegex = regexp.MustCompile(`(?i)^\s*([a-z]+)\s+eq\s+("(?:[^"\\]|\\.)*")\s*$`)
+
+// scimMemberFilterRegex matches the path removing a single group member,
+// `members[value eq "<id>"]`.
+var scimMemberFilterRegex = regexp.MustCompile(`(?i)^members\[\s*value\s+eq\s+"([^"]*)"\s*\]$`)
+
+type SCIMService struct {
+	userRepository         repositories.UserRepository
+	rbacRepository         repositories.RBACRepository
+	refreshTokenRepository repositories.RefreshTokenRepository
+	hashService            IHashService
+	passwordPolicyService  IPasswordPolicyService
+}
+
+// NewSCIMService returns the SCIM provisioning service. SCIM Users map onto
+// the users, with userName as the e-mail and active as the status, and SCIM
+// Groups onto the roles, with displayName as the role name.
+func NewSCIMService(
+	userRepository repositories.UserRepository,
+	rbacRepository repositories.RBACRepository,
+	refreshTokenRepository repositories.RefreshTokenRepository,
+	hashService IHashService,
+	passwordPolicyService IPasswordPolicyService,
+) ISCIMService {
+	return &SCIMService{
+		userRepository:         userRepository,
+		rbacRepository:         rbacRepository,
+		refreshTokenRepository: refreshTokenRepository,
+		hashService:            hashService,
+		passwordPolicyService:  passwordPolicyService,
+	}
+}
+
+// parseSCIMFilter returns the value of a `<attribute> eq "<value>"` filter.
+// filtered is false when filter is empty, and any other filter fails with
+// ErrSCIMFilterInvalid.
+func parseSCIMFilter(filter string, attribute string) (value string, filtered bool, err error) {
+	if strings.TrimSpace(filter) == "" {
+		return "", false, nil
+	}
+
+	matches := scimFilterRegex.FindStringSubmatch(filter)
+	if matches == nil || !strings.EqualFold(matches[1], attribute) {
+		return "", false, utils.ErrSCIMFilterInvalid
+	}
+
+	value, err = strconv.Unquote(matches[2])
+	if err != nil {
+		return "", false, utils.ErrSCIMFilterInvalid
+	}
+
+	return value, true, nil
+}
+
+func scimString(raw json.RawMessage) (string, error) {
+	var value string
+
+	err := json.Unmarshal(raw, &value)
+	if err != nil {
+		return "", utils.ErrSCIMPatchInvalid
+	}
+
+	return value, nil
+}
+
+// scimBool reads a boolean, also accepting "True" and "False" strings as
+// sent by some identity providers.
+func scimBool(raw json.RawMessage) (bool, error) {
+	var value bool
+
+	err := json.Unmarshal(raw, &value)
+	if err == nil {
+		return value, nil
+	}
+
+	text, err := scimString(raw)
+	if err != nil {
+		return false, err
+	}
+
+	value, err = strconv.ParseBool(text)
+	if err != nil {
+		return false, utils.ErrSCIMPatchInvalid
+	}
+
+	return value, nil
+}
+
+func newSCIMUser(user *models.User) models.SCIMUser {
+	active := user.Status == utils.UserStatusActive
+
+	return models.SCIMUser{
+		Schemas:     []string{utils.SCIMSchemaUser},
+		ID:          strconv.Itoa(user.ID),
+		UserName:    user.Email,
+		DisplayName: user.DisplayName,
+		Locale:      user.Locale,
+		Timezone:    user.Timezone,
+		Active:      &active,
+		Emails:      []models.SCIMEmail{{Value: user.Email, Primary: true}},
+		Meta: &models.SCIMMeta{
+			ResourceType: "User",
+			Created:      user.CreatedAt,
+			LastModified: user.UpdatedAt,
+		},
+	}
+}
+
+// scimUserChanges are the user attributes a SCIM request sets. Nil fields are
+// left untouched.
+type scimUserChanges struct {
+	userName    *string
+	displayName *string
+	locale      *string
+	timezone    *string
+	password    *string
+	active      *bool
+}
+
+// setAttribute records the value of the user attribute at path. Attributes
+// the API does not store, such as name or externalId, are ignored so
+// identity providers sending them keep working.
+func (changes *scimUserChanges) setAttribute(path string, raw json.RawMessage) error {
+	attribute := strings.ToLower(strings.TrimPrefix(path, utils.SCIMSchemaUser+":"))
+
+	if attribute == "active" {
+		active, err := scimBool(raw)
+		if err != nil {
+			return err
+		}
+
+		changes.active = &active
+		return nil
+	}
+
+	var field **string
+	switch attribute {
+	case "username":
+		field = &changes.userName
+	case "displayname":
+		field = &changes.displayName
+	case "locale":
+		field = &changes.locale
+	case "timezone":
+		field = &changes.timezone
+	case "password":
+		field = &changes.password
+	default:
+		log.Printf("setAttribute: ignoring unsupported user attribute %s", path)
+		return nil
+	}
+
+	value, err := scimString(raw)
+	if err != nil {
+		return err
+	}
+
+	*field = &value
+	return nil
+}
+
+func (changes *scimUserChanges) profile() *models.UserProfile {
+	return &models.UserProfile{
+		DisplayName: changes.displayName,
+		Locale:      changes.locale,
+		Timezone:    changes.timezone,
+	}
+}
+
+// validateUserChanges checks every change before any is applied, so a
+// rejected request leaves the user untouched. email is the user's current
+// e-mail.
+func (ss *SCIMService) validateUserChanges(email string, changes *scimUserChanges) error {
+	if changes.userName != nil {
+		userName := strings.TrimSpace(*changes.userName)
+		changes.userName = &userName
+
+		err := validators.IsValidEmail(userName)
+		if err != nil {
+			return err
+		}
+
+		email = userName
+	}
+
+	err := changes.profile().Validate()
+	if err != nil {
+		return err
+	}
+
+	if changes.password != nil {
+		err = ss.passwordPolicyService.Validate(*changes.password, email)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ss *SCIMService) applyUserChanges(user *models.User, changes *scimUserChanges) error {
+	if changes.userName != nil && *changes.userName != user.Email {
+		err := ss.userRepository.UpdateUserEmail(user.ID, *changes.userName)
+		if err != nil {
+			return err
+		}
+	}
+
+	if changes.displayName != nil || changes.locale != nil || changes.timezone != nil {
+		changes.profile().Apply(user)
+
+		err := ss.userRepository.UpdateUserProfile(user)
+		if err != nil {
+			log.Printf("applyUserChanges: error updating profile: %s", err.Error())
+			return err
+		}
+	}
+
+	if changes.password != nil {
+		hash, err := ss.hashService.HashPassword(*changes.password)
+		if err != nil {
+			log.Printf("applyUserChanges: error hashing password: %s", err.Error())
+			return err
+		}
+
+		err = ss.userRepository.UpdateUserPassword(user.ID, hash)
+		if err != nil {
+			return err
+		}
+
+		err = ss.refreshTokenRepository.InvalidateRefreshTokensByUserID(user.ID)
+		if err != nil {
+			log.Printf("applyUserChanges: error revoking sessions: %s", err.Error())
+			return err
+		}
+	}
+
+	if changes.active != nil {
+		err := ss.setActive(user, *changes.active)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setActive activates the user, skipping e-mail verification since the
+// identity provider vouches for them, or deprovisions them: the status
+// becomes inactive and their sessions are revoked.
+func (ss *SCIMService) setActive(user *models.User, active bool) error {
+	if active {
+		if user.Status == utils.UserStatusActive {
+			return nil
+		}
+
+		return ss.userRepository.UpdateUserStatus(user.ID, utils.UserStatusActive)
+	}
+
+	if user.Status != utils.UserStatusInactive {
+		err := ss.userRepository.UpdateUserStatus(user.ID, utils.UserStatusInactive)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := ss.refreshTokenRepository.InvalidateRefreshTokensByUserID(user.ID)
+	if err != nil {
+		log.Printf("setActive: error revoking sessions: %s", err.Error())
+		return err
+	}
+
+	log.Printf("setActive: user %d deprovisioned", user.ID)
+	return nil
+}
+
+func (ss *SCIMService) GetUsers(filter string, startIndex int, count int) ([]models.SCIMUser, int, error) {
+	userName, filtered, err := parseSCIMFilter(filter, "userName")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resources := []models.SCIMUser{}
+
+	if filtered {
+		user, err := ss.userRepository.GetUserByEmail(userName)
+		if errors.Is(err, utils.ErrUserNotFound) {
+			return resources, 0, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if startIndex == 1 && count > 0 {
+			resources = append(resources, newSCIMUser(user))
+		}
+
+		return resources, 1, nil
+	}
+
+	total, err := ss.userRepository.CountUsers(models.UserFilter{})
+	if err != nil {
+		log.Printf("GetUsers: error counting users: %s", err.Error())
+		return nil, 0, err
+	}
+
+	if count == 0 {
+		return resources, total, nil
+	}
+
+	users, err := ss.userRepository.SearchUsers(models.UserFilter{Limit: count, Offset: startIndex - 1})
+	if err != nil {
+		log.Printf("GetUsers: error searching users: %s", err.Error())
+		return nil, 0, err
+	}
+
+	for i := range users {
+		resources = append(resources, newSCIMUser(&users[i]))
+	}
+
+	return resources, total, nil
+}
+
+func (ss *SCIMService) GetUser(userID models.UserID) (*models.SCIMUser, error) {
+	user, err := ss.userRepository.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := newSCIMUser(user)
+	return &resource, nil
+}
+
+// CreateUser provisions a user. Users are created active unless active is
+// false. Without a password the user signs in through a password reset,
+// magic link or e-mail code.
+func (ss *SCIMService) CreateUser(resource *models.SCIMUser) (*models.SCIMUser, error) {
+	changes := &scimUserChanges{
+		userName:    &resource.UserName,
+		displayName: &resource.DisplayName,
+		locale:      &resource.Locale,
+		timezone:    &resource.Timezone,
+		active:      resource.Active,
+	}
+	if resource.Password != "" {
+		changes.password = &resource.Password
+	}
+
+	err := ss.validateUserChanges(resource.UserName, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	password := resource.Password
+	if password == "" {
+		password, err = utils.GetRandomString(32)
+		if err != nil {
+			log.Printf("CreateUser: error generating password: %s", err.Error())
+			return nil, err
+		}
+	}
+
+	hash, err := ss.hashService.HashPassword(password)
+	if err != nil {
+		log.Printf("CreateUser: error hashing password: %s", err.Error())
+		return nil, err
+	}
+
+	userID, err := ss.userRepository.CreateUser(&models.User{Email: *changes.userName, Password: hash})
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := ss.userRepository.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if changes.active == nil {
+		active := true
+		changes.active = &active
+	}
+	changes.password = nil
+
+	err = ss.applyUserChanges(user, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("CreateUser: user %d provisioned", userID)
+	return ss.GetUser(userID)
+}
+
+// ReplaceUser sets every supported attribute of the user from resource;
+// profile attributes missing from it are cleared.
+func (ss *SCIMService) ReplaceUser(userID models.UserID, resource *models.SCIMUser) (*models.SCIMUser, error) {
+	user, err := ss.userRepository.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &scimUserChanges{
+		userName:    &resource.UserName,
+		displayName: &resource.DisplayName,
+		locale:      &resource.Locale,
+		timezone:    &resource.Timezone,
+		active:      resource.Active,
+	}
+	if resource.Password != "" {
+		changes.password = &resource.Password
+	}
+
+	err = ss.validateUserChanges(user.Email, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ss.applyUserChanges(user, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	return ss.GetUser(userID)
+}
+
+func (ss *SCIMService) PatchUser(userID models.UserID, operations []models.SCIMPatchOperation) (*models.SCIMUser, error) {
+	user, err := ss.userRepository.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &scimUserChanges{}
+
+	for _, operation := range operations {
+		switch strings.ToLower(operation.Op) {
+		case "add", "replace":
+			if operation.Path != "" {
+				err = changes.setAttribute(operation.Path, operation.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			var values map[string]json.RawMessage
+
+			err = json.Unmarshal(operation.Value, &values)
+			if err != nil {
+				return nil, utils.ErrSCIMPatchInvalid
+			}
+
+			for path, value := range values {
+				err = changes.setAttribute(path, value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		case "remove":
+			switch strings.ToLower(strings.TrimPrefix(operation.Path, utils.SCIMSchemaUser+":")) {
+			case "displayname", "locale", "timezone":
+				err = changes.setAttribute(operation.Path, json.RawMessage(`""`))
+				if err != nil {
+					return nil, err
+				}
+			default:
+				return nil, utils.ErrSCIMPatchInvalid
+			}
+		default:
+			return nil, utils.ErrSCIMPatchInvalid
+		}
+	}
+
+	err = ss.validateUserChanges(user.Email, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ss.applyUserChanges(user, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	return ss.GetUser(userID)
+}
+
+// DeprovisionUser handles SCIM deletes. Users are never deleted through SCIM:
+// they become inactive and lose their sessions, and can be provisioned again
+// by setting active back to true.
+func (ss *SCIMService) DeprovisionUser(userID models.UserID) error {
+	user, err := ss.userRepository.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	return ss.setActive(user, false)
+}
+
+func (ss *SCIMService) newSCIMGroup(role *models.Role) (*models.SCIMGroup, error) {
+	members, err := ss.rbacRepository.GetRoleMembers(role.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	group := &models.SCIMGroup{
+		Schemas:     []string{utils.SCIMSchemaGroup},
+		ID:          strconv.Itoa(role.ID),
+		DisplayName: role.Name,
+		Members:     make([]models.SCIMGroupMember, 0, len(members)),
+		Meta: &models.SCIMMeta{
+			ResourceType: "Group",
+			Created:      role.CreatedAt,
+			LastModified: role.CreatedAt,
+		},
+	}
+
+	for _, member := range members {
+		group.Members = append(group.Members, models.SCIMGroupMember{
+			Value:   strconv.Itoa(member.UserID),
+			Display: member.Email,
+		})
+	}
+
+	return group, nil
+}
+
+func (ss *SCIMService) getRole(roleID models.RoleID) (*models.Role, error) {
+	roles, err := ss.rbacRepository.GetRoles()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range roles {
+		if roles[i].ID == roleID {
+			return &roles[i], nil
+		}
+	}
+
+	return nil, utils.ErrRoleNotFound
+}
+
+// parseGroupMembers returns the user IDs of members. When mustExist is set,
+// every member must be an existing user.
+func (ss *SCIMService) parseGroupMembers(members []models.SCIMGroupMember, mustExist bool) ([]models.UserID, error) {
+	userIDs := make([]models.UserID, 0, len(members))
+
+	for _, member := range members {
+		userID, err := strconv.Atoi(member.Value)
+		if err != nil {
+			return nil, utils.ErrSCIMGroupMemberInvalid
+		}
+
+		if mustExist {
+			_, err = ss.userRepository.GetUserByID(userID)
+			if errors.Is(err, utils.ErrUserNotFound) {
+				return nil, utils.ErrSCIMGroupMemberInvalid
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs, nil
+}
+
+func (ss *SCIMService) parseGroupMembersValue(raw json.RawMessage, mustExist bool) ([]models.UserID, error) {
+	var members []models.SCIMGroupMember
+
+	err := json.Unmarshal(raw, &members)
+	if err != nil {
+		return nil, utils.ErrSCIMPatchInvalid
+	}
+
+	return ss.parseGroupMembers(members, mustExist)
+}
+
+// isProtectedGroup reports whether the role grants control over roles or
+// other users' accounts. Its members are only managed through the admin
+// API, so the provisioning token cannot hand out or take away admin rights.
+func isProtectedGroup(role *models.Role) bool {
+	if role.Name == utils.RoleAdmin {
+		return true
+	}
+
+	for _, permission := range role.Permissions {
+		if permission == utils.PermissionRolesWrite || permission == utils.PermissionUsersImpersonate {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setGroupMembers assigns the role to exactly the users in members, in one
+// transaction. Protected roles fail with ErrSCIMGroupProtected unless their
+// members stay the same.
+func (ss *SCIMService) setGroupMembers(role *models.Role, members map[models.UserID]bool) error {
+	current, err := ss.rbacRepository.GetRoleMembers(role.ID)
+	if err != nil {
+		return err
+	}
+
+	holders := make(map[models.UserID]bool, len(current))
+	removed := []models.UserID{}
+	for _, member := range current {
+		holders[member.UserID] = true
+
+		if !members[member.UserID] {
+			removed = append(removed, member.UserID)
+		}
+	}
+
+	added := []models.UserID{}
+	for userID := range members {
+		if !holders[userID] {
+			added = append(added, userID)
+		}
+	}
+
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	if isProtectedGroup(role) {
+		return utils.ErrSCIMGroupProtected
+	}
+
+	err = ss.rbacRepository.UpdateRoleMembers(role.ID, added, removed)
+	if err != nil {
+		log.Printf("setGroupMembers: error updating members of role %s: %s", role.Name, err.Error())
+		return err
+	}
+
+	log.Printf("setGroupMembers: role %s has %d members", role.Name, len(members))
+	return nil
+}
+
+func checkGroupName(role *models.Role, raw json.RawMessage) error {
+	name, err := scimString(raw)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(name) != role.Name {
+		return utils.ErrSCIMGroupRenameNotSupported
+	}
+
+	return nil
+}
+
+func (ss *SCIMService) GetGroups(filter string, startIndex int, count int) ([]models.SCIMGroup, int, error) {
+	displayName, filtered, err := parseSCIMFilter(filter, "displayName")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	roles, err := ss.rbacRepository.GetRoles()
+	if err != nil {
+		log.Printf("GetGroups: error getting roles: %s", err.Error())
+		return nil, 0, err
+	}
+
+	if filtered {
+		matching := []models.Role{}
+		for _, role := range roles {
+			if strings.EqualFold(role.Name, displayName) {
+				matching = append(matching, role)
+			}
+		}
+
+		roles = matching
+	}
+
+	groups := []models.SCIMGroup{}
+
+	for i := startIndex - 1; i < len(roles) && len(groups) < count; i++ {
+		group, err := ss.newSCIMGroup(&roles[i])
+		if err != nil {
+			return nil, 0, err
+		}
+
+		groups = append(groups, *group)
+	}
+
+	return groups, len(roles), nil
+}
+
+func (ss *SCIMService) GetGroup(roleID models.RoleID) (*models.SCIMGroup, error) {
+	role, err := ss.getRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ss.newSCIMGroup(role)
+}
+
+// CreateGroup creates a role without permissions named after the group.
+// Admins grant it permissions, or link the group to an existing role by
+// giving it the role's name in the identity provider.
+func (ss *SCIMService) CreateGroup(resource *models.SCIMGroup) (*models.SCIMGroup, error) {
+	name := strings.TrimSpace(resource.DisplayName)
+	if !roleNameRegex.MatchString(name) {
+		return nil, utils.ErrRoleNameInvalid
+	}
+
+	userIDs, err := ss.parseGroupMembers(resource.Members, true)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{
+		Name:        name,
+		Permissions: []string{},
+	}
+
+	err = ss.rbacRepository.CreateRole(role)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ss.rbacRepository.UpdateRoleMembers(role.ID, userIDs, nil)
+	if err != nil {
+		log.Printf("CreateGroup: error assigning members: %s", err.Error())
+		return nil, err
+	}
+
+	log.Printf("CreateGroup: role %s provisioned", role.Name)
+	return ss.newSCIMGroup(role)
+}
+
+func (ss *SCIMService) ReplaceGroup(roleID models.RoleID, resource *models.SCIMGroup) (*models.SCIMGroup, error) {
+	role, err := ss.getRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(resource.DisplayName) != role.Name {
+		return nil, utils.ErrSCIMGroupRenameNotSupported
+	}
+
+	userIDs, err := ss.parseGroupMembers(resource.Members, true)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[models.UserID]bool, len(userIDs))
+	for _, userID := range userIDs {
+		members[userID] = true
+	}
+
+	err = ss.setGroupMembers(role, members)
+	if err != nil {
+		return nil, err
+	}
+
+	return ss.newSCIMGroup(role)
+}
+
+// PatchGroup adds, removes and replaces members of the group. Operations are
+// applied in order to the member list, which is then saved at once.
+func (ss *SCIMService) PatchGroup(roleID models.RoleID, operations []models.SCIMPatchOperation) (*models.SCIMGroup, error) {
+	role, err := ss.getRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := ss.rbacRepository.GetRoleMembers(role.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[models.UserID]bool, len(current))
+	for _, member := range current {
+		members[member.UserID] = true
+	}
+
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		path := strings.ToLower(strings.TrimPrefix(operation.Path, utils.SCIMSchemaGroup+":"))
+
+		switch {
+		case (op == "add" || op == "replace") && path == "":
+			var values map[string]json.RawMessage
+
+			err = json.Unmarshal(operation.Value, &values)
+			if err != nil {
+				return nil, utils.ErrSCIMPatchInvalid
+			}
+
+			for attribute, value := range values {
+				switch strings.ToLower(attribute) {
+				case "displayname":
+					err = checkGroupName(role, value)
+					if err != nil {
+						return nil, err
+					}
+				case "members":
+					userIDs, err := ss.parseGroupMembersValue(value, true)
+					if err != nil {
+						return nil, err
+					}
+
+					if op == "replace" {
+						members = map[models.UserID]bool{}
+					}
+					for _, userID := range userIDs {
+						members[userID] = true
+					}
+				default:
+					return nil, utils.ErrSCIMPatchInvalid
+				}
+			}
+		case (op == "add" || op == "replace") && path == "displayname":
+			err = checkGroupName(role, operation.Value)
+			if err != nil {
+				return nil, err
+			}
+		case (op == "add" || op == "replace") && path == "members":
+			userIDs, err := ss.parseGroupMembersValue(operation.Value, true)
+			if err != nil {
+				return nil, err
+			}
+
+			if op == "replace" {
+				members = map[models.UserID]bool{}
+			}
+			for _, userID := range userIDs {
+				members[userID] = true
+			}
+		case op == "remove" && path == "members":
+			if len(operation.Value) == 0 || string(operation.Value) == "null" {
+				members = map[models.UserID]bool{}
+				continue
+			}
+
+			userIDs, err := ss.parseGroupMembersValue(operation.Value, false)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, userID := range userIDs {
+				delete(members, userID)
+			}
+		case op == "remove" && scimMemberFilterRegex.MatchString(operation.Path):
+			value := scimMemberFilterRegex.FindStringSubmatch(operation.Path)[1]
+
+			userIDs, err := ss.parseGroupMembers([]models.SCIMGroupMember{{Value: value}}, false)
+			if err != nil {
+				return nil, err
+			}
+
+			delete(members, userIDs[0])
+		default:
+			return nil, utils.ErrSCIMPatchInvalid
+		}
+	}
+
+	err = ss.setGroupMembers(role, members)
+	if err != nil {
+		return nil, err
+	}
+
+	return ss.newSCIMGroup(role)
+}
+
+func (ss *SCIMService) DeleteGroup(roleID models.RoleID) error {
+	role, err := ss.getRole(roleID)
+	if err != nil {
+		return err
+	}
+
+	if role.Name == utils.RoleAdmin {
+		return utils.ErrRoleProtected
+	}
+
+	err = ss.rbacRepository.DeleteRole(role.ID)
+	if err != nil {
+		log.Printf("DeleteGroup: error deleting role: %s", err.Error())
+		return err
+	}
+
+	log.Printf("DeleteGroup: role %s deleted", role.Name)
+	return nil
+}
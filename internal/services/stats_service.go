@@ -0,0 +1,32 @@
+package services
+
+import (
+	"log"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+)
+
+type IStatsService interface {
+	GetStats() (*models.Stats, error)
+}
+
+type StatsService struct {
+	statsRepository repositories.StatsRepository
+}
+
+func NewStatsService(statsRepository repositories.StatsRepository) IStatsService {
+	return &StatsService{
+		statsRepository: statsRepository,
+	}
+}
+
+func (ss *StatsService) GetStats() (*models.Stats, error) {
+	stats, err := ss.statsRepository.GetStats()
+	if err != nil {
+		log.Printf("GetStats: error getting stats: %s", err.Error())
+		return nil, err
+	}
+
+	return stats, nil
+}
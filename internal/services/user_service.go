@@ -2,6 +2,7 @@ package services
 
 import (
 	"log"
+	"time"
 
 	"github.com/pedrotunin/go-jwt-auth/internal/models"
 	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
@@ -10,9 +11,13 @@ import (
 
 type IUserService interface {
 	GetUserByEmail(email string) (*models.User, error)
+	GetUserByID(userID models.UserID) (*models.User, error)
 	CreateUser(u *models.User) error
 	VerifyActiveUser(u *models.User) error
 	ActivateUser(userID models.UserID) error
+	UpdateProfile(userID models.UserID, profile *models.UserProfile) (*models.User, error)
+	RecordLogin(userID models.UserID) error
+	UpgradePasswordHash(u *models.User, password string) error
 }
 
 type UserService struct {
@@ -39,7 +44,7 @@ func (us *UserService) GetUserByEmail(email string) (*models.User, error) {
 }
 
 func (us *UserService) CreateUser(u *models.User) error {
-	hash, err := us.hashService.HashArgon2id(u.Password)
+	hash, err := us.hashService.HashPassword(u.Password)
 	if err != nil {
 		log.Printf("CreateUser: error hashing password: %s", err.Error())
 		return err
@@ -81,3 +86,73 @@ func (us *UserService) ActivateUser(userID models.UserID) error {
 
 	return nil
 }
+
+func (us *UserService) GetUserByID(userID models.UserID) (*models.User, error) {
+	user, err := us.userRepository.GetUserByID(userID)
+	if err != nil {
+		log.Printf("GetUserByID: error getting user in database: %s", err.Error())
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (us *UserService) UpdateProfile(userID models.UserID, profile *models.UserProfile) (*models.User, error) {
+	err := profile.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := us.userRepository.GetUserByID(userID)
+	if err != nil {
+		log.Printf("UpdateProfile: error getting user in database: %s", err.Error())
+		return nil, err
+	}
+
+	profile.Apply(user)
+
+	err = us.userRepository.UpdateUserProfile(user)
+	if err != nil {
+		log.Printf("UpdateProfile: error updating user profile: %s", err.Error())
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (us *UserService) RecordLogin(userID models.UserID) error {
+	err := us.userRepository.UpdateLastLogin(userID, time.Now())
+	if err != nil {
+		log.Printf("RecordLogin: error updating last login: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// UpgradePasswordHash re-hashes the user's password with the current Argon2id
+// parameters and pepper when the stored hash is weaker, uses an old pepper or
+// a legacy algorithm. It must only be called after the password has been
+// verified.
+func (us *UserService) UpgradePasswordHash(u *models.User, password string) error {
+	if !us.hashService.NeedsRehash(u.Password) {
+		return nil
+	}
+
+	hash, err := us.hashService.HashPassword(password)
+	if err != nil {
+		log.Printf("UpgradePasswordHash: error hashing password: %s", err.Error())
+		return err
+	}
+
+	err = us.userRepository.UpdateUserPassword(u.ID, hash)
+	if err != nil {
+		log.Printf("UpgradePasswordHash: error updating password: %s", err.Error())
+		return err
+	}
+
+	u.Password = hash
+
+	log.Printf("UpgradePasswordHash: password hash upgraded")
+	return nil
+}
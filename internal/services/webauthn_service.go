@@ -0,0 +1,645 @@
+package services
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+// COSE algorithm identifiers accepted for credential public keys.
+const (
+	coseAlgES256 int64 = -7
+	coseAlgEdDSA int64 = -8
+	coseAlgRS256 int64 = -257
+)
+
+// Authenticator data flags (WebAuthn §6.1).
+const (
+	authDataFlagUserPresent  byte = 0x01
+	authDataFlagUserVerified byte = 0x04
+	authDataFlagAttestedData byte = 0x40
+)
+
+type IWebAuthnService interface {
+	BeginRegistration(user *models.User, reauth WebAuthnReauthentication) (*WebAuthnCreationOptions, error)
+	FinishRegistration(userID models.UserID, name string, attestation *WebAuthnAttestation) (*models.WebAuthnCredential, error)
+	BeginLogin(userID *models.UserID) (*WebAuthnRequestOptions, error)
+	FinishLogin(expectedUserID *models.UserID, assertion *WebAuthnAssertion) (models.UserID, error)
+	GetCredentials(userID models.UserID) ([]models.WebAuthnCredential, error)
+	DeleteCredential(userID models.UserID, id models.WebAuthnCredentialID, reauth WebAuthnReauthentication) error
+}
+
+// WebAuthnReauthentication proves the user is present before a passkey is
+// added or removed: either their password or a current TOTP code.
+type WebAuthnReauthentication struct {
+	Password string
+	Code     string
+}
+
+type WebAuthnConfig struct {
+	RPID         string
+	RPName       string
+	Origin       string
+	ChallengeTTL time.Duration
+}
+
+type WebAuthnService struct {
+	credentialRepository repositories.WebAuthnCredentialRepository
+	challengeRepository  repositories.WebAuthnChallengeRepository
+	userRepository       repositories.UserRepository
+	hashService          IHashService
+	mfaService           IMFAService
+	config               WebAuthnConfig
+}
+
+func NewWebAuthnService(
+	credentialRepository repositories.WebAuthnCredentialRepository,
+	challengeRepository repositories.WebAuthnChallengeRepository,
+	userRepository repositories.UserRepository,
+	hashService IHashService,
+	mfaService IMFAService,
+	config WebAuthnConfig,
+) IWebAuthnService {
+	return &WebAuthnService{
+		credentialRepository: credentialRepository,
+		challengeRepository:  challengeRepository,
+		userRepository:       userRepository,
+		hashService:          hashService,
+		mfaService:           mfaService,
+		config:               config,
+	}
+}
+
+type WebAuthnRelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type WebAuthnUserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type WebAuthnCredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type WebAuthnCredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type WebAuthnAuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// WebAuthnCreationOptions is the JSON form of PublicKeyCredentialCreationOptions.
+type WebAuthnCreationOptions struct {
+	Challenge              string                         `json:"challenge"`
+	RP                     WebAuthnRelyingParty           `json:"rp"`
+	User                   WebAuthnUserEntity             `json:"user"`
+	PubKeyCredParams       []WebAuthnCredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                          `json:"timeout"`
+	Attestation            string                         `json:"attestation"`
+	ExcludeCredentials     []WebAuthnCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection WebAuthnAuthenticatorSelection `json:"authenticatorSelection"`
+}
+
+// WebAuthnRequestOptions is the JSON form of PublicKeyCredentialRequestOptions.
+type WebAuthnRequestOptions struct {
+	Challenge        string                         `json:"challenge"`
+	Timeout          int64                          `json:"timeout"`
+	RPID             string                         `json:"rpId"`
+	AllowCredentials []WebAuthnCredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                         `json:"userVerification"`
+}
+
+type WebAuthnAttestation struct {
+	ClientDataJSON    []byte
+	AttestationObject []byte
+}
+
+type WebAuthnAssertion struct {
+	CredentialID      []byte
+	ClientDataJSON    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+	UserHandle        []byte
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type authenticatorData struct {
+	RPIDHash            []byte
+	Flags               byte
+	SignCount           uint32
+	CredentialID        []byte
+	CredentialPublicKey []byte
+}
+
+func webAuthnUserHandle(userID models.UserID) []byte {
+	return []byte(strconv.Itoa(userID))
+}
+
+func (ws *WebAuthnService) newChallenge(userID *models.UserID, ceremony models.WebAuthnCeremony) (string, error) {
+	random, err := utils.GetRandomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	// The hex string is only a source of randomness; clients receive and echo
+	// back the base64url form.
+	challenge := base64.RawURLEncoding.EncodeToString([]byte(random))
+
+	err = ws.challengeRepository.CreateWebAuthnChallenge(&models.WebAuthnChallenge{
+		Challenge: challenge,
+		UserID:    userID,
+		Ceremony:  ceremony,
+		ExpiresAt: time.Now().Add(ws.config.ChallengeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return challenge, nil
+}
+
+func (ws *WebAuthnService) credentialDescriptors(userID models.UserID) ([]WebAuthnCredentialDescriptor, error) {
+	credentials, err := ws.credentialRepository.GetWebAuthnCredentialsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptors := make([]WebAuthnCredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		descriptors = append(descriptors, WebAuthnCredentialDescriptor{
+			Type: "public-key",
+			ID:   credential.CredentialID,
+		})
+	}
+
+	return descriptors, nil
+}
+
+// BeginRegistration starts a passkey registration once the user confirmed it
+// is them. A passkey logs in on its own, so a stolen access token alone must
+// not be enough to add one.
+func (ws *WebAuthnService) BeginRegistration(user *models.User, reauth WebAuthnReauthentication) (*WebAuthnCreationOptions, error) {
+	err := ws.verifyReauthentication(user.ID, reauth)
+	if err != nil {
+		log.Printf("BeginRegistration: reauthentication failed: %s", err.Error())
+		return nil, err
+	}
+
+	exclude, err := ws.credentialDescriptors(user.ID)
+	if err != nil {
+		log.Printf("BeginRegistration: error getting credentials: %s", err.Error())
+		return nil, err
+	}
+
+	challenge, err := ws.newChallenge(&user.ID, models.WebAuthnCeremonyRegistration)
+	if err != nil {
+		log.Printf("BeginRegistration: error creating challenge: %s", err.Error())
+		return nil, err
+	}
+
+	displayName := user.DisplayName
+	if displayName == "" {
+		displayName = user.Email
+	}
+
+	return &WebAuthnCreationOptions{
+		Challenge: challenge,
+		RP: WebAuthnRelyingParty{
+			ID:   ws.config.RPID,
+			Name: ws.config.RPName,
+		},
+		User: WebAuthnUserEntity{
+			ID:          base64.RawURLEncoding.EncodeToString(webAuthnUserHandle(user.ID)),
+			Name:        user.Email,
+			DisplayName: displayName,
+		},
+		PubKeyCredParams: []WebAuthnCredentialParameter{
+			{Type: "public-key", Alg: coseAlgES256},
+			{Type: "public-key", Alg: coseAlgEdDSA},
+			{Type: "public-key", Alg: coseAlgRS256},
+		},
+		Timeout:            ws.config.ChallengeTTL.Milliseconds(),
+		Attestation:        "none",
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: WebAuthnAuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: "preferred",
+		},
+	}, nil
+}
+
+// verifyClientData checks the ceremony type and origin of clientDataJSON and
+// consumes the challenge it answers.
+func (ws *WebAuthnService) verifyClientData(raw []byte, ceremonyType string, ceremony models.WebAuthnCeremony) (*models.WebAuthnChallenge, error) {
+	var cd clientData
+
+	err := json.Unmarshal(raw, &cd)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid client data", utils.ErrWebAuthnVerificationFailed)
+	}
+
+	if cd.Type != ceremonyType {
+		return nil, fmt.Errorf("%w: unexpected client data type %q", utils.ErrWebAuthnVerificationFailed, cd.Type)
+	}
+
+	if cd.Origin != ws.config.Origin {
+		return nil, fmt.Errorf("%w: unexpected origin %q", utils.ErrWebAuthnVerificationFailed, cd.Origin)
+	}
+
+	challenge, err := ws.challengeRepository.ConsumeWebAuthnChallenge(cd.Challenge, ceremony)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(challenge.ExpiresAt) {
+		return nil, utils.ErrWebAuthnChallengeInvalid
+	}
+
+	return challenge, nil
+}
+
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, fmt.Errorf("%w: authenticator data too short", utils.ErrWebAuthnVerificationFailed)
+	}
+
+	ad := &authenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+
+	if ad.Flags&authDataFlagAttestedData == 0 {
+		return ad, nil
+	}
+
+	rest := data[37:]
+	if len(rest) < 18 {
+		return nil, fmt.Errorf("%w: attested credential data too short", utils.ErrWebAuthnVerificationFailed)
+	}
+
+	// Skip the 16 byte AAGUID; attestation is not verified.
+	idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLen {
+		return nil, fmt.Errorf("%w: credential id too short", utils.ErrWebAuthnVerificationFailed)
+	}
+
+	ad.CredentialID = rest[:idLen]
+	rest = rest[idLen:]
+
+	_, after, err := utils.DecodeCBOR(rest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid credential public key", utils.ErrWebAuthnVerificationFailed)
+	}
+
+	ad.CredentialPublicKey = rest[:len(rest)-len(after)]
+	return ad, nil
+}
+
+func coseBytes(key map[any]any, label int64) []byte {
+	value, _ := key[label].([]byte)
+	return value
+}
+
+// parseCOSEKey converts a COSE_Key (RFC 9053) into a Go public key.
+func parseCOSEKey(raw []byte) (crypto.PublicKey, int64, error) {
+	decoded, _, err := utils.DecodeCBOR(raw)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	key, ok := decoded.(map[any]any)
+	if !ok {
+		return nil, 0, utils.ErrCBORMalformed
+	}
+
+	kty, _ := key[int64(1)].(int64)
+	alg, _ := key[int64(3)].(int64)
+
+	switch {
+	case kty == 2 && alg == coseAlgES256:
+		crv, _ := key[int64(-1)].(int64)
+		x, y := coseBytes(key, -2), coseBytes(key, -3)
+		if crv != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, fmt.Errorf("invalid ec2 key")
+		}
+
+		// ecdh validates that the point is on the curve.
+		_, err := ecdh.P256().NewPublicKey(append(append([]byte{0x04}, x...), y...))
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, alg, nil
+	case kty == 1 && alg == coseAlgEdDSA:
+		crv, _ := key[int64(-1)].(int64)
+		x := coseBytes(key, -2)
+		if crv != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, fmt.Errorf("invalid okp key")
+		}
+
+		return ed25519.PublicKey(x), alg, nil
+	case kty == 3 && alg == coseAlgRS256:
+		n, e := coseBytes(key, -1), coseBytes(key, -2)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, fmt.Errorf("invalid rsa key")
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, alg, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported cose key type %d with alg %d", kty, alg)
+	}
+}
+
+func verifyCOSESignature(pub crypto.PublicKey, alg int64, data, signature []byte) bool {
+	switch alg {
+	case coseAlgES256:
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(pub.(*ecdsa.PublicKey), digest[:], signature)
+	case coseAlgRS256:
+		digest := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(pub.(*rsa.PublicKey), crypto.SHA256, digest[:], signature) == nil
+	case coseAlgEdDSA:
+		return ed25519.Verify(pub.(ed25519.PublicKey), data, signature)
+	default:
+		return false
+	}
+}
+
+func (ws *WebAuthnService) verifyRPIDHash(ad *authenticatorData) error {
+	expected := sha256.Sum256([]byte(ws.config.RPID))
+	if !bytes.Equal(ad.RPIDHash, expected[:]) {
+		return fmt.Errorf("%w: rp id hash mismatch", utils.ErrWebAuthnVerificationFailed)
+	}
+
+	if ad.Flags&authDataFlagUserPresent == 0 {
+		return fmt.Errorf("%w: user not present", utils.ErrWebAuthnVerificationFailed)
+	}
+
+	return nil
+}
+
+func (ws *WebAuthnService) FinishRegistration(userID models.UserID, name string, attestation *WebAuthnAttestation) (*models.WebAuthnCredential, error) {
+	challenge, err := ws.verifyClientData(attestation.ClientDataJSON, "webauthn.create", models.WebAuthnCeremonyRegistration)
+	if err != nil {
+		log.Printf("FinishRegistration: error verifying client data: %s", err.Error())
+		return nil, err
+	}
+
+	if challenge.UserID == nil || *challenge.UserID != userID {
+		return nil, utils.ErrWebAuthnChallengeInvalid
+	}
+
+	decoded, _, err := utils.DecodeCBOR(attestation.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid attestation object", utils.ErrWebAuthnVerificationFailed)
+	}
+
+	attestationObject, ok := decoded.(map[any]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid attestation object", utils.ErrWebAuthnVerificationFailed)
+	}
+
+	// We request "none" attestation, so the statement is not verified.
+	format, _ := attestationObject["fmt"].(string)
+	if format != "none" {
+		return nil, fmt.Errorf("%w: unsupported attestation format %q", utils.ErrWebAuthnVerificationFailed, format)
+	}
+
+	rawAuthData, _ := attestationObject["authData"].([]byte)
+
+	ad, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ws.verifyRPIDHash(ad)
+	if err != nil {
+		return nil, err
+	}
+
+	if ad.Flags&authDataFlagAttestedData == 0 || len(ad.CredentialID) == 0 {
+		return nil, fmt.Errorf("%w: missing attested credential data", utils.ErrWebAuthnVerificationFailed)
+	}
+
+	_, _, err = parseCOSEKey(ad.CredentialPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", utils.ErrWebAuthnVerificationFailed, err.Error())
+	}
+
+	credential := &models.WebAuthnCredential{
+		UserID:       userID,
+		CredentialID: base64.RawURLEncoding.EncodeToString(ad.CredentialID),
+		PublicKey:    ad.CredentialPublicKey,
+		SignCount:    ad.SignCount,
+		Name:         name,
+	}
+
+	err = ws.credentialRepository.CreateWebAuthnCredential(credential)
+	if err != nil {
+		log.Printf("FinishRegistration: error saving credential: %s", err.Error())
+		return nil, err
+	}
+
+	log.Printf("FinishRegistration: webauthn credential registered for user %d", userID)
+	return credential, nil
+}
+
+// BeginLogin starts an authentication ceremony. With a userID it is used as a
+// second factor and only that user's credentials are allowed; without one it
+// is a passwordless login with a discoverable credential.
+func (ws *WebAuthnService) BeginLogin(userID *models.UserID) (*WebAuthnRequestOptions, error) {
+	allow := []WebAuthnCredentialDescriptor{}
+	userVerification := "required"
+
+	if userID != nil {
+		descriptors, err := ws.credentialDescriptors(*userID)
+		if err != nil {
+			log.Printf("BeginLogin: error getting credentials: %s", err.Error())
+			return nil, err
+		}
+
+		if len(descriptors) == 0 {
+			return nil, utils.ErrWebAuthnCredentialNotFound
+		}
+
+		allow = descriptors
+		userVerification = "preferred"
+	}
+
+	challenge, err := ws.newChallenge(userID, models.WebAuthnCeremonyAuthentication)
+	if err != nil {
+		log.Printf("BeginLogin: error creating challenge: %s", err.Error())
+		return nil, err
+	}
+
+	return &WebAuthnRequestOptions{
+		Challenge:        challenge,
+		Timeout:          ws.config.ChallengeTTL.Milliseconds(),
+		RPID:             ws.config.RPID,
+		AllowCredentials: allow,
+		UserVerification: userVerification,
+	}, nil
+}
+
+func (ws *WebAuthnService) FinishLogin(expectedUserID *models.UserID, assertion *WebAuthnAssertion) (models.UserID, error) {
+	challenge, err := ws.verifyClientData(assertion.ClientDataJSON, "webauthn.get", models.WebAuthnCeremonyAuthentication)
+	if err != nil {
+		log.Printf("FinishLogin: error verifying client data: %s", err.Error())
+		return 0, err
+	}
+
+	if (expectedUserID == nil) != (challenge.UserID == nil) ||
+		(expectedUserID != nil && *expectedUserID != *challenge.UserID) {
+		return 0, utils.ErrWebAuthnChallengeInvalid
+	}
+
+	credential, err := ws.credentialRepository.GetWebAuthnCredentialByCredentialID(
+		base64.RawURLEncoding.EncodeToString(assertion.CredentialID),
+	)
+	if err != nil {
+		log.Printf("FinishLogin: error getting credential: %s", err.Error())
+		return 0, err
+	}
+
+	if challenge.UserID != nil && credential.UserID != *challenge.UserID {
+		return 0, utils.ErrWebAuthnCredentialNotFound
+	}
+
+	if len(assertion.UserHandle) > 0 && !bytes.Equal(assertion.UserHandle, webAuthnUserHandle(credential.UserID)) {
+		return 0, fmt.Errorf("%w: user handle mismatch", utils.ErrWebAuthnVerificationFailed)
+	}
+
+	ad, err := parseAuthenticatorData(assertion.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+
+	err = ws.verifyRPIDHash(ad)
+	if err != nil {
+		return 0, err
+	}
+
+	if challenge.UserID == nil && ad.Flags&authDataFlagUserVerified == 0 {
+		return 0, fmt.Errorf("%w: user verification required", utils.ErrWebAuthnVerificationFailed)
+	}
+
+	pub, alg, err := parseCOSEKey(credential.PublicKey)
+	if err != nil {
+		log.Printf("FinishLogin: error parsing stored public key: %s", err.Error())
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(assertion.ClientDataJSON)
+	signed := append(append([]byte{}, assertion.AuthenticatorData...), clientDataHash[:]...)
+
+	if !verifyCOSESignature(pub, alg, signed, assertion.Signature) {
+		return 0, fmt.Errorf("%w: invalid signature", utils.ErrWebAuthnVerificationFailed)
+	}
+
+	// A counter that does not move forward may mean the authenticator was
+	// cloned. Authenticators that do not implement counters always send 0.
+	if (ad.SignCount != 0 || credential.SignCount != 0) && ad.SignCount <= credential.SignCount {
+		return 0, fmt.Errorf("%w: sign counter did not increase", utils.ErrWebAuthnVerificationFailed)
+	}
+
+	err = ws.credentialRepository.UpdateWebAuthnSignCount(credential.ID, ad.SignCount)
+	if err != nil {
+		log.Printf("FinishLogin: error updating sign count: %s", err.Error())
+		return 0, err
+	}
+
+	log.Printf("FinishLogin: webauthn assertion verified for user %d", credential.UserID)
+	return credential.UserID, nil
+}
+
+func (ws *WebAuthnService) GetCredentials(userID models.UserID) ([]models.WebAuthnCredential, error) {
+	credentials, err := ws.credentialRepository.GetWebAuthnCredentialsByUserID(userID)
+	if err != nil {
+		log.Printf("GetCredentials: error getting credentials: %s", err.Error())
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+// verifyReauthentication checks the TOTP code when one is given, otherwise
+// the password.
+func (ws *WebAuthnService) verifyReauthentication(userID models.UserID, reauth WebAuthnReauthentication) error {
+	if reauth.Code != "" {
+		return ws.mfaService.VerifyTOTP(userID, reauth.Code)
+	}
+
+	if reauth.Password == "" {
+		return utils.ErrReauthenticationRequired
+	}
+
+	user, err := ws.userRepository.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	err = ws.hashService.ComparePassword(reauth.Password, user.Password)
+	if err != nil {
+		log.Printf("verifyReauthentication: error comparing password and hash: %s", err.Error())
+		return utils.ErrPasswordIncorrect
+	}
+
+	return nil
+}
+
+// DeleteCredential removes a passkey once the user confirmed it is them, so
+// a stolen access token alone cannot remove a second factor.
+func (ws *WebAuthnService) DeleteCredential(userID models.UserID, id models.WebAuthnCredentialID, reauth WebAuthnReauthentication) error {
+	err := ws.verifyReauthentication(userID, reauth)
+	if err != nil {
+		log.Printf("DeleteCredential: reauthentication failed: %s", err.Error())
+		return err
+	}
+
+	err = ws.credentialRepository.DeleteWebAuthnCredential(id, userID)
+	if err != nil {
+		log.Printf("DeleteCredential: error deleting credential: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var ErrCBORMalformed = errors.New("malformed cbor data")
+
+const cborMaxDepth = 16
+
+// DecodeCBOR decodes the first CBOR data item in data (RFC 8949) and returns
+// it with the bytes that follow it. It covers what WebAuthn needs: integers
+// become int64, byte strings []byte, text strings string, arrays []any, maps
+// map[any]any, plus bools, nil and floats. Indefinite lengths are rejected.
+func DecodeCBOR(data []byte) (value any, rest []byte, err error) {
+	return decodeCBOR(data, 0)
+}
+
+func decodeCBORHead(data []byte) (major byte, arg uint64, rest []byte, err error) {
+	if len(data) < 1 {
+		return 0, 0, nil, ErrCBORMalformed
+	}
+
+	major = data[0] >> 5
+	info := data[0] & 0x1f
+	data = data[1:]
+
+	switch {
+	case info < 24:
+		return major, uint64(info), data, nil
+	case info == 24:
+		if len(data) < 1 {
+			return 0, 0, nil, ErrCBORMalformed
+		}
+		return major, uint64(data[0]), data[1:], nil
+	case info == 25:
+		if len(data) < 2 {
+			return 0, 0, nil, ErrCBORMalformed
+		}
+		return major, uint64(binary.BigEndian.Uint16(data)), data[2:], nil
+	case info == 26:
+		if len(data) < 4 {
+			return 0, 0, nil, ErrCBORMalformed
+		}
+		return major, uint64(binary.BigEndian.Uint32(data)), data[4:], nil
+	case info == 27:
+		if len(data) < 8 {
+			return 0, 0, nil, ErrCBORMalformed
+		}
+		return major, binary.BigEndian.Uint64(data), data[8:], nil
+	default:
+		return 0, 0, nil, ErrCBORMalformed
+	}
+}
+
+func decodeCBOR(data []byte, depth int) (any, []byte, error) {
+	if depth > cborMaxDepth {
+		return nil, nil, ErrCBORMalformed
+	}
+
+	info := byte(0)
+	if len(data) > 0 {
+		info = data[0] & 0x1f
+	}
+
+	major, arg, rest, err := decodeCBORHead(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, nil, ErrCBORMalformed
+		}
+		return int64(arg), rest, nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, nil, ErrCBORMalformed
+		}
+		return -1 - int64(arg), rest, nil
+	case 2, 3:
+		if arg > uint64(len(rest)) {
+			return nil, nil, ErrCBORMalformed
+		}
+		value := rest[:arg]
+		if major == 3 {
+			return string(value), rest[arg:], nil
+		}
+		return append([]byte(nil), value...), rest[arg:], nil
+	case 4:
+		if arg > uint64(len(rest)) {
+			return nil, nil, ErrCBORMalformed
+		}
+		items := make([]any, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			var item any
+			item, rest, err = decodeCBOR(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, rest, nil
+	case 5:
+		if arg > uint64(len(rest)) {
+			return nil, nil, ErrCBORMalformed
+		}
+		items := make(map[any]any, arg)
+		for i := uint64(0); i < arg; i++ {
+			var key, value any
+			key, rest, err = decodeCBOR(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, ErrCBORMalformed
+			}
+			value, rest, err = decodeCBOR(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items[key] = value
+		}
+		return items, rest, nil
+	case 6:
+		return decodeCBOR(rest, depth+1)
+	default:
+		switch {
+		case info == 20:
+			return false, rest, nil
+		case info == 21:
+			return true, rest, nil
+		case info == 22 || info == 23:
+			return nil, rest, nil
+		case info == 26:
+			return float64(math.Float32frombits(uint32(arg))), rest, nil
+		case info == 27:
+			return math.Float64frombits(arg), rest, nil
+		default:
+			return nil, nil, ErrCBORMalformed
+		}
+	}
+}
@@ -6,3 +6,66 @@ const (
 	UserStatusInactive = "inactive"
 	UserStatusPending  = "pending"
 )
+
+// Account Deletion Constants
+const (
+	AccountDeletionModeDelete    = "delete"
+	AccountDeletionModeAnonymize = "anonymize"
+)
+
+// Login History Constants
+const (
+	LoginMethodPassword  = "password"
+	LoginMethodMFA       = "mfa"
+	LoginMethodWebAuthn  = "webauthn"
+	LoginMethodMagicLink = "magic_link"
+	LoginMethodEmailOTP  = "email_otp"
+
+	LoginFailurePasswordIncorrect = "password_incorrect"
+	LoginFailureAccountLocked     = "account_locked"
+	LoginFailureThrottled         = "throttled"
+	LoginFailureAccountNotActive  = "account_not_active"
+	LoginFailureMFACodeInvalid    = "mfa_code_invalid"
+	LoginFailureWebAuthnInvalid   = "webauthn_invalid"
+)
+
+// RBAC Constants
+const (
+	RoleAdmin = "admin"
+
+	PermissionUsersRead        = "users:read"
+	PermissionUsersWrite       = "users:write"
+	PermissionRolesRead        = "roles:read"
+	PermissionRolesWrite       = "roles:write"
+	PermissionUsersImpersonate = "users:impersonate"
+	PermissionUsersInvite      = "users:invite"
+)
+
+// Organization Constants
+const (
+	OrganizationRoleOwner  = "owner"
+	OrganizationRoleAdmin  = "admin"
+	OrganizationRoleMember = "member"
+)
+
+// App Constants
+const (
+	AppRoleOwner  = "owner"
+	AppRoleEditor = "editor"
+	AppRoleViewer = "viewer"
+)
+
+// SCIM Constants
+const (
+	SCIMSchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMSchemaGroup        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIMSchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMSchemaPatchOp      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMSchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+
+	SCIMErrorInvalidFilter = "invalidFilter"
+	SCIMErrorInvalidSyntax = "invalidSyntax"
+	SCIMErrorInvalidValue  = "invalidValue"
+	SCIMErrorUniqueness    = "uniqueness"
+	SCIMErrorMutability    = "mutability"
+)
@@ -7,6 +7,9 @@ var ErrInternalServerError = errors.New("internal server error. contact system a
 var ErrAuthorizationHeaderNotFound = errors.New("Authorization header not found")
 var ErrMultipleAuthorizationHeaders = errors.New("multiple Authorization headers not accepted")
 var ErrAuthorizationHeaderMalformed = errors.New("Authorization header malformed")
+var ErrTooManyRequests = errors.New("too many requests, try again later")
+var ErrForbidden = errors.New("you are not allowed to access this resource")
+var ErrInvalidPagination = errors.New("page must be a positive number and page_size between 1 and 100")
 
 // User Errors
 var ErrUserNotFound = errors.New("user not found")
@@ -16,6 +19,16 @@ var ErrUserPending = errors.New("user is pending activation")
 var ErrInvalidUserStatus = errors.New("user status is not valid")
 var ErrInvalidUserID = errors.New("user ID is invalid")
 var ErrUserIDsDoNotMatch = errors.New("user IDs do not match")
+var ErrUserDeletionAlreadyScheduled = errors.New("user deletion is already scheduled")
+var ErrUserDeletionNotScheduled = errors.New("user deletion is not scheduled")
+var ErrUserNotPending = errors.New("user is not pending activation")
+var ErrUserNotInactive = errors.New("user is not inactive")
+
+// Profile Errors
+var ErrDisplayNameInvalid = errors.New("display name must be at most 64 characters long")
+var ErrLocaleInvalid = errors.New("locale is invalid")
+var ErrTimezoneInvalid = errors.New("timezone is invalid")
+var ErrAvatarURLInvalid = errors.New("avatar url is invalid")
 
 // Refresh Token Errors
 var ErrRefreshTokenInvalid = errors.New("refresh token is invalid")
@@ -27,7 +40,12 @@ var ErrTokenInvalid = errors.New("invalid token")
 // Password Errors
 var ErrPasswordsNotMatch = errors.New("passwords don't match")
 var ErrEmailPasswordIncorrect = errors.New("email or password incorrect")
+var ErrPasswordIncorrect = errors.New("password incorrect")
 var ErrPasswordTooShort = errors.New("password must be at least 8 characters long")
+var ErrPasswordPolicy = errors.New("password does not meet the password policy")
+var ErrPasswordPepperUnknown = errors.New("password hash uses an unknown pepper version")
+var ErrPasswordResetTokenInvalid = errors.New("password reset token invalid")
+var ErrPasswordResetTokenExpired = errors.New("password reset token expired")
 
 // E-mail Errors
 var ErrInvalidEmail = errors.New("email is invalid")
@@ -41,3 +59,84 @@ var ErrAppIDInvalid = errors.New("app id is invalid")
 var ErrAppNameInvalid = errors.New("app name is invalid")
 var ErrAppDescInvalid = errors.New("app description invalid")
 var ErrAppNotFound = errors.New("app not found")
+var ErrAppRoleInvalid = errors.New("app role must be owner, editor or viewer")
+var ErrAppMemberNotFound = errors.New("app member not found")
+var ErrAppMemberAlreadyExists = errors.New("user is already a member of the app")
+var ErrAppLastOwner = errors.New("an app must keep at least one owner")
+var ErrDeletedAppNotFound = errors.New("deleted app not found")
+
+// App Transfer Errors
+var ErrAppTransferNotFound = errors.New("app transfer not found or no longer pending")
+var ErrAppTransferInvalid = errors.New("app transfer is invalid, was cancelled or was already accepted")
+var ErrAppTransferExpired = errors.New("app transfer expired")
+var ErrAppTransferRecipientInvalid = errors.New("app transfer recipient must be either another user's email or another organization")
+
+// App User Pool Errors
+var ErrAppUserPoolNotFound = errors.New("app user pool not found or disabled")
+
+// Data Export Errors
+var ErrDataExportNotFound = errors.New("data export not found")
+var ErrDataExportExpired = errors.New("data export expired")
+
+// MFA Errors
+var ErrMFAAlreadyEnabled = errors.New("mfa is already enabled")
+var ErrMFANotEnabled = errors.New("mfa is not enabled")
+var ErrMFAEnrollmentNotFound = errors.New("mfa enrollment not found, start the enrollment again")
+var ErrMFACodeInvalid = errors.New("mfa code is invalid")
+var ErrMFACodeReused = errors.New("mfa code was already used")
+var ErrMFATokenInvalid = errors.New("mfa token is invalid")
+var ErrRecoveryCodeInvalid = errors.New("recovery code is invalid or was already used")
+
+// WebAuthn Errors
+var ErrWebAuthnChallengeInvalid = errors.New("webauthn challenge is invalid or expired")
+var ErrWebAuthnVerificationFailed = errors.New("webauthn verification failed")
+var ErrWebAuthnCredentialNotFound = errors.New("webauthn credential not found")
+var ErrWebAuthnCredentialAlreadyRegistered = errors.New("webauthn credential is already registered")
+var ErrReauthenticationRequired = errors.New("confirm this action with your password or a totp code")
+
+// Magic Link Errors
+var ErrMagicLinkInvalid = errors.New("magic link is invalid or was already used")
+var ErrMagicLinkExpired = errors.New("magic link expired")
+var ErrMagicLinkBrowserMismatch = errors.New("magic link must be opened in the browser it was requested from")
+
+// Email OTP Errors
+var ErrEmailOTPInvalid = errors.New("code is invalid or expired")
+var ErrEmailOTPAttemptsExceeded = errors.New("too many attempts, request a new code")
+
+// Account Lockout Errors
+var ErrAccountLocked = errors.New("account is temporarily locked due to too many failed login attempts")
+var ErrLoginThrottled = errors.New("too many failed login attempts, try again later")
+var ErrUnlockTokenInvalid = errors.New("unlock link is invalid or was already used")
+
+// RBAC Errors
+var ErrRoleNotFound = errors.New("role not found")
+var ErrRoleAlreadyExists = errors.New("role already exists")
+var ErrRoleNameInvalid = errors.New("role name must be 1 to 64 lowercase letters, digits, '_' or '-'")
+var ErrRoleProtected = errors.New("built-in roles cannot be deleted")
+var ErrPermissionNotFound = errors.New("permission not found")
+
+// Impersonation Errors
+var ErrImpersonationSelf = errors.New("you cannot impersonate yourself")
+var ErrImpersonationReasonInvalid = errors.New("impersonation reason must be 1 to 500 characters long")
+var ErrImpersonationForbidden = errors.New("this action is not allowed while impersonating a user")
+
+// Invitation Errors
+var ErrInvitationNotFound = errors.New("invitation not found or no longer pending")
+var ErrInvitationInvalid = errors.New("invitation is invalid, was revoked or was already accepted")
+var ErrInvitationExpired = errors.New("invitation expired")
+
+// Organization Errors
+var ErrOrganizationNotFound = errors.New("organization not found")
+var ErrOrganizationNameInvalid = errors.New("organization name must be 1 to 100 characters long")
+var ErrOrganizationRoleInvalid = errors.New("organization role must be owner, admin or member")
+var ErrOrganizationMemberNotFound = errors.New("organization member not found")
+var ErrOrganizationMemberAlreadyExists = errors.New("user is already a member of the organization")
+var ErrOrganizationLastOwner = errors.New("an organization must keep at least one owner")
+
+// SCIM Errors
+var ErrSCIMTokenInvalid = errors.New("provisioning token is invalid")
+var ErrSCIMFilterInvalid = errors.New("only 'userName eq' filters on users and 'displayName eq' filters on groups are supported")
+var ErrSCIMPatchInvalid = errors.New("patch operation is invalid or not supported")
+var ErrSCIMGroupMemberInvalid = errors.New("group members must be ids of existing users")
+var ErrSCIMGroupRenameNotSupported = errors.New("groups cannot be renamed")
+var ErrSCIMGroupProtected = errors.New("members of roles granting admin permissions cannot be changed through provisioning")
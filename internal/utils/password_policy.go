@@ -0,0 +1,50 @@
+package utils
+
+import "strings"
+
+// Password policy violation codes.
+const (
+	PasswordViolationTooShort      = "too_short"
+	PasswordViolationTooLong       = "too_long"
+	PasswordViolationMissingUpper  = "missing_uppercase"
+	PasswordViolationMissingLower  = "missing_lowercase"
+	PasswordViolationMissingDigit  = "missing_digit"
+	PasswordViolationMissingSymbol = "missing_symbol"
+	PasswordViolationContainsEmail = "contains_email"
+	PasswordViolationTooWeak       = "too_weak"
+	PasswordViolationBreached      = "breached"
+)
+
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError carries every violation of a rejected password, so
+// clients can show them all at once. It matches ErrPasswordPolicy with
+// errors.Is.
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+
+	return ErrPasswordPolicy.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrPasswordPolicy
+}
+
+// GetPasswordPolicyErrorResponse is GetErrorResponse with the list of
+// violations added.
+func GetPasswordPolicyErrorResponse(err *PasswordPolicyError) map[string]any {
+	return map[string]any{
+		"error":      err.Error(),
+		"violations": err.Violations,
+	}
+}
@@ -3,6 +3,8 @@ package utils
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
+	"math/big"
 )
 
 func GetRandomString(numBytes int) (string, error) {
@@ -13,3 +15,16 @@ func GetRandomString(numBytes int) (string, error) {
 	}
 	return hex.EncodeToString(res), nil
 }
+
+// GetRandomDigits returns a uniformly random string of n decimal digits,
+// keeping leading zeros.
+func GetRandomDigits(n int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+
+	value, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%0*d", n, value), nil
+}
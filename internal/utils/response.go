@@ -1,5 +1,7 @@
 package utils
 
+import "strconv"
+
 func GetSuccessResponse() {}
 
 func GetErrorResponse(err error) map[string]string {
@@ -7,3 +9,19 @@ func GetErrorResponse(err error) map[string]string {
 		"error": err.Error(),
 	}
 }
+
+// GetSCIMErrorResponse is the error body of the SCIM endpoints (RFC 7644,
+// section 3.12). scimType may be empty.
+func GetSCIMErrorResponse(status int, scimType string, err error) map[string]any {
+	response := map[string]any{
+		"schemas": []string{SCIMSchemaError},
+		"status":  strconv.Itoa(status),
+		"detail":  err.Error(),
+	}
+
+	if scimType != "" {
+		response["scimType"] = scimType
+	}
+
+	return response
+}
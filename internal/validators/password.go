@@ -1,9 +1,15 @@
 package validators
 
-import "github.com/pedrotunin/go-jwt-auth/internal/utils"
+import (
+	"unicode/utf8"
 
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+// IsValidPassword is the baseline check every password must pass, including
+// on login. New passwords are additionally checked against a PasswordPolicy.
 func IsValidPassword(password string) error {
-	if len(password) < 8 {
+	if utf8.RuneCountInString(password) < 8 {
 		return utils.ErrPasswordTooShort
 	}
 	return nil
@@ -0,0 +1,173 @@
+package validators
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+// PasswordPolicy holds the rules new passwords must follow. Lengths are
+// counted in characters (runes), not bytes.
+type PasswordPolicy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// MinStrength is the lowest EstimatePasswordStrength score accepted.
+	MinStrength int
+}
+
+// Check returns every rule the password breaks, or nil.
+func (p *PasswordPolicy) Check(password, email string) []utils.PasswordViolation {
+	var violations []utils.PasswordViolation
+
+	length := utf8.RuneCountInString(password)
+
+	if length < p.MinLength {
+		violations = append(violations, utils.PasswordViolation{
+			Code:    utils.PasswordViolationTooShort,
+			Message: fmt.Sprintf("password must be at least %d characters long", p.MinLength),
+		})
+	}
+
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, utils.PasswordViolation{
+			Code:    utils.PasswordViolationTooLong,
+			Message: fmt.Sprintf("password must be at most %d characters long", p.MaxLength),
+		})
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+
+	classes := []struct {
+		required bool
+		present  bool
+		code     string
+		name     string
+	}{
+		{p.RequireUpper, hasUpper, utils.PasswordViolationMissingUpper, "an uppercase letter"},
+		{p.RequireLower, hasLower, utils.PasswordViolationMissingLower, "a lowercase letter"},
+		{p.RequireDigit, hasDigit, utils.PasswordViolationMissingDigit, "a digit"},
+		{p.RequireSymbol, hasSymbol, utils.PasswordViolationMissingSymbol, "a symbol"},
+	}
+
+	for _, class := range classes {
+		if class.required && !class.present {
+			violations = append(violations, utils.PasswordViolation{
+				Code:    class.code,
+				Message: "password must contain " + class.name,
+			})
+		}
+	}
+
+	if containsEmail(password, email) {
+		violations = append(violations, utils.PasswordViolation{
+			Code:    utils.PasswordViolationContainsEmail,
+			Message: "password must not contain the e-mail address",
+		})
+	}
+
+	if EstimatePasswordStrength(password) < p.MinStrength {
+		violations = append(violations, utils.PasswordViolation{
+			Code:    utils.PasswordViolationTooWeak,
+			Message: "password is too easy to guess",
+		})
+	}
+
+	return violations
+}
+
+// containsEmail reports whether the password contains the e-mail or its
+// local part. Local parts shorter than three characters are ignored, since
+// they would match too many passwords by chance.
+func containsEmail(password, email string) bool {
+	if email == "" {
+		return false
+	}
+
+	password = strings.ToLower(password)
+	email = strings.ToLower(email)
+
+	if strings.Contains(password, email) {
+		return true
+	}
+
+	local, _, _ := strings.Cut(email, "@")
+	return utf8.RuneCountInString(local) >= 3 && strings.Contains(password, local)
+}
+
+// EstimatePasswordStrength scores a password from 0 (trivial) to 4 (strong)
+// from a rough entropy estimate. Characters that repeat or continue a
+// sequence of the previous one ("aaa", "abc", "321") add no entropy.
+func EstimatePasswordStrength(password string) int {
+	var lower, upper, digit, symbol, other bool
+	effectiveLength := 0
+
+	var prev rune
+	for i, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+
+		if i == 0 || (r != prev && r != prev+1 && r != prev-1) {
+			effectiveLength++
+		}
+		prev = r
+	}
+
+	pool := 0
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.present {
+			pool += class.size
+		}
+	}
+
+	if pool == 0 {
+		return 0
+	}
+
+	bits := float64(effectiveLength) * math.Log2(float64(pool))
+
+	switch {
+	case bits < 28:
+		return 0
+	case bits < 36:
+		return 1
+	case bits < 60:
+		return 2
+	case bits < 80:
+		return 3
+	default:
+		return 4
+	}
+}
@@ -0,0 +1,58 @@
+package validators
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+func IsValidDisplayName(name string) error {
+	if utf8.RuneCountInString(strings.TrimSpace(name)) > 64 {
+		return utils.ErrDisplayNameInvalid
+	}
+
+	return nil
+}
+
+func IsValidLocale(locale string) error {
+	if locale == "" {
+		return nil
+	}
+
+	const localeRegex = `^[a-zA-Z]{2,3}(?:[-_][a-zA-Z0-9]{2,8})*$`
+	re := regexp.MustCompile(localeRegex)
+	if !re.MatchString(locale) {
+		return utils.ErrLocaleInvalid
+	}
+
+	return nil
+}
+
+func IsValidTimezone(timezone string) error {
+	if timezone == "" {
+		return nil
+	}
+
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return utils.ErrTimezoneInvalid
+	}
+
+	return nil
+}
+
+func IsValidAvatarURL(avatarURL string) error {
+	if avatarURL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(avatarURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return utils.ErrAvatarURLInvalid
+	}
+
+	return nil
+}
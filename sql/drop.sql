@@ -1,3 +1,30 @@
+DROP TABLE IF EXISTS app_user_refresh_tokens CASCADE;
+DROP TABLE IF EXISTS app_users CASCADE;
+DROP TABLE IF EXISTS app_user_pools CASCADE;
+DROP TABLE IF EXISTS app_transfers CASCADE;
+DROP TABLE IF EXISTS app_members CASCADE;
+DROP TABLE IF EXISTS organization_members CASCADE;
+DROP TABLE IF EXISTS organizations CASCADE;
+DROP TABLE IF EXISTS invitations CASCADE;
+DROP TABLE IF EXISTS impersonation_events CASCADE;
+DROP TABLE IF EXISTS user_roles CASCADE;
+DROP TABLE IF EXISTS role_permissions CASCADE;
+DROP TABLE IF EXISTS permissions CASCADE;
+DROP TABLE IF EXISTS roles CASCADE;
+DROP TABLE IF EXISTS password_reset_tokens CASCADE;
+DROP TABLE IF EXISTS login_events CASCADE;
+DROP TABLE IF EXISTS rate_limit_buckets CASCADE;
+DROP TABLE IF EXISTS email_lockouts CASCADE;
+DROP TABLE IF EXISTS account_lockouts CASCADE;
+DROP TABLE IF EXISTS email_otp_codes CASCADE;
+DROP TABLE IF EXISTS magic_link_tokens CASCADE;
+DROP TABLE IF EXISTS webauthn_challenges CASCADE;
+DROP TABLE IF EXISTS webauthn_credentials CASCADE;
+DROP TABLE IF EXISTS mfa_challenge_failures CASCADE;
+DROP TABLE IF EXISTS mfa_recovery_codes CASCADE;
+DROP TABLE IF EXISTS totp_credentials CASCADE;
+DROP TABLE IF EXISTS data_exports CASCADE;
+DROP TABLE IF EXISTS apps CASCADE;
 DROP TABLE IF EXISTS refresh_tokens CASCADE;
 DROP TABLE IF EXISTS email_verification_tokens CASCADE;
 DROP TABLE IF EXISTS users CASCADE;
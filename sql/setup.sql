@@ -2,18 +2,42 @@ CREATE TABLE IF NOT EXISTS users (
     id SERIAL PRIMARY KEY,
     email TEXT UNIQUE NOT NULL,
     status TEXT NOT NULL DEFAULT 'pending',
-    password TEXT NOT NULL
+    password TEXT NOT NULL,
+    display_name TEXT NOT NULL DEFAULT '',
+    locale TEXT NOT NULL DEFAULT '',
+    timezone TEXT NOT NULL DEFAULT '',
+    avatar_url TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_login_at TIMESTAMP,
+    deletion_scheduled_at TIMESTAMP
 );
 
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS display_name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS avatar_url TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS last_login_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS deletion_scheduled_at TIMESTAMP;
+
 CREATE TABLE IF NOT EXISTS refresh_tokens (
     id SERIAL PRIMARY KEY,
     content TEXT NOT NULL,
     user_id INT,
     status TEXT NOT NULL DEFAULT 'active',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 
-    CONSTRAINT fk_user_refresh_token FOREIGN KEY (user_id) REFERENCES users(id)
+    CONSTRAINT fk_user_refresh_token FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 );
 
+ALTER TABLE refresh_tokens
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    DROP CONSTRAINT IF EXISTS fk_user_refresh_token,
+    ADD CONSTRAINT fk_user_refresh_token FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
 CREATE TABLE IF NOT EXISTS email_verification_tokens (
     id SERIAL PRIMARY KEY,
     content TEXT NOT NULL,
@@ -22,17 +46,389 @@ CREATE TABLE IF NOT EXISTS email_verification_tokens (
     expires_at TIMESTAMP NOT NULL,
     is_used BOOLEAN DEFAULT FALSE,
 
-    CONSTRAINT fk_user_email_verification_token FOREIGN KEY (user_id) REFERENCES users(id)
+    CONSTRAINT fk_user_email_verification_token FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+ALTER TABLE email_verification_tokens
+    DROP CONSTRAINT IF EXISTS fk_user_email_verification_token,
+    ADD CONSTRAINT fk_user_email_verification_token FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE TABLE IF NOT EXISTS organization_members (
+    organization_id INT NOT NULL,
+    user_id INT NOT NULL,
+    role TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (organization_id, user_id),
+    CONSTRAINT fk_organization_organization_member FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_organization_member FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organization_members_active_user_id ON organization_members (user_id) WHERE active;
+
 CREATE TABLE IF NOT EXISTS apps (
     id SERIAL PRIMARY KEY,
     name TEXT NOT NULL,
     description TEXT NOT NULL,
-    user_id INT NOT NULL,
+    user_id INT,
+    organization_id INT,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     deleted_at TIMESTAMP,
 
-    CONSTRAINT fk_user_app FOREIGN KEY (user_id) REFERENCES users(id)
-);
\ No newline at end of file
+    CONSTRAINT chk_app_owner CHECK (user_id IS NOT NULL OR organization_id IS NOT NULL),
+    CONSTRAINT fk_user_app FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT fk_organization_app FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE
+);
+
+ALTER TABLE apps
+    ADD COLUMN IF NOT EXISTS organization_id INT,
+    ALTER COLUMN user_id DROP NOT NULL,
+    DROP CONSTRAINT IF EXISTS chk_app_owner,
+    ADD CONSTRAINT chk_app_owner CHECK (user_id IS NOT NULL OR organization_id IS NOT NULL),
+    DROP CONSTRAINT IF EXISTS fk_user_app,
+    ADD CONSTRAINT fk_user_app FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+    DROP CONSTRAINT IF EXISTS fk_organization_app,
+    ADD CONSTRAINT fk_organization_app FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE;
+
+CREATE TABLE IF NOT EXISTS app_members (
+    app_id INT NOT NULL,
+    user_id INT NOT NULL,
+    role TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (app_id, user_id),
+    CONSTRAINT fk_app_app_member FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_app_member FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_app_members_user_id ON app_members (user_id);
+
+INSERT INTO app_members (app_id, user_id, role)
+SELECT id, user_id, 'owner' FROM apps WHERE user_id IS NOT NULL AND organization_id IS NULL
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS data_exports (
+    id SERIAL PRIMARY KEY,
+    content TEXT NOT NULL,
+    user_id INT NOT NULL,
+    archive BYTEA NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+
+    CONSTRAINT fk_user_data_export FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS totp_credentials (
+    user_id INT PRIMARY KEY,
+    secret TEXT NOT NULL,
+    confirmed_at TIMESTAMP,
+    last_used_counter BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_user_totp_credential FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    content TEXT NOT NULL,
+    user_id INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    used_at TIMESTAMP,
+
+    CONSTRAINT fk_user_mfa_recovery_code FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS mfa_challenge_failures (
+    challenge_id TEXT PRIMARY KEY,
+    failed_attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    credential_id TEXT UNIQUE NOT NULL,
+    public_key BYTEA NOT NULL,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    name TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP,
+
+    CONSTRAINT fk_user_webauthn_credential FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webauthn_challenges (
+    id SERIAL PRIMARY KEY,
+    challenge TEXT UNIQUE NOT NULL,
+    user_id INT,
+    ceremony TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+
+    CONSTRAINT fk_user_webauthn_challenge FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS magic_link_tokens (
+    id SERIAL PRIMARY KEY,
+    content TEXT UNIQUE NOT NULL,
+    user_id INT NOT NULL,
+    binding TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+
+    CONSTRAINT fk_user_magic_link_token FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS email_otp_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    code_hash TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+
+    CONSTRAINT fk_user_email_otp_code FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS account_lockouts (
+    user_id INT PRIMARY KEY,
+    failed_attempts INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP,
+    locked_until TIMESTAMP,
+    unlock_token_hash TEXT UNIQUE,
+
+    CONSTRAINT fk_user_account_lockout FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS email_lockouts (
+    email_hash TEXT PRIMARY KEY,
+    failed_attempts INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP,
+    locked_until TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS login_events (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    method TEXT NOT NULL,
+    success BOOLEAN NOT NULL,
+    failure_reason TEXT,
+    ip_address TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+
+    CONSTRAINT fk_user_login_event FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_events_user_id_created_at ON login_events (user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id SERIAL PRIMARY KEY,
+    content TEXT UNIQUE NOT NULL,
+    user_id INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+
+    CONSTRAINT fk_user_password_reset_token FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS roles (
+    id SERIAL PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    id SERIAL PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id INT NOT NULL,
+    permission_id INT NOT NULL,
+
+    PRIMARY KEY (role_id, permission_id),
+    CONSTRAINT fk_role_role_permission FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    CONSTRAINT fk_permission_role_permission FOREIGN KEY (permission_id) REFERENCES permissions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id INT NOT NULL,
+    role_id INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (user_id, role_id),
+    CONSTRAINT fk_user_user_role FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_role_user_role FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('users:read', 'List and view users'),
+    ('users:write', 'Deactivate, reactivate, verify users and reset their passwords'),
+    ('roles:read', 'List roles and permissions and view user roles'),
+    ('roles:write', 'Create and delete roles and assign them to users'),
+    ('users:impersonate', 'Obtain short-lived access tokens acting as another user'),
+    ('users:invite', 'Invite new users and list and revoke invitations')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles (name, description) VALUES ('admin', 'Full access to the admin API') ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS impersonation_events (
+    id SERIAL PRIMARY KEY,
+    actor_user_id INT,
+    target_user_id INT,
+    actor_email TEXT NOT NULL,
+    target_email TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    ip_address TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+
+    CONSTRAINT fk_actor_impersonation_event FOREIGN KEY (actor_user_id) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT fk_target_impersonation_event FOREIGN KEY (target_user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+ALTER TABLE impersonation_events
+    ADD COLUMN IF NOT EXISTS actor_email TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS target_email TEXT NOT NULL DEFAULT '',
+    ALTER COLUMN actor_user_id DROP NOT NULL,
+    ALTER COLUMN target_user_id DROP NOT NULL,
+    DROP CONSTRAINT IF EXISTS fk_actor_impersonation_event,
+    ADD CONSTRAINT fk_actor_impersonation_event FOREIGN KEY (actor_user_id) REFERENCES users(id) ON DELETE SET NULL,
+    DROP CONSTRAINT IF EXISTS fk_target_impersonation_event,
+    ADD CONSTRAINT fk_target_impersonation_event FOREIGN KEY (target_user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+UPDATE impersonation_events e SET actor_email = u.email FROM users u WHERE e.actor_email = '' AND u.id = e.actor_user_id;
+UPDATE impersonation_events e SET target_email = u.email FROM users u WHERE e.target_email = '' AND u.id = e.target_user_id;
+
+ALTER TABLE impersonation_events
+    ALTER COLUMN actor_email DROP DEFAULT,
+    ALTER COLUMN target_email DROP DEFAULT;
+
+CREATE INDEX IF NOT EXISTS idx_impersonation_events_target_user_id_created_at ON impersonation_events (target_user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS invitations (
+    id SERIAL PRIMARY KEY,
+    email TEXT NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    invited_by INT NOT NULL,
+    role_name TEXT NOT NULL DEFAULT '',
+    organization_id INT,
+    organization_role TEXT NOT NULL DEFAULT '',
+    app_id INT,
+    app_role TEXT NOT NULL DEFAULT '',
+    user_id INT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+
+    CONSTRAINT fk_inviter_invitation FOREIGN KEY (invited_by) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_invitation FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT fk_organization_invitation FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE,
+    CONSTRAINT fk_app_invitation FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
+);
+
+ALTER TABLE invitations
+    ADD COLUMN IF NOT EXISTS organization_id INT,
+    ADD COLUMN IF NOT EXISTS organization_role TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS app_id INT,
+    ADD COLUMN IF NOT EXISTS app_role TEXT NOT NULL DEFAULT '',
+    DROP CONSTRAINT IF EXISTS fk_organization_invitation,
+    ADD CONSTRAINT fk_organization_invitation FOREIGN KEY (organization_id) REFERENCES organizations(id) ON DELETE CASCADE,
+    DROP CONSTRAINT IF EXISTS fk_app_invitation,
+    ADD CONSTRAINT fk_app_invitation FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE;
+
+CREATE TABLE IF NOT EXISTS app_transfers (
+    id SERIAL PRIMARY KEY,
+    app_id INT NOT NULL,
+    from_user_id INT,
+    from_organization_id INT,
+    to_email TEXT NOT NULL DEFAULT '',
+    to_user_id INT,
+    to_organization_id INT,
+    token_hash TEXT UNIQUE NOT NULL,
+    initiated_by INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP,
+    accepted_by INT,
+    cancelled_at TIMESTAMP,
+
+    CONSTRAINT fk_app_app_transfer FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE,
+    CONSTRAINT fk_from_user_app_transfer FOREIGN KEY (from_user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_to_user_app_transfer FOREIGN KEY (to_user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_to_organization_app_transfer FOREIGN KEY (to_organization_id) REFERENCES organizations(id) ON DELETE CASCADE,
+    CONSTRAINT fk_initiator_app_transfer FOREIGN KEY (initiated_by) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_acceptor_app_transfer FOREIGN KEY (accepted_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+ALTER TABLE app_transfers
+    ADD COLUMN IF NOT EXISTS to_email TEXT NOT NULL DEFAULT '',
+    ALTER COLUMN from_user_id DROP NOT NULL;
+
+UPDATE app_transfers t SET to_email = u.email FROM users u WHERE t.to_email = '' AND u.id = t.to_user_id;
+
+CREATE INDEX IF NOT EXISTS idx_app_transfers_app_id_created_at ON app_transfers (app_id, created_at);
+
+CREATE TABLE IF NOT EXISTS app_user_pools (
+    app_id INT PRIMARY KEY,
+    client_id TEXT UNIQUE NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_app_app_user_pool FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS app_users (
+    id SERIAL PRIMARY KEY,
+    app_id INT NOT NULL,
+    email TEXT NOT NULL,
+    password TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'active',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_login_at TIMESTAMP,
+
+    CONSTRAINT fk_app_user_pool_app_user FOREIGN KEY (app_id) REFERENCES app_user_pools(app_id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_app_users_app_id_email ON app_users (app_id, email);
+
+CREATE TABLE IF NOT EXISTS app_user_refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    content TEXT NOT NULL,
+    app_user_id INT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'active',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_app_user_app_user_refresh_token FOREIGN KEY (app_user_id) REFERENCES app_users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_app_user_refresh_tokens_content ON app_user_refresh_tokens (content);
\ No newline at end of file
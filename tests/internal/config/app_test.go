@@ -39,6 +39,22 @@ func TestSetup(t *testing.T) {
 		app.Setup()
 	})
 
+	t.Run("should fail when ENCRYPTION_KEY is not set", func(t *testing.T) {
+		app := &config.Application{}
+
+		os.Setenv("JWT_TOKEN_SECRET", "test")
+		os.Setenv("JWT_REFRESH_TOKEN_SECRET", "test")
+		os.Unsetenv("ENCRYPTION_KEY")
+
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatal("expected panic, got none")
+			}
+		}()
+
+		app.Setup()
+	})
+
 	t.Run("should run without panicking", func(t *testing.T) {
 		app := &config.Application{
 			DB:     &sql.DB{},
@@ -47,6 +63,7 @@ func TestSetup(t *testing.T) {
 
 		os.Setenv("JWT_TOKEN_SECRET", "test")
 		os.Setenv("JWT_REFRESH_TOKEN_SECRET", "test")
+		os.Setenv("ENCRYPTION_KEY", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
 
 		defer func() {
 			if r := recover(); r != nil {
@@ -0,0 +1,237 @@
+package controllers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/gin-gonic/gin"
+	"github.com/pedrotunin/go-jwt-auth/internal/controllers"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type memoryUserService struct {
+	services.IUserService
+	users map[string]*models.User
+}
+
+func (s *memoryUserService) GetUserByEmail(email string) (*models.User, error) {
+	user, found := s.users[email]
+	if !found {
+		return nil, utils.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (s *memoryUserService) VerifyActiveUser(u *models.User) error {
+	if u.Status != utils.UserStatusActive {
+		return utils.ErrUserPending
+	}
+
+	return nil
+}
+
+type allowAllAccountLockoutService struct {
+	services.IAccountLockoutService
+}
+
+func (s *allowAllAccountLockoutService) CheckAllowed(userID models.UserID) (time.Duration, error) {
+	return 0, nil
+}
+
+func (s *allowAllAccountLockoutService) RecordFailure(userID models.UserID) (*services.AccountLock, error) {
+	return nil, nil
+}
+
+func (s *allowAllAccountLockoutService) CheckAllowedEmail(email string) (time.Duration, error) {
+	return 0, nil
+}
+
+func (s *allowAllAccountLockoutService) RecordEmailFailure(email string) error {
+	return nil
+}
+
+// throttlingAccountLockoutService makes every account and unknown e-mail wait
+// a second after a failed login.
+type throttlingAccountLockoutService struct {
+	services.IAccountLockoutService
+	failedUsers  map[models.UserID]bool
+	failedEmails map[string]bool
+}
+
+func (s *throttlingAccountLockoutService) CheckAllowed(userID models.UserID) (time.Duration, error) {
+	if s.failedUsers[userID] {
+		return time.Second, utils.ErrLoginThrottled
+	}
+
+	return 0, nil
+}
+
+func (s *throttlingAccountLockoutService) RecordFailure(userID models.UserID) (*services.AccountLock, error) {
+	s.failedUsers[userID] = true
+	return nil, nil
+}
+
+func (s *throttlingAccountLockoutService) CheckAllowedEmail(email string) (time.Duration, error) {
+	if s.failedEmails[email] {
+		return time.Second, utils.ErrLoginThrottled
+	}
+
+	return 0, nil
+}
+
+func (s *throttlingAccountLockoutService) RecordEmailFailure(email string) error {
+	s.failedEmails[email] = true
+	return nil
+}
+
+type discardLoginHistoryService struct {
+	services.ILoginHistoryService
+}
+
+func (s *discardLoginHistoryService) RecordFailure(userID models.UserID, method string, reason string, ipAddress string, userAgent string) error {
+	return nil
+}
+
+type countingHashService struct {
+	services.IHashService
+	comparisons int
+}
+
+func (s *countingHashService) ComparePassword(password string, hashedPassword string) error {
+	s.comparisons++
+	return s.IHashService.ComparePassword(password, hashedPassword)
+}
+
+func (s *countingHashService) CompareDummyPassword(password string) {
+	s.comparisons++
+	s.IHashService.CompareDummyPassword(password)
+}
+
+func TestAuthControllerLogin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	hashService := services.NewHashService(&argon2id.Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	hash, _ := hashService.HashPassword("password123")
+
+	counter := &countingHashService{IHashService: hashService}
+	ac := &controllers.AuthController{
+		UserService: &memoryUserService{users: map[string]*models.User{
+			"active@example.com":  {ID: 1, Email: "active@example.com", Password: hash, Status: utils.UserStatusActive},
+			"pending@example.com": {ID: 2, Email: "pending@example.com", Password: hash, Status: utils.UserStatusPending},
+		}},
+		HashService:           counter,
+		AccountLockoutService: &allowAllAccountLockoutService{},
+		LoginHistoryService:   &discardLoginHistoryService{},
+	}
+
+	router := gin.New()
+	router.POST("/login", ac.Login)
+
+	login := func(email, password string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"`+email+`","password":"`+password+`"}`))
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("should answer unknown emails, wrong passwords and pending accounts identically", func(t *testing.T) {
+		counter.comparisons = 0
+		unknown := login("unknown@example.com", "wrongpassword")
+		if counter.comparisons != 1 {
+			t.Errorf("expected unknown email to run one hash comparison, got %d", counter.comparisons)
+		}
+
+		counter.comparisons = 0
+		wrong := login("active@example.com", "wrongpassword")
+		if counter.comparisons != 1 {
+			t.Errorf("expected wrong password to run one hash comparison, got %d", counter.comparisons)
+		}
+
+		pending := login("pending@example.com", "wrongpassword")
+
+		for name, w := range map[string]*httptest.ResponseRecorder{"wrong password": wrong, "pending account": pending} {
+			if w.Code != unknown.Code {
+				t.Errorf("%s: expected status %d, got %d", name, unknown.Code, w.Code)
+			}
+
+			if w.Body.String() != unknown.Body.String() {
+				t.Errorf("%s: expected body %s, got %s", name, unknown.Body.String(), w.Body.String())
+			}
+
+			if len(w.Header()) != len(unknown.Header()) {
+				t.Errorf("%s: expected headers %v, got %v", name, unknown.Header(), w.Header())
+			}
+		}
+	})
+
+	t.Run("should reveal pending status only after a correct password", func(t *testing.T) {
+		w := login("pending@example.com", "password123")
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+
+		if !strings.Contains(w.Body.String(), "pending activation") {
+			t.Errorf("expected pending activation message, got %s", w.Body.String())
+		}
+	})
+}
+
+func TestAuthControllerLoginThrottling(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	hashService := services.NewHashService(&argon2id.Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	hash, _ := hashService.HashPassword("password123")
+
+	ac := &controllers.AuthController{
+		UserService: &memoryUserService{users: map[string]*models.User{
+			"active@example.com": {ID: 1, Email: "active@example.com", Password: hash, Status: utils.UserStatusActive},
+		}},
+		HashService: hashService,
+		AccountLockoutService: &throttlingAccountLockoutService{
+			failedUsers:  map[models.UserID]bool{},
+			failedEmails: map[string]bool{},
+		},
+		LoginHistoryService: &discardLoginHistoryService{},
+	}
+
+	router := gin.New()
+	router.POST("/login", ac.Login)
+
+	login := func(email, password string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"`+email+`","password":"`+password+`"}`))
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("should throttle unknown emails like existing accounts", func(t *testing.T) {
+		for attempt := 1; attempt <= 2; attempt++ {
+			existing := login("active@example.com", "wrongpassword")
+			unknown := login("unknown@example.com", "wrongpassword")
+
+			if attempt == 2 && existing.Code != http.StatusTooManyRequests {
+				t.Errorf("attempt %d: expected status %d, got %d", attempt, http.StatusTooManyRequests, existing.Code)
+			}
+
+			if unknown.Code != existing.Code {
+				t.Errorf("attempt %d: expected status %d, got %d", attempt, existing.Code, unknown.Code)
+			}
+
+			if unknown.Body.String() != existing.Body.String() {
+				t.Errorf("attempt %d: expected body %s, got %s", attempt, existing.Body.String(), unknown.Body.String())
+			}
+
+			if unknown.Header().Get("Retry-After") != existing.Header().Get("Retry-After") || len(unknown.Header()) != len(existing.Header()) {
+				t.Errorf("attempt %d: expected headers %v, got %v", attempt, existing.Header(), unknown.Header())
+			}
+		}
+	})
+}
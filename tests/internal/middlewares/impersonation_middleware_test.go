@@ -0,0 +1,45 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrotunin/go-jwt-auth/internal/middlewares"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+)
+
+func TestImpersonationMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	im := middlewares.NewImpersonationMiddleware()
+
+	request := func(claims *services.TokenClaims) int {
+		router := gin.New()
+		router.DELETE("/users/me", func(c *gin.Context) {
+			c.Set("claims", claims)
+			c.Next()
+		}, im.BlockImpersonation(), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/users/me", nil))
+		return w.Code
+	}
+
+	t.Run("should let regular tokens through", func(t *testing.T) {
+		code := request(&services.TokenClaims{UserID: 2})
+		if code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, code)
+		}
+	})
+
+	t.Run("should forbid impersonation tokens", func(t *testing.T) {
+		code := request(&services.TokenClaims{UserID: 2, Actor: &services.ActorClaims{UserID: 1}, Impersonated: true})
+		if code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, code)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrotunin/go-jwt-auth/internal/middlewares"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+func TestPermissionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	pm := middlewares.NewPermissionMiddleware()
+
+	request := func(permissions []string) int {
+		router := gin.New()
+		router.GET("/admin/users", func(c *gin.Context) {
+			c.Set("claims", &services.TokenClaims{UserID: 1, Permissions: permissions})
+			c.Next()
+		}, pm.RequirePermission(utils.PermissionUsersRead), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/users", nil))
+		return w.Code
+	}
+
+	t.Run("should let tokens with the permission through", func(t *testing.T) {
+		code := request([]string{utils.PermissionRolesRead, utils.PermissionUsersRead})
+		if code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, code)
+		}
+	})
+
+	t.Run("should forbid tokens without the permission", func(t *testing.T) {
+		code := request([]string{utils.PermissionUsersWrite})
+		if code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, code)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrotunin/go-jwt-auth/internal/middlewares"
+)
+
+func TestProvisioningMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	request := func(token string, authorization string) int {
+		pm := middlewares.NewProvisioningMiddleware(token)
+
+		router := gin.New()
+		router.GET("/scim/v2/Users", pm.RequireProvisioningToken(), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/scim/v2/Users", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("should let the provisioning token through", func(t *testing.T) {
+		code := request("scim-secret", "Bearer scim-secret")
+		if code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, code)
+		}
+	})
+
+	t.Run("should reject other tokens", func(t *testing.T) {
+		for _, authorization := range []string{"", "scim-secret", "Bearer scim-secre", "Bearer scim-secret2"} {
+			code := request("scim-secret", authorization)
+			if code != http.StatusUnauthorized {
+				t.Errorf("expected status %d for %q, got %d", http.StatusUnauthorized, authorization, code)
+			}
+		}
+	})
+
+	t.Run("should reject every request while provisioning is disabled", func(t *testing.T) {
+		code := request("", "Bearer ")
+		if code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, code)
+		}
+	})
+}
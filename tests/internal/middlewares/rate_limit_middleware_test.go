@@ -0,0 +1,116 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrotunin/go-jwt-auth/internal/middlewares"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+)
+
+func newRateLimitedRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	rlm := middlewares.NewRateLimitMiddleware(repositories.NewMemoryRateLimitRepository(), map[string][]middlewares.RateLimitRule{
+		"login": {
+			{Name: "account", Key: middlewares.KeyByAccount, Limit: models.RateLimit{Burst: 2, Interval: time.Minute}},
+		},
+		"pool-login": {
+			{Name: "account", Key: middlewares.KeyByPoolAccount, Limit: models.RateLimit{Burst: 2, Interval: time.Minute}},
+		},
+	})
+
+	router := gin.New()
+	router.POST("/login", rlm.Limit("login"), func(c *gin.Context) {
+		var body struct {
+			Email string `json:"email"`
+		}
+		c.ShouldBindJSON(&body)
+		c.String(http.StatusOK, body.Email)
+	})
+	router.POST("/pools/:clientId/login", rlm.Limit("pool-login"), func(c *gin.Context) {
+		c.String(http.StatusOK, "")
+	})
+
+	return router
+}
+
+func login(router *gin.Engine, email string) *httptest.ResponseRecorder {
+	return loginAt(router, "/login", email)
+}
+
+func loginAt(router *gin.Engine, path string, email string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"email":"`+email+`"}`))
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	t.Run("should reject requests over the limit with rate limit headers", func(t *testing.T) {
+		router := newRateLimitedRouter()
+
+		for i := 0; i < 2; i++ {
+			w := login(router, "user@example.com")
+			if w.Code != http.StatusOK {
+				t.Fatalf("request %d: expected status 200, got %d", i+1, w.Code)
+			}
+		}
+
+		w := login(router, "user@example.com")
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected status 429, got %d", w.Code)
+		}
+
+		if w.Header().Get("Retry-After") != "30" {
+			t.Errorf("expected Retry-After 30, got %q", w.Header().Get("Retry-After"))
+		}
+
+		if w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != "0" {
+			t.Errorf("unexpected RateLimit headers: %v", w.Header())
+		}
+	})
+
+	t.Run("should count accounts separately and keep the body readable", func(t *testing.T) {
+		router := newRateLimitedRouter()
+
+		login(router, "user@example.com")
+		login(router, "user@example.com")
+
+		w := login(router, "other@example.com")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+
+		if w.Body.String() != "other@example.com" {
+			t.Errorf("expected handler to read the body, got %q", w.Body.String())
+		}
+	})
+
+	t.Run("should count pool accounts per pool", func(t *testing.T) {
+		router := newRateLimitedRouter()
+
+		loginAt(router, "/pools/tenant-a/login", "user@example.com")
+		loginAt(router, "/pools/tenant-a/login", "user@example.com")
+
+		w := loginAt(router, "/pools/tenant-a/login", "user@example.com")
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected status 429, got %d", w.Code)
+		}
+
+		w = loginAt(router, "/pools/tenant-b/login", "user@example.com")
+		if w.Code != http.StatusOK {
+			t.Errorf("expected another pool to keep its budget, got %d", w.Code)
+		}
+
+		w = login(router, "user@example.com")
+		if w.Code != http.StatusOK {
+			t.Errorf("expected the global account to keep its budget, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+)
+
+func TestRateLimitBucket(t *testing.T) {
+	limit := models.RateLimit{Burst: 3, Interval: 3 * time.Second}
+	now := time.Unix(1700000000, 0)
+
+	t.Run("should allow a burst and then reject", func(t *testing.T) {
+		bucket := models.NewRateLimitBucket("key", limit, now)
+
+		for i := 0; i < 3; i++ {
+			if result := bucket.Take(limit, now); !result.Allowed {
+				t.Fatalf("request %d: expected to be allowed", i+1)
+			}
+		}
+
+		result := bucket.Take(limit, now)
+		if result.Allowed {
+			t.Fatal("expected request to be rejected")
+		}
+
+		if result.RetryAfter != time.Second {
+			t.Errorf("expected retry after 1s, got %s", result.RetryAfter)
+		}
+
+		if result.Reset != 3*time.Second {
+			t.Errorf("expected reset in 3s, got %s", result.Reset)
+		}
+	})
+
+	t.Run("should refill over time without exceeding the burst", func(t *testing.T) {
+		bucket := models.NewRateLimitBucket("key", limit, now)
+
+		for i := 0; i < 3; i++ {
+			bucket.Take(limit, now)
+		}
+
+		result := bucket.Take(limit, now.Add(time.Second))
+		if !result.Allowed || result.Remaining != 0 {
+			t.Errorf("expected one refilled token, got %+v", result)
+		}
+
+		result = bucket.Take(limit, now.Add(time.Hour))
+		if !result.Allowed || result.Remaining != 2 {
+			t.Errorf("expected a full bucket minus one, got %+v", result)
+		}
+	})
+}
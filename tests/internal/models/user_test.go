@@ -50,3 +50,51 @@ func TestNewUser(t *testing.T) {
 
 	})
 }
+
+func TestUserProfileValidate(t *testing.T) {
+	t.Run("should return error when timezone is invalid", func(t *testing.T) {
+		timezone := "Mars/Olympus_Mons"
+		profile := &models.UserProfile{Timezone: &timezone}
+
+		err := profile.Validate()
+		if !errors.Is(err, utils.ErrTimezoneInvalid) {
+			t.Errorf("expected ErrTimezoneInvalid, got other: %v", err)
+		}
+	})
+
+	t.Run("should return error when avatar url is not http", func(t *testing.T) {
+		avatarURL := "javascript:alert(1)"
+		profile := &models.UserProfile{AvatarURL: &avatarURL}
+
+		err := profile.Validate()
+		if !errors.Is(err, utils.ErrAvatarURLInvalid) {
+			t.Errorf("expected ErrAvatarURLInvalid, got other: %v", err)
+		}
+	})
+
+	t.Run("should apply only provided fields", func(t *testing.T) {
+		displayName := " Jane Doe "
+		locale := "pt-BR"
+		profile := &models.UserProfile{DisplayName: &displayName, Locale: &locale}
+
+		err := profile.Validate()
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		u := &models.User{Timezone: "UTC"}
+		profile.Apply(u)
+
+		if u.DisplayName != "Jane Doe" {
+			t.Errorf("expected trimmed display name, got %q", u.DisplayName)
+		}
+
+		if u.Locale != locale {
+			t.Errorf("locales do not match")
+		}
+
+		if u.Timezone != "UTC" {
+			t.Errorf("timezone should not change")
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package repositories_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+)
+
+// recordingDriver accepts every statement and remembers it, so the queries a
+// repository method runs can be checked without a database.
+type recordingDriver struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+func (d *recordingDriver) Open(name string) (driver.Conn, error) {
+	return &recordingConn{driver: d}, nil
+}
+
+func (d *recordingDriver) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries = nil
+}
+
+type recordingConn struct {
+	driver *recordingDriver
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	c.driver.mu.Lock()
+	defer c.driver.mu.Unlock()
+	c.driver.queries = append(c.driver.queries, query)
+	return &recordingStmt{}, nil
+}
+
+func (c *recordingConn) Close() error              { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error) { return &recordingTx{}, nil }
+
+type recordingTx struct{}
+
+func (tx *recordingTx) Commit() error   { return nil }
+func (tx *recordingTx) Rollback() error { return nil }
+
+type recordingStmt struct{}
+
+func (s *recordingStmt) Close() error  { return nil }
+func (s *recordingStmt) NumInput() int { return -1 }
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &emptyRows{}, nil
+}
+
+type emptyRows struct{}
+
+func (r *emptyRows) Columns() []string              { return nil }
+func (r *emptyRows) Close() error                   { return nil }
+func (r *emptyRows) Next(dest []driver.Value) error { return io.EOF }
+
+var recorder = &recordingDriver{}
+
+func init() {
+	sql.Register("recording", recorder)
+}
+
+func TestPSQLUserRepositoryKeepsOrganizationData(t *testing.T) {
+	db, err := sql.Open("recording", "")
+	if err != nil {
+		t.Fatalf("expected no error, got one: %s", err.Error())
+	}
+	defer db.Close()
+
+	repo := repositories.NewPSQLUserRepository(db)
+
+	checkQueries := func(t *testing.T) {
+		if !strings.Contains(strings.Join(recorder.queries, "\n"), "apps SET user_id=NULL") {
+			t.Errorf("expected organization apps to be detached from the user, got %v", recorder.queries)
+		}
+
+		for _, query := range recorder.queries {
+			if strings.Contains(query, "impersonation_events") {
+				t.Errorf("expected impersonation events to be kept, got %q", query)
+			}
+
+			if strings.Contains(query, "apps ") && !strings.Contains(query, "app_") &&
+				!strings.Contains(query, "organization_id IS NULL") && !strings.Contains(query, "SET user_id=NULL") {
+				t.Errorf("expected organization apps to be kept, got %q", query)
+			}
+		}
+	}
+
+	t.Run("should only delete personal apps with the user", func(t *testing.T) {
+		recorder.reset()
+
+		err := repo.DeleteUser(1)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		checkQueries(t)
+	})
+
+	t.Run("should only anonymize personal apps with the user", func(t *testing.T) {
+		recorder.reset()
+
+		err := repo.AnonymizeUser(1)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		checkQueries(t)
+	})
+}
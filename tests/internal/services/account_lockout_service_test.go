@@ -0,0 +1,237 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type memoryAccountLockoutRepository struct {
+	lockouts map[models.UserID]models.AccountLockout
+	emails   map[string]models.AccountLockout
+}
+
+func (r *memoryAccountLockoutRepository) GetAccountLockout(userID models.UserID) (*models.AccountLockout, error) {
+	lockout, ok := r.lockouts[userID]
+	if !ok {
+		return &models.AccountLockout{UserID: userID}, nil
+	}
+
+	return &lockout, nil
+}
+
+func (r *memoryAccountLockoutRepository) GetAccountLockoutByUnlockToken(unlockTokenHash string) (*models.AccountLockout, error) {
+	for _, lockout := range r.lockouts {
+		if lockout.UnlockTokenHash == unlockTokenHash {
+			return &lockout, nil
+		}
+	}
+
+	return nil, utils.ErrUnlockTokenInvalid
+}
+
+func (r *memoryAccountLockoutRepository) RecordFailedLogin(userID models.UserID, now time.Time, windowStart time.Time) (*models.AccountLockout, error) {
+	lockout := r.lockouts[userID]
+	lockout.UserID = userID
+
+	if lockout.LastFailedAt != nil && lockout.LastFailedAt.Before(windowStart) {
+		lockout.FailedAttempts = 0
+	}
+
+	lockout.FailedAttempts++
+	lockout.LastFailedAt = &now
+	r.lockouts[userID] = lockout
+
+	return &lockout, nil
+}
+
+func (r *memoryAccountLockoutRepository) LockAccount(userID models.UserID, lockedUntil time.Time, unlockTokenHash string) error {
+	lockout := r.lockouts[userID]
+	lockout.LockedUntil = &lockedUntil
+	lockout.UnlockTokenHash = unlockTokenHash
+	r.lockouts[userID] = lockout
+	return nil
+}
+
+func (r *memoryAccountLockoutRepository) ResetAccountLockout(userID models.UserID) error {
+	delete(r.lockouts, userID)
+	return nil
+}
+
+func (r *memoryAccountLockoutRepository) GetEmailLockout(emailHash string) (*models.AccountLockout, error) {
+	lockout, ok := r.emails[emailHash]
+	if !ok {
+		return &models.AccountLockout{EmailHash: emailHash}, nil
+	}
+
+	return &lockout, nil
+}
+
+func (r *memoryAccountLockoutRepository) RecordFailedEmailLogin(emailHash string, now time.Time, windowStart time.Time) (*models.AccountLockout, error) {
+	lockout := r.emails[emailHash]
+	lockout.EmailHash = emailHash
+
+	if lockout.LastFailedAt != nil && lockout.LastFailedAt.Before(windowStart) {
+		lockout.FailedAttempts = 0
+	}
+
+	lockout.FailedAttempts++
+	lockout.LastFailedAt = &now
+	r.emails[emailHash] = lockout
+
+	return &lockout, nil
+}
+
+func (r *memoryAccountLockoutRepository) LockEmail(emailHash string, lockedUntil time.Time) error {
+	lockout := r.emails[emailHash]
+	lockout.LockedUntil = &lockedUntil
+	r.emails[emailHash] = lockout
+	return nil
+}
+
+func (r *memoryAccountLockoutRepository) ResetEmailLockout(emailHash string) error {
+	delete(r.emails, emailHash)
+	return nil
+}
+
+func (r *memoryAccountLockoutRepository) DeleteStaleEmailLockouts(windowStart time.Time) error {
+	for emailHash, lockout := range r.emails {
+		if lockout.LastFailedAt.Before(windowStart) {
+			delete(r.emails, emailHash)
+		}
+	}
+	return nil
+}
+
+func newTestAccountLockoutService(config services.AccountLockoutConfig) services.IAccountLockoutService {
+	return services.NewAccountLockoutService(
+		&memoryAccountLockoutRepository{
+			lockouts: map[models.UserID]models.AccountLockout{},
+			emails:   map[string]models.AccountLockout{},
+		},
+		services.NewHashService(argon2id.DefaultParams),
+		config,
+	)
+}
+
+func TestAccountLockout(t *testing.T) {
+	t.Run("should lock the account once the threshold is reached", func(t *testing.T) {
+		als := newTestAccountLockoutService(services.AccountLockoutConfig{Threshold: 3, Duration: time.Hour})
+
+		for i := 0; i < 2; i++ {
+			lock, err := als.RecordFailure(1)
+			if err != nil || lock != nil {
+				t.Fatalf("expected no lock after %d failures, got %v, %v", i+1, lock, err)
+			}
+		}
+
+		lock, err := als.RecordFailure(1)
+		if err != nil || lock == nil {
+			t.Fatalf("expected a lock, got %v, %v", lock, err)
+		}
+
+		retryAfter, err := als.CheckAllowed(1)
+		if !errors.Is(err, utils.ErrAccountLocked) {
+			t.Errorf("expected %v, got %v", utils.ErrAccountLocked, err)
+		}
+
+		if retryAfter <= 59*time.Minute || retryAfter > time.Hour {
+			t.Errorf("expected retry after about an hour, got %s", retryAfter)
+		}
+	})
+
+	t.Run("should double the wait after each failure", func(t *testing.T) {
+		als := newTestAccountLockoutService(services.AccountLockoutConfig{Threshold: 10, Duration: 24 * time.Hour, BackoffBase: time.Minute})
+
+		for i, expected := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute} {
+			_, err := als.RecordFailure(1)
+			if err != nil {
+				t.Fatalf("expected no error, got one: %s", err.Error())
+			}
+
+			retryAfter, err := als.CheckAllowed(1)
+			if !errors.Is(err, utils.ErrLoginThrottled) {
+				t.Fatalf("failure %d: expected %v, got %v", i+1, utils.ErrLoginThrottled, err)
+			}
+
+			if retryAfter <= expected-time.Second || retryAfter > expected {
+				t.Errorf("failure %d: expected retry after %s, got %s", i+1, expected, retryAfter)
+			}
+		}
+	})
+
+	t.Run("should unlock the account with the unlock token", func(t *testing.T) {
+		als := newTestAccountLockoutService(services.AccountLockoutConfig{Threshold: 1, Duration: time.Hour})
+
+		lock, err := als.RecordFailure(1)
+		if err != nil || lock == nil {
+			t.Fatalf("expected a lock, got %v, %v", lock, err)
+		}
+
+		err = als.Unlock(lock.UnlockToken)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		_, err = als.CheckAllowed(1)
+		if err != nil {
+			t.Errorf("expected login to be allowed, got %v", err)
+		}
+
+		err = als.Unlock(lock.UnlockToken)
+		if !errors.Is(err, utils.ErrUnlockTokenInvalid) {
+			t.Errorf("expected %v, got %v", utils.ErrUnlockTokenInvalid, err)
+		}
+	})
+
+	t.Run("should forget failures after a successful login", func(t *testing.T) {
+		als := newTestAccountLockoutService(services.AccountLockoutConfig{Threshold: 2, Duration: time.Hour})
+
+		als.RecordFailure(1)
+
+		err := als.RecordSuccess(1)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		lock, err := als.RecordFailure(1)
+		if err != nil || lock != nil {
+			t.Errorf("expected no lock, got %v, %v", lock, err)
+		}
+	})
+
+	t.Run("should throttle and lock unknown e-mails like accounts", func(t *testing.T) {
+		als := newTestAccountLockoutService(services.AccountLockoutConfig{Threshold: 2, Duration: time.Hour, BackoffBase: time.Minute})
+
+		err := als.RecordEmailFailure("unknown@example.com")
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		retryAfter, err := als.CheckAllowedEmail(" Unknown@Example.com")
+		if !errors.Is(err, utils.ErrLoginThrottled) {
+			t.Errorf("expected %v, got %v", utils.ErrLoginThrottled, err)
+		}
+
+		if retryAfter <= 59*time.Second || retryAfter > time.Minute {
+			t.Errorf("expected retry after about a minute, got %s", retryAfter)
+		}
+
+		als.RecordEmailFailure("unknown@example.com")
+
+		_, err = als.CheckAllowedEmail("unknown@example.com")
+		if !errors.Is(err, utils.ErrAccountLocked) {
+			t.Errorf("expected %v, got %v", utils.ErrAccountLocked, err)
+		}
+
+		_, err = als.CheckAllowedEmail("other@example.com")
+		if err != nil {
+			t.Errorf("expected other e-mails to be allowed, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+func TestAdminServiceRevokeSessions(t *testing.T) {
+	userRepository := &memoryUserRepository{users: map[models.UserID]*models.User{
+		1: {ID: 1, Email: "jane@example.com", Status: utils.UserStatusActive},
+	}}
+	refreshTokenRepository := &memoryRefreshTokenRepository{revoked: map[models.UserID]bool{}}
+	as := services.NewAdminService(userRepository, refreshTokenRepository)
+
+	t.Run("should revoke the sessions of the user", func(t *testing.T) {
+		err := as.RevokeSessions(1)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if !refreshTokenRepository.revoked[1] {
+			t.Error("expected the sessions to be revoked")
+		}
+
+		if userRepository.users[1].Status != utils.UserStatusActive {
+			t.Errorf("expected the user to stay active, got %q", userRepository.users[1].Status)
+		}
+	})
+
+	t.Run("should fail for unknown users", func(t *testing.T) {
+		err := as.RevokeSessions(2)
+		if !errors.Is(err, utils.ErrUserNotFound) {
+			t.Errorf("expected ErrUserNotFound, got %v", err)
+		}
+
+		if refreshTokenRepository.revoked[2] {
+			t.Error("expected nothing to be revoked")
+		}
+	})
+}
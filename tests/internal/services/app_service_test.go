@@ -0,0 +1,193 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+	"github.com/pedrotunin/go-jwt-auth/internal/validators"
+)
+
+type memoryAppMemberRepository struct {
+	repositories.AppMemberRepository
+	members map[models.AppID]map[models.UserID]models.AppRole
+	emails  map[models.UserID]models.UserEmail
+}
+
+func (r *memoryAppMemberRepository) GetAppMembers(appID models.AppID) ([]models.AppMember, error) {
+	members := []models.AppMember{}
+	for userID, role := range r.members[appID] {
+		members = append(members, models.AppMember{AppID: appID, UserID: userID, Email: r.emails[userID], Role: role})
+	}
+	return members, nil
+}
+
+func (r *memoryAppMemberRepository) GetAppMember(appID models.AppID, userID models.UserID) (*models.AppMember, error) {
+	role, ok := r.members[appID][userID]
+	if !ok {
+		return nil, utils.ErrAppMemberNotFound
+	}
+	return &models.AppMember{AppID: appID, UserID: userID, Role: role}, nil
+}
+
+func (r *memoryAppMemberRepository) AddAppMember(appID models.AppID, userID models.UserID, role models.AppRole) error {
+	if _, ok := r.members[appID][userID]; ok {
+		return utils.ErrAppMemberAlreadyExists
+	}
+	r.members[appID][userID] = role
+	return nil
+}
+
+func (r *memoryAppMemberRepository) RemoveAppMember(appID models.AppID, userID models.UserID) error {
+	delete(r.members[appID], userID)
+	return nil
+}
+
+func (r *memoryAppMemberRepository) CountAppOwners(appID models.AppID) (int, error) {
+	owners := 0
+	for _, role := range r.members[appID] {
+		if role == utils.AppRoleOwner {
+			owners++
+		}
+	}
+	return owners, nil
+}
+
+func TestHasAppRole(t *testing.T) {
+	tests := []struct {
+		role     models.AppRole
+		required models.AppRole
+		want     bool
+	}{
+		{utils.AppRoleOwner, utils.AppRoleOwner, true},
+		{utils.AppRoleOwner, utils.AppRoleViewer, true},
+		{utils.AppRoleEditor, utils.AppRoleViewer, true},
+		{utils.AppRoleEditor, utils.AppRoleOwner, false},
+		{utils.AppRoleViewer, utils.AppRoleEditor, false},
+		{"", utils.AppRoleViewer, false},
+		{"admin", utils.AppRoleViewer, false},
+	}
+
+	for _, tt := range tests {
+		if got := services.HasAppRole(tt.role, tt.required); got != tt.want {
+			t.Errorf("HasAppRole(%q, %q) = %v, want %v", tt.role, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestAppServiceMembers(t *testing.T) {
+	userRepository := &memoryUserRepository{users: map[models.UserID]*models.User{
+		1: {ID: 1, Email: "owner@example.com", Status: utils.UserStatusActive},
+		2: {ID: 2, Email: "teammate@example.com", Status: utils.UserStatusActive},
+	}}
+	appMemberRepository := &memoryAppMemberRepository{
+		members: map[models.AppID]map[models.UserID]models.AppRole{
+			1: {1: utils.AppRoleOwner},
+		},
+		emails: map[models.UserID]models.UserEmail{1: "owner@example.com", 2: "teammate@example.com"},
+	}
+	invitationService := services.NewInvitationService(
+		&memoryInvitationRepository{},
+		userRepository,
+		&memoryRBACRepository{},
+		newMemoryOrganizationRepository(),
+		appMemberRepository,
+		services.NewHashService(&argon2id.Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}),
+		services.NewPasswordPolicyService(validators.PasswordPolicy{MinLength: 8, MaxLength: 128}, nil),
+		time.Hour,
+	)
+	as := services.NewAppService(nil, appMemberRepository, invitationService)
+	app := &models.App{ID: 1, UserID: 1}
+
+	t.Run("should reject unknown roles", func(t *testing.T) {
+		_, err := as.AddAppMember(app.ID, 1, "teammate@example.com", "admin")
+		if !errors.Is(err, utils.ErrAppRoleInvalid) {
+			t.Errorf("expected ErrAppRoleInvalid, got %v", err)
+		}
+	})
+
+	t.Run("should invite registered and unknown e-mails alike", func(t *testing.T) {
+		registered, err := as.AddAppMember(app.ID, 1, " teammate@example.com ", utils.AppRoleEditor)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		unknown, err := as.AddAppMember(app.ID, 1, "nobody@example.com", utils.AppRoleViewer)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		for _, invitation := range []*services.InvitationToken{registered, unknown} {
+			if invitation.Invitation.AppID == nil || *invitation.Invitation.AppID != app.ID {
+				t.Errorf("expected the invitation to carry app %d", app.ID)
+			}
+		}
+
+		if len(appMemberRepository.members[app.ID]) != 1 {
+			t.Errorf("expected nobody to be added before accepting, got %v", appMemberRepository.members[app.ID])
+		}
+	})
+
+	t.Run("should add collaborators once they accept", func(t *testing.T) {
+		invitation, err := as.AddAppMember(app.ID, 1, "teammate@example.com", utils.AppRoleEditor)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		_, err = invitationService.JoinWithInvitation(invitation.Token, 1)
+		if !errors.Is(err, utils.ErrForbidden) {
+			t.Errorf("expected ErrForbidden for another user, got %v", err)
+		}
+
+		_, err = invitationService.JoinWithInvitation(invitation.Token, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		role, err := as.GetAppRole(app.ID, 2)
+		if err != nil || role != utils.AppRoleEditor {
+			t.Errorf("expected editor role, got %q, %v", role, err)
+		}
+	})
+
+	t.Run("should refuse to invite current collaborators", func(t *testing.T) {
+		_, err := as.AddAppMember(app.ID, 1, "TEAMMATE@example.com", utils.AppRoleViewer)
+		if !errors.Is(err, utils.ErrAppMemberAlreadyExists) {
+			t.Errorf("expected ErrAppMemberAlreadyExists, got %v", err)
+		}
+	})
+
+	t.Run("should keep the last owner of personal apps", func(t *testing.T) {
+		err := as.RemoveAppMember(app, 1)
+		if !errors.Is(err, utils.ErrAppLastOwner) {
+			t.Errorf("expected ErrAppLastOwner, got %v", err)
+		}
+	})
+
+	t.Run("should let organization apps lose their last owner", func(t *testing.T) {
+		organizationID := models.OrganizationID(1)
+		appMemberRepository.members[2] = map[models.UserID]models.AppRole{1: utils.AppRoleOwner}
+
+		err := as.RemoveAppMember(&models.App{ID: 2, UserID: 1, OrganizationID: &organizationID}, 1)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+	})
+
+	t.Run("should remove collaborators", func(t *testing.T) {
+		err := as.RemoveAppMember(app, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		_, err = as.GetAppRole(app.ID, 2)
+		if !errors.Is(err, utils.ErrAppMemberNotFound) {
+			t.Errorf("expected ErrAppMemberNotFound, got %v", err)
+		}
+	})
+}
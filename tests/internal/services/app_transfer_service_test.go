@@ -0,0 +1,255 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+func (r *memoryOrganizationRepository) GetOrganizationByID(organizationID models.OrganizationID) (*models.Organization, error) {
+	organization, ok := r.organizations[organizationID]
+	if !ok {
+		return nil, utils.ErrOrganizationNotFound
+	}
+	return organization, nil
+}
+
+func (r *memoryOrganizationRepository) GetOrganizationMembers(organizationID models.OrganizationID) ([]models.OrganizationMember, error) {
+	members := []models.OrganizationMember{}
+	for userID, role := range r.members[organizationID] {
+		members = append(members, models.OrganizationMember{OrganizationID: organizationID, UserID: userID, Email: "member@example.com", Role: role})
+	}
+	return members, nil
+}
+
+type memoryAppTransferRepository struct {
+	repositories.AppTransferRepository
+	transfers []*models.AppTransfer
+}
+
+func (r *memoryAppTransferRepository) CreateAppTransfer(transfer *models.AppTransfer) error {
+	now := time.Now()
+	for _, previous := range r.transfers {
+		if previous.AppID == transfer.AppID && previous.AcceptedAt == nil && previous.CancelledAt == nil {
+			previous.CancelledAt = &now
+		}
+	}
+
+	transfer.ID = len(r.transfers) + 1
+	transfer.CreatedAt = now
+	stored := *transfer
+	r.transfers = append(r.transfers, &stored)
+	return nil
+}
+
+func (r *memoryAppTransferRepository) GetAppTransferByID(id models.AppTransferID) (*models.AppTransfer, error) {
+	if id < 1 || id > len(r.transfers) {
+		return nil, utils.ErrAppTransferNotFound
+	}
+	found := *r.transfers[id-1]
+	return &found, nil
+}
+
+func (r *memoryAppTransferRepository) GetAppTransferByTokenHash(tokenHash string) (*models.AppTransfer, error) {
+	for _, transfer := range r.transfers {
+		if transfer.TokenHash == tokenHash {
+			found := *transfer
+			return &found, nil
+		}
+	}
+	return nil, utils.ErrAppTransferInvalid
+}
+
+type memoryAppRepository struct {
+	repositories.AppRepository
+	apps      map[models.AppID]*models.App
+	transfers *memoryAppTransferRepository
+}
+
+func (r *memoryAppRepository) TransferApp(transfer *models.AppTransfer, acceptedBy models.UserID) error {
+	stored := r.transfers.transfers[transfer.ID-1]
+	if stored.AcceptedAt != nil || stored.CancelledAt != nil {
+		return utils.ErrAppTransferInvalid
+	}
+
+	now := time.Now()
+	stored.AcceptedAt = &now
+	stored.AcceptedBy = &acceptedBy
+
+	app := r.apps[transfer.AppID]
+	app.UserID = acceptedBy
+	if transfer.ToUserID != nil {
+		app.UserID = *transfer.ToUserID
+	}
+	app.OrganizationID = transfer.ToOrganizationID
+	return nil
+}
+
+func TestAppTransferService(t *testing.T) {
+	userRepository := &memoryUserRepository{users: map[models.UserID]*models.User{
+		1: {ID: 1, Email: "leaving@example.com", Status: utils.UserStatusActive},
+		2: {ID: 2, Email: "successor@example.com", Status: utils.UserStatusActive},
+		3: {ID: 3, Email: "inactive@example.com", Status: utils.UserStatusInactive},
+		4: {ID: 4, Email: "org-admin@example.com", Status: utils.UserStatusActive},
+		5: {ID: 5, Email: "org-member@example.com", Status: utils.UserStatusActive},
+	}}
+	organizationRepository := newMemoryOrganizationRepository()
+	organization := &models.Organization{Name: "Acme"}
+	organizationRepository.CreateOrganization(organization, 4)
+	organizationRepository.AddOrganizationMember(organization.ID, 5, utils.OrganizationRoleMember)
+
+	transferRepository := &memoryAppTransferRepository{}
+	appRepository := &memoryAppRepository{
+		apps: map[models.AppID]*models.App{
+			1: {ID: 1, Name: "billing", UserID: 1},
+			2: {ID: 2, Name: "reports", UserID: 1},
+		},
+		transfers: transferRepository,
+	}
+	hashService := services.NewHashService(&argon2id.Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	ats := services.NewAppTransferService(transferRepository, appRepository, userRepository, organizationRepository, hashService, time.Hour)
+
+	t.Run("should require exactly one recipient", func(t *testing.T) {
+		_, err := ats.CreateTransfer(appRepository.apps[1], 1, "", nil)
+		if !errors.Is(err, utils.ErrAppTransferRecipientInvalid) {
+			t.Errorf("expected ErrAppTransferRecipientInvalid, got %v", err)
+		}
+
+		_, err = ats.CreateTransfer(appRepository.apps[1], 1, "successor@example.com", &organization.ID)
+		if !errors.Is(err, utils.ErrAppTransferRecipientInvalid) {
+			t.Errorf("expected ErrAppTransferRecipientInvalid, got %v", err)
+		}
+	})
+
+	t.Run("should refuse the current owner", func(t *testing.T) {
+		_, err := ats.CreateTransfer(appRepository.apps[1], 1, "leaving@example.com", nil)
+		if !errors.Is(err, utils.ErrAppTransferRecipientInvalid) {
+			t.Errorf("expected ErrAppTransferRecipientInvalid, got %v", err)
+		}
+	})
+
+	t.Run("should answer unknown and inactive e-mails like real recipients", func(t *testing.T) {
+		for _, email := range []string{"nobody@example.com", "inactive@example.com"} {
+			transfer, err := ats.CreateTransfer(appRepository.apps[1], 1, email, nil)
+			if err != nil {
+				t.Fatalf("unexpected error for %s: %s", email, err.Error())
+			}
+			if len(transfer.RecipientEmails) != 0 {
+				t.Errorf("expected nobody to be e-mailed for %s, got %v", email, transfer.RecipientEmails)
+			}
+			if transfer.Transfer.ToEmail != email || transfer.Transfer.Status != models.AppTransferStatusPending {
+				t.Errorf("expected a pending transfer to %s, got %+v", email, transfer.Transfer)
+			}
+
+			_, err = ats.AcceptTransferByToken(transfer.Token, 3)
+			if !errors.Is(err, utils.ErrForbidden) {
+				t.Errorf("expected nobody to accept a transfer to %s, got %v", email, err)
+			}
+		}
+	})
+
+	t.Run("should transfer an app to the recipient through the e-mailed token", func(t *testing.T) {
+		transfer, err := ats.CreateTransfer(appRepository.apps[1], 1, "successor@example.com", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if len(transfer.RecipientEmails) != 1 || transfer.RecipientEmails[0] != "successor@example.com" {
+			t.Errorf("expected the successor to be e-mailed, got %v", transfer.RecipientEmails)
+		}
+
+		_, err = ats.AcceptTransferByToken(transfer.Token, 5)
+		if !errors.Is(err, utils.ErrForbidden) {
+			t.Errorf("expected only the recipient to accept, got %v", err)
+		}
+
+		accepted, err := ats.AcceptTransferByToken(transfer.Token, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if accepted.Status != models.AppTransferStatusAccepted || appRepository.apps[1].UserID != 2 {
+			t.Errorf("expected the app to belong to user 2, got status %s and owner %d", accepted.Status, appRepository.apps[1].UserID)
+		}
+
+		_, err = ats.AcceptTransfer(transfer.Transfer.ID, 2)
+		if !errors.Is(err, utils.ErrAppTransferInvalid) {
+			t.Errorf("expected ErrAppTransferInvalid on a second acceptance, got %v", err)
+		}
+	})
+
+	t.Run("should let organization managers accept transfers to the organization", func(t *testing.T) {
+		transfer, err := ats.CreateTransfer(appRepository.apps[2], 1, "", &organization.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if len(transfer.RecipientEmails) != 1 {
+			t.Errorf("expected only the organization admin to be e-mailed, got %v", transfer.RecipientEmails)
+		}
+
+		_, err = ats.AcceptTransfer(transfer.Transfer.ID, 5)
+		if !errors.Is(err, utils.ErrForbidden) {
+			t.Errorf("expected plain members not to accept, got %v", err)
+		}
+
+		_, err = ats.AcceptTransfer(transfer.Transfer.ID, 4)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		app := appRepository.apps[2]
+		if app.OrganizationID == nil || *app.OrganizationID != organization.ID || app.UserID != 4 {
+			t.Errorf("expected the app to belong to the organization, got %+v", app)
+		}
+	})
+
+	t.Run("should replace pending transfers and reject expired ones", func(t *testing.T) {
+		first, err := ats.CreateTransfer(appRepository.apps[1], 2, "leaving@example.com", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		second, err := ats.CreateTransfer(appRepository.apps[1], 2, "leaving@example.com", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		_, err = ats.AcceptTransfer(first.Transfer.ID, 1)
+		if !errors.Is(err, utils.ErrAppTransferInvalid) {
+			t.Errorf("expected the replaced transfer to be invalid, got %v", err)
+		}
+
+		transferRepository.transfers[second.Transfer.ID-1].ExpiresAt = time.Now().Add(-time.Minute)
+
+		_, err = ats.AcceptTransfer(second.Transfer.ID, 1)
+		if !errors.Is(err, utils.ErrAppTransferExpired) {
+			t.Errorf("expected ErrAppTransferExpired, got %v", err)
+		}
+	})
+
+	t.Run("should transfer organization apps whose creator was deleted", func(t *testing.T) {
+		appRepository.apps[3] = &models.App{ID: 3, Name: "orphan", OrganizationID: &organization.ID}
+
+		transfer, err := ats.CreateTransfer(appRepository.apps[3], 4, "successor@example.com", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if transfer.Transfer.FromUserID != 0 {
+			t.Errorf("expected no previous user, got %d", transfer.Transfer.FromUserID)
+		}
+
+		_, err = ats.AcceptTransferByToken(transfer.Token, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		app := appRepository.apps[3]
+		if app.OrganizationID != nil || app.UserID != 2 {
+			t.Errorf("expected the app to belong to user 2, got %+v", app)
+		}
+	})
+}
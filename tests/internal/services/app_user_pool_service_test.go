@@ -0,0 +1,231 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+	"github.com/pedrotunin/go-jwt-auth/internal/validators"
+)
+
+type memoryAppUserPoolRepository struct {
+	repositories.AppUserPoolRepository
+	pools map[models.AppID]*models.AppUserPool
+}
+
+func (r *memoryAppUserPoolRepository) GetUserPoolByAppID(appID models.AppID) (*models.AppUserPool, error) {
+	pool, ok := r.pools[appID]
+	if !ok {
+		return nil, utils.ErrAppUserPoolNotFound
+	}
+	found := *pool
+	return &found, nil
+}
+
+func (r *memoryAppUserPoolRepository) GetUserPoolByClientID(clientID models.AppClientID) (*models.AppUserPool, error) {
+	for _, pool := range r.pools {
+		if pool.ClientID == clientID && pool.Enabled {
+			found := *pool
+			return &found, nil
+		}
+	}
+	return nil, utils.ErrAppUserPoolNotFound
+}
+
+func (r *memoryAppUserPoolRepository) SaveUserPool(pool *models.AppUserPool) error {
+	if existing, ok := r.pools[pool.AppID]; ok {
+		pool.ClientID = existing.ClientID
+	}
+	stored := *pool
+	r.pools[pool.AppID] = &stored
+	return nil
+}
+
+type memoryAppUserRepository struct {
+	repositories.AppUserRepository
+	users         []*models.AppUser
+	refreshTokens map[models.RefreshTokenContent]*models.AppUserRefreshToken
+}
+
+func (r *memoryAppUserRepository) CreateAppUser(user *models.AppUser) error {
+	for _, existing := range r.users {
+		if existing.AppID == user.AppID && existing.Email == user.Email {
+			return utils.ErrUserEmailAlreadyExists
+		}
+	}
+	user.ID = len(r.users) + 1
+	stored := *user
+	r.users = append(r.users, &stored)
+	return nil
+}
+
+func (r *memoryAppUserRepository) GetAppUserByID(appID models.AppID, appUserID models.AppUserID) (*models.AppUser, error) {
+	for _, user := range r.users {
+		if user.AppID == appID && user.ID == appUserID {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, utils.ErrUserNotFound
+}
+
+func (r *memoryAppUserRepository) GetAppUserByEmail(appID models.AppID, email models.UserEmail) (*models.AppUser, error) {
+	for _, user := range r.users {
+		if user.AppID == appID && user.Email == email {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, utils.ErrUserNotFound
+}
+
+func (r *memoryAppUserRepository) UpdateAppUserLastLogin(appUserID models.AppUserID, at time.Time) error {
+	return nil
+}
+
+func (r *memoryAppUserRepository) CreateAppUserRefreshToken(token *models.AppUserRefreshToken) error {
+	stored := *token
+	r.refreshTokens[token.Content] = &stored
+	return nil
+}
+
+func (r *memoryAppUserRepository) GetAppUserRefreshTokenByContent(content models.RefreshTokenContent) (*models.AppUserRefreshToken, error) {
+	token, ok := r.refreshTokens[content]
+	if !ok {
+		return nil, utils.ErrRefreshTokenNotFound
+	}
+	found := *token
+	return &found, nil
+}
+
+func (r *memoryAppUserRepository) InvalidateAppUserRefreshTokenByContent(content models.RefreshTokenContent) error {
+	if token, ok := r.refreshTokens[content]; ok {
+		token.Status = models.RefreshTokenStatusInactive
+	}
+	return nil
+}
+
+func TestAppUserPoolService(t *testing.T) {
+	poolRepository := &memoryAppUserPoolRepository{pools: map[models.AppID]*models.AppUserPool{}}
+	appUserRepository := &memoryAppUserRepository{refreshTokens: map[models.RefreshTokenContent]*models.AppUserRefreshToken{}}
+	hashService := services.NewHashService(&argon2id.Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	passwordPolicyService := services.NewPasswordPolicyService(validators.PasswordPolicy{MinLength: 8, MaxLength: 128}, nil)
+	ups := services.NewAppUserPoolService(poolRepository, appUserRepository, hashService, passwordPolicyService, "token-secret", "refresh-token-secret", 5*time.Minute, 7*24*time.Hour)
+
+	billing, err := ups.ConfigureUserPool(1, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	reports, err := ups.ConfigureUserPool(2, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	t.Run("should keep the client id when the pool is reconfigured", func(t *testing.T) {
+		pool, err := ups.ConfigureUserPool(1, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if pool.ClientID != billing.ClientID {
+			t.Errorf("expected client id %s, got %s", billing.ClientID, pool.ClientID)
+		}
+	})
+
+	t.Run("should register the same email separately in two pools", func(t *testing.T) {
+		_, err := ups.SignUp(billing.ClientID, "end-user@example.com", "billing-password")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		_, err = ups.SignUp(reports.ClientID, "end-user@example.com", "reports-password")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		_, err = ups.SignUp(billing.ClientID, "end-user@example.com", "another-password")
+		if !errors.Is(err, utils.ErrUserEmailAlreadyExists) {
+			t.Errorf("expected ErrUserEmailAlreadyExists, got %v", err)
+		}
+	})
+
+	t.Run("should only accept the password of the pool", func(t *testing.T) {
+		_, err := ups.Login(billing.ClientID, "end-user@example.com", "reports-password")
+		if !errors.Is(err, utils.ErrEmailPasswordIncorrect) {
+			t.Errorf("expected ErrEmailPasswordIncorrect, got %v", err)
+		}
+
+		_, err = ups.Login(billing.ClientID, "unknown@example.com", "billing-password")
+		if !errors.Is(err, utils.ErrEmailPasswordIncorrect) {
+			t.Errorf("expected ErrEmailPasswordIncorrect, got %v", err)
+		}
+	})
+
+	t.Run("should scope tokens to the pool and rotate refresh tokens", func(t *testing.T) {
+		tokens, err := ups.Login(billing.ClientID, "end-user@example.com", "billing-password")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		_, err = ups.Refresh(reports.ClientID, tokens.RefreshToken)
+		if !errors.Is(err, utils.ErrRefreshTokenInvalid) {
+			t.Errorf("expected a refresh token of another pool to be invalid, got %v", err)
+		}
+
+		refreshed, err := ups.Refresh(billing.ClientID, tokens.RefreshToken)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if refreshed.RefreshToken == "" {
+			t.Errorf("expected a new refresh token")
+		}
+
+		_, err = ups.Refresh(billing.ClientID, tokens.RefreshToken)
+		if !errors.Is(err, utils.ErrRefreshTokenInvalid) {
+			t.Errorf("expected a rotated refresh token to be invalid, got %v", err)
+		}
+	})
+
+	t.Run("should not accept pool tokens as global access tokens", func(t *testing.T) {
+		tokens, err := ups.Login(billing.ClientID, "end-user@example.com", "billing-password")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		jwtService := services.NewJWTService("token-secret", "refresh-token-secret", 5*time.Minute, 7*24*time.Hour, nil, hashService, nil, nil)
+
+		_, err = jwtService.ValidateToken(tokens.AccessToken)
+		if err == nil {
+			t.Error("expected pool access tokens to be refused")
+		}
+
+		for _, tc := range []struct{ token, secret string }{
+			{tokens.AccessToken, "token-secret"},
+			{tokens.RefreshToken, "refresh-token-secret"},
+		} {
+			_, err = jwt.Parse(tc.token, func(token *jwt.Token) (interface{}, error) {
+				return []byte(tc.secret), nil
+			})
+			if !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+				t.Errorf("expected pool tokens not to be signed with the API's secrets, got %v", err)
+			}
+		}
+	})
+
+	t.Run("should refuse disabled pools", func(t *testing.T) {
+		_, err := ups.ConfigureUserPool(2, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		_, err = ups.Login(reports.ClientID, "end-user@example.com", "reports-password")
+		if !errors.Is(err, utils.ErrAppUserPoolNotFound) {
+			t.Errorf("expected ErrAppUserPoolNotFound, got %v", err)
+		}
+	})
+}
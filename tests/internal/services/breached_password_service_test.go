@@ -0,0 +1,57 @@
+package services_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+)
+
+// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8.
+
+func TestBreachedPasswordChecker(t *testing.T) {
+	t.Run("should find passwords in a range directory", func(t *testing.T) {
+		dir := t.TempDir()
+
+		err := os.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte("003D68EB55068C33ACE09247EE4C639306B:3\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\n"), 0o600)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		checker, err := services.NewBreachedPasswordChecker(dir)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		for password, expected := range map[string]bool{"password": true, "not-in-the-list": false} {
+			breached, err := checker.IsBreached(password)
+			if err != nil {
+				t.Fatalf("expected no error, got one: %s", err.Error())
+			}
+
+			if breached != expected {
+				t.Errorf("%q: expected breached=%v, got %v", password, expected, breached)
+			}
+		}
+	})
+
+	t.Run("should find passwords in a hash list file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "breached.txt")
+
+		err := os.WriteFile(path, []byte("5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8:9545824\n"), 0o600)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		checker, err := services.NewBreachedPasswordChecker(path)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		breached, _ := checker.IsBreached("password")
+		if !breached {
+			t.Error("expected password to be breached")
+		}
+	})
+}
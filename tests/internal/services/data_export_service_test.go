@@ -0,0 +1,233 @@
+package services_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type memoryDataExportRepository struct {
+	exports map[models.DataExportContent]*models.DataExport
+}
+
+func (r *memoryDataExportRepository) CreateDataExport(export *models.DataExport) error {
+	r.exports[export.Content] = export
+	return nil
+}
+
+func (r *memoryDataExportRepository) GetDataExportByContent(content models.DataExportContent) (*models.DataExport, error) {
+	export, ok := r.exports[content]
+	if !ok {
+		return nil, utils.ErrDataExportNotFound
+	}
+
+	return export, nil
+}
+
+func (r *memoryDataExportRepository) DeleteExpiredDataExports(now time.Time) error {
+	return nil
+}
+
+type memoryEmailVerificationTokenRepository struct {
+	repositories.EmailVerificationTokenRepository
+}
+
+func (r *memoryEmailVerificationTokenRepository) GetVerificationTokensByUserID(userID models.UserID) ([]models.EmailVerificationToken, error) {
+	return []models.EmailVerificationToken{}, nil
+}
+
+type memoryTOTPCredentialRepository struct {
+	repositories.TOTPCredentialRepository
+	credentials map[models.UserID]*models.TOTPCredential
+}
+
+func (r *memoryTOTPCredentialRepository) GetTOTPCredentialByUserID(userID models.UserID) (*models.TOTPCredential, error) {
+	credential, ok := r.credentials[userID]
+	if !ok {
+		return nil, utils.ErrMFANotEnabled
+	}
+
+	return credential, nil
+}
+
+type memoryMFARecoveryCodeRepository struct {
+	repositories.MFARecoveryCodeRepository
+	unused map[models.UserID]int
+}
+
+func (r *memoryMFARecoveryCodeRepository) CountUnusedRecoveryCodes(userID models.UserID) (int, error) {
+	return r.unused[userID], nil
+}
+
+func (r *memoryAppRepository) GetAllAppsByUserID(userID models.UserID) ([]models.App, error) {
+	apps := []models.App{}
+	for _, app := range r.apps {
+		if app.UserID == userID {
+			apps = append(apps, *app)
+		}
+	}
+	return apps, nil
+}
+
+func (r *memoryRefreshTokenRepository) GetRefreshTokensByUserID(userID models.UserID) ([]models.RefreshToken, error) {
+	return []models.RefreshToken{}, nil
+}
+
+func (r *memoryOrganizationRepository) GetOrganizationsByUserID(userID models.UserID) ([]models.OrganizationMembership, error) {
+	memberships := []models.OrganizationMembership{}
+	for organizationID, members := range r.members {
+		role, ok := members[userID]
+		if !ok {
+			continue
+		}
+
+		memberships = append(memberships, models.OrganizationMembership{
+			Organization: *r.organizations[organizationID],
+			Role:         role,
+			Active:       r.active[userID] == organizationID,
+		})
+	}
+	return memberships, nil
+}
+
+func (r *memoryInvitationRepository) GetInvitationsByUser(userID models.UserID, email models.UserEmail) ([]models.Invitation, error) {
+	invitations := []models.Invitation{}
+	for _, invitation := range r.invitations {
+		if invitation.InvitedBy == userID || strings.EqualFold(invitation.Email, email) || (invitation.UserID != nil && *invitation.UserID == userID) {
+			invitations = append(invitations, *invitation)
+		}
+	}
+	return invitations, nil
+}
+
+func (r *memoryAppMemberRepository) GetAppMembershipsByUserID(userID models.UserID) ([]models.AppMember, error) {
+	memberships := []models.AppMember{}
+	for appID, members := range r.members {
+		role, ok := members[userID]
+		if ok {
+			memberships = append(memberships, models.AppMember{AppID: appID, UserID: userID, Role: role})
+		}
+	}
+	return memberships, nil
+}
+
+func TestDataExportArchive(t *testing.T) {
+	hashService := services.NewHashService(&argon2id.Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	confirmedAt := time.Now()
+
+	des := services.NewDataExportService(
+		&memoryDataExportRepository{exports: map[models.DataExportContent]*models.DataExport{}},
+		&memoryUserRepository{users: map[models.UserID]*models.User{
+			1: {ID: 1, Email: "user@example.com", Password: "password-hash"},
+		}},
+		&memoryAppRepository{apps: map[models.AppID]*models.App{
+			1: {ID: 1, Name: "owned app", UserID: 1},
+		}},
+		&memoryRefreshTokenRepository{},
+		&memoryEmailVerificationTokenRepository{},
+		&memoryLoginEventRepository{events: []models.LoginEvent{
+			{UserID: 1, Method: utils.LoginMethodPassword, Success: true, IPAddress: "203.0.113.7", UserAgent: "test-agent"},
+			{UserID: 2, Method: utils.LoginMethodPassword, Success: true, IPAddress: "198.51.100.1", UserAgent: "other-agent"},
+		}},
+		&memoryTOTPCredentialRepository{credentials: map[models.UserID]*models.TOTPCredential{
+			1: {UserID: 1, Secret: "totp-secret", ConfirmedAt: &confirmedAt},
+		}},
+		&memoryMFARecoveryCodeRepository{unused: map[models.UserID]int{1: 8}},
+		&memoryWebAuthnCredentialRepository{credentials: []models.WebAuthnCredential{
+			{ID: 1, UserID: 1, CredentialID: "credential-1", PublicKey: []byte("public-key"), Name: "laptop"},
+		}},
+		&memoryOrganizationRepository{
+			organizations: map[models.OrganizationID]*models.Organization{1: {ID: 1, Name: "acme"}},
+			members:       map[models.OrganizationID]map[models.UserID]models.OrganizationRole{1: {1: utils.OrganizationRoleOwner}},
+			active:        map[models.UserID]models.OrganizationID{},
+		},
+		&memoryInvitationRepository{invitations: []*models.Invitation{
+			{ID: 1, Email: "USER@example.com", TokenHash: "invitation-hash", InvitedBy: 2, ExpiresAt: time.Now().Add(time.Hour)},
+			{ID: 2, Email: "someone@example.com", TokenHash: "unrelated-hash", InvitedBy: 2, ExpiresAt: time.Now().Add(time.Hour)},
+		}},
+		&memoryAppMemberRepository{members: map[models.AppID]map[models.UserID]models.AppRole{
+			2: {1: utils.AppRoleViewer},
+		}},
+		hashService,
+		time.Hour,
+	)
+
+	token, _, err := des.CreateExport(1)
+	if err != nil {
+		t.Fatalf("expected no error, got one: %s", err.Error())
+	}
+
+	export, err := des.GetExport(token)
+	if err != nil {
+		t.Fatalf("expected no error, got one: %s", err.Error())
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(export.Archive), int64(len(export.Archive)))
+	if err != nil {
+		t.Fatalf("expected a zip archive, got error: %s", err.Error())
+	}
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+
+	t.Run("should include every data set", func(t *testing.T) {
+		expected := map[string]string{
+			"login_events.json":         "203.0.113.7",
+			"mfa.json":                  `"unused_recovery_codes": 8`,
+			"webauthn_credentials.json": "laptop",
+			"organizations.json":        "acme",
+			"invitations.json":          "USER@example.com",
+			"app_memberships.json":      utils.AppRoleViewer,
+		}
+
+		for name, content := range expected {
+			file, ok := files[name]
+			if !ok {
+				t.Errorf("expected %s in the archive", name)
+				continue
+			}
+
+			if !strings.Contains(file, content) {
+				t.Errorf("expected %s to contain %q, got %s", name, content, file)
+			}
+		}
+
+		var mfa map[string]any
+		json.Unmarshal([]byte(files["mfa.json"]), &mfa)
+		if mfa["totp_enabled"] != true {
+			t.Errorf("expected totp to be enabled, got %v", mfa["totp_enabled"])
+		}
+	})
+
+	t.Run("should leave out other users and secrets", func(t *testing.T) {
+		var all strings.Builder
+		for _, content := range files {
+			all.WriteString(content)
+		}
+
+		for _, leaked := range []string{"198.51.100.1", "someone@example.com", "totp-secret", "invitation-hash", "password-hash", "cHVibGljLWtleQ"} {
+			if strings.Contains(all.String(), leaked) {
+				t.Errorf("expected the archive not to contain %q", leaked)
+			}
+		}
+	})
+}
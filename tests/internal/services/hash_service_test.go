@@ -0,0 +1,126 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashServiceComparePassword(t *testing.T) {
+	hashService := services.NewHashService(argon2id.DefaultParams)
+
+	t.Run("should verify argon2id hashes", func(t *testing.T) {
+		hash, _ := hashService.HashArgon2id("password123")
+
+		err := hashService.ComparePassword("password123", hash)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+	})
+
+	t.Run("should verify legacy bcrypt hashes", func(t *testing.T) {
+		hash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+
+		err := hashService.ComparePassword("password123", string(hash))
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		err = hashService.ComparePassword("wrong-password", string(hash))
+		if !errors.Is(err, utils.ErrPasswordsNotMatch) {
+			t.Errorf("expected ErrPasswordsNotMatch, got other: %v", err)
+		}
+	})
+}
+
+func TestHashServiceNeedsRehash(t *testing.T) {
+	weakParams := &argon2id.Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	strongParams := &argon2id.Params{Memory: 16 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+	t.Run("should request rehash for weaker argon2id parameters", func(t *testing.T) {
+		hash, _ := services.NewHashService(weakParams).HashArgon2id("password123")
+
+		if !services.NewHashService(strongParams).NeedsRehash(hash) {
+			t.Errorf("expected hash with weaker parameters to need rehash")
+		}
+	})
+
+	t.Run("should not request rehash for current parameters", func(t *testing.T) {
+		hashService := services.NewHashService(strongParams)
+		hash, _ := hashService.HashArgon2id("password123")
+
+		if hashService.NeedsRehash(hash) {
+			t.Errorf("expected hash with current parameters not to need rehash")
+		}
+	})
+
+	t.Run("should request rehash for bcrypt hashes", func(t *testing.T) {
+		hash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+
+		if !services.NewHashService(weakParams).NeedsRehash(string(hash)) {
+			t.Errorf("expected bcrypt hash to need rehash")
+		}
+	})
+}
+
+func TestHashServicePepper(t *testing.T) {
+	params := &argon2id.Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	oldPepper := services.PasswordPepper{Version: "1", Secret: []byte("old-pepper-secret-value")}
+	newPepper := services.PasswordPepper{Version: "2", Secret: []byte("new-pepper-secret-value")}
+
+	t.Run("should not verify peppered hash with a plain argon2id comparison", func(t *testing.T) {
+		hashService := services.NewHashService(params, newPepper)
+		hash, _ := hashService.HashPassword("password123")
+
+		err := services.NewHashService(params).CompareArgon2id("password123", hash)
+		if err == nil {
+			t.Errorf("expected peppered hash not to verify without the pepper")
+		}
+
+		err = hashService.ComparePassword("password123", hash)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+	})
+
+	t.Run("should verify hashes made with an older pepper and request rehash", func(t *testing.T) {
+		hash, _ := services.NewHashService(params, oldPepper).HashPassword("password123")
+
+		hashService := services.NewHashService(params, newPepper, oldPepper)
+
+		err := hashService.ComparePassword("password123", hash)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		if !hashService.NeedsRehash(hash) {
+			t.Errorf("expected hash with an old pepper to need rehash")
+		}
+
+		rehashed, _ := hashService.HashPassword("password123")
+		if hashService.NeedsRehash(rehashed) {
+			t.Errorf("expected hash with the current pepper not to need rehash")
+		}
+	})
+
+	t.Run("should reject hashes made with a removed pepper", func(t *testing.T) {
+		hash, _ := services.NewHashService(params, oldPepper).HashPassword("password123")
+
+		err := services.NewHashService(params, newPepper).ComparePassword("password123", hash)
+		if !errors.Is(err, utils.ErrPasswordPepperUnknown) {
+			t.Errorf("expected ErrPasswordPepperUnknown, got other: %v", err)
+		}
+	})
+
+	t.Run("should request rehash for unpeppered hashes once a pepper is configured", func(t *testing.T) {
+		hash, _ := services.NewHashService(params).HashPassword("password123")
+
+		if !services.NewHashService(params, newPepper).NeedsRehash(hash) {
+			t.Errorf("expected unpeppered hash to need rehash")
+		}
+	})
+}
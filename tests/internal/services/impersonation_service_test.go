@@ -0,0 +1,108 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type memoryImpersonationEventRepository struct {
+	repositories.ImpersonationEventRepository
+	events []models.ImpersonationEvent
+}
+
+func (r *memoryImpersonationEventRepository) CreateImpersonationEvent(event *models.ImpersonationEvent) error {
+	event.ID = len(r.events) + 1
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func TestImpersonationService(t *testing.T) {
+	rbacRepository := &memoryRBACRepository{
+		roles: map[string]models.Role{
+			utils.RoleAdmin: {ID: 1, Name: utils.RoleAdmin, Permissions: []string{utils.PermissionUsersRead, utils.PermissionUsersImpersonate}},
+			"support":       {ID: 2, Name: "support", Permissions: []string{utils.PermissionUsersRead}},
+		},
+		userRoles: map[models.UserID][]string{1: {utils.RoleAdmin}, 2: {"support"}},
+	}
+	userRepository := &memoryUserRepository{users: map[models.UserID]*models.User{
+		1: {ID: 1, Email: "admin@example.com", Status: utils.UserStatusActive},
+		2: {ID: 2, Email: "user@example.com", Status: utils.UserStatusActive},
+		3: {ID: 3, Email: "inactive@example.com", Status: utils.UserStatusInactive},
+	}}
+	rbacService := services.NewRBACService(rbacRepository, userRepository, nil)
+	organizationService := services.NewOrganizationService(newMemoryOrganizationRepository(), nil)
+	jwtService := services.NewJWTService("token-secret", "refresh-token-secret", 5*time.Minute, 7*24*time.Hour, nil, nil, rbacService, organizationService)
+	eventRepository := &memoryImpersonationEventRepository{}
+	is := services.NewImpersonationService(eventRepository, userRepository, jwtService, 15*time.Minute)
+
+	t.Run("should issue a token carrying the actor and the target's permissions", func(t *testing.T) {
+		impersonation, err := is.Impersonate(1, 2, "ticket #42", "203.0.113.7", "test-agent")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		claims, err := jwtService.ValidateToken(impersonation.Token)
+		if err != nil {
+			t.Fatalf("unexpected error validating token: %s", err.Error())
+		}
+
+		if claims.UserID != 2 {
+			t.Errorf("expected token for user 2, got %d", claims.UserID)
+		}
+		if !claims.IsImpersonation() || claims.Actor.UserID != 1 || !claims.Impersonated {
+			t.Errorf("expected actor 1 in the token, got %+v", claims.Actor)
+		}
+		if claims.HasPermission(utils.PermissionUsersImpersonate) {
+			t.Error("expected the token to carry the target's permissions, not the admin's")
+		}
+		if time.Until(impersonation.ExpiresAt) > 15*time.Minute {
+			t.Errorf("expected token to expire within 15 minutes, expires at %s", impersonation.ExpiresAt)
+		}
+	})
+
+	t.Run("should record every impersonation", func(t *testing.T) {
+		if len(eventRepository.events) != 1 {
+			t.Fatalf("expected 1 impersonation event, got %d", len(eventRepository.events))
+		}
+
+		event := eventRepository.events[0]
+		if *event.ActorUserID != 1 || *event.TargetUserID != 2 || event.Reason != "ticket #42" || event.IPAddress != "203.0.113.7" {
+			t.Errorf("unexpected impersonation event %+v", event)
+		}
+
+		if event.ActorEmail != "admin@example.com" || event.TargetEmail != "user@example.com" {
+			t.Errorf("unexpected impersonation event %+v", event)
+		}
+	})
+
+	t.Run("should refuse invalid impersonations", func(t *testing.T) {
+		cases := []struct {
+			name     string
+			targetID models.UserID
+			reason   string
+			expected error
+		}{
+			{"self", 1, "testing", utils.ErrImpersonationSelf},
+			{"missing reason", 2, "  ", utils.ErrImpersonationReasonInvalid},
+			{"unknown user", 9, "testing", utils.ErrUserNotFound},
+			{"inactive user", 3, "testing", utils.ErrUserInactive},
+		}
+
+		for _, tc := range cases {
+			_, err := is.Impersonate(1, tc.targetID, tc.reason, "203.0.113.7", "test-agent")
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("%s: expected error %v, got %v", tc.name, tc.expected, err)
+			}
+		}
+
+		if len(eventRepository.events) != 1 {
+			t.Errorf("expected refused impersonations not to be recorded, got %d events", len(eventRepository.events))
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package services_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+	"github.com/pedrotunin/go-jwt-auth/internal/validators"
+)
+
+func (r *memoryUserRepository) GetUserByEmail(email string) (*models.User, error) {
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			return user, nil
+		}
+	}
+	return nil, utils.ErrUserNotFound
+}
+
+func (r *memoryUserRepository) CreateUser(u *models.User) (int, error) {
+	if _, err := r.GetUserByEmail(u.Email); err == nil {
+		return -1, utils.ErrUserEmailAlreadyExists
+	}
+
+	id := len(r.users) + 1
+	r.users[id] = &models.User{ID: id, Email: u.Email, Password: u.Password, Status: utils.UserStatusPending}
+	return id, nil
+}
+
+func (r *memoryUserRepository) ActivateUser(userID models.UserID) error {
+	r.users[userID].Status = utils.UserStatusActive
+	return nil
+}
+
+func (r *memoryRBACRepository) AssignUserRole(userID models.UserID, roleID models.RoleID) error {
+	for name, role := range r.roles {
+		if role.ID == roleID {
+			r.userRoles[userID] = append(r.userRoles[userID], name)
+		}
+	}
+	return nil
+}
+
+type memoryInvitationRepository struct {
+	repositories.InvitationRepository
+	invitations []*models.Invitation
+}
+
+func (r *memoryInvitationRepository) CreateInvitation(invitation *models.Invitation) error {
+	now := time.Now()
+	for _, previous := range r.invitations {
+		sameOrganization := (previous.OrganizationID == nil && invitation.OrganizationID == nil) ||
+			(previous.OrganizationID != nil && invitation.OrganizationID != nil && *previous.OrganizationID == *invitation.OrganizationID)
+		if strings.EqualFold(previous.Email, invitation.Email) && sameOrganization && previous.AcceptedAt == nil && previous.RevokedAt == nil {
+			previous.RevokedAt = &now
+		}
+	}
+
+	invitation.ID = len(r.invitations) + 1
+	invitation.CreatedAt = now
+	stored := *invitation
+	r.invitations = append(r.invitations, &stored)
+	return nil
+}
+
+func (r *memoryInvitationRepository) GetInvitationByTokenHash(tokenHash string) (*models.Invitation, error) {
+	for _, invitation := range r.invitations {
+		if invitation.TokenHash == tokenHash {
+			found := *invitation
+			return &found, nil
+		}
+	}
+	return nil, utils.ErrInvitationInvalid
+}
+
+func (r *memoryInvitationRepository) AcceptInvitation(id models.InvitationID, userID models.UserID) error {
+	invitation := r.invitations[id-1]
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil {
+		return utils.ErrInvitationInvalid
+	}
+
+	now := time.Now()
+	invitation.AcceptedAt = &now
+	invitation.UserID = &userID
+	return nil
+}
+
+func TestInvitationService(t *testing.T) {
+	userRepository := &memoryUserRepository{users: map[models.UserID]*models.User{
+		1: {ID: 1, Email: "admin@example.com", Status: utils.UserStatusActive},
+	}}
+	rbacRepository := &memoryRBACRepository{
+		roles: map[string]models.Role{
+			"support": {ID: 2, Name: "support", Permissions: []string{utils.PermissionUsersRead}},
+		},
+		userRoles: map[models.UserID][]string{},
+	}
+	invitationRepository := &memoryInvitationRepository{}
+	hashService := services.NewHashService(&argon2id.Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	passwordPolicyService := services.NewPasswordPolicyService(validators.PasswordPolicy{MinLength: 8, MaxLength: 128}, nil)
+
+	is := services.NewInvitationService(
+		invitationRepository,
+		userRepository,
+		rbacRepository,
+		newMemoryOrganizationRepository(),
+		nil,
+		hashService,
+		passwordPolicyService,
+		time.Hour,
+	)
+
+	t.Run("should create an active user with the invited role", func(t *testing.T) {
+		invitation, err := is.CreateInvitation(1, "new@example.com", "support")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if invitation.Invitation.Status != models.InvitationStatusPending {
+			t.Errorf("expected pending invitation, got %s", invitation.Invitation.Status)
+		}
+
+		user, err := is.AcceptInvitation(invitation.Token, "correct horse battery")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if user.Email != "new@example.com" || userRepository.users[user.ID].Status != utils.UserStatusActive {
+			t.Errorf("expected active user new@example.com, got %+v", userRepository.users[user.ID])
+		}
+		if err := hashService.ComparePassword("correct horse battery", userRepository.users[user.ID].Password); err != nil {
+			t.Errorf("expected the chosen password to be stored hashed: %s", err.Error())
+		}
+		if roles := rbacRepository.userRoles[user.ID]; len(roles) != 1 || roles[0] != "support" {
+			t.Errorf("expected role support, got %v", roles)
+		}
+
+		_, err = is.AcceptInvitation(invitation.Token, "correct horse battery")
+		if err == nil {
+			t.Error("expected the invitation to work only once")
+		}
+	})
+
+	t.Run("should refuse to invite existing users or unknown roles", func(t *testing.T) {
+		_, err := is.CreateInvitation(1, "ADMIN@example.com", "")
+		if !errors.Is(err, utils.ErrUserEmailAlreadyExists) {
+			t.Errorf("expected ErrUserEmailAlreadyExists, got %v", err)
+		}
+
+		_, err = is.CreateInvitation(1, "other@example.com", "missing")
+		if !errors.Is(err, utils.ErrRoleNotFound) {
+			t.Errorf("expected ErrRoleNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should only accept the newest invitation of an e-mail", func(t *testing.T) {
+		first, _ := is.CreateInvitation(1, "twice@example.com", "")
+		second, _ := is.CreateInvitation(1, "twice@example.com", "")
+
+		_, err := is.AcceptInvitation(first.Token, "correct horse battery")
+		if !errors.Is(err, utils.ErrInvitationInvalid) {
+			t.Errorf("expected ErrInvitationInvalid, got %v", err)
+		}
+
+		_, err = is.AcceptInvitation(second.Token, "correct horse battery")
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+	})
+
+	t.Run("should refuse expired invitations", func(t *testing.T) {
+		invitation, _ := is.CreateInvitation(1, "late@example.com", "")
+		invitationRepository.invitations[invitation.Invitation.ID-1].ExpiresAt = time.Now().Add(-time.Minute)
+
+		_, err := is.AcceptInvitation(invitation.Token, "correct horse battery")
+		if !errors.Is(err, utils.ErrInvitationExpired) {
+			t.Errorf("expected ErrInvitationExpired, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+)
+
+type memoryLoginEventRepository struct {
+	events []models.LoginEvent
+}
+
+func (r *memoryLoginEventRepository) CreateLoginEvent(event *models.LoginEvent) error {
+	event.ID = len(r.events) + 1
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func (r *memoryLoginEventRepository) GetLoginEventsByUserID(userID models.UserID, limit int, offset int) ([]models.LoginEvent, error) {
+	events := []models.LoginEvent{}
+	for i := len(r.events) - 1; i >= 0; i-- {
+		if r.events[i].UserID == userID {
+			events = append(events, r.events[i])
+		}
+	}
+
+	if offset >= len(events) {
+		return []models.LoginEvent{}, nil
+	}
+
+	return events[offset:min(offset+limit, len(events))], nil
+}
+
+func (r *memoryLoginEventRepository) CountLoginEventsByUserID(userID models.UserID) (int, error) {
+	count := 0
+	for _, event := range r.events {
+		if event.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryLoginEventRepository) GetKnownLoginDevice(userID models.UserID, ipAddress string, userAgent string) (bool, bool, error) {
+	hasLogins, known := false, false
+	for _, event := range r.events {
+		if event.UserID != userID || !event.Success {
+			continue
+		}
+
+		hasLogins = true
+		if event.IPAddress == ipAddress && event.UserAgent == userAgent {
+			known = true
+		}
+	}
+	return hasLogins, known, nil
+}
+
+func TestLoginHistoryService(t *testing.T) {
+	t.Run("should flag only logins from unseen devices after the first one", func(t *testing.T) {
+		lhs := services.NewLoginHistoryService(&memoryLoginEventRepository{})
+
+		newDevice, _ := lhs.RecordSuccess(1, "password", "10.0.0.1", "firefox")
+		if newDevice {
+			t.Errorf("expected first login not to be flagged as a new device")
+		}
+
+		newDevice, _ = lhs.RecordSuccess(1, "password", "10.0.0.1", "firefox")
+		if newDevice {
+			t.Errorf("expected login from a known device not to be flagged")
+		}
+
+		_ = lhs.RecordFailure(1, "password", "password_incorrect", "10.0.0.2", "curl")
+
+		newDevice, _ = lhs.RecordSuccess(1, "password", "10.0.0.2", "curl")
+		if !newDevice {
+			t.Errorf("expected login from an unseen device to be flagged")
+		}
+	})
+
+	t.Run("should page through the history newest first", func(t *testing.T) {
+		lhs := services.NewLoginHistoryService(&memoryLoginEventRepository{})
+
+		for i := 0; i < 5; i++ {
+			_ = lhs.RecordFailure(1, "password", "password_incorrect", "10.0.0.1", "firefox")
+		}
+		_, _ = lhs.RecordSuccess(2, "password", "10.0.0.1", "firefox")
+
+		events, total, err := lhs.GetLoginHistory(1, 2, 2)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		if total != 5 {
+			t.Errorf("expected total 5, got %d", total)
+		}
+
+		if len(events) != 2 || events[0].ID != 3 || events[1].ID != 2 {
+			t.Errorf("expected events 3 and 2 on the second page, got %v", events)
+		}
+	})
+}
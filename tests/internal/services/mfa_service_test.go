@@ -0,0 +1,61 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type memoryMFAChallengeRepository struct {
+	repositories.MFAChallengeRepository
+	failures map[string]int
+}
+
+func (r *memoryMFAChallengeRepository) GetMFAChallengeFailures(challengeID string) (int, error) {
+	return r.failures[challengeID], nil
+}
+
+func (r *memoryMFAChallengeRepository) RecordMFAChallengeFailure(challengeID string, expiresAt time.Time) (int, error) {
+	r.failures[challengeID]++
+	return r.failures[challengeID], nil
+}
+
+func (r *memoryMFAChallengeRepository) DeleteExpiredMFAChallenges(now time.Time) error {
+	return nil
+}
+
+func TestMFAServiceChallenge(t *testing.T) {
+	challengeRepository := &memoryMFAChallengeRepository{failures: map[string]int{}}
+	ms := services.NewMFAService(nil, nil, nil, challengeRepository, nil, nil, "go-jwt-auth", 3)
+	expiresAt := time.Now().Add(5 * time.Minute)
+
+	t.Run("should accept the challenge until it runs out of attempts", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			err := ms.CheckChallenge("token-1")
+			if err != nil {
+				t.Fatalf("expected challenge to be valid after %d failures, got %v", i, err)
+			}
+
+			err = ms.RecordChallengeFailure("token-1", expiresAt)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+		}
+
+		err := ms.CheckChallenge("token-1")
+		if !errors.Is(err, utils.ErrMFATokenInvalid) {
+			t.Errorf("expected ErrMFATokenInvalid, got %v", err)
+		}
+	})
+
+	t.Run("should count failures per challenge", func(t *testing.T) {
+		err := ms.CheckChallenge("token-2")
+		if err != nil {
+			t.Errorf("expected a fresh challenge to be valid, got %v", err)
+		}
+	})
+}
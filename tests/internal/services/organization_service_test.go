@@ -0,0 +1,264 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+	"github.com/pedrotunin/go-jwt-auth/internal/validators"
+)
+
+type memoryOrganizationRepository struct {
+	repositories.OrganizationRepository
+	organizations map[models.OrganizationID]*models.Organization
+	members       map[models.OrganizationID]map[models.UserID]models.OrganizationRole
+	active        map[models.UserID]models.OrganizationID
+}
+
+func newMemoryOrganizationRepository() *memoryOrganizationRepository {
+	return &memoryOrganizationRepository{
+		organizations: map[models.OrganizationID]*models.Organization{},
+		members:       map[models.OrganizationID]map[models.UserID]models.OrganizationRole{},
+		active:        map[models.UserID]models.OrganizationID{},
+	}
+}
+
+func (r *memoryOrganizationRepository) CreateOrganization(organization *models.Organization, ownerID models.UserID) error {
+	organization.ID = len(r.organizations) + 1
+	r.organizations[organization.ID] = organization
+	r.members[organization.ID] = map[models.UserID]models.OrganizationRole{ownerID: utils.OrganizationRoleOwner}
+	return nil
+}
+
+func (r *memoryOrganizationRepository) GetOrganizationMember(organizationID models.OrganizationID, userID models.UserID) (*models.OrganizationMember, error) {
+	role, ok := r.members[organizationID][userID]
+	if !ok {
+		return nil, utils.ErrOrganizationMemberNotFound
+	}
+	return &models.OrganizationMember{OrganizationID: organizationID, UserID: userID, Role: role}, nil
+}
+
+func (r *memoryOrganizationRepository) AddOrganizationMember(organizationID models.OrganizationID, userID models.UserID, role models.OrganizationRole) error {
+	if _, ok := r.members[organizationID][userID]; ok {
+		return utils.ErrOrganizationMemberAlreadyExists
+	}
+	r.members[organizationID][userID] = role
+	return nil
+}
+
+func (r *memoryOrganizationRepository) UpdateOrganizationMemberRole(organizationID models.OrganizationID, userID models.UserID, role models.OrganizationRole) error {
+	r.members[organizationID][userID] = role
+	return nil
+}
+
+func (r *memoryOrganizationRepository) RemoveOrganizationMember(organizationID models.OrganizationID, userID models.UserID) error {
+	delete(r.members[organizationID], userID)
+	return nil
+}
+
+func (r *memoryOrganizationRepository) CountOrganizationOwners(organizationID models.OrganizationID) (int, error) {
+	owners := 0
+	for _, role := range r.members[organizationID] {
+		if role == utils.OrganizationRoleOwner {
+			owners++
+		}
+	}
+	return owners, nil
+}
+
+func (r *memoryOrganizationRepository) SetActiveOrganization(userID models.UserID, organizationID *models.OrganizationID) error {
+	if organizationID == nil {
+		delete(r.active, userID)
+		return nil
+	}
+	if _, ok := r.members[*organizationID][userID]; !ok {
+		return utils.ErrOrganizationMemberNotFound
+	}
+	r.active[userID] = *organizationID
+	return nil
+}
+
+func (r *memoryOrganizationRepository) GetActiveOrganizationID(userID models.UserID) (*models.OrganizationID, error) {
+	organizationID, ok := r.active[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &organizationID, nil
+}
+
+func TestOrganizationService(t *testing.T) {
+	userRepository := &memoryUserRepository{users: map[models.UserID]*models.User{
+		1: {ID: 1, Email: "owner@example.com", Status: utils.UserStatusActive},
+		2: {ID: 2, Email: "admin@example.com", Status: utils.UserStatusActive},
+		3: {ID: 3, Email: "member@example.com", Status: utils.UserStatusActive},
+		4: {ID: 4, Email: "outsider@example.com", Status: utils.UserStatusActive},
+	}}
+	organizationRepository := newMemoryOrganizationRepository()
+	invitationRepository := &memoryInvitationRepository{}
+	hashService := services.NewHashService(&argon2id.Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	passwordPolicyService := services.NewPasswordPolicyService(validators.PasswordPolicy{MinLength: 8, MaxLength: 128}, nil)
+	invitationService := services.NewInvitationService(
+		invitationRepository,
+		userRepository,
+		&memoryRBACRepository{},
+		organizationRepository,
+		nil,
+		hashService,
+		passwordPolicyService,
+		time.Hour,
+	)
+	orgs := services.NewOrganizationService(organizationRepository, invitationService)
+
+	organization, err := orgs.CreateOrganization(1, "  Acme  ")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if organization.Name != "Acme" {
+		t.Errorf("expected trimmed name, got %q", organization.Name)
+	}
+
+	t.Run("should reject an empty name", func(t *testing.T) {
+		_, err := orgs.CreateOrganization(1, " ")
+		if !errors.Is(err, utils.ErrOrganizationNameInvalid) {
+			t.Errorf("expected ErrOrganizationNameInvalid, got %v", err)
+		}
+	})
+
+	t.Run("should invite registered and unknown e-mails alike", func(t *testing.T) {
+		registered, err := orgs.AddMember(organization.ID, 1, "admin@example.com", utils.OrganizationRoleAdmin)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		unknown, err := orgs.AddMember(organization.ID, 1, "new@example.com", utils.OrganizationRoleMember)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		for _, invitation := range []*services.InvitationToken{registered, unknown} {
+			if invitation.Invitation.OrganizationID == nil || *invitation.Invitation.OrganizationID != organization.ID {
+				t.Errorf("expected the invitation to carry organization %d", organization.ID)
+			}
+			if invitation.Invitation.Status != models.InvitationStatusPending {
+				t.Errorf("expected a pending invitation, got %s", invitation.Invitation.Status)
+			}
+		}
+
+		_, err = orgs.GetMemberRole(organization.ID, 2)
+		if !errors.Is(err, utils.ErrOrganizationNotFound) {
+			t.Errorf("expected user 2 not to join before accepting, got %v", err)
+		}
+	})
+
+	t.Run("should add registered users once they accept", func(t *testing.T) {
+		invitation, err := orgs.AddMember(organization.ID, 1, "admin@example.com", utils.OrganizationRoleAdmin)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		_, err = invitationService.JoinWithInvitation(invitation.Token, 3)
+		if !errors.Is(err, utils.ErrForbidden) {
+			t.Errorf("expected ErrForbidden for another user, got %v", err)
+		}
+
+		_, err = invitationService.JoinWithInvitation(invitation.Token, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		role, err := orgs.GetMemberRole(organization.ID, 2)
+		if err != nil || role != utils.OrganizationRoleAdmin {
+			t.Fatalf("expected user 2 to be admin, got %q, %v", role, err)
+		}
+
+		_, err = invitationService.JoinWithInvitation(invitation.Token, 2)
+		if !errors.Is(err, utils.ErrInvitationInvalid) {
+			t.Errorf("expected the invitation to work only once, got %v", err)
+		}
+
+		invitation, err = orgs.AddMember(organization.ID, 2, "member@example.com", utils.OrganizationRoleMember)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		_, err = invitationService.JoinWithInvitation(invitation.Token, 3)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	})
+
+	t.Run("should hide the organization from outsiders", func(t *testing.T) {
+		_, err := orgs.GetOrganization(organization.ID, 4)
+		if !errors.Is(err, utils.ErrOrganizationNotFound) {
+			t.Errorf("expected ErrOrganizationNotFound, got %v", err)
+		}
+
+		err = orgs.SwitchOrganization(4, &organization.ID)
+		if !errors.Is(err, utils.ErrOrganizationNotFound) {
+			t.Errorf("expected ErrOrganizationNotFound, got %v", err)
+		}
+	})
+
+	t.Run("should not let members manage members", func(t *testing.T) {
+		_, err := orgs.AddMember(organization.ID, 3, "outsider@example.com", utils.OrganizationRoleMember)
+		if !errors.Is(err, utils.ErrForbidden) {
+			t.Errorf("expected ErrForbidden, got %v", err)
+		}
+
+		err = orgs.RemoveMember(organization.ID, 3, 2)
+		if !errors.Is(err, utils.ErrForbidden) {
+			t.Errorf("expected ErrForbidden, got %v", err)
+		}
+	})
+
+	t.Run("should only let owners hand out ownership", func(t *testing.T) {
+		err := orgs.UpdateMemberRole(organization.ID, 2, 3, utils.OrganizationRoleOwner)
+		if !errors.Is(err, utils.ErrForbidden) {
+			t.Errorf("expected ErrForbidden, got %v", err)
+		}
+
+		err = orgs.UpdateMemberRole(organization.ID, 2, 1, utils.OrganizationRoleMember)
+		if !errors.Is(err, utils.ErrForbidden) {
+			t.Errorf("expected admins not to demote owners, got %v", err)
+		}
+	})
+
+	t.Run("should keep the last owner", func(t *testing.T) {
+		err := orgs.RemoveMember(organization.ID, 1, 1)
+		if !errors.Is(err, utils.ErrOrganizationLastOwner) {
+			t.Errorf("expected ErrOrganizationLastOwner, got %v", err)
+		}
+
+		err = orgs.UpdateMemberRole(organization.ID, 1, 1, utils.OrganizationRoleAdmin)
+		if !errors.Is(err, utils.ErrOrganizationLastOwner) {
+			t.Errorf("expected ErrOrganizationLastOwner, got %v", err)
+		}
+
+		err = orgs.UpdateMemberRole(organization.ID, 1, 2, utils.OrganizationRoleOwner)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		err = orgs.RemoveMember(organization.ID, 1, 1)
+		if err != nil {
+			t.Errorf("expected the owner to leave once another owner exists, got %v", err)
+		}
+	})
+
+	t.Run("should let members leave", func(t *testing.T) {
+		err := orgs.RemoveMember(organization.ID, 3, 3)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		_, err = orgs.GetMemberRole(organization.ID, 3)
+		if !errors.Is(err, utils.ErrOrganizationNotFound) {
+			t.Errorf("expected the member to be gone, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package services_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+type memoryRBACRepository struct {
+	repositories.RBACRepository
+	roles     map[string]models.Role
+	userRoles map[models.UserID][]string
+}
+
+func (r *memoryRBACRepository) GetRoleByName(name models.RoleName) (*models.Role, error) {
+	role, found := r.roles[name]
+	if !found {
+		return nil, utils.ErrRoleNotFound
+	}
+	return &role, nil
+}
+
+func (r *memoryRBACRepository) GetUserPermissions(userID models.UserID) ([]string, error) {
+	permissions := []string{}
+	for _, name := range r.userRoles[userID] {
+		permissions = append(permissions, r.roles[name].Permissions...)
+	}
+	return permissions, nil
+}
+
+type memoryUserRepository struct {
+	repositories.UserRepository
+	users map[models.UserID]*models.User
+}
+
+func (r *memoryUserRepository) GetUserByID(userID models.UserID) (*models.User, error) {
+	user, found := r.users[userID]
+	if !found {
+		return nil, utils.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func TestRBACServiceGetUserPermissions(t *testing.T) {
+	rbacRepository := &memoryRBACRepository{
+		roles: map[string]models.Role{
+			utils.RoleAdmin: {ID: 1, Name: utils.RoleAdmin, Permissions: []string{utils.PermissionUsersRead, utils.PermissionUsersWrite}},
+			"support":       {ID: 2, Name: "support", Permissions: []string{utils.PermissionUsersRead}},
+		},
+		userRoles: map[models.UserID][]string{2: {"support"}},
+	}
+	userRepository := &memoryUserRepository{users: map[models.UserID]*models.User{
+		1: {ID: 1, Email: "Root@example.com"},
+		2: {ID: 2, Email: "support@example.com"},
+	}}
+
+	rs := services.NewRBACService(rbacRepository, userRepository, []string{"root@example.com"})
+
+	t.Run("should resolve permissions from assigned roles", func(t *testing.T) {
+		permissions, err := rs.GetUserPermissions(2)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		if !reflect.DeepEqual(permissions, []string{utils.PermissionUsersRead}) {
+			t.Errorf("expected support permissions, got %v", permissions)
+		}
+	})
+
+	t.Run("should grant the admin role to bootstrap administrators", func(t *testing.T) {
+		permissions, err := rs.GetUserPermissions(1)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		if !reflect.DeepEqual(permissions, []string{utils.PermissionUsersRead, utils.PermissionUsersWrite}) {
+			t.Errorf("expected admin permissions, got %v", permissions)
+		}
+	})
+
+	t.Run("should refuse to delete the admin role", func(t *testing.T) {
+		err := rs.DeleteRole(utils.RoleAdmin)
+		if !errors.Is(err, utils.ErrRoleProtected) {
+			t.Errorf("expected ErrRoleProtected, got other: %v", err)
+		}
+	})
+}
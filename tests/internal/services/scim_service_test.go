@@ -0,0 +1,266 @@
+package services_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/repositories"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+	"github.com/pedrotunin/go-jwt-auth/internal/validators"
+)
+
+func (r *memoryUserRepository) UpdateUserStatus(userID models.UserID, status models.UserStatus) error {
+	r.users[userID].Status = status
+	return nil
+}
+
+func (r *memoryUserRepository) UpdateUserProfile(u *models.User) error {
+	r.users[u.ID].DisplayName = u.DisplayName
+	r.users[u.ID].Locale = u.Locale
+	r.users[u.ID].Timezone = u.Timezone
+	return nil
+}
+
+func (r *memoryUserRepository) UpdateUserEmail(userID models.UserID, email models.UserEmail) error {
+	if user, err := r.GetUserByEmail(email); err == nil && user.ID != userID {
+		return utils.ErrUserEmailAlreadyExists
+	}
+	r.users[userID].Email = email
+	return nil
+}
+
+func (r *memoryUserRepository) UpdateUserPassword(userID models.UserID, hash string) error {
+	r.users[userID].Password = hash
+	return nil
+}
+
+func (r *memoryRBACRepository) GetRoles() ([]models.Role, error) {
+	roles := []models.Role{}
+	for _, role := range r.roles {
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+func (r *memoryRBACRepository) CreateRole(role *models.Role) error {
+	if _, found := r.roles[role.Name]; found {
+		return utils.ErrRoleAlreadyExists
+	}
+	role.ID = len(r.roles) + 1
+	r.roles[role.Name] = *role
+	return nil
+}
+
+func (r *memoryRBACRepository) DeleteRole(id models.RoleID) error {
+	for name, role := range r.roles {
+		if role.ID == id {
+			delete(r.roles, name)
+		}
+	}
+	return nil
+}
+
+func (r *memoryRBACRepository) GetRoleMembers(roleID models.RoleID) ([]models.RoleMember, error) {
+	members := []models.RoleMember{}
+	for userID, names := range r.userRoles {
+		for _, name := range names {
+			if r.roles[name].ID == roleID {
+				members = append(members, models.RoleMember{UserID: userID})
+			}
+		}
+	}
+	return members, nil
+}
+
+func (r *memoryRBACRepository) RemoveUserRole(userID models.UserID, roleID models.RoleID) error {
+	names := []string{}
+	for _, name := range r.userRoles[userID] {
+		if r.roles[name].ID != roleID {
+			names = append(names, name)
+		}
+	}
+	r.userRoles[userID] = names
+	return nil
+}
+
+func (r *memoryRBACRepository) UpdateRoleMembers(roleID models.RoleID, added []models.UserID, removed []models.UserID) error {
+	for _, userID := range removed {
+		r.RemoveUserRole(userID, roleID)
+	}
+	for _, userID := range added {
+		r.AssignUserRole(userID, roleID)
+	}
+	return nil
+}
+
+type memoryRefreshTokenRepository struct {
+	repositories.RefreshTokenRepository
+	revoked map[models.UserID]bool
+}
+
+func (r *memoryRefreshTokenRepository) InvalidateRefreshTokensByUserID(userID models.UserID) error {
+	r.revoked[userID] = true
+	return nil
+}
+
+func TestSCIMServiceUsers(t *testing.T) {
+	userRepository := &memoryUserRepository{users: map[models.UserID]*models.User{}}
+	rbacRepository := &memoryRBACRepository{roles: map[string]models.Role{}, userRoles: map[models.UserID][]string{}}
+	refreshTokenRepository := &memoryRefreshTokenRepository{revoked: map[models.UserID]bool{}}
+	hashService := services.NewHashService(&argon2id.Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	passwordPolicyService := services.NewPasswordPolicyService(validators.PasswordPolicy{MinLength: 8, MaxLength: 128}, nil)
+	ss := services.NewSCIMService(userRepository, rbacRepository, refreshTokenRepository, hashService, passwordPolicyService)
+
+	t.Run("should provision active users without a password", func(t *testing.T) {
+		user, err := ss.CreateUser(&models.SCIMUser{UserName: " jane@example.com ", DisplayName: "Jane"})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if user.UserName != "jane@example.com" || user.Active == nil || !*user.Active || user.DisplayName != "Jane" {
+			t.Errorf("expected an active jane@example.com named Jane, got %+v", user)
+		}
+
+		_, err = ss.CreateUser(&models.SCIMUser{UserName: "jane@example.com"})
+		if !errors.Is(err, utils.ErrUserEmailAlreadyExists) {
+			t.Errorf("expected ErrUserEmailAlreadyExists, got %v", err)
+		}
+	})
+
+	t.Run("should only filter on userName", func(t *testing.T) {
+		users, total, err := ss.GetUsers(`userName eq "jane@example.com"`, 1, 100)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if total != 1 || len(users) != 1 || users[0].UserName != "jane@example.com" {
+			t.Errorf("expected to find jane@example.com, got %d users", total)
+		}
+
+		_, _, err = ss.GetUsers(`emails co "example.com"`, 1, 100)
+		if !errors.Is(err, utils.ErrSCIMFilterInvalid) {
+			t.Errorf("expected ErrSCIMFilterInvalid, got %v", err)
+		}
+	})
+
+	t.Run("should deprovision users patched inactive", func(t *testing.T) {
+		user, err := ss.PatchUser(1, []models.SCIMPatchOperation{
+			{Op: "Replace", Path: "active", Value: json.RawMessage(`"False"`)},
+			{Op: "replace", Value: json.RawMessage(`{"displayName": "Jane Doe", "name.givenName": "Jane"}`)},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if *user.Active || user.DisplayName != "Jane Doe" {
+			t.Errorf("expected an inactive Jane Doe, got %+v", user)
+		}
+		if userRepository.users[1].Status != utils.UserStatusInactive || !refreshTokenRepository.revoked[1] {
+			t.Errorf("expected the user to be inactive without sessions")
+		}
+	})
+
+	t.Run("should keep deleted users as inactive", func(t *testing.T) {
+		_, err := ss.PatchUser(1, []models.SCIMPatchOperation{{Op: "replace", Path: "active", Value: json.RawMessage(`true`)}})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		err = ss.DeprovisionUser(1)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if userRepository.users[1].Status != utils.UserStatusInactive {
+			t.Errorf("expected status %s, got %s", utils.UserStatusInactive, userRepository.users[1].Status)
+		}
+	})
+}
+
+func TestSCIMServiceGroups(t *testing.T) {
+	userRepository := &memoryUserRepository{users: map[models.UserID]*models.User{
+		1: {ID: 1, Email: "jane@example.com", Status: utils.UserStatusActive},
+		2: {ID: 2, Email: "john@example.com", Status: utils.UserStatusActive},
+	}}
+	rbacRepository := &memoryRBACRepository{
+		roles: map[string]models.Role{
+			utils.RoleAdmin: {ID: 1, Name: utils.RoleAdmin},
+			"role-manager":  {ID: 2, Name: "role-manager", Permissions: []string{utils.PermissionRolesRead, utils.PermissionRolesWrite}},
+		},
+		userRoles: map[models.UserID][]string{1: {utils.RoleAdmin}},
+	}
+	refreshTokenRepository := &memoryRefreshTokenRepository{revoked: map[models.UserID]bool{}}
+	hashService := services.NewHashService(&argon2id.Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	passwordPolicyService := services.NewPasswordPolicyService(validators.PasswordPolicy{MinLength: 8, MaxLength: 128}, nil)
+	ss := services.NewSCIMService(userRepository, rbacRepository, refreshTokenRepository, hashService, passwordPolicyService)
+
+	group, err := ss.CreateGroup(&models.SCIMGroup{
+		DisplayName: "engineering",
+		Members:     []models.SCIMGroupMember{{Value: "1"}, {Value: "2"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	roleID := rbacRepository.roles["engineering"].ID
+
+	t.Run("should assign the role to the group members", func(t *testing.T) {
+		if len(group.Members) != 2 || len(rbacRepository.userRoles[1]) != 2 || len(rbacRepository.userRoles[2]) != 1 {
+			t.Errorf("expected both users to hold the role, got %+v", group.Members)
+		}
+	})
+
+	t.Run("should remove members by filter", func(t *testing.T) {
+		group, err := ss.PatchGroup(roleID, []models.SCIMPatchOperation{{Op: "remove", Path: `members[value eq "1"]`}})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if len(group.Members) != 1 || group.Members[0].Value != "2" {
+			t.Errorf("expected only user 2 to be left, got %+v", group.Members)
+		}
+	})
+
+	t.Run("should refuse unknown members and renames", func(t *testing.T) {
+		_, err := ss.PatchGroup(roleID, []models.SCIMPatchOperation{{Op: "add", Path: "members", Value: json.RawMessage(`[{"value": "42"}]`)}})
+		if !errors.Is(err, utils.ErrSCIMGroupMemberInvalid) {
+			t.Errorf("expected ErrSCIMGroupMemberInvalid, got %v", err)
+		}
+
+		_, err = ss.ReplaceGroup(roleID, &models.SCIMGroup{DisplayName: "platform"})
+		if !errors.Is(err, utils.ErrSCIMGroupRenameNotSupported) {
+			t.Errorf("expected ErrSCIMGroupRenameNotSupported, got %v", err)
+		}
+	})
+
+	t.Run("should not change the members of privileged roles", func(t *testing.T) {
+		_, err := ss.PatchGroup(1, []models.SCIMPatchOperation{{Op: "add", Path: "members", Value: json.RawMessage(`[{"value": "2"}]`)}})
+		if !errors.Is(err, utils.ErrSCIMGroupProtected) {
+			t.Errorf("expected ErrSCIMGroupProtected, got %v", err)
+		}
+
+		_, err = ss.ReplaceGroup(1, &models.SCIMGroup{DisplayName: utils.RoleAdmin, Members: []models.SCIMGroupMember{}})
+		if !errors.Is(err, utils.ErrSCIMGroupProtected) {
+			t.Errorf("expected ErrSCIMGroupProtected, got %v", err)
+		}
+
+		_, err = ss.PatchGroup(2, []models.SCIMPatchOperation{{Op: "add", Path: "members", Value: json.RawMessage(`[{"value": "2"}]`)}})
+		if !errors.Is(err, utils.ErrSCIMGroupProtected) {
+			t.Errorf("expected ErrSCIMGroupProtected for a role granting roles:write, got %v", err)
+		}
+
+		_, err = ss.ReplaceGroup(1, &models.SCIMGroup{DisplayName: utils.RoleAdmin, Members: []models.SCIMGroupMember{{Value: "1"}}})
+		if err != nil {
+			t.Errorf("expected a replace that keeps the members to succeed, got %v", err)
+		}
+
+		if roles := rbacRepository.userRoles[2]; len(roles) != 1 || roles[0] != "engineering" {
+			t.Errorf("expected user 2 to only hold engineering, got %v", roles)
+		}
+	})
+
+	t.Run("should not delete the admin role", func(t *testing.T) {
+		err := ss.DeleteGroup(1)
+		if !errors.Is(err, utils.ErrRoleProtected) {
+			t.Errorf("expected ErrRoleProtected, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,488 @@
+package services_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/pedrotunin/go-jwt-auth/internal/models"
+	"github.com/pedrotunin/go-jwt-auth/internal/services"
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost:8080"
+)
+
+type memoryWebAuthnCredentialRepository struct {
+	credentials []models.WebAuthnCredential
+}
+
+func (r *memoryWebAuthnCredentialRepository) CreateWebAuthnCredential(credential *models.WebAuthnCredential) error {
+	for _, c := range r.credentials {
+		if c.CredentialID == credential.CredentialID {
+			return utils.ErrWebAuthnCredentialAlreadyRegistered
+		}
+	}
+
+	credential.ID = len(r.credentials) + 1
+	r.credentials = append(r.credentials, *credential)
+	return nil
+}
+
+func (r *memoryWebAuthnCredentialRepository) GetWebAuthnCredentialByCredentialID(credentialID string) (*models.WebAuthnCredential, error) {
+	for _, c := range r.credentials {
+		if c.CredentialID == credentialID {
+			return &c, nil
+		}
+	}
+
+	return nil, utils.ErrWebAuthnCredentialNotFound
+}
+
+func (r *memoryWebAuthnCredentialRepository) GetWebAuthnCredentialsByUserID(userID models.UserID) ([]models.WebAuthnCredential, error) {
+	var credentials []models.WebAuthnCredential
+	for _, c := range r.credentials {
+		if c.UserID == userID {
+			credentials = append(credentials, c)
+		}
+	}
+
+	return credentials, nil
+}
+
+func (r *memoryWebAuthnCredentialRepository) UpdateWebAuthnSignCount(id models.WebAuthnCredentialID, signCount uint32) error {
+	for i := range r.credentials {
+		if r.credentials[i].ID == id {
+			r.credentials[i].SignCount = signCount
+			return nil
+		}
+	}
+
+	return utils.ErrWebAuthnCredentialNotFound
+}
+
+func (r *memoryWebAuthnCredentialRepository) DeleteWebAuthnCredential(id models.WebAuthnCredentialID, userID models.UserID) error {
+	for i, c := range r.credentials {
+		if c.ID == id && c.UserID == userID {
+			r.credentials = append(r.credentials[:i], r.credentials[i+1:]...)
+			return nil
+		}
+	}
+
+	return utils.ErrWebAuthnCredentialNotFound
+}
+
+type memoryWebAuthnChallengeRepository struct {
+	challenges map[string]models.WebAuthnChallenge
+}
+
+func (r *memoryWebAuthnChallengeRepository) CreateWebAuthnChallenge(challenge *models.WebAuthnChallenge) error {
+	r.challenges[challenge.Challenge] = *challenge
+	return nil
+}
+
+func (r *memoryWebAuthnChallengeRepository) ConsumeWebAuthnChallenge(challenge string, ceremony models.WebAuthnCeremony) (*models.WebAuthnChallenge, error) {
+	c, ok := r.challenges[challenge]
+	if !ok || c.Ceremony != ceremony {
+		return nil, utils.ErrWebAuthnChallengeInvalid
+	}
+
+	delete(r.challenges, challenge)
+	return &c, nil
+}
+
+// cborPair is a map entry for the minimal CBOR encoder below, which covers
+// what an authenticator emits.
+type cborPair struct {
+	key   any
+	value any
+}
+
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n <= 0xff:
+		return []byte{major<<5 | 24, byte(n)}
+	default:
+		out := []byte{major<<5 | 25, 0, 0}
+		binary.BigEndian.PutUint16(out[1:], uint16(n))
+		return out
+	}
+}
+
+func cborEncode(value any) []byte {
+	switch v := value.(type) {
+	case int:
+		if v < 0 {
+			return cborHead(1, uint64(-1-v))
+		}
+		return cborHead(0, uint64(v))
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case []cborPair:
+		out := cborHead(5, uint64(len(v)))
+		for _, pair := range v {
+			out = append(out, cborEncode(pair.key)...)
+			out = append(out, cborEncode(pair.value)...)
+		}
+		return out
+	default:
+		panic("unsupported cbor value")
+	}
+}
+
+// softwareAuthenticator is an ES256 authenticator kept in memory.
+type softwareAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+	userHandle   []byte
+}
+
+func newSoftwareAuthenticator(t *testing.T) *softwareAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("expected no error, got one: %s", err.Error())
+	}
+
+	credentialID := make([]byte, 16)
+	rand.Read(credentialID)
+
+	return &softwareAuthenticator{key: key, credentialID: credentialID}
+}
+
+func (a *softwareAuthenticator) authenticatorData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+func clientDataJSON(ceremonyType, challenge, origin string) []byte {
+	data, _ := json.Marshal(map[string]string{
+		"type":      ceremonyType,
+		"challenge": challenge,
+		"origin":    origin,
+	})
+	return data
+}
+
+func (a *softwareAuthenticator) create(options *services.WebAuthnCreationOptions, origin string) *services.WebAuthnAttestation {
+	userHandle, _ := base64.RawURLEncoding.DecodeString(options.User.ID)
+	a.userHandle = userHandle
+
+	x := a.key.PublicKey.X.FillBytes(make([]byte, 32))
+	y := a.key.PublicKey.Y.FillBytes(make([]byte, 32))
+	coseKey := cborEncode([]cborPair{{1, 2}, {3, -7}, {-1, 1}, {-2, x}, {-3, y}})
+
+	attested := make([]byte, 16)
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, coseKey...)
+
+	attestationObject := cborEncode([]cborPair{
+		{"fmt", "none"},
+		{"attStmt", []cborPair{}},
+		{"authData", a.authenticatorData(0x45, attested)},
+	})
+
+	return &services.WebAuthnAttestation{
+		ClientDataJSON:    clientDataJSON("webauthn.create", options.Challenge, origin),
+		AttestationObject: attestationObject,
+	}
+}
+
+func (a *softwareAuthenticator) get(t *testing.T, options *services.WebAuthnRequestOptions, flags byte) *services.WebAuthnAssertion {
+	a.signCount++
+
+	authData := a.authenticatorData(flags, nil)
+	clientData := clientDataJSON("webauthn.get", options.Challenge, testOrigin)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("expected no error, got one: %s", err.Error())
+	}
+
+	return &services.WebAuthnAssertion{
+		CredentialID:      a.credentialID,
+		ClientDataJSON:    clientData,
+		AuthenticatorData: authData,
+		Signature:         signature,
+		UserHandle:        a.userHandle,
+	}
+}
+
+// newTestWebAuthnService builds the service for user 1, whose password is
+// "password123".
+func newTestWebAuthnService() services.IWebAuthnService {
+	hashService := services.NewHashService(&argon2id.Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	hash, _ := hashService.HashArgon2id("password123")
+
+	return services.NewWebAuthnService(
+		&memoryWebAuthnCredentialRepository{},
+		&memoryWebAuthnChallengeRepository{challenges: map[string]models.WebAuthnChallenge{}},
+		&memoryUserRepository{users: map[models.UserID]*models.User{
+			1: {ID: 1, Email: "user@example.com", Password: hash},
+		}},
+		hashService,
+		nil,
+		services.WebAuthnConfig{
+			RPID:         testRPID,
+			RPName:       "go-jwt-auth",
+			Origin:       testOrigin,
+			ChallengeTTL: time.Minute,
+		},
+	)
+}
+
+// registerAuthenticator runs a full registration ceremony for user 1.
+func registerAuthenticator(t *testing.T, ws services.IWebAuthnService) *softwareAuthenticator {
+	user := &models.User{ID: 1, Email: "user@example.com"}
+	authenticator := newSoftwareAuthenticator(t)
+
+	options, err := ws.BeginRegistration(user, services.WebAuthnReauthentication{Password: "password123"})
+	if err != nil {
+		t.Fatalf("expected no error, got one: %s", err.Error())
+	}
+
+	_, err = ws.FinishRegistration(user.ID, "laptop", authenticator.create(options, testOrigin))
+	if err != nil {
+		t.Fatalf("expected no error, got one: %s", err.Error())
+	}
+
+	return authenticator
+}
+
+func TestWebAuthnRegistration(t *testing.T) {
+	t.Run("should register a credential", func(t *testing.T) {
+		ws := newTestWebAuthnService()
+		registerAuthenticator(t, ws)
+
+		credentials, err := ws.GetCredentials(1)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		if len(credentials) != 1 || credentials[0].Name != "laptop" {
+			t.Errorf("expected one credential named laptop, got %+v", credentials)
+		}
+	})
+
+	t.Run("should require the password to start a registration", func(t *testing.T) {
+		ws := newTestWebAuthnService()
+		user := &models.User{ID: 1, Email: "user@example.com"}
+
+		_, err := ws.BeginRegistration(user, services.WebAuthnReauthentication{})
+		if !errors.Is(err, utils.ErrReauthenticationRequired) {
+			t.Errorf("expected %v, got %v", utils.ErrReauthenticationRequired, err)
+		}
+
+		_, err = ws.BeginRegistration(user, services.WebAuthnReauthentication{Password: "wrong-password"})
+		if !errors.Is(err, utils.ErrPasswordIncorrect) {
+			t.Errorf("expected %v, got %v", utils.ErrPasswordIncorrect, err)
+		}
+
+		credentials, err := ws.GetCredentials(user.ID)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		if len(credentials) != 0 {
+			t.Errorf("expected no credentials, got %+v", credentials)
+		}
+	})
+
+	t.Run("should reject a different origin", func(t *testing.T) {
+		ws := newTestWebAuthnService()
+		user := &models.User{ID: 1, Email: "user@example.com"}
+
+		options, err := ws.BeginRegistration(user, services.WebAuthnReauthentication{Password: "password123"})
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		attestation := newSoftwareAuthenticator(t).create(options, "https://evil.example.com")
+
+		_, err = ws.FinishRegistration(user.ID, "laptop", attestation)
+		if !errors.Is(err, utils.ErrWebAuthnVerificationFailed) {
+			t.Errorf("expected %v, got %v", utils.ErrWebAuthnVerificationFailed, err)
+		}
+	})
+
+	t.Run("should reject a challenge issued to another user", func(t *testing.T) {
+		ws := newTestWebAuthnService()
+
+		options, err := ws.BeginRegistration(&models.User{ID: 1, Email: "user@example.com"}, services.WebAuthnReauthentication{Password: "password123"})
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		_, err = ws.FinishRegistration(2, "laptop", newSoftwareAuthenticator(t).create(options, testOrigin))
+		if !errors.Is(err, utils.ErrWebAuthnChallengeInvalid) {
+			t.Errorf("expected %v, got %v", utils.ErrWebAuthnChallengeInvalid, err)
+		}
+	})
+}
+
+func TestWebAuthnLogin(t *testing.T) {
+	t.Run("should log in without a password", func(t *testing.T) {
+		ws := newTestWebAuthnService()
+		authenticator := registerAuthenticator(t, ws)
+
+		options, err := ws.BeginLogin(nil)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		userID, err := ws.FinishLogin(nil, authenticator.get(t, options, 0x05))
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		if userID != 1 {
+			t.Errorf("expected user 1, got %d", userID)
+		}
+	})
+
+	t.Run("should require user verification without a password", func(t *testing.T) {
+		ws := newTestWebAuthnService()
+		authenticator := registerAuthenticator(t, ws)
+
+		options, _ := ws.BeginLogin(nil)
+
+		_, err := ws.FinishLogin(nil, authenticator.get(t, options, 0x01))
+		if !errors.Is(err, utils.ErrWebAuthnVerificationFailed) {
+			t.Errorf("expected %v, got %v", utils.ErrWebAuthnVerificationFailed, err)
+		}
+	})
+
+	t.Run("should work as a second factor", func(t *testing.T) {
+		ws := newTestWebAuthnService()
+		authenticator := registerAuthenticator(t, ws)
+		userID := models.UserID(1)
+
+		options, err := ws.BeginLogin(&userID)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		if len(options.AllowCredentials) != 1 {
+			t.Errorf("expected 1 allowed credential, got %d", len(options.AllowCredentials))
+		}
+
+		_, err = ws.FinishLogin(&userID, authenticator.get(t, options, 0x01))
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+	})
+
+	t.Run("should reject a replayed assertion", func(t *testing.T) {
+		ws := newTestWebAuthnService()
+		authenticator := registerAuthenticator(t, ws)
+
+		options, _ := ws.BeginLogin(nil)
+		assertion := authenticator.get(t, options, 0x05)
+
+		_, err := ws.FinishLogin(nil, assertion)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		_, err = ws.FinishLogin(nil, assertion)
+		if !errors.Is(err, utils.ErrWebAuthnChallengeInvalid) {
+			t.Errorf("expected %v, got %v", utils.ErrWebAuthnChallengeInvalid, err)
+		}
+	})
+
+	t.Run("should reject a sign count that did not increase", func(t *testing.T) {
+		ws := newTestWebAuthnService()
+		authenticator := registerAuthenticator(t, ws)
+
+		options, _ := ws.BeginLogin(nil)
+		_, err := ws.FinishLogin(nil, authenticator.get(t, options, 0x05))
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		authenticator.signCount--
+
+		options, _ = ws.BeginLogin(nil)
+		_, err = ws.FinishLogin(nil, authenticator.get(t, options, 0x05))
+		if !errors.Is(err, utils.ErrWebAuthnVerificationFailed) {
+			t.Errorf("expected %v, got %v", utils.ErrWebAuthnVerificationFailed, err)
+		}
+	})
+
+	t.Run("should reject an invalid signature", func(t *testing.T) {
+		ws := newTestWebAuthnService()
+		authenticator := registerAuthenticator(t, ws)
+
+		options, _ := ws.BeginLogin(nil)
+		assertion := authenticator.get(t, options, 0x05)
+		assertion.Signature[len(assertion.Signature)-1] ^= 0xff
+
+		_, err := ws.FinishLogin(nil, assertion)
+		if !errors.Is(err, utils.ErrWebAuthnVerificationFailed) {
+			t.Errorf("expected %v, got %v", utils.ErrWebAuthnVerificationFailed, err)
+		}
+	})
+}
+
+func TestWebAuthnDeleteCredential(t *testing.T) {
+	t.Run("should require the password or a totp code", func(t *testing.T) {
+		ws := newTestWebAuthnService()
+		registerAuthenticator(t, ws)
+
+		err := ws.DeleteCredential(1, 1, services.WebAuthnReauthentication{})
+		if !errors.Is(err, utils.ErrReauthenticationRequired) {
+			t.Errorf("expected %v, got %v", utils.ErrReauthenticationRequired, err)
+		}
+
+		credentials, _ := ws.GetCredentials(1)
+		if len(credentials) != 1 {
+			t.Errorf("expected the credential to be kept, got %d credentials", len(credentials))
+		}
+	})
+
+	t.Run("should reject a wrong password", func(t *testing.T) {
+		ws := newTestWebAuthnService()
+		registerAuthenticator(t, ws)
+
+		err := ws.DeleteCredential(1, 1, services.WebAuthnReauthentication{Password: "wrong-password"})
+		if !errors.Is(err, utils.ErrPasswordIncorrect) {
+			t.Errorf("expected %v, got %v", utils.ErrPasswordIncorrect, err)
+		}
+	})
+
+	t.Run("should delete the credential with the password", func(t *testing.T) {
+		ws := newTestWebAuthnService()
+		registerAuthenticator(t, ws)
+
+		err := ws.DeleteCredential(1, 1, services.WebAuthnReauthentication{Password: "password123"})
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		credentials, _ := ws.GetCredentials(1)
+		if len(credentials) != 0 {
+			t.Errorf("expected no credentials, got %d", len(credentials))
+		}
+	})
+}
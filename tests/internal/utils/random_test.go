@@ -0,0 +1,28 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+func TestGetRandomDigits(t *testing.T) {
+	t.Run("should return only digits with the requested length", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			code, err := utils.GetRandomDigits(6)
+			if err != nil {
+				t.Fatalf("expected no error, got one: %s", err.Error())
+			}
+
+			if len(code) != 6 {
+				t.Fatalf("expected 6 digits, got %q", code)
+			}
+
+			for _, r := range code {
+				if r < '0' || r > '9' {
+					t.Fatalf("expected only digits, got %q", code)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package utils_test
+
+import (
+	"encoding/base32"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+)
+
+// RFC 6238 appendix B secret for SHA-1.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestGetHOTPCode(t *testing.T) {
+	t.Run("should match RFC 6238 test vectors", func(t *testing.T) {
+		vectors := map[int64]string{
+			59:         "287082",
+			1111111109: "081804",
+			1111111111: "050471",
+			1234567890: "005924",
+			2000000000: "279037",
+		}
+
+		for unix, expected := range vectors {
+			code, err := utils.GetHOTPCode(rfcSecret, utils.GetTOTPCounter(time.Unix(unix, 0)))
+			if err != nil {
+				t.Fatalf("expected no error, got one: %s", err.Error())
+			}
+
+			if code != expected {
+				t.Errorf("time %d: expected %s, got %s", unix, expected, code)
+			}
+		}
+	})
+}
+
+func TestValidateTOTPCode(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	t.Run("should accept code from previous step", func(t *testing.T) {
+		code, _ := utils.GetHOTPCode(rfcSecret, utils.GetTOTPCounter(now)-1)
+
+		counter, err := utils.ValidateTOTPCode(rfcSecret, code, now)
+		if err != nil {
+			t.Fatalf("expected no error, got one: %s", err.Error())
+		}
+
+		if counter != utils.GetTOTPCounter(now)-1 {
+			t.Errorf("expected matched counter to be the previous step")
+		}
+	})
+
+	t.Run("should reject code outside the window", func(t *testing.T) {
+		code, _ := utils.GetHOTPCode(rfcSecret, utils.GetTOTPCounter(now)-5)
+
+		_, err := utils.ValidateTOTPCode(rfcSecret, code, now)
+		if !errors.Is(err, utils.ErrMFACodeInvalid) {
+			t.Errorf("expected ErrMFACodeInvalid, got other: %v", err)
+		}
+	})
+}
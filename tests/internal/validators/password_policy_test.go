@@ -0,0 +1,90 @@
+package validators_test
+
+import (
+	"testing"
+
+	"github.com/pedrotunin/go-jwt-auth/internal/utils"
+	"github.com/pedrotunin/go-jwt-auth/internal/validators"
+)
+
+func violationCodes(violations []utils.PasswordViolation) map[string]bool {
+	codes := map[string]bool{}
+	for _, v := range violations {
+		codes[v.Code] = true
+	}
+	return codes
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := validators.PasswordPolicy{
+		MinLength:     8,
+		MaxLength:     16,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		MinStrength:   2,
+	}
+
+	t.Run("should accept a password following every rule", func(t *testing.T) {
+		violations := policy.Check("Tr0ub4dor&3x", "user@example.com")
+		if len(violations) != 0 {
+			t.Errorf("expected no violations, got %+v", violations)
+		}
+	})
+
+	t.Run("should return every violation at once", func(t *testing.T) {
+		codes := violationCodes(policy.Check("aaaa", "user@example.com"))
+
+		for _, code := range []string{
+			utils.PasswordViolationTooShort,
+			utils.PasswordViolationMissingUpper,
+			utils.PasswordViolationMissingDigit,
+			utils.PasswordViolationMissingSymbol,
+			utils.PasswordViolationTooWeak,
+		} {
+			if !codes[code] {
+				t.Errorf("expected violation %s, got %v", code, codes)
+			}
+		}
+
+		if codes[utils.PasswordViolationMissingLower] {
+			t.Errorf("did not expect violation %s", utils.PasswordViolationMissingLower)
+		}
+	})
+
+	t.Run("should count characters instead of bytes", func(t *testing.T) {
+		codes := violationCodes(policy.Check("Çãõ1!Éüñ", ""))
+		if codes[utils.PasswordViolationTooShort] {
+			t.Error("expected 8 multi-byte characters to be long enough")
+		}
+
+		codes = violationCodes(policy.Check("Çãõ1!Éüñ€€€€€€€€€", ""))
+		if !codes[utils.PasswordViolationTooLong] {
+			t.Error("expected 17 characters to be too long")
+		}
+	})
+
+	t.Run("should reject passwords containing the e-mail", func(t *testing.T) {
+		codes := violationCodes(policy.Check("JohnDoe!2024x", "johndoe@example.com"))
+		if !codes[utils.PasswordViolationContainsEmail] {
+			t.Errorf("expected violation %s, got %v", utils.PasswordViolationContainsEmail, codes)
+		}
+	})
+}
+
+func TestEstimatePasswordStrength(t *testing.T) {
+	t.Run("should score sequences and repetitions low", func(t *testing.T) {
+		for _, password := range []string{"", "aaaaaaaaaaaa", "abcdefghijkl", "123456789"} {
+			if score := validators.EstimatePasswordStrength(password); score != 0 {
+				t.Errorf("%q: expected score 0, got %d", password, score)
+			}
+		}
+	})
+
+	t.Run("should score long mixed passwords high", func(t *testing.T) {
+		if score := validators.EstimatePasswordStrength("x7#Kp9!qLm2$Vw"); score < 3 {
+			t.Errorf("expected score of at least 3, got %d", score)
+		}
+	})
+}